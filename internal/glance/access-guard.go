@@ -0,0 +1,102 @@
+package glance
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A shared secret required for every request, as an alternative to the users
+// of the auth section for setups which don't need sessions or a login page
+type accessGuardOptions struct {
+	Enabled  bool   `yaml:"enabled"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+}
+
+func (o *accessGuardOptions) validate() error {
+	if !o.Enabled {
+		return nil
+	}
+
+	if (o.Username == "") != (o.Password == "") {
+		return errors.New("server guard username and password must be set together")
+	}
+
+	if o.Password == "" && o.Token == "" {
+		return errors.New("server guard requires a username and password, a token or both")
+	}
+
+	return nil
+}
+
+// Requests which don't provide either the credentials or the token get a 401,
+// except for the health check so that it keeps working for container runtimes
+// and for webhooks, which are guarded by their own tokens. When a limiter is
+// given, each 401 counts towards the limit of the client regardless of the
+// path, and clients over it don't get their credentials checked at all
+func guardRequests(options *accessGuardOptions, limiter *clientRateLimiter, addressOf func(*http.Request) string, next http.Handler) http.Handler {
+	// comparing hashes rather than the values themselves means that the
+	// comparisons take the same time regardless of the length of the input
+	username := sha256.Sum256([]byte(options.Username))
+	password := sha256.Sum256([]byte(options.Password))
+	token := sha256.Sum256([]byte(options.Token))
+	allowBasic := options.Password != ""
+	allowToken := options.Token != ""
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/api/healthz" || strings.HasPrefix(r.URL.Path, "/hooks/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if limiter != nil {
+			if delay := limiter.delay(addressOf(r), time.Now()); delay > 0 {
+				writeTooManyRequests(w, delay)
+				return
+			}
+		}
+
+		authorized := false
+
+		if allowBasic {
+			if givenUsername, givenPassword, ok := r.BasicAuth(); ok {
+				usernameHash := sha256.Sum256([]byte(givenUsername))
+				passwordHash := sha256.Sum256([]byte(givenPassword))
+				// both get compared even if the username doesn't match
+				usernameMatches := subtle.ConstantTimeCompare(usernameHash[:], username[:])
+				passwordMatches := subtle.ConstantTimeCompare(passwordHash[:], password[:])
+				authorized = usernameMatches&passwordMatches == 1
+			}
+		}
+
+		if !authorized && allowToken {
+			header := r.Header.Get("Authorization")
+			if scheme, givenToken, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+				tokenHash := sha256.Sum256([]byte(strings.TrimSpace(givenToken)))
+				authorized = subtle.ConstantTimeCompare(tokenHash[:], token[:]) == 1
+			}
+		}
+
+		if authorized {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if limiter != nil {
+			limiter.reserve(addressOf(r), time.Now())
+		}
+
+		if allowBasic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Glance", charset="UTF-8"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Glance"`)
+		}
+
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
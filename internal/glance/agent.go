@@ -0,0 +1,137 @@
+package glance
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/glanceapp/glance/pkg/sysinfo"
+)
+
+// Same as the default port of the standalone agent
+const defaultAgentAddress = ":27973"
+
+const agentTokenEnvVar = "GLANCE_AGENT_TOKEN"
+
+// Serves the stats of the machine it runs on through the same API as the
+// standalone agent, so that the server-stats widget of another instance
+// can display them as a remote server
+func cliAgent(args []string) int {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("Usage: glance agent [options]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+
+	address := flags.String("address", defaultAgentAddress, "Address to listen on")
+	token := flags.String("token", "", "Token that requests must include, defaults to the "+agentTokenEnvVar+" environment variable")
+	cpuTempSensor := flags.String("cpu-temp-sensor", "", "Sensor to read the CPU temperature from, see sensors:print")
+	mountpoints := flags.String("mountpoints", "", "Comma separated list of mountpoints to report, all of them are reported by default")
+	flags.Parse(args)
+
+	if *token == "" {
+		*token = os.Getenv(agentTokenEnvVar)
+	}
+
+	if *token == "" {
+		log.Println("No token set, the stats will be available to anyone who can reach the agent")
+	}
+
+	request := &sysinfo.SystemInfoRequest{CPUTempSensor: *cpuTempSensor}
+	if *mountpoints != "" {
+		request.Mountpoints.ByPath = make(map[string]sysinfo.MointpointRequest)
+		for _, path := range strings.Split(*mountpoints, ",") {
+			path = strings.TrimSpace(path)
+			if path == "" {
+				continue
+			}
+
+			request.Mountpoints.ByPath[path] = sysinfo.MointpointRequest{Path: path}
+			request.Mountpoints.Listed = append(request.Mountpoints.Listed, path)
+		}
+	}
+
+	server := &http.Server{
+		Addr:         *address,
+		Handler:      agentHandler(*token, request),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting agent on %s", *address)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	signals, stopListeningForSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopListeningForSignals()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Failed to start agent: %v\n", err)
+			return 1
+		}
+	case <-signals.Done():
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+
+	return 0
+}
+
+func agentHandler(token string, request *sysinfo.SystemInfoRequest) http.Handler {
+	// collecting the CPU usage samples the times since the previous
+	// collection, which concurrent requests would interfere with
+	var collectMu sync.Mutex
+	tokenHash := sha256.Sum256([]byte(token))
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("GET /api/sysinfo/all", func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			provided, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			providedHash := sha256.Sum256([]byte(provided))
+
+			if subtle.ConstantTimeCompare(providedHash[:], tokenHash[:]) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="glance agent"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		collectMu.Lock()
+		info, errs := sysinfo.Collect(request)
+		collectMu.Unlock()
+
+		for i := range errs {
+			slog.Warn("Getting system info: " + errs[i].Error())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(info)
+	})
+
+	return mux
+}
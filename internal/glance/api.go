@@ -0,0 +1,220 @@
+package glance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Widgets along with the page they're on, since reading their data
+// requires holding the lock of the page
+type pageWidget struct {
+	page   *page
+	widget widget
+}
+
+type containerWidget interface {
+	children() widgets
+}
+
+func (a *application) indexWidgetsByStableID() error {
+	a.widgetByStableID = make(map[string]pageWidget)
+
+	var index func(*page, widgets) error
+	index = func(p *page, ws widgets) error {
+		for _, w := range ws {
+			id := w.stableID()
+			if existing, exists := a.widgetByStableID[id]; !exists {
+				a.widgetByStableID[id] = pageWidget{page: p, widget: w}
+			} else if existing.widget.GetType() != "to-do" || w.GetType() != "to-do" {
+				// to-do lists are allowed to share an ID since that's how
+				// the same list gets shown in multiple places
+				return fmt.Errorf("widget id \"%s\" is used by more than one widget (on pages %s and %s)", id, existing.page.Title, p.Title)
+			}
+
+			if container, ok := w.(containerWidget); ok {
+				if err := index(p, container.children()); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+
+		if err := index(page, page.HeadWidgets); err != nil {
+			return err
+		}
+
+		for c := range page.Columns {
+			if err := index(page, page.Columns[c].Widgets); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Implemented by widgets which refer to other widgets by their id, which
+// can only be looked up once all of the widgets have been created
+type referencingWidget interface {
+	resolveReferences(lookup func(id string) (widget, bool)) error
+}
+
+func (a *application) resolveWidgetReferences() error {
+	lookup := func(id string) (widget, bool) {
+		entry, exists := a.widgetByStableID[id]
+		return entry.widget, exists
+	}
+
+	for _, entry := range a.widgetByStableID {
+		referencing, ok := entry.widget.(referencingWidget)
+		if !ok {
+			continue
+		}
+
+		if err := referencing.resolveReferences(lookup); err != nil {
+			return formatWidgetInitError(err, entry.widget)
+		}
+	}
+
+	return nil
+}
+
+type apiWidgetSummary struct {
+	ID      string             `json:"id"`
+	Type    string             `json:"type"`
+	Title   string             `json:"title"`
+	Widgets []apiWidgetSummary `json:"widgets,omitempty"`
+}
+
+type apiColumn struct {
+	Size    string             `json:"size"`
+	Widgets []apiWidgetSummary `json:"widgets"`
+}
+
+type apiPage struct {
+	Name        string             `json:"name"`
+	Slug        string             `json:"slug"`
+	HeadWidgets []apiWidgetSummary `json:"head_widgets"`
+	Columns     []apiColumn        `json:"columns"`
+}
+
+func newAPIWidgetSummaries(ws widgets) []apiWidgetSummary {
+	summaries := make([]apiWidgetSummary, 0, len(ws))
+
+	for _, w := range ws {
+		summary := apiWidgetSummary{
+			ID:    w.stableID(),
+			Type:  w.GetType(),
+			Title: w.getTitle(),
+		}
+
+		if container, ok := w.(containerWidget); ok {
+			summary.Widgets = newAPIWidgetSummaries(container.children())
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+func (a *application) handleAPIPagesRequest(w http.ResponseWriter, r *http.Request) {
+	if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+		return
+	}
+
+	pages := make([]apiPage, 0, len(a.Config.Pages))
+
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+
+		columns := make([]apiColumn, 0, len(page.Columns))
+		for c := range page.Columns {
+			columns = append(columns, apiColumn{
+				Size:    page.Columns[c].Size,
+				Widgets: newAPIWidgetSummaries(page.Columns[c].Widgets),
+			})
+		}
+
+		pages = append(pages, apiPage{
+			Name:        page.Title,
+			Slug:        page.Slug,
+			HeadWidgets: newAPIWidgetSummaries(page.HeadWidgets),
+			Columns:     columns,
+		})
+	}
+
+	writeAPIResponse(w, struct {
+		Pages []apiPage `json:"pages"`
+	}{pages})
+}
+
+type apiWidget struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Error      string `json:"error,omitempty"`
+	StaleSince *int64 `json:"stale_since,omitempty"`
+	// null for widgets which don't expose their data
+	Data any `json:"data"`
+}
+
+// Only ever returns the data which the widget currently has, if the page
+// that the widget is on hasn't been visited yet there may be none
+func (a *application) handleAPIWidgetRequest(w http.ResponseWriter, r *http.Request) {
+	if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+		return
+	}
+
+	entry, exists := a.widgetByStableID[r.PathValue("id")]
+	if !exists {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"widget not found"}`))
+		return
+	}
+
+	// the data gets encoded while holding the lock
+	// since the widget could otherwise be updating
+	entry.page.mu.Lock()
+	defer entry.page.mu.Unlock()
+
+	response := apiWidget{
+		ID:    entry.widget.stableID(),
+		Type:  entry.widget.GetType(),
+		Title: entry.widget.getTitle(),
+	}
+
+	if err := entry.widget.updateError(); err != nil {
+		response.Error = err.Error()
+	}
+
+	if staleSince := entry.widget.StaleSince(); !staleSince.IsZero() {
+		unix := staleSince.Unix()
+		response.StaleSince = &unix
+	}
+
+	if withData, ok := entry.widget.(dataModelWidget); ok {
+		response.Data = withData.dataModel()
+	}
+
+	writeAPIResponse(w, response)
+}
+
+func writeAPIResponse(w http.ResponseWriter, response any) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(encoded)
+}
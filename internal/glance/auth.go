@@ -30,10 +30,10 @@ const AUTH_SECRET_KEY_LENGTH = AUTH_TOKEN_SECRET_LENGTH + AUTH_USERNAME_HASH_LEN
 const AUTH_TIMESTAMP_LENGTH = 4 // uint32
 const AUTH_TOKEN_DATA_LENGTH = AUTH_USERNAME_HASH_LENGTH + AUTH_TIMESTAMP_LENGTH
 
-// How long the token will be valid for
+// How long the token will be valid for unless changed through auth.session-lifetime
 const AUTH_TOKEN_VALID_PERIOD = 14 * 24 * time.Hour // 14 days
-// How long the token has left before it should be regenerated
-const AUTH_TOKEN_REGEN_BEFORE = 7 * 24 * time.Hour // 7 days
+// How long the token has left before it should be regenerated, always half of the lifetime
+const AUTH_TOKEN_REGEN_BEFORE = AUTH_TOKEN_VALID_PERIOD / 2
 
 var loginPageTemplate = mustParseTemplate("login.html", "document.html", "footer.html")
 
@@ -49,7 +49,7 @@ type failedAuthAttempt struct {
 	first    time.Time
 }
 
-func generateSessionToken(username string, secret []byte, now time.Time) (string, error) {
+func generateSessionToken(username string, secret []byte, now time.Time, validFor time.Duration) (string, error) {
 	if len(secret) != AUTH_SECRET_KEY_LENGTH {
 		return "", fmt.Errorf("secret key length is not %d bytes", AUTH_SECRET_KEY_LENGTH)
 	}
@@ -61,7 +61,7 @@ func generateSessionToken(username string, secret []byte, now time.Time) (string
 
 	data := make([]byte, AUTH_TOKEN_DATA_LENGTH)
 	copy(data, usernameHash)
-	expires := now.Add(AUTH_TOKEN_VALID_PERIOD).Unix()
+	expires := now.Add(validFor).Unix()
 	binary.LittleEndian.PutUint32(data[AUTH_USERNAME_HASH_LENGTH:], uint32(expires))
 
 	h := hmac.New(sha256.New, secret[0:AUTH_TOKEN_SECRET_LENGTH])
@@ -85,7 +85,7 @@ func computeUsernameHash(username string, secret []byte) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
-func verifySessionToken(token string, secretBytes []byte, now time.Time) ([]byte, bool, error) {
+func verifySessionToken(token string, secretBytes []byte, now time.Time, regenerateBefore time.Duration) ([]byte, bool, error) {
 	tokenBytes, err := base64.StdEncoding.DecodeString(token)
 	if err != nil {
 		return nil, false, err
@@ -118,7 +118,7 @@ func verifySessionToken(token string, secretBytes []byte, now time.Time) ([]byte
 
 	return usernameHashBytes,
 		// True if the token should be regenerated
-		time.Unix(expiresTimestamp, 0).Add(-AUTH_TOKEN_REGEN_BEFORE).Before(now),
+		time.Unix(expiresTimestamp, 0).Add(-regenerateBefore).Before(now),
 		nil
 }
 
@@ -230,7 +230,8 @@ func (a *application) handleAuthenticationAttempt(w http.ResponseWriter, r *http
 		return
 	}
 
-	token, err := generateSessionToken(creds.Username, a.authSecretKey, time.Now())
+	lifetime := a.authSessionLifetime()
+	token, err := generateSessionToken(creds.Username, a.authSecretKey, time.Now(), lifetime)
 	if err != nil {
 		log.Printf("Could not compute session token during login attempt: %v", err)
 		time.Sleep(waitOnFailure)
@@ -238,7 +239,7 @@ func (a *application) handleAuthenticationAttempt(w http.ResponseWriter, r *http
 		return
 	}
 
-	a.setAuthSessionCookie(w, r, token, time.Now().Add(AUTH_TOKEN_VALID_PERIOD))
+	a.setAuthSessionCookie(w, r, token, time.Now().Add(lifetime))
 
 	a.authAttemptsMu.Lock()
 	delete(a.failedAuthAttempts, ip)
@@ -248,41 +249,49 @@ func (a *application) handleAuthenticationAttempt(w http.ResponseWriter, r *http
 }
 
 func (a *application) isAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	_, authorized := a.authorizedUsername(w, r)
+	return authorized
+}
+
+// Returns the name of the user that the request was made by, which is empty
+// when the application doesn't require authentication
+func (a *application) authorizedUsername(w http.ResponseWriter, r *http.Request) (string, bool) {
 	if !a.RequiresAuth {
-		return true
+		return "", true
 	}
 
 	token, err := r.Cookie(AUTH_SESSION_COOKIE_NAME)
 	if err != nil || token.Value == "" {
-		return false
+		return "", false
 	}
 
-	usernameHash, shouldRegenerate, err := verifySessionToken(token.Value, a.authSecretKey, time.Now())
+	lifetime := a.authSessionLifetime()
+	usernameHash, shouldRegenerate, err := verifySessionToken(token.Value, a.authSecretKey, time.Now(), lifetime/2)
 	if err != nil {
-		return false
+		return "", false
 	}
 
 	username, exists := a.usernameHashToUsername[string(usernameHash)]
 	if !exists {
-		return false
+		return "", false
 	}
 
 	_, exists = a.Config.Auth.Users[username]
 	if !exists {
-		return false
+		return "", false
 	}
 
 	if shouldRegenerate {
-		newToken, err := generateSessionToken(username, a.authSecretKey, time.Now())
+		newToken, err := generateSessionToken(username, a.authSecretKey, time.Now(), lifetime)
 		if err != nil {
 			log.Printf("Could not compute session token during regeneration: %v", err)
-			return false
+			return "", false
 		}
 
-		a.setAuthSessionCookie(w, r, newToken, time.Now().Add(AUTH_TOKEN_VALID_PERIOD))
+		a.setAuthSessionCookie(w, r, newToken, time.Now().Add(lifetime))
 	}
 
-	return true
+	return username, true
 }
 
 // Handles sending the appropriate response for an unauthorized request and returns true if the request was unauthorized
@@ -291,21 +300,32 @@ func (a *application) handleUnauthorizedResponse(w http.ResponseWriter, r *http.
 		return false
 	}
 
+	a.respondUnauthorized(w, r, fallback)
+	return true
+}
+
+func (a *application) respondUnauthorized(w http.ResponseWriter, r *http.Request, fallback doWhenUnauthorized) {
 	switch fallback {
 	case redirectToLogin:
-		http.Redirect(w, r, a.Config.Server.BaseURL+"/login", http.StatusSeeOther)
+		http.Redirect(w, r, a.rootBaseURL()+"/login", http.StatusSeeOther)
 	case showUnauthorizedJSON:
 		w.WriteHeader(http.StatusUnauthorized)
 		w.Write([]byte(`{"error": "Unauthorized"}`))
 	}
+}
 
-	return true
+func (a *application) authSessionLifetime() time.Duration {
+	if a.Config.Auth.SessionLifetime > 0 {
+		return time.Duration(a.Config.Auth.SessionLifetime)
+	}
+
+	return AUTH_TOKEN_VALID_PERIOD
 }
 
-// Maybe this should be a POST request instead?
+// Available as both GET for links and POST for forms
 func (a *application) handleLogoutRequest(w http.ResponseWriter, r *http.Request) {
 	a.setAuthSessionCookie(w, r, "", time.Now().Add(-1*time.Hour))
-	http.Redirect(w, r, a.Config.Server.BaseURL+"/login", http.StatusSeeOther)
+	http.Redirect(w, r, a.rootBaseURL()+"/login", http.StatusSeeOther)
 }
 
 func (a *application) setAuthSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
@@ -314,7 +334,7 @@ func (a *application) setAuthSessionCookie(w http.ResponseWriter, r *http.Reques
 		Value:    token,
 		Expires:  expires,
 		Secure:   strings.ToLower(r.Header.Get("X-Forwarded-Proto")) == "https",
-		Path:     a.Config.Server.BaseURL + "/",
+		Path:     a.rootBaseURL() + "/",
 		SameSite: http.SameSiteLaxMode,
 		HttpOnly: true,
 	})
@@ -3,6 +3,8 @@ package glance
 import (
 	"bytes"
 	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -25,12 +27,12 @@ func TestAuthTokenGenerationAndVerification(t *testing.T) {
 	now := time.Now()
 	username := "admin"
 
-	token, err := generateSessionToken(username, secretBytes, now)
+	token, err := generateSessionToken(username, secretBytes, now, AUTH_TOKEN_VALID_PERIOD)
 	if err != nil {
 		t.Fatalf("Failed to generate session token: %v", err)
 	}
 
-	usernameHashBytes, shouldRegen, err := verifySessionToken(token, secretBytes, now)
+	usernameHashBytes, shouldRegen, err := verifySessionToken(token, secretBytes, now, AUTH_TOKEN_REGEN_BEFORE)
 	if err != nil {
 		t.Fatalf("Failed to verify session token: %v", err)
 	}
@@ -50,7 +52,7 @@ func TestAuthTokenGenerationAndVerification(t *testing.T) {
 
 	// Test token regeneration
 	timeRightAfterRegenPeriod := now.Add(AUTH_TOKEN_VALID_PERIOD - AUTH_TOKEN_REGEN_BEFORE + 2*time.Second)
-	_, shouldRegen, err = verifySessionToken(token, secretBytes, timeRightAfterRegenPeriod)
+	_, shouldRegen, err = verifySessionToken(token, secretBytes, timeRightAfterRegenPeriod, AUTH_TOKEN_REGEN_BEFORE)
 	if err != nil {
 		t.Fatalf("Token verification should not fail during regeneration period, err: %v", err)
 	}
@@ -60,7 +62,7 @@ func TestAuthTokenGenerationAndVerification(t *testing.T) {
 	}
 
 	// Test token expiration
-	_, _, err = verifySessionToken(token, secretBytes, now.Add(AUTH_TOKEN_VALID_PERIOD+2*time.Second))
+	_, _, err = verifySessionToken(token, secretBytes, now.Add(AUTH_TOKEN_VALID_PERIOD+2*time.Second), AUTH_TOKEN_REGEN_BEFORE)
 	if err == nil {
 		t.Fatal("Expected token verification to fail after token expiration")
 	}
@@ -77,9 +79,165 @@ func TestAuthTokenGenerationAndVerification(t *testing.T) {
 		copy(tampered, decodedToken)
 		tampered[i] += 1
 
-		_, _, err = verifySessionToken(base64.StdEncoding.EncodeToString(tampered), secretBytes, now)
+		_, _, err = verifySessionToken(base64.StdEncoding.EncodeToString(tampered), secretBytes, now, AUTH_TOKEN_REGEN_BEFORE)
 		if err == nil {
 			t.Fatalf("Expected token verification to fail for tampered token at index %d", i)
 		}
 	}
 }
+
+func TestGuardRequests(t *testing.T) {
+	handler := guardRequests(&accessGuardOptions{
+		Enabled:  true,
+		Username: "admin",
+		Password: "password",
+		Token:    "token",
+	}, nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no credentials", "/", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"health check", "/healthz", func(r *http.Request) {}, http.StatusOK},
+		{"webhook", "/hooks/builds", func(r *http.Request) {}, http.StatusOK},
+		{"valid basic", "/", func(r *http.Request) { r.SetBasicAuth("admin", "password") }, http.StatusOK},
+		{"wrong password", "/", func(r *http.Request) { r.SetBasicAuth("admin", "passwor") }, http.StatusUnauthorized},
+		{"wrong username", "/", func(r *http.Request) { r.SetBasicAuth("root", "password") }, http.StatusUnauthorized},
+		{"valid token", "/api/v1/pages", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{"wrong token", "/", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tokens") }, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", test.path, nil)
+		test.setup(request)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, recorder.Code)
+		}
+
+		if recorder.Code == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate header", test.name)
+		}
+	}
+}
+
+func TestLimitClientRequests(t *testing.T) {
+	limiter := newClientRateLimiter(&clientRateLimitOptions{
+		Rate:  &requestRateField{Requests: 60, Per: time.Minute},
+		Burst: 2,
+	})
+
+	handler := limitClientRequests(limiter, func(r *http.Request) string {
+		return r.RemoteAddr
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(path, client string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = client
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	for i := range 2 {
+		if code := request("/api/widgets/search/suggestions", "a").Code; code != http.StatusOK {
+			t.Fatalf("request %d within the burst: expected status 200, got %d", i+1, code)
+		}
+	}
+
+	limited := request("/dashboard/api/widgets/search/suggestions", "a")
+	if limited.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 after the burst, got %d", limited.Code)
+	}
+
+	if limited.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected a Retry-After of 1, got %q", limited.Header().Get("Retry-After"))
+	}
+
+	if code := request("/api/widgets/search/suggestions", "b").Code; code != http.StatusOK {
+		t.Errorf("expected other clients to not be limited, got status %d", code)
+	}
+
+	if code := request("/api/pages/home/content/", "a").Code; code != http.StatusOK {
+		t.Errorf("expected pages to not be limited, got status %d", code)
+	}
+}
+
+func TestGuardRequestsCountsFailedAttempts(t *testing.T) {
+	limiter := newClientRateLimiter(&clientRateLimitOptions{
+		Rate:  &requestRateField{Requests: 60, Per: time.Minute},
+		Burst: 2,
+	})
+
+	handler := guardRequests(&accessGuardOptions{Enabled: true, Token: "token"}, limiter, func(r *http.Request) string {
+		return r.RemoteAddr
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(token, client string) int {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = client
+		r.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder.Code
+	}
+
+	for i := range 3 {
+		if code := request("token", "a"); code != http.StatusOK {
+			t.Fatalf("authorized request %d: expected status 200, got %d", i+1, code)
+		}
+	}
+
+	for i := range 2 {
+		if code := request("wrong", "a"); code != http.StatusUnauthorized {
+			t.Fatalf("failed attempt %d within the burst: expected status 401, got %d", i+1, code)
+		}
+	}
+
+	if code := request("token", "a"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the credentials to not be checked after the burst, got status %d", code)
+	}
+
+	if code := request("wrong", "b"); code != http.StatusUnauthorized {
+		t.Errorf("expected other clients to not be limited, got status %d", code)
+	}
+}
+
+func TestHostAllowlist(t *testing.T) {
+	allowlist := hostAllowlist{"suggestqueries.google.com", "*.example.com"}
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"suggestqueries.google.com", true},
+		{"SuggestQueries.Google.com.", true},
+		{"google.com", false},
+		{"api.example.com", true},
+		{"a.b.example.com", true},
+		{"example.com", false},
+		{"badexample.com", false},
+		{"169.254.169.254", false},
+	}
+
+	for _, test := range tests {
+		if allowlist.allows(test.host) != test.allowed {
+			t.Errorf("%s: expected allowed to be %t", test.host, test.allowed)
+		}
+	}
+
+	if !(hostAllowlist{}).allows("anything.internal") {
+		t.Error("expected an empty allowlist to allow every host")
+	}
+}
@@ -0,0 +1,184 @@
+package glance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Needs to be bumped whenever the structure of the files changes, changes to
+// the data of widgets are covered by the files also being tied to the version
+const widgetCacheFormatVersion = 1
+
+// Stores the data of widgets so that it can outlive the application, the data
+// kept within the widgets themselves remains the primary cache and stores are
+// only used to initially populate widgets and to keep a copy of their data
+type widgetCacheStore interface {
+	load(key string, data any) (updatedAt, expiresAt time.Time, ok bool)
+	save(key string, data any, updatedAt, expiresAt time.Time) error
+}
+
+// Implemented by widgets whose data can be kept in a widgetCacheStore
+type persistentlyCachedWidget interface {
+	dataModelWidget
+	setPersistentCacheKey(string)
+	loadFromPersistentCache(data any) bool
+	saveToPersistentCache(data any)
+}
+
+// The key is derived from the widget's config, so any change to it, including
+// to its cache duration, results in the widget no longer using the old data
+func persistentCacheKeyFromNode(widgetType string, node *yaml.Node) (string, error) {
+	encoded, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(append([]byte(widgetType+"\n"), encoded...))
+	return widgetType + "-" + hex.EncodeToString(hash[:16]), nil
+}
+
+func (w *widgetBase) setPersistentCacheKey(key string) {
+	w.persistentCacheKey = key
+}
+
+// Only reads from the store on the first update of the widget, returns true
+// if the data hasn't expired yet, meaning that the widget can skip updating.
+// Expired data is used until the widget successfully updates, or if it fails
+// to do so
+func (w *widgetBase) loadFromPersistentCache(data any) bool {
+	if w.persistentCacheRead || w.Providers == nil || w.Providers.cache == nil || w.persistentCacheKey == "" {
+		return false
+	}
+	w.persistentCacheRead = true
+
+	updatedAt, expiresAt, ok := w.Providers.cache.load(w.persistentCacheKey, data)
+	if !ok {
+		return false
+	}
+
+	w.ContentAvailable = true
+	w.lastSuccessfulUpdate = updatedAt
+	if time.Now().Before(expiresAt) {
+		w.nextUpdate = expiresAt
+		return true
+	}
+
+	return false
+}
+
+func (w *widgetBase) saveToPersistentCache(data any) {
+	if w.Providers == nil || w.Providers.cache == nil || w.persistentCacheKey == "" {
+		return
+	}
+
+	if w.Error != nil || !w.ContentAvailable || w.nextUpdate.IsZero() {
+		return
+	}
+
+	if err := w.Providers.cache.save(w.persistentCacheKey, data, w.lastSuccessfulUpdate, w.nextUpdate); err != nil {
+		slog.Warn("Failed to save widget data to cache", "type", w.Type, "error", err)
+	}
+}
+
+type diskWidgetCacheStore struct {
+	dir string
+}
+
+type diskWidgetCacheEntry struct {
+	FormatVersion int             `json:"format_version"`
+	AppVersion    string          `json:"app_version"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	ExpiresAt     time.Time       `json:"expires_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func newDiskWidgetCacheStore(dir string) (*diskWidgetCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %v", err)
+	}
+
+	return &diskWidgetCacheStore{dir: dir}, nil
+}
+
+func (s *diskWidgetCacheStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Files that can't be read for whatever reason get ignored, the widget
+// will simply fetch its data as though there was no cache
+func (s *diskWidgetCacheStore) load(key string, data any) (time.Time, time.Time, bool) {
+	contents, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to read widget cache file", "key", key, "error", err)
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	var entry diskWidgetCacheEntry
+	if err := json.Unmarshal(contents, &entry); err != nil {
+		slog.Warn("Ignoring corrupt widget cache file", "key", key, "error", err)
+		return time.Time{}, time.Time{}, false
+	}
+
+	if entry.FormatVersion != widgetCacheFormatVersion || entry.AppVersion != buildVersion {
+		return time.Time{}, time.Time{}, false
+	}
+
+	if err := json.Unmarshal(entry.Data, data); err != nil {
+		slog.Warn("Ignoring corrupt widget cache file", "key", key, "error", err)
+		return time.Time{}, time.Time{}, false
+	}
+
+	return entry.UpdatedAt, entry.ExpiresAt, true
+}
+
+func (s *diskWidgetCacheStore) save(key string, data any, updatedAt, expiresAt time.Time) error {
+	encodedData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(diskWidgetCacheEntry{
+		FormatVersion: widgetCacheFormatVersion,
+		AppVersion:    buildVersion,
+		UpdatedAt:     updatedAt,
+		ExpiresAt:     expiresAt,
+		Data:          encodedData,
+	})
+	if err != nil {
+		return err
+	}
+
+	// written to a temporary file first so that a crash
+	// midway through doesn't leave behind a partial file
+	file, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(contents)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), s.path(key)); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
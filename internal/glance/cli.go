@@ -22,12 +22,19 @@ const (
 	cliIntentMountpointInfo
 	cliIntentSecretMake
 	cliIntentPasswordHash
+	cliIntentFeedDiscover
+	cliIntentAgent
+	cliIntentRender
+	cliIntentConfigMigrate
 )
 
 type cliOptions struct {
-	intent     cliIntent
-	configPath string
-	args       []string
+	intent           cliIntent
+	configPath       string
+	overlayPaths     []string
+	restrictIncludes bool
+	noWatch          bool
+	args             []string
 }
 
 func parseCliOptions() (*cliOptions, error) {
@@ -48,16 +55,27 @@ func parseCliOptions() (*cliOptions, error) {
 		flags.PrintDefaults()
 
 		fmt.Println("\nCommands:")
-		fmt.Println("  config:validate       Validate the config file")
+		fmt.Println("  config:validate [path] Validate the config file and print all errors, also available as validate")
 		fmt.Println("  config:print          Print the parsed config file with embedded includes")
+		fmt.Println("  config:migrate [path] Update the config file to the latest schema version, also available as migrate")
 		fmt.Println("  password:hash <pwd>   Hash a password")
 		fmt.Println("  secret:make           Generate a random secret key")
 		fmt.Println("  sensors:print         List all sensors")
 		fmt.Println("  mountpoint:info       Print information about a given mountpoint path")
+		fmt.Println("  feed:discover <url>   List the RSS/Atom feeds linked to from a page, also available as discover-feed")
 		fmt.Println("  diagnose              Run diagnostic checks")
+		fmt.Println("  agent [options]       Serve the stats of this machine to other instances, see agent -h")
+		fmt.Println("  render [path]         Update all widgets once and print the pages as a single static HTML document")
 	}
 
 	configPath := flags.String("config", "glance.yml", "Set config path")
+	restrictIncludes := flags.Bool("restrict-includes", false, "Only allow including files from within the config file's directory")
+	noWatch := flags.Bool("no-watch", false, "Don't reload the config when it or any of its included files change")
+	var overlayPaths []string
+	flags.Func("overlay", "Merge a config file over the main one, can be used multiple times", func(path string) error {
+		overlayPaths = append(overlayPaths, path)
+		return nil
+	})
 	err := flags.Parse(os.Args[1:])
 	if err != nil {
 		return nil, err
@@ -69,8 +87,10 @@ func parseCliOptions() (*cliOptions, error) {
 
 	if len(args) == 0 {
 		intent = cliIntentServe
+	} else if args[0] == "agent" {
+		intent = cliIntentAgent
 	} else if len(args) == 1 {
-		if args[0] == "config:validate" {
+		if args[0] == "config:validate" || args[0] == "validate" {
 			intent = cliIntentConfigValidate
 		} else if args[0] == "config:print" {
 			intent = cliIntentConfigPrint
@@ -80,18 +100,29 @@ func parseCliOptions() (*cliOptions, error) {
 			intent = cliIntentDiagnose
 		} else if args[0] == "secret:make" {
 			intent = cliIntentSecretMake
+		} else if args[0] == "render" {
+			intent = cliIntentRender
+		} else if args[0] == "config:migrate" || args[0] == "migrate" {
+			intent = cliIntentConfigMigrate
 		} else {
 			return nil, unknownCommandErr
 		}
 	} else if len(args) == 2 {
 		if args[0] == "password:hash" {
 			intent = cliIntentPasswordHash
-		} else {
-			return nil, unknownCommandErr
-		}
-	} else if len(args) == 2 {
-		if args[0] == "mountpoint:info" {
+		} else if args[0] == "mountpoint:info" {
 			intent = cliIntentMountpointInfo
+		} else if args[0] == "feed:discover" || args[0] == "discover-feed" {
+			intent = cliIntentFeedDiscover
+		} else if args[0] == "config:validate" || args[0] == "validate" {
+			intent = cliIntentConfigValidate
+			*configPath = args[1]
+		} else if args[0] == "render" {
+			intent = cliIntentRender
+			*configPath = args[1]
+		} else if args[0] == "config:migrate" || args[0] == "migrate" {
+			intent = cliIntentConfigMigrate
+			*configPath = args[1]
 		} else {
 			return nil, unknownCommandErr
 		}
@@ -100,9 +131,12 @@ func parseCliOptions() (*cliOptions, error) {
 	}
 
 	return &cliOptions{
-		intent:     intent,
-		configPath: *configPath,
-		args:       args,
+		intent:           intent,
+		configPath:       *configPath,
+		overlayPaths:     configOverlayPaths(*configPath, overlayPaths),
+		restrictIncludes: *restrictIncludes,
+		noWatch:          *noWatch,
+		args:             args,
 	}, nil
 }
 
@@ -153,3 +187,25 @@ func cliMountpointInfo(requestedPath string) int {
 
 	return 0
 }
+
+func cliFeedDiscover(pageURL string) int {
+	if !strings.Contains(pageURL, "://") {
+		pageURL = "https://" + pageURL
+	}
+
+	feeds, err := discoverFeeds(defaultHTTPClient, pageURL)
+	if err != nil {
+		fmt.Printf("Failed to discover feeds on %s: %v\n", pageURL, err)
+		return 1
+	}
+
+	for _, feed := range feeds {
+		if feed.Title != "" {
+			fmt.Printf("%s (%s, %s)\n", feed.URL, feed.Title, feed.Type)
+		} else {
+			fmt.Printf("%s (%s)\n", feed.URL, feed.Type)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,188 @@
+package glance
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits how often each client can make requests to the routes which do work
+// on every request, such as making a request elsewhere on behalf of the client.
+// Loading pages isn't limited since the widgets are only updated as needed
+type clientRateLimitOptions struct {
+	Rate  *requestRateField `yaml:"rate"`
+	Burst int               `yaml:"burst"`
+}
+
+const defaultClientRateLimitBurst = 20
+
+func (o *clientRateLimitOptions) initialize() error {
+	if o.Rate == nil {
+		return nil
+	}
+
+	if o.Burst < 0 {
+		return errors.New("rate-limit burst must be a positive number")
+	} else if o.Burst == 0 {
+		o.Burst = defaultClientRateLimitBurst
+	}
+
+	return nil
+}
+
+// Matches the same routes of dashboards, which are served under their slug
+var clientRateLimitedPathPattern = regexp.MustCompile(`/api/(?:widgets/|set-theme/|authenticate$)`)
+
+type clientRateLimiter struct {
+	mu        sync.Mutex
+	rate      requestRateField
+	perSecond float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newClientRateLimiter(options *clientRateLimitOptions) *clientRateLimiter {
+	return &clientRateLimiter{
+		rate:      *options.Rate,
+		perSecond: options.Rate.perSecond(),
+		burst:     float64(options.Burst),
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+// Returns how long the client has to wait before its next request is
+// allowed, the request isn't counted when it's not allowed
+func (l *clientRateLimiter) reserve(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the clients which have been idle for long enough to be back to the
+	// full burst are no different from ones that were never seen
+	if now.Sub(l.lastSweep) >= time.Minute {
+		l.lastSweep = now
+		for key, bucket := range l.buckets {
+			if bucket.tokens+now.Sub(bucket.last).Seconds()*l.perSecond >= l.burst {
+				delete(l.buckets, key)
+			}
+		}
+	}
+
+	bucket, exists := l.buckets[client]
+	if !exists {
+		bucket = &tokenBucket{
+			rate:      l.rate,
+			perSecond: l.perSecond,
+			burst:     l.burst,
+			tokens:    l.burst,
+			last:      now,
+		}
+		l.buckets[client] = bucket
+	}
+
+	delay := bucket.reserve(now)
+	if delay > 0 {
+		bucket.tokens++
+	}
+
+	return delay
+}
+
+// Same as reserve except that the request isn't counted either way
+func (l *clientRateLimiter) delay(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, exists := l.buckets[client]
+	if !exists {
+		return 0
+	}
+
+	tokens := min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.perSecond)
+	if tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - tokens) / l.perSecond * float64(time.Second))
+}
+
+func writeTooManyRequests(w http.ResponseWriter, delay time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+func limitClientRequests(limiter *clientRateLimiter, addressOf func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !clientRateLimitedPathPattern.MatchString(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if delay := limiter.reserve(addressOf(r), time.Now()); delay > 0 {
+			writeTooManyRequests(w, delay)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The hosts which the server can make requests to on behalf of clients, either
+// exact or starting with *. to match any of their subdomains. All hosts are
+// allowed when the list is empty
+type hostAllowlist []string
+
+func (l hostAllowlist) allows(host string) bool {
+	if len(l) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, allowed := range l {
+		allowed = strings.ToLower(allowed)
+
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Implemented by widgets which make requests on behalf of clients, so that the
+// hosts they make them to can be checked against the allowlist on startup
+type proxyingWidget interface {
+	proxiedHosts() []string
+}
+
+func (a *application) checkProxiedHosts() error {
+	allowlist := a.Config.Server.ProxyAllowedHosts
+
+	for _, entry := range a.widgetByStableID {
+		proxying, ok := entry.widget.(proxyingWidget)
+		if !ok {
+			continue
+		}
+
+		for _, host := range proxying.proxiedHosts() {
+			if !allowlist.allows(host) {
+				return formatWidgetInitError(
+					errors.New("requests to "+host+" are made on behalf of clients but it's not in the proxy-allowed-hosts of the server"),
+					entry.widget,
+				)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package glance
+
+import (
+	"net/http"
+	"strconv"
+)
+
+type commandPaletteItem struct {
+	// One of page, dashboard, widget or bookmark
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	// Where the item is, such as the page of a widget, shown next to the title
+	Context string `json:"context,omitempty"`
+	URL     string `json:"url"`
+	Target  string `json:"target,omitempty"`
+	// Lets widgets on the current page be scrolled to without reloading it
+	WidgetID uint64 `json:"widget_id,omitempty"`
+}
+
+// Built on every request rather than once when the config loads since widgets
+// can be shown or hidden while the server is running
+func (a *application) commandPaletteItems() []commandPaletteItem {
+	items := make([]commandPaletteItem, 0, len(a.Config.Pages)+len(a.Dashboards))
+
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+		items = append(items, commandPaletteItem{
+			Kind:  "page",
+			Title: page.Title,
+			URL:   a.Config.Server.BaseURL + "/" + page.Slug,
+		})
+	}
+
+	for _, dashboard := range a.Dashboards {
+		if dashboard.Name == a.CurrentDashboard {
+			continue
+		}
+
+		items = append(items, commandPaletteItem{
+			Kind:  "dashboard",
+			Title: dashboard.Name,
+			URL:   dashboard.URL,
+		})
+	}
+
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+		pageURL := a.Config.Server.BaseURL + "/" + page.Slug
+
+		page.mu.Lock()
+		items = appendCommandPaletteWidgetItems(items, page, pageURL, page.HeadWidgets)
+		for c := range page.Columns {
+			items = appendCommandPaletteWidgetItems(items, page, pageURL, page.Columns[c].Widgets)
+		}
+		page.mu.Unlock()
+	}
+
+	return items
+}
+
+// Must be called while holding the lock of the page
+func appendCommandPaletteWidgetItems(items []commandPaletteItem, page *page, pageURL string, ws widgets) []commandPaletteItem {
+	for _, w := range ws {
+		if w.IsHidden() {
+			continue
+		}
+
+		// containers only show the titles of their children
+		if container, ok := w.(containerWidget); ok {
+			items = appendCommandPaletteWidgetItems(items, page, pageURL, container.children())
+			continue
+		}
+
+		if title := w.getTitle(); title != "" {
+			items = append(items, commandPaletteItem{
+				Kind:     "widget",
+				Title:    title,
+				Context:  page.Title,
+				URL:      pageURL + "#widget-" + strconv.FormatUint(w.GetID(), 10),
+				WidgetID: w.GetID(),
+			})
+		}
+
+		bookmarks, ok := w.(*bookmarksWidget)
+		if !ok {
+			continue
+		}
+
+		for g := range bookmarks.Groups {
+			group := &bookmarks.Groups[g]
+			context := page.Title
+			if group.Title != "" {
+				context += " / " + group.Title
+			}
+
+			for l := range group.Links {
+				link := &group.Links[l]
+				items = append(items, commandPaletteItem{
+					Kind:    "bookmark",
+					Title:   link.Title,
+					Context: context,
+					URL:     link.URL,
+					Target:  link.Target,
+				})
+			}
+		}
+	}
+
+	return items
+}
+
+func (a *application) handleCommandPaletteRequest(w http.ResponseWriter, r *http.Request) {
+	if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+		return
+	}
+
+	writeAPIResponse(w, struct {
+		Items []commandPaletteItem `json:"items"`
+	}{a.commandPaletteItems()})
+}
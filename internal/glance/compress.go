@@ -0,0 +1,287 @@
+package glance
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Responses smaller than this aren't worth the overhead of compressing
+const compressionMinSize = 1024
+
+var compressibleContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/manifest+json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+func isCompressibleContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	for _, prefix := range compressibleContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Only gzip is supported since it's the only one of the common encodings that
+// the standard library implements and virtually every client accepts it
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			encoding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			encoding = strings.TrimSpace(encoding)
+			if encoding != "gzip" && encoding != "*" {
+				continue
+			}
+
+			q, hasQ := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+			if !hasQ {
+				return true
+			}
+
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil && parsed > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// The ETags of compressed responses get a suffix since they're a different
+// representation of the resource, which then gets stripped from conditional
+// requests so that the wrapped handler can still match them
+const compressedETagSuffix = "-gzip"
+
+func compressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// partial content can't be compressed without breaking the ranges
+		if !acceptsGzip(r) || r.Header.Get("Range") != "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", strings.ReplaceAll(ifNoneMatch, compressedETagSuffix+`"`, `"`))
+		}
+
+		cw := &compressingResponseWriter{ResponseWriter: w}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+type compressingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	decided     bool
+	compress    bool
+	buffer      bytes.Buffer
+	gzipWriter  *gzip.Writer
+}
+
+func (w *compressingResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *compressingResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.decided {
+		if w.compress {
+			return w.gzipWriter.Write(data)
+		}
+
+		return w.ResponseWriter.Write(data)
+	}
+
+	// held back until there's enough to know whether it's worth compressing
+	w.buffer.Write(data)
+	if w.buffer.Len() >= compressionMinSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+func (w *compressingResponseWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+
+	if header.Get("Content-Type") == "" && w.buffer.Len() > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer.Bytes()))
+	}
+
+	w.compress = w.buffer.Len() >= compressionMinSize &&
+		w.status == http.StatusOK &&
+		header.Get("Content-Encoding") == "" &&
+		isCompressibleContentType(header.Get("Content-Type"))
+
+	if w.compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+
+		if etag := header.Get("ETag"); strings.HasSuffix(etag, `"`) {
+			header.Set("ETag", strings.TrimSuffix(etag, `"`)+compressedETagSuffix+`"`)
+		}
+
+		w.gzipWriter = gzipWriterPool.Get().(*gzip.Writer)
+		w.gzipWriter.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if w.compress {
+		_, err = w.gzipWriter.Write(w.buffer.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+	w.buffer.Reset()
+
+	return err
+}
+
+func (w *compressingResponseWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader {
+			return
+		}
+		w.decide()
+	}
+
+	if w.compress {
+		w.gzipWriter.Close()
+		w.gzipWriter.Reset(io.Discard)
+		gzipWriterPool.Put(w.gzipWriter)
+	}
+}
+
+func (w *compressingResponseWriter) Flush() {
+	if !w.decided && w.wroteHeader {
+		w.decide()
+	}
+
+	if w.compress {
+		w.gzipWriter.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *compressingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Static assets never change while the server is running, so rather than
+// compressing them on every request they get compressed once when first
+// requested and kept in memory
+type precompressedAssets struct {
+	mu    sync.Mutex
+	cache map[string][]byte
+}
+
+func (c *precompressedAssets) get(key string, contents func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if compressed, exists := c.cache[key]; exists {
+		return compressed, nil
+	}
+
+	uncompressed, err := contents()
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer, _ := gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+	writer.Write(uncompressed)
+	writer.Close()
+
+	if c.cache == nil {
+		c.cache = make(map[string][]byte)
+	}
+	c.cache[key] = buffer.Bytes()
+
+	return c.cache[key], nil
+}
+
+var staticAssetsCompressed = &precompressedAssets{}
+
+// Serves the gzipped version of compressible files from the given FS when
+// the client accepts it, everything else is left to the wrapped handler
+func serveStaticPrecompressed(files fs.FS, cacheControlValue string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		contentType := mime.TypeByExtension(path.Ext(name))
+
+		if !acceptsGzip(r) || r.Header.Get("Range") != "" || !isCompressibleContentType(contentType) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		compressed, err := staticAssetsCompressed.get(name, func() ([]byte, error) {
+			return fs.ReadFile(files, name)
+		})
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writePrecompressed(w, contentType, cacheControlValue, compressed)
+	})
+}
+
+func writePrecompressed(w http.ResponseWriter, contentType, cacheControlValue string, compressed []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControlValue)
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+	w.Write(compressed)
+}
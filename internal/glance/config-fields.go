@@ -1,10 +1,8 @@
 package glance
 
 import (
-	"crypto/tls"
 	"fmt"
 	"html/template"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -129,6 +127,118 @@ func (d *durationField) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+var requestRateFieldPattern = regexp.MustCompile(`^(\d+)\s*/\s*(s|m|h|d)$`)
+
+// A number of requests per unit of time, such as 60/m
+type requestRateField struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (r *requestRateField) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	matches := requestRateFieldPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if len(matches) != 3 {
+		return fmt.Errorf("invalid rate format: %s, expected a number of requests per s, m, h or d such as 60/m", value)
+	}
+
+	requests, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return err
+	}
+
+	if requests == 0 {
+		return fmt.Errorf("rate %s must allow at least one request", value)
+	}
+
+	r.Requests = requests
+	r.Per = map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+	}[matches[2]]
+
+	return nil
+}
+
+func (r requestRateField) perSecond() float64 {
+	return float64(r.Requests) / r.Per.Seconds()
+}
+
+func (r requestRateField) String() string {
+	unit := map[time.Duration]string{
+		time.Second:    "s",
+		time.Minute:    "m",
+		time.Hour:      "h",
+		24 * time.Hour: "d",
+	}[r.Per]
+
+	return strconv.Itoa(r.Requests) + "/" + unit
+}
+
+// Either no-cache, where browsers have to check whether the response has changed
+// every time, no-store, where they can't keep it at all, or a duration such as
+// 10m during which they can reuse the response without checking
+type browserCacheField struct {
+	noStore bool
+	maxAge  time.Duration
+}
+
+func (f *browserCacheField) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	switch value = strings.TrimSpace(value); value {
+	case "no-cache":
+		*f = browserCacheField{}
+		return nil
+	case "no-store":
+		*f = browserCacheField{noStore: true}
+		return nil
+	}
+
+	var maxAge durationField
+	if err := node.Decode(&maxAge); err != nil {
+		return fmt.Errorf("invalid browser cache value %s, expected no-cache, no-store or a duration such as 10m", value)
+	}
+
+	*f = browserCacheField{maxAge: time.Duration(maxAge)}
+	return nil
+}
+
+// Whichever of the two lets browsers reuse the response the least
+func (f browserCacheField) stricter(other browserCacheField) browserCacheField {
+	switch {
+	case f.noStore || other.noStore:
+		return browserCacheField{noStore: true}
+	case f.maxAge == 0 || other.maxAge == 0:
+		return browserCacheField{}
+	}
+
+	return browserCacheField{maxAge: min(f.maxAge, other.maxAge)}
+}
+
+func (f browserCacheField) headerValue() string {
+	if f.noStore {
+		return "no-store"
+	}
+
+	if f.maxAge > 0 {
+		return "private, max-age=" + strconv.Itoa(int(f.maxAge.Seconds()))
+	}
+
+	return "private, no-cache"
+}
+
 type customIconField struct {
 	URL        template.URL
 	AutoInvert bool
@@ -191,7 +301,7 @@ type proxyOptionsField struct {
 	URL           string        `yaml:"url"`
 	AllowInsecure bool          `yaml:"allow-insecure"`
 	Timeout       durationField `yaml:"timeout"`
-	client        *http.Client  `yaml:"-"`
+	parsedURL     *url.URL      `yaml:"-"`
 }
 
 func (p *proxyOptionsField) UnmarshalYAML(node *yaml.Node) error {
@@ -213,24 +323,12 @@ func (p *proxyOptionsField) UnmarshalYAML(node *yaml.Node) error {
 		proxyURL = p.URL
 	}
 
-	parsedUrl, err := url.Parse(proxyURL)
+	parsedURL, err := url.Parse(proxyURL)
 	if err != nil {
 		return fmt.Errorf("parsing proxy URL: %v", err)
 	}
 
-	var timeout = defaultClientTimeout
-	if p.Timeout > 0 {
-		timeout = time.Duration(p.Timeout)
-	}
-
-	p.client = &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			Proxy:           http.ProxyURL(parsedUrl),
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: p.AllowInsecure},
-		},
-	}
-
+	p.parsedURL = parsedURL
 	return nil
 }
 
@@ -288,3 +386,148 @@ func (q *queryParametersField) toQueryString() string {
 
 	return query.Encode()
 }
+
+var visibleWhenTimePattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})\s*-\s*(\d{1,2}):(\d{2})$`)
+
+var visibleWhenDayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Either a time range such as `06:00-12:00`, or an object with a time range
+// and/or the days of the week on which the widget is visible. Ranges whose
+// end is before their start go past midnight, in which case the days refer
+// to the day on which the range starts
+type visibleWhenField struct {
+	Time string   `yaml:"time"`
+	Days []string `yaml:"days"`
+
+	hasTime bool
+	start   int // minutes since midnight
+	end     int
+	days    [7]bool
+	hasDays bool
+}
+
+func (f *visibleWhenField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if err := node.Decode(&f.Time); err != nil {
+			return err
+		}
+	} else {
+		type alias visibleWhenField
+		if err := node.Decode((*alias)(f)); err != nil {
+			return err
+		}
+	}
+
+	if f.Time == "" && len(f.Days) == 0 {
+		return fmt.Errorf("line %d: visible-when must specify a time range and/or days", node.Line)
+	}
+
+	if f.Time != "" {
+		if err := f.parseTime(f.Time); err != nil {
+			return fmt.Errorf("line %d: visible-when: %v", node.Line, err)
+		}
+	}
+
+	for _, day := range f.Days {
+		if err := f.parseDays(day); err != nil {
+			return fmt.Errorf("line %d: visible-when: %v", node.Line, err)
+		}
+	}
+
+	return nil
+}
+
+func (f *visibleWhenField) parseTime(value string) error {
+	matches := visibleWhenTimePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return fmt.Errorf("invalid time range %s, expected a format such as 06:00-12:00", value)
+	}
+
+	toMinutes := func(hours, minutes string) (int, error) {
+		h, _ := strconv.Atoi(hours)
+		m, _ := strconv.Atoi(minutes)
+
+		// 24:00 is allowed so that ranges can end at midnight
+		if h > 24 || m > 59 || (h == 24 && m != 0) {
+			return 0, fmt.Errorf("invalid time %s:%s", hours, minutes)
+		}
+
+		return h*60 + m, nil
+	}
+
+	var err error
+	if f.start, err = toMinutes(matches[1], matches[2]); err != nil {
+		return err
+	}
+
+	if f.end, err = toMinutes(matches[3], matches[4]); err != nil {
+		return err
+	}
+
+	if f.start == f.end {
+		return fmt.Errorf("time range %s is empty", value)
+	}
+
+	f.hasTime = true
+	return nil
+}
+
+// Accepts single days as well as ranges of days such as mon-fri
+func (f *visibleWhenField) parseDays(value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	from, to, isRange := strings.Cut(value, "-")
+
+	first, ok := visibleWhenDayNames[strings.TrimSpace(from)]
+	if !ok {
+		return fmt.Errorf("invalid day %s", value)
+	}
+
+	last := first
+	if isRange {
+		if last, ok = visibleWhenDayNames[strings.TrimSpace(to)]; !ok {
+			return fmt.Errorf("invalid day %s", value)
+		}
+	}
+
+	for day := first; ; day = (day + 1) % 7 {
+		f.days[day] = true
+		if day == last {
+			break
+		}
+	}
+
+	f.hasDays = true
+	return nil
+}
+
+// The given time must already be in the timezone the ranges are meant for
+func (f *visibleWhenField) isVisibleAt(t time.Time) bool {
+	minutes := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	dayMatches := func(day time.Weekday) bool {
+		return !f.hasDays || f.days[day]
+	}
+
+	if !f.hasTime {
+		return dayMatches(day)
+	}
+
+	if f.start < f.end {
+		return minutes >= f.start && minutes < f.end && dayMatches(day)
+	}
+
+	if minutes >= f.start {
+		return dayMatches(day)
+	}
+
+	// past midnight, so the range started on the previous day
+	return minutes < f.end && dayMatches((day+6)%7)
+}
@@ -0,0 +1,625 @@
+package glance
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configSchemaVersionKey = "schema-version"
+
+// Configs which don't specify a schema version are assumed to have been
+// written before versions existed
+const initialConfigSchemaVersion = 1
+
+type configMigrationChange struct {
+	line        int
+	description string
+}
+
+type configMigration struct {
+	// The schema version that the config is at once the migration is done
+	version int
+	// Works on the config as written, so that the same migration can be used
+	// both when loading the config and when rewriting the file
+	apply func(root *yaml.Node) []configMigrationChange
+}
+
+// Append only, each migration has to bump the version by one
+var configMigrations = []configMigration{
+	{
+		version: 2,
+		apply: func(root *yaml.Node) []configMigrationChange {
+			var changes []configMigrationChange
+
+			forEachConfigWidgetNode(root, func(widget, widgetType *yaml.Node) {
+				if widgetType.Value == "stocks" {
+					widgetType.Value = "markets"
+					changes = append(changes, configMigrationChange{widgetType.Line, "renamed the stocks widget type to markets"})
+				}
+
+				if widgetType.Value == "markets" {
+					if key := renameYAMLMapKey(widget, "stocks", "markets"); key != nil {
+						changes = append(changes, configMigrationChange{key.Line, "renamed stocks to markets in a markets widget"})
+					}
+				}
+			})
+
+			return changes
+		},
+	},
+}
+
+var currentConfigSchemaVersion = configMigrations[len(configMigrations)-1].version
+
+// Widgets can be anywhere, such as within groups, split columns, presets and
+// included files, so every map with a type is treated as a widget
+func forEachConfigWidgetNode(node *yaml.Node, fn func(widget, widgetType *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			forEachConfigWidgetNode(child, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "type" && node.Content[i+1].Kind == yaml.ScalarNode {
+				fn(node, node.Content[i+1])
+				break
+			}
+		}
+
+		for i := 1; i < len(node.Content); i += 2 {
+			forEachConfigWidgetNode(node.Content[i], fn)
+		}
+	}
+}
+
+// The key keeps its position and comments. Nothing gets renamed if the new
+// key already exists, in which case the old one gets reported as unknown
+func renameYAMLMapKey(node *yaml.Node, from, to string) *yaml.Node {
+	var fromKey *yaml.Node
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case to:
+			return nil
+		case from:
+			fromKey = node.Content[i]
+		}
+	}
+
+	if fromKey != nil {
+		fromKey.Value = to
+	}
+
+	return fromKey
+}
+
+func configSchemaVersionNode(root *yaml.Node) (key, value *yaml.Node) {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	document := root.Content[0]
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if document.Content[i].Value == configSchemaVersionKey {
+			return document.Content[i], document.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+func configSchemaVersion(root *yaml.Node) (int, error) {
+	_, value := configSchemaVersionNode(root)
+	if value == nil {
+		return initialConfigSchemaVersion, nil
+	}
+
+	version, err := strconv.Atoi(value.Value)
+	if value.Kind != yaml.ScalarNode || err != nil || version < initialConfigSchemaVersion {
+		return 0, fmt.Errorf("line %d: %s must be a whole number that's at least %d", value.Line, configSchemaVersionKey, initialConfigSchemaVersion)
+	}
+
+	return version, nil
+}
+
+func migrateConfigNode(root *yaml.Node, fromVersion int) []configMigrationChange {
+	var changes []configMigrationChange
+
+	for _, migration := range configMigrations {
+		if migration.version > fromVersion {
+			changes = append(changes, migration.apply(root)...)
+		}
+	}
+
+	return changes
+}
+
+// Older configs keep working by being migrated every time they get loaded,
+// with each change being logged so that the file can be updated, either by
+// hand or through the migrate command
+func applyConfigMigrations(root *yaml.Node) error {
+	version, err := configSchemaVersion(root)
+	if err != nil {
+		return err
+	}
+
+	if version > currentConfigSchemaVersion {
+		slog.Warn("Config was written for a newer version of Glance, some of it may not work as expected",
+			"schema_version", version, "supported_schema_version", currentConfigSchemaVersion)
+		return nil
+	}
+
+	changes := migrateConfigNode(root, version)
+	for _, change := range changes {
+		slog.Warn("Config uses an outdated format", "line", change.line, "change", change.description)
+	}
+
+	if len(changes) > 0 {
+		slog.Warn("Migrated the config in memory, run `glance migrate` to update the file", "from_schema_version", version, "to_schema_version", currentConfigSchemaVersion)
+	}
+
+	return nil
+}
+
+type unknownConfigKey struct {
+	line       int
+	key        string
+	where      string
+	suggestion string
+}
+
+func (k unknownConfigKey) String() string {
+	message := fmt.Sprintf("line %d: unknown property %q in %s", k.line, k.key, k.where)
+	if k.suggestion != "" {
+		message += fmt.Sprintf(", did you mean %q?", k.suggestion)
+	}
+
+	return message
+}
+
+// Unknown keys get ignored when decoding, which makes typos easy to miss
+// since the only sign of them is that the property has no effect
+func warnAboutUnknownConfigKeys(root *yaml.Node) {
+	for _, key := range findUnknownConfigKeys(root) {
+		slog.Warn(key.String())
+	}
+}
+
+func findUnknownConfigKeys(root *yaml.Node) []unknownConfigKey {
+	var found []unknownConfigKey
+
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		checkConfigKeys(root.Content[0], reflect.TypeFor[config](), "the config", &found)
+	}
+
+	return found
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+	yamlNodeType        = reflect.TypeFor[yaml.Node]()
+	widgetsType         = reflect.TypeFor[widgets]()
+)
+
+func checkConfigKeys(node *yaml.Node, t reflect.Type, where string, found *[]unknownConfigKey) {
+	node = resolveYAMLAlias(node)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == yamlNodeType:
+		return
+	case t == widgetsType:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for _, item := range node.Content {
+			item = resolveYAMLAlias(item)
+
+			var meta struct {
+				Type string `yaml:"type"`
+			}
+
+			if item.Kind != yaml.MappingNode || item.Decode(&meta) != nil {
+				continue
+			}
+
+			// unknown types are already reported as errors
+			if widget, err := newWidgetOfType(meta.Type); err == nil {
+				checkConfigKeys(item, reflect.TypeOf(widget), meta.Type+" widget", found)
+			}
+		}
+
+		return
+	case strings.HasPrefix(t.Name(), "orderedYAMLMap["):
+		if data, ok := t.FieldByName("data"); ok && node.Kind == yaml.MappingNode {
+			for i := 1; i < len(node.Content); i += 2 {
+				checkConfigKeys(node.Content[i], data.Type.Elem(), node.Content[i-1].Value, found)
+			}
+		}
+
+		return
+	case reflect.PointerTo(t).Implements(yamlUnmarshalerType) && !hasOnlyYAMLTaggedFields(t):
+		// types with their own formats, such as search bangs, where
+		// the keys don't match up with the fields of the type
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		fields := make(map[string]reflect.Type)
+		if acceptsAnyKey := collectConfigStructFields(t, fields); acceptsAnyKey {
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+
+			// merge keys get resolved by the decoder
+			if key == "<<" {
+				continue
+			}
+
+			fieldType, exists := fields[key]
+
+			// keys which only hold anchors to be used elsewhere
+			if !exists && hasYAMLAnchor(node.Content[i+1]) {
+				continue
+			}
+
+			if !exists {
+				*found = append(*found, unknownConfigKey{
+					line:       node.Content[i].Line,
+					key:        key,
+					where:      where,
+					suggestion: closestConfigKey(key, fields),
+				})
+				continue
+			}
+
+			checkConfigKeys(node.Content[i+1], fieldType, key, found)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind == yaml.SequenceNode {
+			for _, item := range node.Content {
+				checkConfigKeys(item, t.Elem(), where, found)
+			}
+		}
+	case reflect.Map:
+		if node.Kind == yaml.MappingNode {
+			for i := 1; i < len(node.Content); i += 2 {
+				checkConfigKeys(node.Content[i], t.Elem(), node.Content[i-1].Value, found)
+			}
+		}
+	}
+}
+
+// Structs with their own unmarshaling, such as the kiosk options, still use
+// their fields when written as a map, which can be told apart from those
+// that don't by all of their fields being tagged
+func hasOnlyYAMLTaggedFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	tagged := false
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if _, ok := field.Tag.Lookup("yaml"); !ok {
+			return false
+		}
+
+		tagged = true
+	}
+
+	return tagged
+}
+
+func hasYAMLAnchor(node *yaml.Node) bool {
+	if node.Anchor != "" {
+		return true
+	}
+
+	for _, child := range node.Content {
+		if hasYAMLAnchor(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Follows the same rules as the decoder, returns true if the struct has an
+// inlined map, in which case any key is accepted
+func collectConfigStructFields(t reflect.Type, fields map[string]reflect.Type) bool {
+	acceptsAnyKey := false
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			inlined := field.Type
+			for inlined.Kind() == reflect.Pointer {
+				inlined = inlined.Elem()
+			}
+
+			if inlined.Kind() == reflect.Map {
+				acceptsAnyKey = true
+			} else if inlined.Kind() == reflect.Struct && collectConfigStructFields(inlined, fields) {
+				acceptsAnyKey = true
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field.Type
+	}
+
+	return acceptsAnyKey
+}
+
+// Only keys which are close enough to likely be a typo are suggested
+func closestConfigKey(key string, fields map[string]reflect.Type) string {
+	maxDistance := ternary(len(key) > 4, 2, 1)
+	closest, closestDistance := "", maxDistance+1
+
+	for name := range fields {
+		distance := editDistance(strings.ToLower(key), name)
+		if distance < closestDistance || distance == closestDistance && name < closest {
+			closest, closestDistance = name, distance
+		}
+	}
+
+	return ternary(closestDistance <= maxDistance, closest, "")
+}
+
+// The number of insertions, deletions, substitutions and transpositions of
+// adjacent characters needed to turn one string into the other
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	rows := make([][]int, len(ar)+1)
+
+	for i := range rows {
+		rows[i] = make([]int, len(br)+1)
+		rows[i][0] = i
+	}
+
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		for j := 1; j <= len(br); j++ {
+			cost := ternary(ar[i-1] == br[j-1], 0, 1)
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(ar)][len(br)]
+}
+
+// Rewrites the main config file along with all of the files it includes and
+// any overlays. Only files which end up changing get written, the originals
+// are kept next to them with a .bak extension
+func cliMigrateConfig(mainFilePath string, overlayPaths []string, restrictIncludes bool) int {
+	_, includes, err := parseConfigFiles(mainFilePath, overlayPaths, restrictIncludes)
+	if err != nil {
+		fmt.Printf("Could not parse config file: %v\n", err)
+		return 1
+	}
+
+	mainFileAbsPath, err := filepath.Abs(mainFilePath)
+	if err != nil {
+		fmt.Printf("Could not get the absolute path of %s: %v\n", mainFilePath, err)
+		return 1
+	}
+
+	mainRoot, mainContents, err := readConfigFileNode(mainFileAbsPath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	version, err := configSchemaVersion(mainRoot)
+	if err != nil {
+		fmt.Printf("%s: %v\n", mainFilePath, err)
+		return 1
+	}
+
+	if version > currentConfigSchemaVersion {
+		fmt.Printf("Config is at schema version %d, which is newer than the %d supported by this version of Glance\n", version, currentConfigSchemaVersion)
+		return 1
+	}
+
+	otherPaths := make([]string, 0, len(includes))
+	for path := range includes {
+		if path != mainFileAbsPath {
+			otherPaths = append(otherPaths, path)
+		}
+	}
+	slices.Sort(otherPaths)
+
+	failed := false
+	changedFiles := 0
+
+	migrate := func(path string, root *yaml.Node, original []byte, isMain bool) {
+		changes := migrateConfigNode(root, version)
+
+		if isMain && setConfigSchemaVersion(root, currentConfigSchemaVersion) {
+			changes = append(changes, configMigrationChange{1, fmt.Sprintf("set %s to %d", configSchemaVersionKey, currentConfigSchemaVersion)})
+		}
+
+		if len(changes) == 0 {
+			return
+		}
+
+		for _, change := range changes {
+			fmt.Printf("%s:%d: %s\n", path, change.line, change.description)
+		}
+
+		if err := writeMigratedConfigFile(path, root, original); err != nil {
+			fmt.Printf("Could not write %s: %v\n", path, err)
+			failed = true
+			return
+		}
+
+		changedFiles++
+	}
+
+	migrate(mainFileAbsPath, mainRoot, mainContents, true)
+
+	for _, path := range otherPaths {
+		root, contents, err := readConfigFileNode(path)
+		if err != nil {
+			fmt.Println(err)
+			failed = true
+			continue
+		}
+
+		migrate(path, root, contents, false)
+	}
+
+	if failed {
+		return 1
+	}
+
+	if changedFiles == 0 {
+		fmt.Printf("Config is already at schema version %d, nothing to migrate\n", currentConfigSchemaVersion)
+		return 0
+	}
+
+	fmt.Printf("Migrated %d file(s) from schema version %d to %d, the originals were saved with a .bak extension\n", changedFiles, version, currentConfigSchemaVersion)
+	return 0
+}
+
+func readConfigFileNode(path string) (*yaml.Node, []byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return &root, contents, nil
+}
+
+// Returns false if the version was already set to the given one
+func setConfigSchemaVersion(root *yaml.Node, version int) bool {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+
+	value := strconv.Itoa(version)
+	_, existing := configSchemaVersionNode(root)
+
+	if existing != nil {
+		if existing.Value == value {
+			return false
+		}
+
+		existing.Kind, existing.Tag, existing.Value = yaml.ScalarNode, "!!int", value
+		return true
+	}
+
+	document := root.Content[0]
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: configSchemaVersionKey}
+
+	// comments at the top of the file are kept above the version
+	if len(document.Content) > 0 {
+		key.HeadComment, document.Content[0].HeadComment = document.Content[0].HeadComment, ""
+	}
+
+	document.Content = append([]*yaml.Node{key, {Kind: yaml.ScalarNode, Tag: "!!int", Value: value}}, document.Content...)
+
+	return true
+}
+
+func writeMigratedConfigFile(path string, root *yaml.Node, original []byte) error {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".bak", original, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// same as with the widget cache, a crash midway through
+	// writing shouldn't leave behind a partial file
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(buffer.Bytes())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
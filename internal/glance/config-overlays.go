@@ -0,0 +1,150 @@
+package glance
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The overlay selected through GLANCE_ENV is the file next to the main config
+// with the name of the environment before its extension, such as glance.prod.yml
+// for glance.yml, and gets merged before any of the ones given through flags
+func configOverlayPaths(mainFilePath string, flagPaths []string) []string {
+	paths := make([]string, 0, len(flagPaths)+1)
+
+	if env := strings.TrimSpace(os.Getenv("GLANCE_ENV")); env != "" {
+		ext := filepath.Ext(mainFilePath)
+		paths = append(paths, strings.TrimSuffix(mainFilePath, ext)+"."+env+ext)
+	}
+
+	return append(paths, flagPaths...)
+}
+
+// Same as parseYAMLIncludes, except that the overlays, after having their own
+// includes resolved, get merged over the main file one after another. The
+// overlays and their includes are part of the returned includes so that
+// changes to them also get picked up by the watcher
+func parseConfigFiles(mainFilePath string, overlayPaths []string, restrictToConfigDir bool) ([]byte, map[string]struct{}, error) {
+	contents, includes, err := parseYAMLIncludes(mainFilePath, restrictToConfigDir)
+	if err != nil || len(overlayPaths) == 0 {
+		return contents, includes, err
+	}
+
+	var merged yaml.Node
+	if err := yaml.Unmarshal(contents, &merged); err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %w", mainFilePath, err)
+	}
+
+	for _, overlayPath := range overlayPaths {
+		overlayContents, overlayIncludes, err := parseYAMLIncludes(overlayPath, restrictToConfigDir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		overlayAbsPath, err := filepath.Abs(overlayPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting absolute path of %s: %w", overlayPath, err)
+		}
+
+		includes[overlayAbsPath] = struct{}{}
+		maps.Copy(includes, overlayIncludes)
+
+		var overlay yaml.Node
+		if err := yaml.Unmarshal(overlayContents, &overlay); err != nil {
+			return nil, nil, fmt.Errorf("parsing overlay %s: %w", overlayPath, err)
+		}
+
+		mergedNode, err := mergeConfigOverlay(&merged, &overlay)
+		if err != nil {
+			return nil, nil, fmt.Errorf("merging overlay %s: %w", overlayPath, err)
+		}
+		merged = *mergedNode
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&merged); err != nil {
+		return nil, nil, fmt.Errorf("encoding merged config: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("encoding merged config: %w", err)
+	}
+
+	return buffer.Bytes(), includes, nil
+}
+
+// Maps are merged key by key, the same way as orderedYAMLMap.Merge, where the
+// keys of the base keep their position and keys which only exist in the overlay
+// are added after them. Everything else, including arrays, gets replaced by the
+// value of the overlay as a whole
+func mergeConfigOverlay(base, overlay *yaml.Node) (*yaml.Node, error) {
+	switch {
+	// empty files
+	case overlay.Kind == 0:
+		return base, nil
+	case base.Kind == 0:
+		return overlay, nil
+	case base.Kind == yaml.DocumentNode && overlay.Kind == yaml.DocumentNode:
+		content, err := mergeConfigOverlay(base.Content[0], overlay.Content[0])
+		if err != nil {
+			return nil, err
+		}
+
+		merged := *base
+		merged.Content = []*yaml.Node{content}
+		return &merged, nil
+	case base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode:
+		return overlay, nil
+	}
+
+	var baseMap, overlayMap orderedYAMLMap[string, yaml.Node]
+
+	if err := base.Decode(&baseMap); err != nil {
+		return nil, fmt.Errorf("line %d: %v", base.Line, err)
+	}
+
+	if err := overlay.Decode(&overlayMap); err != nil {
+		return nil, fmt.Errorf("line %d: %v", overlay.Line, err)
+	}
+
+	// the original key nodes are kept so that their style and comments aren't lost
+	keyNodes := make(map[string]*yaml.Node, len(base.Content)/2+len(overlay.Content)/2)
+	for _, node := range []*yaml.Node{overlay, base} {
+		for i := 0; i < len(node.Content); i += 2 {
+			var key string
+			if err := node.Content[i].Decode(&key); err == nil {
+				keyNodes[key] = node.Content[i]
+			}
+		}
+	}
+
+	merged := *base
+	merged.Content = make([]*yaml.Node, 0, len(keyNodes)*2)
+
+	for key, value := range baseMap.Merge(&overlayMap).Items() {
+		baseValue, inBase := baseMap.Get(key)
+		_, inOverlay := overlayMap.Get(key)
+
+		if !inBase || !inOverlay {
+			merged.Content = append(merged.Content, keyNodes[key], &value)
+			continue
+		}
+
+		mergedValue, err := mergeConfigOverlay(&baseValue, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		merged.Content = append(merged.Content, keyNodes[key], mergedValue)
+	}
+
+	return &merged, nil
+}
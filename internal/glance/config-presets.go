@@ -0,0 +1,198 @@
+package glance
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configPresetsKey = "presets"
+const configPresetUseKey = "use"
+
+// Expands items of lists which reference one of the presets defined at the top
+// level of the config through `use: preset-name`. A preset is either a single
+// widget/column or a list of them, all other properties set next to `use` get
+// deep merged into each one of the preset's items. Presets can make use of
+// other presets.
+//
+// The presets themselves are removed from the config once they've been expanded.
+func expandConfigPresets(root *yaml.Node) error {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	document := root.Content[0]
+	presets := make(map[string]*yaml.Node)
+
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if document.Content[i].Value != configPresetsKey {
+			continue
+		}
+
+		presetsNode := resolveYAMLAlias(document.Content[i+1])
+		if presetsNode.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: presets must be a map", presetsNode.Line)
+		}
+
+		for j := 0; j+1 < len(presetsNode.Content); j += 2 {
+			name, value := presetsNode.Content[j], resolveYAMLAlias(presetsNode.Content[j+1])
+			if value.Kind != yaml.MappingNode && value.Kind != yaml.SequenceNode {
+				return fmt.Errorf("line %d: presets.%s must be a widget, a column or a list of them", value.Line, name.Value)
+			}
+
+			presets[name.Value] = value
+		}
+
+		document.Content = append(document.Content[:i], document.Content[i+2:]...)
+		break
+	}
+
+	var expand func(node *yaml.Node, expanding []string) error
+	expand = func(node *yaml.Node, expanding []string) error {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 1; i < len(node.Content); i += 2 {
+				if err := expand(node.Content[i], expanding); err != nil {
+					return err
+				}
+			}
+		case yaml.SequenceNode:
+			expanded := make([]*yaml.Node, 0, len(node.Content))
+
+			for _, item := range node.Content {
+				name, overrides, uses := configPresetReference(item)
+				if !uses {
+					if err := expand(item, expanding); err != nil {
+						return err
+					}
+					expanded = append(expanded, item)
+					continue
+				}
+
+				preset, exists := presets[name]
+				if !exists {
+					return fmt.Errorf("line %d: preset %s does not exist", item.Line, name)
+				}
+
+				for _, n := range expanding {
+					if n == name {
+						return fmt.Errorf("line %d: preset %s uses itself (%s -> %s)", item.Line, name, strings.Join(expanding, " -> "), name)
+					}
+				}
+
+				presetItems := []*yaml.Node{preset}
+				if preset.Kind == yaml.SequenceNode {
+					presetItems = preset.Content
+				}
+
+				// wrapped in a list so that presets referenced from within
+				// the preset also get expanded in place
+				copied := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: item.Line}
+				for _, presetItem := range presetItems {
+					copied.Content = append(copied.Content, copyYAMLNode(presetItem))
+				}
+
+				if err := expand(copied, append(expanding, name)); err != nil {
+					return err
+				}
+
+				for _, presetItem := range copied.Content {
+					if overrides != nil {
+						mergeYAMLNodes(presetItem, copyYAMLNode(overrides))
+					}
+					expanded = append(expanded, presetItem)
+				}
+			}
+
+			node.Content = expanded
+		}
+
+		return nil
+	}
+
+	return expand(document, nil)
+}
+
+// Returns the name of the preset that the node uses along with a mapping of the
+// rest of its properties, or nil if it doesn't have any
+func configPresetReference(node *yaml.Node) (string, *yaml.Node, bool) {
+	if node.Kind != yaml.MappingNode {
+		return "", nil, false
+	}
+
+	var name string
+	var uses bool
+	overrides := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: node.Line}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == configPresetUseKey && node.Content[i+1].Kind == yaml.ScalarNode {
+			name, uses = node.Content[i+1].Value, true
+			continue
+		}
+
+		overrides.Content = append(overrides.Content, node.Content[i], node.Content[i+1])
+	}
+
+	if !uses {
+		return "", nil, false
+	}
+
+	if len(overrides.Content) == 0 {
+		return name, nil, true
+	}
+
+	return name, overrides, true
+}
+
+// Maps get merged key by key, anything else in dst gets replaced by src
+func mergeYAMLNodes(dst, src *yaml.Node) {
+	src = resolveYAMLAlias(src)
+
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		*dst = *src
+		return
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		merged := false
+
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				mergeYAMLNodes(dst.Content[j+1], value)
+				merged = true
+				break
+			}
+		}
+
+		if !merged {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
+
+// Aliases get replaced with copies of the nodes they point to so that
+// changes made to the copy don't end up in other parts of the config
+func copyYAMLNode(node *yaml.Node) *yaml.Node {
+	node = resolveYAMLAlias(node)
+	copied := *node
+	copied.Anchor = ""
+
+	if len(node.Content) > 0 {
+		copied.Content = make([]*yaml.Node, len(node.Content))
+		for i := range node.Content {
+			copied.Content[i] = copyYAMLNode(node.Content[i])
+		}
+	}
+
+	return &copied
+}
+
+func resolveYAMLAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
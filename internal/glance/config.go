@@ -10,7 +10,9 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -25,20 +27,75 @@ const (
 	configVarTypeEnv         = "env"
 	configVarTypeSecret      = "secret"
 	configVarTypeFileFromEnv = "readFileFromEnv"
+	configVarTypeFile        = "file"
 )
 
+// Widgets whose cache or refresh is set lower than this get updated at
+// this interval instead, so that they don't overwhelm the upstreams
+const defaultMinWidgetRefresh = 10 * time.Second
+
+type serverConfig struct {
+	Host       string `yaml:"host"`
+	Port       uint16 `yaml:"port"`
+	Proxied    bool   `yaml:"proxied"`
+	AssetsPath string `yaml:"assets-path"`
+	CachePath  string `yaml:"cache-path"`
+	DataPath   string `yaml:"data-path"`
+	BaseURL    string `yaml:"base-url"`
+
+	MaxConcurrentRequests        int           `yaml:"max-concurrent-requests"`
+	MaxConcurrentRequestsPerHost int           `yaml:"max-concurrent-requests-per-host"`
+	InitialUpdateJitter          durationField `yaml:"initial-update-jitter"`
+	MinWidgetRefresh             durationField `yaml:"min-widget-refresh"`
+	LiveUpdates                  bool          `yaml:"live-updates"`
+
+	ReadTimeout     durationField `yaml:"read-timeout"`
+	WriteTimeout    durationField `yaml:"write-timeout"`
+	IdleTimeout     durationField `yaml:"idle-timeout"`
+	ShutdownTimeout durationField `yaml:"shutdown-timeout"`
+
+	// Used to evaluate the visible-when condition of widgets, defaults
+	// to the local timezone of the server
+	Timezone string         `yaml:"timezone"`
+	location *time.Location `yaml:"-"`
+
+	Metrics struct {
+		Enabled bool   `yaml:"enabled"`
+		Address string `yaml:"address"`
+	} `yaml:"metrics"`
+
+	TLS               serverTLSOptions       `yaml:"tls"`
+	Guard             accessGuardOptions     `yaml:"guard"`
+	RateLimit         clientRateLimitOptions `yaml:"rate-limit"`
+	ProxyAllowedHosts hostAllowlist          `yaml:"proxy-allowed-hosts"`
+
+	LogLevel  string `yaml:"log-level"`
+	LogFormat string `yaml:"log-format"`
+}
+
+// The rate limit, the proxy hosts and the live updates are only used by the
+// handler, so they don't need a restart since it gets replaced every time the
+// config is reloaded
+func (c *serverConfig) requiresRestart(previous *serverConfig) bool {
+	current, before := *c, *previous
+	current.RateLimit, before.RateLimit = clientRateLimitOptions{}, clientRateLimitOptions{}
+	current.ProxyAllowedHosts, before.ProxyAllowedHosts = nil, nil
+	current.LiveUpdates, before.LiveUpdates = false, false
+
+	return !reflect.DeepEqual(current, before)
+}
+
 type config struct {
-	Server struct {
-		Host       string `yaml:"host"`
-		Port       uint16 `yaml:"port"`
-		Proxied    bool   `yaml:"proxied"`
-		AssetsPath string `yaml:"assets-path"`
-		BaseURL    string `yaml:"base-url"`
-	} `yaml:"server"`
+	SchemaVersion int `yaml:"schema-version"`
+
+	Server     serverConfig      `yaml:"server"`
+	HTTPClient httpClientOptions `yaml:"http-client"`
+	Vars       map[string]string `yaml:"vars"`
 
 	Auth struct {
-		SecretKey string           `yaml:"secret-key"`
-		Users     map[string]*user `yaml:"users"`
+		SecretKey       string           `yaml:"secret-key"`
+		SessionLifetime durationField    `yaml:"session-lifetime"`
+		Users           map[string]*user `yaml:"users"`
 	} `yaml:"auth"`
 
 	Document struct {
@@ -49,8 +106,10 @@ type config struct {
 		themeProperties `yaml:",inline"`
 		CustomCSSFile   string `yaml:"custom-css-file"`
 
-		DisablePicker bool                                     `yaml:"disable-picker"`
-		Presets       orderedYAMLMap[string, *themeProperties] `yaml:"presets"`
+		DisablePicker  bool                                     `yaml:"disable-picker"`
+		Presets        orderedYAMLMap[string, *themeProperties] `yaml:"presets"`
+		Auto           themeAutoField                           `yaml:"auto"`
+		AutoProperties *themeProperties                         `yaml:"-"`
 	} `yaml:"theme"`
 
 	Branding struct {
@@ -61,10 +120,36 @@ type config struct {
 		FaviconURL         string        `yaml:"favicon-url"`
 		FaviconType        string        `yaml:"-"`
 		AppName            string        `yaml:"app-name"`
+		AppShortName       string        `yaml:"app-short-name"`
 		AppIconURL         string        `yaml:"app-icon-url"`
+		AppIcons           []appIcon     `yaml:"app-icons"`
+		AppleTouchIcons    []appIcon     `yaml:"-"`
 		AppBackgroundColor string        `yaml:"app-background-color"`
+		AppThemeColor      string        `yaml:"app-theme-color"`
 	} `yaml:"branding"`
 
+	CommandPalette struct {
+		Disabled bool `yaml:"disabled"`
+	} `yaml:"command-palette"`
+
+	Formatting formattingOptions `yaml:"formatting"`
+
+	Pages      []page      `yaml:"pages"`
+	Dashboards []dashboard `yaml:"dashboards"`
+}
+
+type appIcon struct {
+	URL     string `yaml:"url"`
+	Sizes   string `yaml:"sizes"`
+	Type    string `yaml:"type"`
+	Purpose string `yaml:"purpose"`
+}
+
+// A separate set of pages served under its own path, the pages of the first
+// dashboard are the ones served at the root
+type dashboard struct {
+	Name  string `yaml:"name"`
+	Slug  string `yaml:"slug"`
 	Pages []page `yaml:"pages"`
 }
 
@@ -75,119 +160,439 @@ type user struct {
 }
 
 type page struct {
-	Title                  string  `yaml:"name"`
-	Slug                   string  `yaml:"slug"`
-	Width                  string  `yaml:"width"`
-	DesktopNavigationWidth string  `yaml:"desktop-navigation-width"`
-	ShowMobileHeader       bool    `yaml:"show-mobile-header"`
-	HideDesktopNavigation  bool    `yaml:"hide-desktop-navigation"`
-	CenterVertically       bool    `yaml:"center-vertically"`
-	HeadWidgets            widgets `yaml:"head-widgets"`
+	Title                  string            `yaml:"name"`
+	Slug                   string            `yaml:"slug"`
+	Width                  string            `yaml:"width"`
+	DesktopNavigationWidth string            `yaml:"desktop-navigation-width"`
+	ShowMobileHeader       bool              `yaml:"show-mobile-header"`
+	HideDesktopNavigation  bool              `yaml:"hide-desktop-navigation"`
+	CenterVertically       bool              `yaml:"center-vertically"`
+	Kiosk                  kioskOptions      `yaml:"kiosk"`
+	BrowserCache           browserCacheField `yaml:"browser-cache"`
+	Theme                  *themeProperties  `yaml:"theme"`
+	HeadWidgets            widgets           `yaml:"head-widgets"`
 	Columns                []struct {
 		Size    string  `yaml:"size"`
 		Widgets widgets `yaml:"widgets"`
 	} `yaml:"columns"`
-	PrimaryColumnIndex int8       `yaml:"-"`
-	mu                 sync.Mutex `yaml:"-"`
+	PrimaryColumnIndex int8 `yaml:"-"`
+	// The browser cache of the page, limited by that of its widgets since
+	// they're all part of the same response
+	contentBrowserCache browserCacheField `yaml:"-"`
+	mu                  sync.Mutex        `yaml:"-"`
+	// Bumped whenever the content of any of the widgets may have changed,
+	// all three are guarded by mu
+	dataVersion   uint64    `yaml:"-"`
+	dataUpdatedAt time.Time `yaml:"-"`
+	// The data version at which widgets were last shown or hidden
+	layoutVersion uint64          `yaml:"-"`
+	live          pageLiveUpdates `yaml:"-"`
+}
+
+// Either `kiosk: true` or an object with the options of the kiosk mode, which
+// can also be enabled for any page through the kiosk query parameter
+type kioskOptions struct {
+	Enabled         bool          `yaml:"enabled"`
+	RefreshInterval durationField `yaml:"refresh-interval"`
+	FontScale       float64       `yaml:"font-scale"`
 }
 
-func newConfigFromYAML(contents []byte) (*config, error) {
+func (o *kioskOptions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&o.Enabled)
+	}
+
+	type alias kioskOptions
+	if err := node.Decode((*alias)(o)); err != nil {
+		return err
+	}
+
+	o.Enabled = true
+	return nil
+}
+
+// Used within the templates, which don't have a way of converting durations
+func (o *kioskOptions) RefreshIntervalMs() int64 {
+	return time.Duration(o.RefreshInterval).Milliseconds()
+}
+
+// Relative paths within the config, such as those of template files,
+// are resolved against configDir
+func newConfigFromYAML(contents []byte, configDir string) (*config, error) {
 	contents, err := parseConfigVariables(contents)
 	if err != nil {
 		return nil, err
 	}
 
+	var root yaml.Node
+	if err = yaml.Unmarshal(contents, &root); err != nil {
+		return nil, err
+	}
+
+	if err = resolveConfigVarReferences(&root); err != nil {
+		return nil, err
+	}
+
+	if err = expandConfigPresets(&root); err != nil {
+		return nil, err
+	}
+
+	if err = applyConfigMigrations(&root); err != nil {
+		return nil, err
+	}
+
+	warnAboutUnknownConfigKeys(&root)
+
 	config := &config{}
 	config.Server.Port = 8080
-
-	err = yaml.Unmarshal(contents, config)
-	if err != nil {
+	config.Server.ReadTimeout = durationField(30 * time.Second)
+	config.Server.WriteTimeout = durationField(2 * time.Minute)
+	config.Server.IdleTimeout = durationField(2 * time.Minute)
+	config.Server.ShutdownTimeout = durationField(10 * time.Second)
+	config.Server.MinWidgetRefresh = durationField(defaultMinWidgetRefresh)
+
+	var errs configErrors
+
+	err = root.Decode(config)
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		// type errors don't stop the decoding, so the rest of
+		// the config can still be checked for further errors
+		for _, message := range typeErr.Errors {
+			errs = append(errs, errors.New(message))
+		}
+	} else if err != nil {
 		return nil, err
 	}
 
+	if len(config.Dashboards) > 0 {
+		if len(config.Pages) > 0 {
+			errs = append(errs, errors.New("pages can not be set at the top level when using dashboards, they must be set within each dashboard"))
+		}
+
+		config.Pages = config.Dashboards[0].Pages
+	}
+
 	if err = isConfigStateValid(config); err != nil {
-		return nil, err
+		errs = append(errs, err)
 	}
 
-	for p := range config.Pages {
-		for w := range config.Pages[p].HeadWidgets {
-			if err := config.Pages[p].HeadWidgets[w].initialize(); err != nil {
-				return nil, formatWidgetInitError(err, config.Pages[p].HeadWidgets[w])
+	for _, pages := range config.pagesOfAllDashboards() {
+		for p := range pages {
+			for w := range pages[p].HeadWidgets {
+				if err := pages[p].HeadWidgets[w].initialize(); err != nil {
+					errs = append(errs, formatWidgetInitError(err, pages[p].HeadWidgets[w]))
+				}
+			}
+
+			for c := range pages[p].Columns {
+				for w := range pages[p].Columns[c].Widgets {
+					if err := pages[p].Columns[c].Widgets[w].initialize(); err != nil {
+						errs = append(errs, formatWidgetInitError(err, pages[p].Columns[c].Widgets[w]))
+					}
+				}
 			}
 		}
+	}
+
+	if len(errs) == 0 {
+		minRefresh := time.Duration(config.Server.MinWidgetRefresh)
+
+		for _, pages := range config.pagesOfAllDashboards() {
+			for p := range pages {
+				if err := enforceMinimumWidgetRefresh(pages[p].HeadWidgets, minRefresh); err != nil {
+					errs = append(errs, err)
+				}
+
+				if err := loadWidgetTemplateFiles(pages[p].HeadWidgets, configDir); err != nil {
+					errs = append(errs, err)
+				}
 
-		for c := range config.Pages[p].Columns {
-			for w := range config.Pages[p].Columns[c].Widgets {
-				if err := config.Pages[p].Columns[c].Widgets[w].initialize(); err != nil {
-					return nil, formatWidgetInitError(err, config.Pages[p].Columns[c].Widgets[w])
+				for c := range pages[p].Columns {
+					if err := enforceMinimumWidgetRefresh(pages[p].Columns[c].Widgets, minRefresh); err != nil {
+						errs = append(errs, err)
+					}
+
+					if err := loadWidgetTemplateFiles(pages[p].Columns[c].Widgets, configDir); err != nil {
+						errs = append(errs, err)
+					}
 				}
 			}
 		}
 	}
 
+	if len(errs) == 1 {
+		return nil, errs[0]
+	} else if len(errs) > 1 {
+		return nil, errs
+	}
+
 	return config, nil
 }
 
+type configErrors []error
+
+func (errs configErrors) Error() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d errors:", len(errs))
+
+	for _, err := range errs {
+		builder.WriteString("\n  ")
+		builder.WriteString(err.Error())
+	}
+
+	return builder.String()
+}
+
 var envVariableNamePattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
-var configVariablePattern = regexp.MustCompile(`(^|.)\$\{(?:([a-zA-Z]+):)?([a-zA-Z0-9_-]+)\}`)
+var configVariablePattern = regexp.MustCompile(`\$\{(?:([a-zA-Z]+):)?([a-zA-Z0-9_][a-zA-Z0-9_-]*|[/.~][^}\s]*?)(?::-([^}\n]*))?\}`)
+var configKeyOnLinePattern = regexp.MustCompile(`^\s*(?:-\s+)?([^\s:#][^:#]*?)\s*:`)
 
 // Parses variables defined in the config such as:
 // ${API_KEY} 				            - gets replaced with the value of the API_KEY environment variable
+// ${API_KEY:-default}			        - same as above but uses default when API_KEY is not set or is empty
 // \${API_KEY} 					        - escaped, gets used as is without the \ in the config
+// $${API_KEY} 					        - escaped, gets used as is without the first $ in the config
 // ${secret:api_key} 			        - value gets loaded from /run/secrets/api_key
 // ${readFileFromEnv:PATH_TO_SECRET}    - value gets loaded from the file path specified in the environment variable PATH_TO_SECRET
+// ${file:/run/secrets/token}           - value gets loaded from the given absolute path, without its trailing newlines
+//
+// Variables are only substituted within values, never within keys.
 //
 // TODO: don't match against commented out sections, not sure exactly how since
 // variables can be placed anywhere and used to modify the YAML structure itself
 func parseConfigVariables(contents []byte) ([]byte, error) {
-	var err error
+	matches := configVariablePattern.FindAllSubmatchIndex(contents, -1)
+	if len(matches) == 0 {
+		return contents, nil
+	}
 
-	replaced := configVariablePattern.ReplaceAllFunc(contents, func(match []byte) []byte {
-		if err != nil {
-			return nil
+	var replaced bytes.Buffer
+	replaced.Grow(len(contents))
+	lastEnd := 0
+
+	for _, m := range matches {
+		start, end := m[0], m[1]
+
+		if start > 0 && (contents[start-1] == '\\' || contents[start-1] == '$') {
+			replaced.Write(contents[lastEnd : start-1])
+			replaced.Write(contents[start:end])
+			lastEnd = end
+			continue
 		}
 
-		groups := configVariablePattern.FindSubmatch(match)
-		if len(groups) != 4 {
-			// we can't handle this match, this shouldn't happen unless the number of groups
-			// in the regex has been changed without updating the below code
-			return match
+		replaced.Write(contents[lastEnd:start])
+		lastEnd = end
+
+		if isConfigVariableInKeyPosition(contents, start, end) {
+			replaced.Write(contents[start:end])
+			continue
 		}
 
-		prefix := string(groups[1])
-		if prefix == `\` {
-			if len(match) >= 2 {
-				return match[1:]
-			} else {
-				return nil
+		submatch := func(i int) string {
+			if m[i*2] == -1 {
+				return ""
 			}
+			return string(contents[m[i*2]:m[i*2+1]])
 		}
 
-		typeAsString, variableName := string(groups[2]), string(groups[3])
+		typeAsString, variableName := submatch(1), submatch(2)
 		variableType := ternary(typeAsString == "", configVarTypeEnv, typeAsString)
 
-		parsedValue, returnOriginal, localErr := parseConfigVariableOfType(variableType, variableName)
-		if localErr != nil {
-			err = fmt.Errorf("parsing variable: %v", localErr)
-			return nil
+		// paths are only read as files when explicitly asked to, anything else
+		// would end up in the config as is, which is almost certainly a typo
+		if strings.ContainsAny(variableName[:1], "/.~") && variableType != configVarTypeFile {
+			return nil, fmt.Errorf(
+				"parsing variable %s: %s is a path, files are read with ${file:%s}",
+				describeConfigLocation(contents, start), variableName, variableName,
+			)
+		}
+
+		defaultValue, hasDefault := "", m[6] != -1
+		if hasDefault {
+			defaultValue = submatch(3)
+		}
+
+		parsedValue, returnOriginal, err := parseConfigVariableOfType(variableType, variableName, defaultValue, hasDefault)
+		if err != nil {
+			return nil, fmt.Errorf("parsing variable %s: %v", describeConfigLocation(contents, start), err)
 		}
 
 		if returnOriginal {
-			return match
+			replaced.Write(contents[start:end])
+			continue
 		}
 
-		return []byte(prefix + parsedValue)
-	})
+		replaced.WriteString(parsedValue)
+	}
 
-	if err != nil {
-		return nil, err
+	replaced.Write(contents[lastEnd:])
+
+	return replaced.Bytes(), nil
+}
+
+var configVarReferencePattern = regexp.MustCompile(`\$\{vars\.([a-zA-Z0-9_][a-zA-Z0-9_-]*)\}`)
+
+// Replaces references such as ${vars.base_url} with the values defined in the
+// top level vars property. This happens after environment variables have been
+// substituted so that vars can be built from them, and on the parsed document
+// rather than the raw text so that the values can't change the structure of the
+// YAML. Nodes referenced through aliases are resolved where their anchor is
+// defined, meaning that anchors and vars can be used together.
+//
+// Values of vars can't reference other vars.
+func resolveConfigVarReferences(root *yaml.Node) error {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
 	}
 
-	return replaced, nil
+	document := root.Content[0]
+	vars := make(map[string]string)
+	var varsNode *yaml.Node
+
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if document.Content[i].Value != "vars" {
+			continue
+		}
+
+		varsNode = document.Content[i+1]
+		if varsNode.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: vars must be a map", varsNode.Line)
+		}
+
+		for j := 0; j+1 < len(varsNode.Content); j += 2 {
+			name, value := varsNode.Content[j], varsNode.Content[j+1]
+			if value.Kind == yaml.AliasNode {
+				value = value.Alias
+			}
+
+			if value.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: vars.%s must be a string, number or boolean", value.Line, name.Value)
+			}
+
+			vars[name.Value] = value.Value
+		}
+	}
+
+	var resolve func(node *yaml.Node, path string) error
+	resolve = func(node *yaml.Node, path string) error {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				value := node.Content[i+1]
+				if value == varsNode {
+					continue
+				}
+
+				if err := resolve(value, joinConfigKeyPath(path, node.Content[i].Value)); err != nil {
+					return err
+				}
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				if err := resolve(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		case yaml.ScalarNode:
+			resolved, changed, err := replaceConfigVarReferences(node.Value, vars)
+			if err != nil {
+				return fmt.Errorf("line %d: %s: %v", node.Line, path, err)
+			}
+
+			if changed {
+				node.Value = resolved
+				// lets the type get inferred from the new value, so that
+				// vars can also be used for things like numbers
+				if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+					node.Tag = ""
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return resolve(document, "")
+}
+
+func joinConfigKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// Same as with environment variables, references can be escaped with either a \ or a $
+func replaceConfigVarReferences(value string, vars map[string]string) (string, bool, error) {
+	matches := configVarReferencePattern.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, false, nil
+	}
+
+	var replaced strings.Builder
+	lastEnd := 0
+
+	for _, m := range matches {
+		start, end := m[0], m[1]
+
+		if start > 0 && (value[start-1] == '\\' || value[start-1] == '$') {
+			replaced.WriteString(value[lastEnd : start-1])
+			replaced.WriteString(value[start:end])
+			lastEnd = end
+			continue
+		}
+
+		name := value[m[2]:m[3]]
+		varValue, exists := vars[name]
+		if !exists {
+			return "", false, fmt.Errorf("undefined variable vars.%s", name)
+		}
+
+		replaced.WriteString(value[lastEnd:start])
+		replaced.WriteString(varValue)
+		lastEnd = end
+	}
+
+	replaced.WriteString(value[lastEnd:])
+
+	return replaced.String(), true, nil
+}
+
+var configKeyPrefixPattern = regexp.MustCompile(`^[ \t]*(?:-[ \t]+)?$`)
+var configKeySuffixPattern = regexp.MustCompile(`^[ \t]*:(?:[ \t]|$)`)
+
+// Variables only get substituted within values, a variable that makes up
+// an entire key such as `${NAME}: value` is kept as is
+func isConfigVariableInKeyPosition(contents []byte, start, end int) bool {
+	lineStart := bytes.LastIndexByte(contents[:start], '\n') + 1
+	lineEnd := bytes.IndexByte(contents[end:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(contents)
+	} else {
+		lineEnd += end
+	}
+
+	return configKeyPrefixPattern.Match(contents[lineStart:start]) &&
+		configKeySuffixPattern.Match(contents[end:lineEnd])
+}
+
+// Returns the line number and if possible the key at the given offset,
+// used to point users at the right place when a variable can't be parsed
+func describeConfigLocation(contents []byte, offset int) string {
+	lineStart := bytes.LastIndexByte(contents[:offset], '\n') + 1
+	line := bytes.Count(contents[:lineStart], []byte("\n")) + 1
+
+	matches := configKeyOnLinePattern.FindSubmatch(contents[lineStart:offset])
+	if len(matches) != 2 {
+		return fmt.Sprintf("on line %d", line)
+	}
+
+	return fmt.Sprintf("on line %d (key %s)", line, matches[1])
 }
 
 // When the bool return value is true, it indicates that the caller should use the original value
-func parseConfigVariableOfType(variableType, variableName string) (string, bool, error) {
+func parseConfigVariableOfType(variableType, variableName, defaultValue string, hasDefault bool) (string, bool, error) {
 	switch variableType {
 	case configVarTypeEnv:
 		if !envVariableNamePattern.MatchString(variableName) {
@@ -195,6 +600,10 @@ func parseConfigVariableOfType(variableType, variableName string) (string, bool,
 		}
 
 		v, found := os.LookupEnv(variableName)
+		if hasDefault && v == "" {
+			return defaultValue, false, nil
+		}
+
 		if !found {
 			return "", false, fmt.Errorf("environment variable %s not found", variableName)
 		}
@@ -228,22 +637,61 @@ func parseConfigVariableOfType(variableType, variableName string) (string, bool,
 		}
 
 		return strings.TrimSpace(string(fileContents)), false, nil
+	case configVarTypeFile:
+		if !strings.HasPrefix(variableName, "/") {
+			return "", false, fmt.Errorf("file: path %s is not absolute", variableName)
+		}
+
+		if hasDefault {
+			return "", false, fmt.Errorf("file: %s can't have a default value", variableName)
+		}
+
+		fileContents, err := os.ReadFile(variableName)
+		if err != nil {
+			return "", false, fmt.Errorf("file: %v", err)
+		}
+
+		// only the newlines get trimmed since editors and tools such as echo add
+		// them, any other whitespace may well be part of the secret
+		return strings.TrimRight(string(fileContents), "\r\n"), false, nil
 	default:
 		return "", true, nil
 	}
 }
 
 func formatWidgetInitError(err error, w widget) error {
+	if line := w.getLine(); line > 0 {
+		return fmt.Errorf("line %d: %s widget: %v", line, w.GetType(), err)
+	}
+
 	return fmt.Errorf("%s widget: %v", w.GetType(), err)
 }
 
-var configIncludePattern = regexp.MustCompile(`(?m)^([ \t]*)(?:-[ \t]*)?(?:!|\$)include:[ \t]*(.+)$`)
+var configIncludePattern = regexp.MustCompile(`(?m)^([ \t]*)(?:-[ \t]*)?(?:!|\$)include(?::[ \t]*|[ \t]+)(.+)$`)
 
-func parseYAMLIncludes(mainFilePath string) ([]byte, map[string]struct{}, error) {
-	return recursiveParseYAMLIncludes(mainFilePath, nil, 0)
+// When restrictToConfigDir is true, including files that are outside of
+// the directory of the main config file results in an error
+func parseYAMLIncludes(mainFilePath string, restrictToConfigDir bool) ([]byte, map[string]struct{}, error) {
+	rootDir := ""
+	if restrictToConfigDir {
+		mainFileAbsPath, err := filepath.Abs(mainFilePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting absolute path of %s: %w", mainFilePath, err)
+		}
+
+		rootDir = resolveSymlinksIfPossible(filepath.Dir(mainFileAbsPath))
+	}
+
+	return recursiveParseYAMLIncludes(mainFilePath, nil, nil, rootDir, 0)
 }
 
-func recursiveParseYAMLIncludes(mainFilePath string, includes map[string]struct{}, depth int) ([]byte, map[string]struct{}, error) {
+func recursiveParseYAMLIncludes(
+	mainFilePath string,
+	includes map[string]struct{},
+	chain []string,
+	rootDir string,
+	depth int,
+) ([]byte, map[string]struct{}, error) {
 	if depth > CONFIG_INCLUDE_RECURSION_DEPTH_LIMIT {
 		return nil, nil, fmt.Errorf("recursion depth limit of %d reached", CONFIG_INCLUDE_RECURSION_DEPTH_LIMIT)
 	}
@@ -258,6 +706,7 @@ func recursiveParseYAMLIncludes(mainFilePath string, includes map[string]struct{
 		return nil, nil, fmt.Errorf("getting absolute path of %s: %w", mainFilePath, err)
 	}
 	mainFileDir := filepath.Dir(mainFileAbsPath)
+	chain = append(chain, mainFileAbsPath)
 
 	if includes == nil {
 		includes = make(map[string]struct{})
@@ -281,12 +730,28 @@ func recursiveParseYAMLIncludes(mainFilePath string, includes map[string]struct{
 			includeFilePath = filepath.Join(mainFileDir, includeFilePath)
 		}
 
+		if slices.Contains(chain, includeFilePath) {
+			includesLastErr = fmt.Errorf(
+				"circular include detected: %s",
+				strings.Join(append(chain, includeFilePath), " -> "),
+			)
+			return nil
+		}
+
+		if rootDir != "" && !isPathWithinDir(resolveSymlinksIfPossible(includeFilePath), rootDir) {
+			includesLastErr = fmt.Errorf(
+				"including %s from %s is not allowed since it is outside of %s",
+				includeFilePath, mainFileAbsPath, rootDir,
+			)
+			return nil
+		}
+
 		var fileContents []byte
 		var err error
 
 		includes[includeFilePath] = struct{}{}
 
-		fileContents, includes, err = recursiveParseYAMLIncludes(includeFilePath, includes, depth+1)
+		fileContents, includes, err = recursiveParseYAMLIncludes(includeFilePath, includes, chain, rootDir, depth+1)
 		if err != nil {
 			includesLastErr = err
 			return nil
@@ -302,8 +767,28 @@ func recursiveParseYAMLIncludes(mainFilePath string, includes map[string]struct{
 	return mainFileContents, includes, nil
 }
 
+func resolveSymlinksIfPossible(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+
+	return resolved
+}
+
+func isPathWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func configFilesWatcher(
 	mainFilePath string,
+	overlayPaths []string,
+	restrictIncludes bool,
 	lastContents []byte,
 	lastIncludes map[string]struct{},
 	onChange func(newContents []byte),
@@ -347,7 +832,7 @@ func configFilesWatcher(
 	mu := sync.Mutex{}
 
 	parseAndCompareBeforeCallback := func() {
-		currentContents, currentIncludes, err := parseYAMLIncludes(mainFilePath)
+		currentContents, currentIncludes, err := parseConfigFiles(mainFilePath, overlayPaths, restrictIncludes)
 		if err != nil {
 			onErr(fmt.Errorf("parsing main file contents for comparison: %w", err))
 			return
@@ -449,14 +934,70 @@ func configFilesWatcher(
 // and then again when creating the application which does modify the data and do
 // further validation. Would be better if validation was done in a single place.
 func isConfigStateValid(config *config) error {
-	if len(config.Pages) == 0 {
+	if len(config.Dashboards) == 0 && len(config.Pages) == 0 {
 		return fmt.Errorf("no pages configured")
 	}
 
+	for i := range config.Dashboards {
+		dashboard := &config.Dashboards[i]
+
+		if dashboard.Name == "" {
+			return fmt.Errorf("dashboard %d has no name", i+1)
+		}
+
+		if len(dashboard.Pages) == 0 {
+			return fmt.Errorf("dashboard %s has no pages configured", dashboard.Name)
+		}
+	}
+
+	if config.Server.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("server max-concurrent-requests must not be negative")
+	}
+
+	if config.Server.MaxConcurrentRequestsPerHost < 0 {
+		return fmt.Errorf("server max-concurrent-requests-per-host must not be negative")
+	}
+
+	if config.Server.ReadTimeout < 0 || config.Server.WriteTimeout < 0 || config.Server.IdleTimeout < 0 || config.Server.ShutdownTimeout < 0 {
+		return fmt.Errorf("server timeouts must not be negative")
+	}
+
+	config.Server.location = time.Local
+	if config.Server.Timezone != "" {
+		location, err := time.LoadLocation(config.Server.Timezone)
+		if err != nil {
+			return fmt.Errorf("server timezone: %v", err)
+		}
+		config.Server.location = location
+	}
+
+	if err := config.Server.TLS.validate(config.Server.DataPath); err != nil {
+		return err
+	}
+
+	if err := config.Server.Guard.validate(); err != nil {
+		return err
+	}
+
+	if err := config.Server.RateLimit.initialize(); err != nil {
+		return fmt.Errorf("server %v", err)
+	}
+
+	if err := validateLogOptions(&config.Server); err != nil {
+		return err
+	}
+
 	if len(config.Auth.Users) > 0 && config.Auth.SecretKey == "" {
 		return fmt.Errorf("secret-key must be set when users are configured")
 	}
 
+	if config.Auth.SessionLifetime != 0 {
+		lifetime := time.Duration(config.Auth.SessionLifetime)
+		if lifetime < time.Minute || lifetime > 365*24*time.Hour {
+			return errors.New("auth session-lifetime must be between 1m and 365d")
+		}
+	}
+
 	for username := range config.Auth.Users {
 		if username == "" {
 			return fmt.Errorf("user has no name")
@@ -483,8 +1024,22 @@ func isConfigStateValid(config *config) error {
 		}
 	}
 
-	for i := range config.Pages {
-		page := &config.Pages[i]
+	if len(config.Dashboards) == 0 {
+		return arePagesValid(config.Pages)
+	}
+
+	for i := range config.Dashboards {
+		if err := arePagesValid(config.Dashboards[i].Pages); err != nil {
+			return fmt.Errorf("dashboard %s: %v", config.Dashboards[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+func arePagesValid(pages []page) error {
+	for i := range pages {
+		page := &pages[i]
 
 		if page.Title == "" {
 			return fmt.Errorf("page %d has no name", i+1)
@@ -500,6 +1055,16 @@ func isConfigStateValid(config *config) error {
 			}
 		}
 
+		if page.Theme != nil {
+			if page.Theme.Light {
+				return fmt.Errorf("page %d: theme can not change whether the scheme is light", i+1)
+			}
+
+			if page.Theme.ContrastMultiplier < 0 || page.Theme.TextSaturationMultiplier < 0 {
+				return fmt.Errorf("page %d: theme multipliers can not be negative", i+1)
+			}
+		}
+
 		if len(page.Columns) == 0 {
 			return fmt.Errorf("page %d has no columns", i+1)
 		}
@@ -536,6 +1101,21 @@ func isConfigStateValid(config *config) error {
 	return nil
 }
 
+// The top level pages when dashboards aren't used, otherwise the pages of each
+// dashboard, with the first dashboard's pages also being the top level ones
+func (c *config) pagesOfAllDashboards() [][]page {
+	if len(c.Dashboards) == 0 {
+		return [][]page{c.Pages}
+	}
+
+	pages := make([][]page, 0, len(c.Dashboards))
+	for i := range c.Dashboards {
+		pages = append(pages, c.Dashboards[i].Pages)
+	}
+
+	return pages
+}
+
 // Read-only way to store ordered maps from a YAML structure
 type orderedYAMLMap[K comparable, V any] struct {
 	keys []K
@@ -0,0 +1,467 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Either `check-updates: true` or an object with the options of the check
+type dockerUpdateCheckOptions struct {
+	Enabled bool          `yaml:"enabled"`
+	Cache   durationField `yaml:"cache"`
+	// Keyed by the host of the registry, such as ghcr.io
+	Registries map[string]dockerRegistryCredentials `yaml:"registries"`
+}
+
+type dockerRegistryCredentials struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Used as is instead of requesting one from the registry
+	Token string `yaml:"token"`
+}
+
+func (o *dockerUpdateCheckOptions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&o.Enabled)
+	}
+
+	type alias dockerUpdateCheckOptions
+	if err := node.Decode((*alias)(o)); err != nil {
+		return err
+	}
+
+	o.Enabled = true
+	return nil
+}
+
+const defaultDockerUpdateCheckCache = 6 * time.Hour
+
+// Failed checks are retried sooner than successful ones get repeated, though
+// not so soon that a registry which is down gets hammered with requests
+const dockerUpdateCheckErrorCache = 30 * time.Minute
+
+const dockerHubRegistryHost = "registry-1.docker.io"
+
+// Both the single-platform and the multi-platform manifest types have to be
+// accepted, otherwise registries respond with a different digest than the one
+// that the image was pulled by
+var dockerManifestAcceptHeader = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+type dockerImageReference struct {
+	// The host used for requests, which for Docker Hub differs from the one in image names
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+func (r dockerImageReference) String() string {
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// Images referenced by their digest can't have updates, so they return false
+// along with images which are referenced by their ID
+func parseDockerImageReference(image string) (dockerImageReference, bool) {
+	if image == "" || strings.Contains(image, "@") || strings.HasPrefix(image, "sha256:") {
+		return dockerImageReference{}, false
+	}
+
+	ref := dockerImageReference{Registry: dockerHubRegistryHost}
+	name := image
+
+	if first, rest, found := strings.Cut(image, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry = first
+		name = rest
+	}
+
+	if ref.Registry == "docker.io" || ref.Registry == "index.docker.io" {
+		ref.Registry = dockerHubRegistryHost
+	}
+
+	// a colon after the last slash separates the tag, any
+	// other colon would be part of the port of the registry
+	ref.Tag = "latest"
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	if ref.Registry == dockerHubRegistryHost && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	ref.Repository = name
+
+	return ref, true
+}
+
+type dockerUpdateChecker struct {
+	options *dockerUpdateCheckOptions
+	client  requestDoer
+
+	mu sync.Mutex
+	// keyed by image reference
+	remoteDigests map[string]dockerRemoteDigest
+	// keyed by image ID, the digests of an image never change
+	localDigests map[string][]string
+	// keyed by registry and scope, tokens get reused until they expire
+	tokens map[string]dockerRegistryToken
+}
+
+type dockerRemoteDigest struct {
+	digest    string
+	err       error
+	checkedAt time.Time
+}
+
+type dockerRegistryToken struct {
+	token   string
+	expires time.Time
+}
+
+func newDockerUpdateChecker(options *dockerUpdateCheckOptions, client requestDoer) *dockerUpdateChecker {
+	if options.Cache == 0 {
+		options.Cache = durationField(defaultDockerUpdateCheckCache)
+	}
+
+	return &dockerUpdateChecker{
+		options:       options,
+		client:        client,
+		remoteDigests: make(map[string]dockerRemoteDigest),
+		localDigests:  make(map[string][]string),
+		tokens:        make(map[string]dockerRegistryToken),
+	}
+}
+
+// Sets UpdateAvailable on the containers whose image has a different digest
+// in the registry than the one it was pulled with. Containers which can't be
+// checked are skipped
+func (c *dockerUpdateChecker) markUpdates(ctx context.Context, docker *dockerClient, containers dockerContainerList) {
+	type check struct {
+		container *dockerContainer
+		ref       dockerImageReference
+	}
+
+	var checks []check
+	for i := range containers {
+		if ref, ok := parseDockerImageReference(containers[i].Image); ok && containers[i].imageID != "" {
+			checks = append(checks, check{container: &containers[i], ref: ref})
+		}
+	}
+
+	if len(checks) == 0 {
+		return
+	}
+
+	job := newJob(func(ch check) (bool, error) {
+		localDigests, err := c.localImageDigests(ctx, docker, ch.container.imageID)
+		if err != nil {
+			return false, err
+		}
+
+		// images which were built locally have no digests to compare with
+		if len(localDigests) == 0 {
+			return false, nil
+		}
+
+		remoteDigest, err := c.remoteImageDigest(ctx, ch.ref)
+		if err != nil {
+			return false, err
+		}
+
+		for _, digest := range localDigests {
+			if digest == remoteDigest {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	}, checks).withWorkers(4)
+
+	results, errs, err := workerPoolDo(job)
+	if err != nil {
+		slog.Error("Failed to check containers for image updates", "error", err)
+		return
+	}
+
+	for i := range checks {
+		if errs[i] != nil {
+			slog.Warn("Failed to check image for updates", "image", checks[i].container.Image, "error", errs[i])
+			continue
+		}
+
+		checks[i].container.UpdateAvailable = results[i]
+	}
+}
+
+type dockerImageInspectJsonResponse struct {
+	RepoDigests []string `json:"RepoDigests"`
+}
+
+// Returns only the digest part of the repo digests, such as sha256:abc...
+func (c *dockerUpdateChecker) localImageDigests(ctx context.Context, docker *dockerClient, imageID string) ([]string, error) {
+	c.mu.Lock()
+	digests, exists := c.localDigests[imageID]
+	c.mu.Unlock()
+	if exists {
+		return digests, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", docker.url("/images/"+url.PathEscape(imageID)+"/json"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := decodeJsonFromRequest[dockerImageInspectJsonResponse](docker.client, request)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting image: %w", err)
+	}
+
+	digests = make([]string, 0, len(response.RepoDigests))
+	for _, repoDigest := range response.RepoDigests {
+		if _, digest, found := strings.Cut(repoDigest, "@"); found {
+			digests = append(digests, digest)
+		}
+	}
+
+	c.mu.Lock()
+	c.localDigests[imageID] = digests
+	c.mu.Unlock()
+
+	return digests, nil
+}
+
+func (c *dockerUpdateChecker) remoteImageDigest(ctx context.Context, ref dockerImageReference) (string, error) {
+	key := ref.String()
+
+	c.mu.Lock()
+	cached, exists := c.remoteDigests[key]
+	c.mu.Unlock()
+
+	if exists {
+		maxAge := ternary(cached.err != nil, min(dockerUpdateCheckErrorCache, time.Duration(c.options.Cache)), time.Duration(c.options.Cache))
+		if time.Since(cached.checkedAt) < maxAge {
+			return cached.digest, cached.err
+		}
+	}
+
+	digest, err := c.fetchRemoteImageDigest(ctx, ref)
+
+	// canceled requests say nothing about the image, so they shouldn't
+	// prevent it from being checked again on the next update
+	if ctx.Err() == nil {
+		c.mu.Lock()
+		c.remoteDigests[key] = dockerRemoteDigest{digest: digest, err: err, checkedAt: time.Now()}
+		c.mu.Unlock()
+	}
+
+	return digest, err
+}
+
+// HEAD requests for manifests don't count towards the pull limits of Docker Hub
+func (c *dockerUpdateChecker) fetchRemoteImageDigest(ctx context.Context, ref dockerImageReference) (string, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, url.PathEscape(ref.Tag))
+	credentials := c.credentialsFor(ref.Registry)
+
+	newRequest := func(authorization string) (*http.Request, error) {
+		request, err := http.NewRequestWithContext(ctx, "HEAD", manifestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Accept", dockerManifestAcceptHeader)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		return request, nil
+	}
+
+	authorization := ""
+	if credentials.Token != "" {
+		authorization = "Bearer " + credentials.Token
+	} else if token, ok := c.cachedToken(ref); ok {
+		authorization = "Bearer " + token
+	}
+
+	request, err := newRequest(authorization)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+
+	// registries say how to authenticate through the challenge of the
+	// first response, even for public images which need an anonymous token
+	if response.StatusCode == http.StatusUnauthorized && credentials.Token == "" {
+		authorization, err = c.authorizationFromChallenge(ctx, ref, response.Header.Get("WWW-Authenticate"), credentials)
+		if err != nil {
+			return "", err
+		}
+
+		if request, err = newRequest(authorization); err != nil {
+			return "", err
+		}
+
+		if response, err = c.client.Do(request); err != nil {
+			return "", err
+		}
+		response.Body.Close()
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", response.StatusCode, manifestURL)
+	}
+
+	digest := response.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s did not return a digest for %s", ref.Registry, ref.Repository)
+	}
+
+	return digest, nil
+}
+
+func (c *dockerUpdateChecker) credentialsFor(registry string) dockerRegistryCredentials {
+	if credentials, exists := c.options.Registries[registry]; exists {
+		return credentials
+	}
+
+	if registry == dockerHubRegistryHost {
+		for _, alias := range []string{"docker.io", "index.docker.io", "hub.docker.com"} {
+			if credentials, exists := c.options.Registries[alias]; exists {
+				return credentials
+			}
+		}
+	}
+
+	return dockerRegistryCredentials{}
+}
+
+func (c *dockerUpdateChecker) tokenKey(ref dockerImageReference) string {
+	return ref.Registry + "/" + ref.Repository
+}
+
+func (c *dockerUpdateChecker) cachedToken(ref dockerImageReference) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, exists := c.tokens[c.tokenKey(ref)]
+	if !exists || time.Now().After(token.expires) {
+		return "", false
+	}
+
+	return token.token, true
+}
+
+type dockerRegistryTokenJsonResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (c *dockerUpdateChecker) authorizationFromChallenge(
+	ctx context.Context,
+	ref dockerImageReference,
+	challenge string,
+	credentials dockerRegistryCredentials,
+) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+	scheme = strings.ToLower(scheme)
+
+	if scheme == "basic" {
+		if credentials.Username == "" {
+			return "", fmt.Errorf("registry %s requires credentials", ref.Registry)
+		}
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+		return request.Header.Get("Authorization"), nil
+	}
+
+	if scheme != "bearer" {
+		return "", fmt.Errorf("registry %s uses an unsupported authentication scheme: %s", ref.Registry, scheme)
+	}
+
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry %s did not specify where to get a token from", ref.Registry)
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+ref.Repository+":pull")
+
+	request, err := http.NewRequestWithContext(ctx, "GET", realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if credentials.Username != "" {
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	response, err := decodeJsonFromRequest[dockerRegistryTokenJsonResponse](c.client, request)
+	if err != nil {
+		return "", fmt.Errorf("requesting token: %w", err)
+	}
+
+	token := ternary(response.Token != "", response.Token, response.AccessToken)
+	if token == "" {
+		return "", errors.New("registry did not return a token")
+	}
+
+	// tokens are valid for at least 60 seconds according to the spec
+	expiresIn := time.Duration(max(response.ExpiresIn, 60)) * time.Second
+
+	c.mu.Lock()
+	c.tokens[c.tokenKey(ref)] = dockerRegistryToken{token: token, expires: time.Now().Add(expiresIn - 10*time.Second)}
+	c.mu.Unlock()
+
+	return "Bearer " + token, nil
+}
+
+// Parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest != "" {
+		var key, value string
+		var found bool
+
+		key, rest, found = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if !found {
+			break
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return scheme, params
+}
@@ -0,0 +1,150 @@
+package glance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type discoveredFeed struct {
+	URL   string
+	Title string
+	// Either rss, atom or json
+	Type string
+}
+
+var feedLinkTypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+	"application/json":      "json",
+}
+
+// Pages are rarely this large, anything past it is unlikely to be the head
+const feedDiscoveryBodySizeLimit = 2 * 1024 * 1024
+
+// Fetches the given URL and returns the feeds it links to. If the URL is
+// already a feed, it's returned on its own
+func discoverFeeds(client requestDoer, pageURL string) ([]discoveredFeed, error) {
+	request, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	setBrowserUserAgentHeader(request)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", response.StatusCode, pageURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, feedDiscoveryBodySizeLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	if !isHTMLResponse(response, body) {
+		feed, err := feedParser.Parse(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("%s is neither a page nor a feed", pageURL)
+		}
+
+		return []discoveredFeed{{
+			URL:   response.Request.URL.String(),
+			Title: feed.Title,
+			Type:  feed.FeedType,
+		}}, nil
+	}
+
+	// redirects are followed, so relative links are resolved against
+	// the URL that the page was actually served from
+	feeds, err := discoverFeedsInHTML(body, response.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(feeds) == 0 {
+		return nil, errors.New("no feeds found")
+	}
+
+	return feeds, nil
+}
+
+func isHTMLResponse(response *http.Response, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type")); err == nil {
+		if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return true
+		}
+	}
+
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// Looks for <link rel="alternate"> elements with a feed type, in the order
+// they appear within the page
+func discoverFeedsInHTML(body []byte, pageURL *url.URL) ([]discoveredFeed, error) {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parsing page: %v", err)
+	}
+
+	baseURL := pageURL
+	if href, exists := document.Find("base[href]").First().Attr("href"); exists {
+		if parsed, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+			baseURL = parsed
+		}
+	}
+
+	var feeds []discoveredFeed
+	seen := make(map[string]struct{})
+
+	document.Find("link[rel][href]").Each(func(_ int, link *goquery.Selection) {
+		rel := strings.Fields(strings.ToLower(link.AttrOr("rel", "")))
+		if !slices.Contains(rel, "alternate") {
+			return
+		}
+
+		linkType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		feedType, isFeed := feedLinkTypes[linkType]
+		if !isFeed {
+			return
+		}
+
+		// plenty of pages link to JSON versions of themselves which aren't
+		// feeds, so plain JSON only counts when the link says it's a feed
+		if linkType == "application/json" && !strings.Contains(strings.ToLower(link.AttrOr("title", "")), "feed") {
+			return
+		}
+
+		feedURL, err := baseURL.Parse(strings.TrimSpace(link.AttrOr("href", "")))
+		if err != nil || (feedURL.Scheme != "http" && feedURL.Scheme != "https") {
+			return
+		}
+
+		key := feedURL.String()
+		if _, exists := seen[key]; exists {
+			return
+		}
+		seen[key] = struct{}{}
+
+		feeds = append(feeds, discoveredFeed{
+			URL:   key,
+			Title: strings.TrimSpace(link.AttrOr("title", "")),
+			Type:  feedType,
+		})
+	})
+
+	return feeds, nil
+}
@@ -0,0 +1,351 @@
+package glance
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// Set under the top level formatting key and optionally overridden per widget,
+// fields left empty are taken from the level above
+type formattingOptions struct {
+	Locale     string `yaml:"locale"`
+	DateFormat string `yaml:"date-format"`
+	TimeFormat string `yaml:"time-format"`
+	ByteUnits  string `yaml:"byte-units"`
+}
+
+func (o *formattingOptions) UnmarshalYAML(node *yaml.Node) error {
+	type options formattingOptions
+	if err := node.Decode((*options)(o)); err != nil {
+		return err
+	}
+
+	if o.Locale != "" {
+		tag, err := language.Parse(o.Locale)
+		if err != nil {
+			return fmt.Errorf("invalid locale %s, it must be a language tag such as en-US or de: %v", o.Locale, err)
+		}
+		o.Locale = tag.String()
+	}
+
+	if o.ByteUnits != "" && o.ByteUnits != "si" && o.ByteUnits != "binary" {
+		return fmt.Errorf("invalid byte-units %s, must be either si or binary", o.ByteUnits)
+	}
+
+	return nil
+}
+
+func (o formattingOptions) mergedWith(override *formattingOptions) formattingOptions {
+	if override == nil {
+		return o
+	}
+
+	if override.Locale != "" {
+		o.Locale = override.Locale
+	}
+	if override.DateFormat != "" {
+		o.DateFormat = override.DateFormat
+	}
+	if override.TimeFormat != "" {
+		o.TimeFormat = override.TimeFormat
+	}
+	if override.ByteUnits != "" {
+		o.ByteUnits = override.ByteUnits
+	}
+
+	return o
+}
+
+type localeFormatter struct {
+	options     formattingOptions
+	printer     *message.Printer
+	dateLayout  string
+	timeLayout  string
+	binaryBytes bool
+	funcs       template.FuncMap
+}
+
+// Formatters are shared by all widgets with the same options, which is also
+// what the templates using them get cached by
+var localeFormatters = struct {
+	mu        sync.Mutex
+	byOptions map[formattingOptions]*localeFormatter
+}{byOptions: make(map[formattingOptions]*localeFormatter)}
+
+// Uses the same formatting as before it could be configured
+var defaultLocaleFormatter = newLocaleFormatter(formattingOptions{})
+
+func newLocaleFormatter(options formattingOptions) *localeFormatter {
+	localeFormatters.mu.Lock()
+	defer localeFormatters.mu.Unlock()
+
+	if f, exists := localeFormatters.byOptions[options]; exists {
+		return f
+	}
+
+	tag := language.English
+	if options.Locale != "" {
+		tag = language.Make(options.Locale)
+	}
+
+	f := &localeFormatter{
+		options:     options,
+		printer:     message.NewPrinter(tag),
+		dateLayout:  options.DateFormat,
+		binaryBytes: options.ByteUnits == "binary",
+	}
+
+	if f.dateLayout == "" {
+		// the month comes first pretty much only in the US
+		if region, _ := tag.Region(); region.String() == "US" {
+			f.dateLayout = "Jan 2, 2006"
+		} else {
+			f.dateLayout = "2 Jan 2006"
+		}
+	}
+
+	switch options.TimeFormat {
+	case "", "24h":
+		f.timeLayout = "15:04"
+	case "12h":
+		f.timeLayout = "3:04 PM"
+	default:
+		f.timeLayout = options.TimeFormat
+	}
+
+	f.funcs = template.FuncMap{
+		"formatNumber":             f.printer.Sprint,
+		"formatApproxNumber":       f.approxNumber,
+		"formatPrice":              f.price,
+		"formatPriceWithPrecision": f.number,
+		"formatSignedNumber":       f.signedNumber,
+		"formatBytes":              f.bytes,
+		"formatServerMegabytes":    f.serverMegabytes,
+		"formatDate":               f.date,
+		"formatTimeOfDay":          f.timeOfDay,
+		"formatDateTime":           f.dateTime,
+	}
+
+	localeFormatters.byOptions[options] = f
+
+	return f
+}
+
+func (f *localeFormatter) withOverride(override *formattingOptions) *localeFormatter {
+	if override == nil {
+		return f
+	}
+
+	return newLocaleFormatter(f.options.mergedWith(override))
+}
+
+func (f *localeFormatter) number(precision int, value float64) string {
+	return f.printer.Sprintf("%."+strconv.Itoa(precision)+"f", value)
+}
+
+func (f *localeFormatter) price(value float64) string {
+	return f.number(2, value)
+}
+
+// Same as number except positive values get a plus sign, such as for changes
+func (f *localeFormatter) signedNumber(precision int, value float64) string {
+	return f.printer.Sprintf("%+."+strconv.Itoa(precision)+"f", value)
+}
+
+func (f *localeFormatter) approxNumber(count int) string {
+	if count < 1_000 {
+		return strconv.Itoa(count)
+	}
+
+	if count < 10_000 {
+		return f.number(1, float64(count)/1_000) + "k"
+	}
+
+	if count < 1_000_000 {
+		return strconv.Itoa(count/1_000) + "k"
+	}
+
+	return f.number(1, float64(count)/1_000_000) + "m"
+}
+
+var (
+	siByteUnits     = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	binaryByteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+// Returns the value in the largest unit in which it's at least 1, using either
+// powers of 1000 or of 1024 depending on the byte-units option
+func (f *localeFormatter) scaleBytes(value float64) (float64, string) {
+	base, units := 1000.0, siByteUnits
+	if f.binaryBytes {
+		base, units = 1024, binaryByteUnits
+	}
+
+	i := 0
+	for math.Abs(value) >= base && i < len(units)-1 {
+		value /= base
+		i++
+	}
+
+	return value, units[i]
+}
+
+func (f *localeFormatter) bytes(value any) string {
+	number, ok := toFloat64(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	number, unit := f.scaleBytes(number)
+	if unit == "B" {
+		return f.number(0, number) + " " + unit
+	}
+
+	return f.number(1, number) + " " + unit
+}
+
+// The values reported by sysinfo are in MiB. They're shown as MB, GB and TB
+// unless binary units are used, which is how they've always been shown
+func (f *localeFormatter) serverMegabytes(mb uint64) template.HTML {
+	base, labels := uint64(1_000), [3]string{"MB", "GB", "TB"}
+	if f.binaryBytes {
+		base, labels = 1024, [3]string{"MiB", "GiB", "TiB"}
+	}
+
+	var value string
+	var label string
+
+	if mb < base {
+		value = strconv.FormatUint(mb, 10)
+		label = labels[0]
+	} else if mb < base*base {
+		if mb < 10*base {
+			value = f.number(1, float64(mb)/float64(base))
+		} else {
+			value = strconv.FormatUint(mb/base, 10)
+		}
+
+		label = labels[1]
+	} else {
+		value = f.number(1, float64(mb)/float64(base*base))
+		label = labels[2]
+	}
+
+	return template.HTML(value + ` <span class="color-base size-h5">` + label + `</span>`)
+}
+
+func (f *localeFormatter) date(t time.Time) string {
+	return t.Format(f.dateLayout)
+}
+
+func (f *localeFormatter) timeOfDay(t time.Time) string {
+	return t.Format(f.timeLayout)
+}
+
+func (f *localeFormatter) dateTime(t time.Time) string {
+	return t.Format(f.dateLayout + " " + f.timeLayout)
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// Templates which get executed can no longer be cloned, so a copy of each
+// built-in one is kept aside untouched for localized versions to be made from
+var pristineTemplates sync.Map
+
+type localizedTemplateKey struct {
+	template  *template.Template
+	formatter *localeFormatter
+}
+
+var localizedTemplates = struct {
+	mu        sync.Mutex
+	templates map[localizedTemplateKey]*template.Template
+}{templates: make(map[localizedTemplateKey]*template.Template)}
+
+func keepPristineTemplate(t *template.Template) {
+	pristine, err := t.Clone()
+	if err != nil {
+		panic(err)
+	}
+
+	pristineTemplates.Store(t, pristine)
+}
+
+// Returns the built-in template with its formatting functions replaced by
+// those of the formatter, or the template itself if it's the default one
+func localizeTemplate(t *template.Template, f *localeFormatter) *template.Template {
+	if f == nil || f == defaultLocaleFormatter {
+		return t
+	}
+
+	localizedTemplates.mu.Lock()
+	defer localizedTemplates.mu.Unlock()
+
+	key := localizedTemplateKey{template: t, formatter: f}
+	if localized, exists := localizedTemplates.templates[key]; exists {
+		return localized
+	}
+
+	pristine, exists := pristineTemplates.Load(t)
+	if !exists {
+		return t
+	}
+
+	localized, err := pristine.(*template.Template).Clone()
+	if err != nil {
+		return t
+	}
+
+	localized.Funcs(f.funcs)
+	localizedTemplates.templates[key] = localized
+
+	return localized
+}
+
+// Custom templates are parsed per widget and haven't been executed by the
+// time the providers are set, so they can be localized in place
+func localizeCustomTemplate(t *template.Template, f *localeFormatter) *template.Template {
+	if t == nil || f == nil || f == defaultLocaleFormatter {
+		return t
+	}
+
+	localized, err := t.Clone()
+	if err != nil {
+		return t
+	}
+
+	return localized.Funcs(f.funcs)
+}
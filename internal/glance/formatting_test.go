@@ -0,0 +1,150 @@
+package glance
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocaleFormatterLocales(t *testing.T) {
+	date := time.Date(2025, time.March, 7, 21, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale      string
+		number      string
+		price       string
+		approx      string
+		signed      string
+		date        string
+		serverBytes string
+	}{
+		{"", "1,234,567", "1,234.50", "1.5k", "+3.14", "Mar 7, 2025", "2.5"},
+		{"en-GB", "1,234,567", "1,234.50", "1.5k", "+3.14", "7 Mar 2025", "2.5"},
+		{"de", "1.234.567", "1.234,50", "1,5k", "+3,14", "7 Mar 2025", "2,5"},
+		{"fr", "1 234 567", "1 234,50", "1,5k", "+3,14", "7 Mar 2025", "2,5"},
+		{"de-CH", "1’234’567", "1’234.50", "1.5k", "+3.14", "7 Mar 2025", "2.5"},
+	}
+
+	for _, test := range tests {
+		f := newLocaleFormatter(formattingOptions{Locale: test.locale})
+
+		check := func(what, expected, got string) {
+			if got != expected {
+				t.Errorf("Locale %q, %s: expected %q, got %q", test.locale, what, expected, got)
+			}
+		}
+
+		check("number", test.number, f.printer.Sprint(1234567))
+		check("price", test.price, f.price(1234.5))
+		check("approx number", test.approx, f.approxNumber(1500))
+		check("signed number", test.signed, f.signedNumber(2, 3.14159))
+		check("date", test.date, f.date(date))
+		check("server megabytes", test.serverBytes, strings.Fields(string(f.serverMegabytes(2500)))[0])
+	}
+}
+
+func TestLocaleFormatterOptions(t *testing.T) {
+	date := time.Date(2025, time.March, 7, 21, 5, 0, 0, time.UTC)
+
+	global := newLocaleFormatter(formattingOptions{Locale: "de", TimeFormat: "12h"})
+	widget := global.withOverride(&formattingOptions{DateFormat: "2006-01-02", ByteUnits: "binary"})
+
+	if widget.options.Locale != "de" || widget.options.TimeFormat != "12h" {
+		t.Errorf("Expected the unset options to be inherited, got %+v", widget.options)
+	}
+
+	if widget != newLocaleFormatter(widget.options) {
+		t.Errorf("Expected formatters with the same options to be shared")
+	}
+
+	if got := widget.dateTime(date); got != "2025-03-07 9:05 PM" {
+		t.Errorf("Expected the date and time in the configured layouts, got %q", got)
+	}
+
+	if got := newLocaleFormatter(formattingOptions{TimeFormat: "15.04 Uhr"}).timeOfDay(date); got != "21.05 Uhr" {
+		t.Errorf("Expected a custom time layout to be used, got %q", got)
+	}
+
+	bytes := []struct {
+		value  any
+		si     string
+		binary string
+	}{
+		{512, "512 B", "512 B"},
+		{int64(1536), "1,5 kB", "1,5 KiB"},
+		{uint64(5_000_000_000), "5,0 GB", "4,7 GiB"},
+		{"1048576", "1,0 MB", "1,0 MiB"},
+		{"not a number", "not a number", "not a number"},
+	}
+
+	for _, test := range bytes {
+		if got := global.bytes(test.value); got != test.si {
+			t.Errorf("Formatting %v as SI bytes: expected %q, got %q", test.value, test.si, got)
+		}
+
+		if got := widget.bytes(test.value); got != test.binary {
+			t.Errorf("Formatting %v as binary bytes: expected %q, got %q", test.value, test.binary, got)
+		}
+	}
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+	input := `formatting:
+  locale: de
+pages:
+  - name: Home
+    columns:
+      - size: full
+        widgets:
+          - type: group
+            formatting:
+              byte-units: binary
+            widgets:
+              - type: html
+                source: ""
+                formatting:
+                  locale: fr
+              - type: html
+                source: ""
+`
+
+	config, err := newConfigFromYAML([]byte(input), "")
+	if err != nil {
+		t.Fatalf("Parsing the config returned an error: %v", err)
+	}
+
+	group := config.Pages[0].Columns[0].Widgets[0].(*groupWidget)
+	group.setProviders(&widgetProviders{formatter: newLocaleFormatter(config.Formatting)})
+
+	first := group.Widgets[0].(*htmlWidget).formatter().options
+	if first != (formattingOptions{Locale: "fr", ByteUnits: "binary"}) {
+		t.Errorf("Expected the options of the widget to be merged with those of its group, got %+v", first)
+	}
+
+	second := group.Widgets[1].(*htmlWidget).formatter().options
+	if second != (formattingOptions{Locale: "de", ByteUnits: "binary"}) {
+		t.Errorf("Expected the options of the group to be passed on to its widgets, got %+v", second)
+	}
+
+	builtIn := template.Must(template.New("").Funcs(globalTemplateFunctions).Parse(`{{ formatNumber . }} {{ formatBytes . }}`))
+	keepPristineTemplate(builtIn)
+
+	var output strings.Builder
+	for _, f := range []*localeFormatter{defaultLocaleFormatter, group.formatter()} {
+		if err := localizeTemplate(builtIn, f).Execute(&output, 1536); err != nil {
+			t.Fatalf("Executing the template returned an error: %v", err)
+		}
+		output.WriteString("|")
+	}
+
+	// the default one gets executed first to make sure that it doesn't keep
+	// localized versions from being made
+	if expected := "1,536 1.5 kB|1.536 1,5 KiB|"; output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+
+	if _, err := newConfigFromYAML([]byte("formatting:\n  byte-units: decimal\npages: []\n"), ""); err == nil {
+		t.Errorf("Expected an invalid byte-units to return an error")
+	}
+}
@@ -3,10 +3,13 @@ package glance
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"slices"
 	"strconv"
@@ -20,28 +23,62 @@ import (
 var (
 	pageTemplate        = mustParseTemplate("page.html", "document.html", "footer.html")
 	pageContentTemplate = mustParseTemplate("page-content.html")
-	manifestTemplate    = mustParseTemplate("manifest.json")
 )
 
 const STATIC_ASSETS_CACHE_DURATION = 24 * time.Hour
 
 var reservedPageSlugs = []string{"login", "logout"}
 
+// Dashboards are served under their slug, so they can't use the paths which
+// the server itself handles
+var reservedDashboardSlugs = []string{
+	"login", "logout", "api", "static", "assets", "manifest.json", "manifest.webmanifest",
+	"service-worker.js", "healthz", "metrics", "hooks",
+}
+
 type application struct {
 	Version   string
 	CreatedAt time.Time
 	Config    config
 
-	parsedManifest []byte
+	parsedManifest      []byte
+	parsedServiceWorker []byte
+	themeStyles         map[string]themeStyle
 
-	slugToPage map[string]*page
-	widgetByID map[uint64]widget
+	slugToPage       map[string]*page
+	widgetByID       map[uint64]widget
+	widgetByStableID map[string]pageWidget
+	// Only populated on the application of the first dashboard
+	webhooks map[string]webhookHook
 
 	RequiresAuth           bool
 	authSecretKey          []byte
 	usernameHashToUsername map[string]string
 	authAttemptsMu         sync.Mutex
 	failedAuthAttempts     map[string]*failedAuthAttempt
+
+	// Closed once the application gets replaced or the server shuts down,
+	// which ends the streams of live updates. Shared with the applications
+	// of the other dashboards
+	liveUpdatesStopped  chan struct{}
+	stopLiveUpdatesOnce sync.Once
+
+	// Only populated when more than one dashboard is configured
+	Dashboards       []dashboardLink
+	CurrentDashboard string
+	dashboardApps    map[string]*application
+	// Set on the applications of all dashboards other than the first
+	// one, which handles logging in and scopes cookies for all of them
+	parent *application
+
+	// Only populated on the application of the first dashboard, applied
+	// through applyGlobalState
+	httpClientState *globalHTTPClientState
+}
+
+type dashboardLink struct {
+	Name string
+	URL  string
 }
 
 func newApplication(c *config) (*application, error) {
@@ -51,6 +88,8 @@ func newApplication(c *config) (*application, error) {
 		Config:     *c,
 		slugToPage: make(map[string]*page),
 		widgetByID: make(map[uint64]widget),
+
+		liveUpdatesStopped: make(chan struct{}),
 	}
 	config := &app.Config
 
@@ -112,14 +151,7 @@ func newApplication(c *config) (*application, error) {
 		}
 
 		themeKeys = append(themeKeys, "default-light")
-		themeProps = append(themeProps, &themeProperties{
-			Light:                    true,
-			BackgroundColor:          &hslColorField{240, 13, 95},
-			PrimaryColor:             &hslColorField{230, 100, 30},
-			NegativeColor:            &hslColorField{0, 70, 50},
-			ContrastMultiplier:       1.3,
-			TextSaturationMultiplier: 0.5,
-		})
+		themeProps = append(themeProps, newDefaultLightTheme())
 
 		themePresets, err := newOrderedYAMLMap(themeKeys, themeProps)
 		if err != nil {
@@ -140,16 +172,165 @@ func newApplication(c *config) (*application, error) {
 		return nil, fmt.Errorf("initializing default theme: %v", err)
 	}
 
+	if config.Theme.Auto.Enabled {
+		if config.Theme.Auto.Light == "" {
+			config.Theme.Auto.Light = "default-light"
+		}
+
+		if config.Theme.Auto.Dark == "" {
+			config.Theme.Auto.Dark = "default"
+		}
+
+		resolve := func(key string) (*themeProperties, error) {
+			if key == "default" {
+				return &config.Theme.themeProperties, nil
+			}
+
+			properties, exists := config.Theme.Presets.Get(key)
+			if !exists && key == "default-light" {
+				// built-in presets are only added when the picker is enabled
+				properties, exists = newDefaultLightTheme(), true
+			}
+
+			if !exists {
+				return nil, fmt.Errorf("theme preset %s does not exist", key)
+			}
+
+			if properties.CSS == "" {
+				properties.Key = key
+				if err := properties.init(); err != nil {
+					return nil, fmt.Errorf("initializing preset theme %s: %v", key, err)
+				}
+			}
+
+			return properties, nil
+		}
+
+		light, err := resolve(config.Theme.Auto.Light)
+		if err != nil {
+			return nil, fmt.Errorf("light theme for auto: %v", err)
+		}
+
+		dark, err := resolve(config.Theme.Auto.Dark)
+		if err != nil {
+			return nil, fmt.Errorf("dark theme for auto: %v", err)
+		}
+
+		config.Theme.AutoProperties, err = newAutoTheme(light, dark)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !config.Theme.DisablePicker {
+		app.initThemeStyles()
+	}
+
 	//
 	// Init pages
 	//
 
-	app.slugToPage[""] = &config.Pages[0]
-
 	providers := &widgetProviders{
-		assetResolver: app.StaticAssetPath,
+		assetResolver:     app.StaticAssetPath,
+		requiresAuth:      app.RequiresAuth,
+		proxyAllowedHosts: config.Server.ProxyAllowedHosts,
+		formatter:         newLocaleFormatter(config.Formatting),
+		location:          config.Server.location,
+	}
+
+	httpClientState, err := newGlobalHTTPClientState(&config.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	app.httpClientState = httpClientState
+
+	if config.Server.CachePath != "" {
+		store, err := newDiskWidgetCacheStore(config.Server.CachePath)
+		if err != nil {
+			return nil, err
+		}
+		providers.cache = store
+	}
+
+	if config.Server.DataPath != "" {
+		store, err := newDiskNoteStore(filepath.Join(config.Server.DataPath, "notes"))
+		if err != nil {
+			return nil, err
+		}
+		providers.notes = store
+	}
+
+	if err := app.initPages(providers); err != nil {
+		return nil, err
+	}
+
+	config.Server.BaseURL = strings.TrimRight(config.Server.BaseURL, "/")
+	config.Theme.CustomCSSFile = app.resolveUserDefinedAssetPath(config.Theme.CustomCSSFile)
+	config.Branding.LogoURL = app.resolveUserDefinedAssetPath(config.Branding.LogoURL)
+
+	config.Branding.FaviconURL = ternary(
+		config.Branding.FaviconURL == "",
+		app.StaticAssetPath("favicon.svg"),
+		app.resolveUserDefinedAssetPath(config.Branding.FaviconURL),
+	)
+
+	config.Branding.FaviconType = ternary(
+		strings.HasSuffix(config.Branding.FaviconURL, ".svg"),
+		"image/svg+xml",
+		"image/png",
+	)
+
+	if config.Branding.AppName == "" {
+		config.Branding.AppName = "Glance"
+	}
+
+	if config.Branding.AppIconURL == "" {
+		config.Branding.AppIconURL = app.StaticAssetPath("app-icon.png")
+	}
+
+	if config.Branding.AppShortName == "" {
+		config.Branding.AppShortName = config.Branding.AppName
+	}
+
+	if config.Branding.AppBackgroundColor == "" {
+		config.Branding.AppBackgroundColor = config.Theme.BackgroundColorAsHex
+	}
+
+	if config.Branding.AppThemeColor == "" {
+		config.Branding.AppThemeColor = config.Branding.AppBackgroundColor
+	}
+
+	if err := app.initAppIcons(); err != nil {
+		return nil, err
 	}
 
+	app.parsedManifest, err = app.buildManifest()
+	if err != nil {
+		return nil, fmt.Errorf("building manifest: %v", err)
+	}
+
+	app.parsedServiceWorker, err = app.buildServiceWorker()
+	if err != nil {
+		return nil, fmt.Errorf("building service worker: %v", err)
+	}
+
+	if len(config.Dashboards) > 1 {
+		if err := app.initDashboards(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := app.initWebhooks(); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func (a *application) initPages(providers *widgetProviders) error {
+	config := &a.Config
+	a.slugToPage[""] = &config.Pages[0]
+
 	for p := range config.Pages {
 		page := &config.Pages[p]
 		page.PrimaryColumnIndex = -1
@@ -159,10 +340,10 @@ func newApplication(c *config) (*application, error) {
 		}
 
 		if slices.Contains(reservedPageSlugs, page.Slug) {
-			return nil, fmt.Errorf("page slug \"%s\" is reserved", page.Slug)
+			return fmt.Errorf("page slug \"%s\" is reserved", page.Slug)
 		}
 
-		app.slugToPage[page.Slug] = page
+		a.slugToPage[page.Slug] = page
 
 		if page.Width == "default" {
 			page.Width = ""
@@ -172,9 +353,31 @@ func newApplication(c *config) (*application, error) {
 			page.DesktopNavigationWidth = page.Width
 		}
 
+		// the defaults are also needed when the kiosk mode is only
+		// enabled through the query parameter
+		if page.Kiosk.RefreshInterval == 0 {
+			page.Kiosk.RefreshInterval = durationField(5 * time.Minute)
+		} else if time.Duration(page.Kiosk.RefreshInterval) < 10*time.Second {
+			return fmt.Errorf("kiosk refresh-interval of page %s must be at least 10s", page.Title)
+		}
+
+		if page.Kiosk.FontScale == 0 {
+			page.Kiosk.FontScale = 1.25
+		} else if page.Kiosk.FontScale < 0.5 || page.Kiosk.FontScale > 3 {
+			return fmt.Errorf("kiosk font-scale of page %s must be between 0.5 and 3", page.Title)
+		}
+
+		if page.Theme != nil {
+			if err := page.Theme.init(); err != nil {
+				return fmt.Errorf("initializing theme of page %s: %v", page.Title, err)
+			}
+		}
+
+		page.contentBrowserCache = stricterBrowserCacheOfWidgets(page.BrowserCache, page.HeadWidgets)
+
 		for i := range page.HeadWidgets {
 			widget := page.HeadWidgets[i]
-			app.widgetByID[widget.GetID()] = widget
+			a.widgetByID[widget.GetID()] = widget
 			widget.setProviders(providers)
 		}
 
@@ -185,68 +388,228 @@ func newApplication(c *config) (*application, error) {
 				page.PrimaryColumnIndex = int8(c)
 			}
 
+			page.contentBrowserCache = stricterBrowserCacheOfWidgets(page.contentBrowserCache, column.Widgets)
+
 			for w := range column.Widgets {
 				widget := column.Widgets[w]
-				app.widgetByID[widget.GetID()] = widget
+				a.widgetByID[widget.GetID()] = widget
 				widget.setProviders(providers)
 			}
 		}
 	}
 
-	config.Server.BaseURL = strings.TrimRight(config.Server.BaseURL, "/")
-	config.Theme.CustomCSSFile = app.resolveUserDefinedAssetPath(config.Theme.CustomCSSFile)
-	config.Branding.LogoURL = app.resolveUserDefinedAssetPath(config.Branding.LogoURL)
+	if err := a.indexWidgetsByStableID(); err != nil {
+		return err
+	}
 
-	config.Branding.FaviconURL = ternary(
-		config.Branding.FaviconURL == "",
-		app.StaticAssetPath("favicon.svg"),
-		app.resolveUserDefinedAssetPath(config.Branding.FaviconURL),
-	)
+	if err := a.resolveWidgetReferences(); err != nil {
+		return err
+	}
 
-	config.Branding.FaviconType = ternary(
-		strings.HasSuffix(config.Branding.FaviconURL, ".svg"),
-		"image/svg+xml",
-		"image/png",
-	)
+	if err := a.checkNotesStorage(); err != nil {
+		return err
+	}
 
-	if config.Branding.AppName == "" {
-		config.Branding.AppName = "Glance"
+	return a.checkProxiedHosts()
+}
+
+// Every dashboard other than the first one gets its own application which
+// is served under the slug of the dashboard. It shares everything with the
+// first dashboard's application aside from its pages and its widget cache
+func (a *application) initDashboards() error {
+	dashboards := a.Config.Dashboards
+	a.dashboardApps = make(map[string]*application, len(dashboards)-1)
+	a.Dashboards = make([]dashboardLink, 0, len(dashboards))
+	a.Dashboards = append(a.Dashboards, dashboardLink{
+		Name: dashboards[0].Name,
+		URL:  a.Config.Server.BaseURL + "/",
+	})
+
+	for i := 1; i < len(dashboards); i++ {
+		dashboard := &dashboards[i]
+
+		if dashboard.Slug == "" {
+			dashboard.Slug = titleToSlug(dashboard.Name)
+		}
+
+		if slices.Contains(reservedDashboardSlugs, dashboard.Slug) {
+			return fmt.Errorf("dashboard slug \"%s\" is reserved", dashboard.Slug)
+		}
+
+		if strings.Contains(dashboard.Slug, "/") {
+			return fmt.Errorf("dashboard slug \"%s\" can not contain slashes", dashboard.Slug)
+		}
+
+		if _, exists := a.slugToPage[dashboard.Slug]; exists {
+			return fmt.Errorf("dashboard slug \"%s\" is already used by a page of dashboard %s", dashboard.Slug, dashboards[0].Name)
+		}
+
+		if _, exists := a.dashboardApps[dashboard.Slug]; exists {
+			return fmt.Errorf("dashboard slug \"%s\" is used by more than one dashboard", dashboard.Slug)
+		}
+
+		dashboardApp := &application{
+			Version:                a.Version,
+			CreatedAt:              a.CreatedAt,
+			Config:                 a.Config,
+			parsedManifest:         a.parsedManifest,
+			slugToPage:             make(map[string]*page),
+			widgetByID:             make(map[uint64]widget),
+			RequiresAuth:           a.RequiresAuth,
+			authSecretKey:          a.authSecretKey,
+			usernameHashToUsername: a.usernameHashToUsername,
+			CurrentDashboard:       dashboard.Name,
+			liveUpdatesStopped:     a.liveUpdatesStopped,
+			parent:                 a,
+		}
+		dashboardApp.Config.Pages = dashboard.Pages
+		dashboardApp.Config.Server.BaseURL = a.Config.Server.BaseURL + "/" + dashboard.Slug
+
+		providers := &widgetProviders{
+			assetResolver:     dashboardApp.StaticAssetPath,
+			requiresAuth:      a.RequiresAuth,
+			proxyAllowedHosts: a.Config.Server.ProxyAllowedHosts,
+			formatter:         newLocaleFormatter(a.Config.Formatting),
+			location:          a.Config.Server.location,
+		}
+
+		// kept in a separate directory so that identical widgets
+		// on different dashboards don't end up sharing their data
+		if a.Config.Server.CachePath != "" {
+			store, err := newDiskWidgetCacheStore(filepath.Join(a.Config.Server.CachePath, "dashboards", dashboard.Slug))
+			if err != nil {
+				return err
+			}
+			providers.cache = store
+		}
+
+		if a.Config.Server.DataPath != "" {
+			store, err := newDiskNoteStore(filepath.Join(a.Config.Server.DataPath, "dashboards", dashboard.Slug, "notes"))
+			if err != nil {
+				return err
+			}
+			providers.notes = store
+		}
+
+		if err := dashboardApp.initPages(providers); err != nil {
+			return fmt.Errorf("dashboard %s: %v", dashboard.Name, err)
+		}
+
+		a.dashboardApps[dashboard.Slug] = dashboardApp
+		a.Dashboards = append(a.Dashboards, dashboardLink{
+			Name: dashboard.Name,
+			URL:  dashboardApp.Config.Server.BaseURL + "/",
+		})
 	}
 
-	if config.Branding.AppIconURL == "" {
-		config.Branding.AppIconURL = app.StaticAssetPath("app-icon.png")
+	a.CurrentDashboard = dashboards[0].Name
+	for _, dashboardApp := range a.dashboardApps {
+		dashboardApp.Dashboards = a.Dashboards
 	}
 
-	if config.Branding.AppBackgroundColor == "" {
-		config.Branding.AppBackgroundColor = config.Theme.BackgroundColorAsHex
+	return nil
+}
+
+// Applies the options which are shared by all widgets rather than kept per
+// application, which is only done once the application has been created
+// successfully so that a config with errors doesn't change them
+func (a *application) applyGlobalState() {
+	a.httpClientState.apply()
+	outboundRequestLimiter.configure(a.Config.Server.MaxConcurrentRequests, a.Config.Server.MaxConcurrentRequestsPerHost)
+	initialUpdateJitter.Store(int64(a.Config.Server.InitialUpdateJitter))
+	a.forEachMQTTWidget((*mqttWidget).acquireClient)
+
+	// left until now so that the first updates already use all of the above
+	if a.Config.Server.CachePath != "" {
+		go a.updatePagesInBackground()
+
+		for _, dashboardApp := range a.dashboardApps {
+			go dashboardApp.updatePagesInBackground()
+		}
 	}
+}
 
-	manifest, err := executeTemplateToString(manifestTemplate, templateData{App: app})
-	if err != nil {
-		return nil, fmt.Errorf("parsing manifest.json: %v", err)
+// Called once the application has been replaced, after the new one has
+// applied its state so that the connections both of them use are kept
+func (a *application) releaseGlobalState() {
+	a.forEachMQTTWidget((*mqttWidget).releaseClient)
+}
+
+// The base URL under which the login page is served and which cookies are
+// scoped to, shared by all dashboards
+func (a *application) rootBaseURL() string {
+	if a.parent != nil {
+		return a.parent.rootBaseURL()
 	}
-	app.parsedManifest = []byte(manifest)
 
-	return app, nil
+	return a.Config.Server.BaseURL
 }
 
-func (p *page) updateOutdatedWidgets() {
-	now := time.Now()
+// Populates widgets from the persistent cache and refreshes any expired data
+// without waiting for someone to visit the page. Requests for a page that
+// come in while it's being refreshed wait for the refresh to finish
+func (a *application) updatePagesInBackground() {
+	for p := range a.Config.Pages {
+		page := &a.Config.Pages[p]
+		go func() {
+			page.mu.Lock()
+			defer page.mu.Unlock()
+
+			page.updateOutdatedWidgets(widgetUpdatesCtx, a.now())
+		}()
+	}
+}
+
+// The kiosk query parameter takes precedence over the config of the page,
+// so that ?kiosk=0 can be used to interact with a page that has it enabled
+func (p *page) kioskForRequest(r *http.Request) *kioskOptions {
+	query := r.URL.Query()
 
+	if query.Has("kiosk") {
+		value := query.Get("kiosk")
+		if value == "0" || value == "false" {
+			return nil
+		}
+
+		return &p.Kiosk
+	}
+
+	return ternary(p.Kiosk.Enabled, &p.Kiosk, nil)
+}
+
+// The current time in the timezone of the dashboard, which is what the
+// visibility of widgets gets evaluated against
+func (a *application) now() time.Time {
+	return time.Now().In(a.Config.Server.location)
+}
+
+// Returns the widgets which were updated and whether any of them were shown or
+// hidden, in which case the layout of the page is no longer the same
+func (p *page) updateOutdatedWidgets(ctx context.Context, now time.Time) (updated []widget, visibilityChanged bool) {
 	var wg sync.WaitGroup
-	context := context.Background()
+	changed := p.dataUpdatedAt.IsZero()
+	ctx = context.WithValue(ctx, widgetPageContextKey{}, p)
 
 	for w := range p.HeadWidgets {
 		widget := p.HeadWidgets[w]
 
+		wasHidden := widget.IsHidden()
+		widget.updateVisibility(now)
+		visibilityChanged = visibilityChanged || widget.IsHidden() != wasHidden
+		if widget.IsHidden() {
+			continue
+		}
+
 		if !widget.requiresUpdate(&now) {
+			recordWidgetCacheHit(ctx, widget)
 			continue
 		}
 
+		updated = append(updated, widget)
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			widget.update(context)
+			updateWidget(ctx, widget)
 		}()
 	}
 
@@ -254,19 +617,39 @@ func (p *page) updateOutdatedWidgets() {
 		for w := range p.Columns[c].Widgets {
 			widget := p.Columns[c].Widgets[w]
 
+			wasHidden := widget.IsHidden()
+			widget.updateVisibility(now)
+			visibilityChanged = visibilityChanged || widget.IsHidden() != wasHidden
+			if widget.IsHidden() {
+				continue
+			}
+
 			if !widget.requiresUpdate(&now) {
+				recordWidgetCacheHit(ctx, widget)
 				continue
 			}
 
+			updated = append(updated, widget)
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				widget.update(context)
+				updateWidget(ctx, widget)
 			}()
 		}
 	}
 
 	wg.Wait()
+
+	if changed || visibilityChanged || len(updated) > 0 {
+		p.dataVersion++
+		p.dataUpdatedAt = now
+	}
+
+	if visibilityChanged {
+		p.layoutVersion = p.dataVersion
+	}
+
+	return updated, visibilityChanged
 }
 
 func (a *application) resolveUserDefinedAssetPath(path string) string {
@@ -279,6 +662,8 @@ func (a *application) resolveUserDefinedAssetPath(path string) string {
 
 type templateRequestData struct {
 	Theme *themeProperties
+	// Only set when the page is shown in kiosk mode
+	Kiosk *kioskOptions
 }
 
 type templateData struct {
@@ -289,6 +674,9 @@ type templateData struct {
 
 func (a *application) populateTemplateRequestData(data *templateRequestData, r *http.Request) {
 	theme := &a.Config.Theme.themeProperties
+	if a.Config.Theme.AutoProperties != nil {
+		theme = a.Config.Theme.AutoProperties
+	}
 
 	if !a.Config.Theme.DisablePicker {
 		selectedTheme, err := r.Cookie("theme")
@@ -296,6 +684,8 @@ func (a *application) populateTemplateRequestData(data *templateRequestData, r *
 			preset, exists := a.Config.Theme.Presets.Get(selectedTheme.Value)
 			if exists {
 				theme = preset
+			} else if selectedTheme.Value == "default" {
+				theme = &a.Config.Theme.themeProperties
 			}
 		}
 	}
@@ -303,6 +693,20 @@ func (a *application) populateTemplateRequestData(data *templateRequestData, r *
 	data.Theme = theme
 }
 
+func stricterBrowserCacheOfWidgets(cache browserCacheField, ws widgets) browserCacheField {
+	for _, w := range ws {
+		if own := w.browserCache(); own != nil {
+			cache = cache.stricter(*own)
+		}
+
+		if container, ok := w.(containerWidget); ok {
+			cache = stricterBrowserCacheOfWidgets(cache, container.children())
+		}
+	}
+
+	return cache
+}
+
 func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request) {
 	page, exists := a.slugToPage[r.PathValue("page")]
 	if !exists {
@@ -310,7 +714,9 @@ func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if a.handleUnauthorizedResponse(w, r, redirectToLogin) {
+	username, authorized := a.authorizedUsername(w, r)
+	if !authorized {
+		a.respondUnauthorized(w, r, redirectToLogin)
 		return
 	}
 
@@ -319,6 +725,7 @@ func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request)
 		App:  a,
 	}
 	a.populateTemplateRequestData(&data.Request, r)
+	data.Request.Kiosk = page.kioskForRequest(r)
 
 	var responseBytes bytes.Buffer
 	err := pageTemplate.Execute(&responseBytes, data)
@@ -328,7 +735,9 @@ func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.Write(responseBytes.Bytes())
+	// nothing in the document itself depends on the data of the widgets,
+	// it only changes when the config gets reloaded
+	serveRenderedPage(w, r, page.BrowserCache, responseBytes.Bytes(), a.CreatedAt, themeCookieValue(r), username)
 }
 
 func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Request) {
@@ -338,7 +747,9 @@ func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+	username, authorized := a.authorizedUsername(w, r)
+	if !authorized {
+		a.respondUnauthorized(w, r, showUnauthorizedJSON)
 		return
 	}
 
@@ -348,13 +759,16 @@ func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Re
 
 	var err error
 	var responseBytes bytes.Buffer
+	var dataVersion uint64
+	var dataUpdatedAt time.Time
 
 	func() {
 		page.mu.Lock()
 		defer page.mu.Unlock()
 
-		page.updateOutdatedWidgets()
+		page.updateOutdatedWidgets(withRequestIDFrom(widgetUpdatesCtx, r), a.now())
 		err = pageContentTemplate.Execute(&responseBytes, pageData)
+		dataVersion, dataUpdatedAt = page.dataVersion, page.dataUpdatedAt
 	}()
 
 	if err != nil {
@@ -363,7 +777,53 @@ func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	w.Write(responseBytes.Bytes())
+	browserCache := page.contentBrowserCache
+
+	// lets the live updates pick up from where the content left off, which
+	// only works if the content is never older than what the server has
+	if a.Config.Server.LiveUpdates {
+		w.Header().Set("X-Page-Version", a.livePageVersion(dataVersion))
+		browserCache = browserCache.stricter(browserCacheField{})
+	}
+
+	serveRenderedPage(
+		w, r, browserCache, responseBytes.Bytes(), dataUpdatedAt,
+		strconv.FormatUint(dataVersion, 10), themeCookieValue(r), username,
+	)
+}
+
+// Unless the page allows browsers to reuse them for a while, rendered pages get
+// revalidated on every load and a 304 is sent back if they haven't changed since.
+// The ETag also covers everything else that the response varies by, so that a
+// page cached for one theme or user never gets reused for another, even if the
+// rendered content happens to be the same.
+func serveRenderedPage(w http.ResponseWriter, r *http.Request, cache browserCacheField, body []byte, lastModified time.Time, varies ...string) {
+	hash := sha256.New()
+	for _, value := range varies {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+
+	header := w.Header()
+	header.Set("ETag", `"`+hex.EncodeToString(hash.Sum(nil)[:16])+`"`)
+	header.Set("Cache-Control", cache.headerValue())
+	header.Add("Vary", "Cookie")
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	// handles If-None-Match and If-Modified-Since, with the former taking
+	// precedence since the last modified time doesn't account for the theme
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(body))
+}
+
+func themeCookieValue(r *http.Request) string {
+	if cookie, err := r.Cookie("theme"); err == nil {
+		return cookie.Value
+	}
+
+	return ""
 }
 
 func (a *application) addressOfRequest(r *http.Request) string {
@@ -401,31 +861,47 @@ func (a *application) handleNotFound(w http.ResponseWriter, _ *http.Request) {
 	w.Write([]byte("Page not found"))
 }
 
+// Implemented by widgets which handle requests made to /api/widgets/{widget}/
+type requestHandlingWidget interface {
+	handleRequest(w http.ResponseWriter, r *http.Request)
+}
+
 func (a *application) handleWidgetRequest(w http.ResponseWriter, r *http.Request) {
-	// TODO: this requires a rework of the widget update logic so that rather
-	// than locking the entire page we lock individual widgets
-	w.WriteHeader(http.StatusNotImplemented)
+	if a.handleUnauthorizedResponse(w, r, showUnauthorizedJSON) {
+		return
+	}
 
-	// widgetValue := r.PathValue("widget")
+	entry, exists := a.widgetByStableID[r.PathValue("widget")]
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
 
-	// widgetID, err := strconv.ParseUint(widgetValue, 10, 64)
-	// if err != nil {
-	// 	a.handleNotFound(w, r)
-	// 	return
-	// }
+	handler, ok := entry.widget.(requestHandlingWidget)
+	if !ok {
+		a.handleNotFound(w, r)
+		return
+	}
 
-	// widget, exists := a.widgetByID[widgetID]
+	// TODO: the page doesn't get locked since that would block the request for
+	// as long as the page is updating, which means that handlers can only use
+	// the parts of the widget which don't change when it updates. Locking
+	// individual widgets would require a rework of the widget update logic
+	handler.handleRequest(w, r)
+}
 
-	// if !exists {
-	// 	a.handleNotFound(w, r)
-	// 	return
-	// }
+func (a *application) StaticAssetPath(asset string) string {
+	return a.rootBaseURL() + "/static/" + staticFSHash + "/" + asset
+}
 
-	// widget.handleRequest(w, r)
+// The manifest and the service worker are shared by all dashboards, so
+// they're always served from the root
+func (a *application) ManifestPath() string {
+	return a.rootBaseURL() + "/manifest.webmanifest?v=" + strconv.FormatInt(a.CreatedAt.Unix(), 10)
 }
 
-func (a *application) StaticAssetPath(asset string) string {
-	return a.Config.Server.BaseURL + "/static/" + staticFSHash + "/" + asset
+func (a *application) ServiceWorkerPath() string {
+	return a.rootBaseURL() + "/service-worker.js"
 }
 
 func (a *application) VersionedAssetPath(asset string) string {
@@ -433,83 +909,300 @@ func (a *application) VersionedAssetPath(asset string) string {
 		"?v=" + strconv.FormatInt(a.CreatedAt.Unix(), 10)
 }
 
-func (a *application) server() (func() error, func() error) {
+func (a *application) handler() http.Handler {
+	handler := compressResponses(a.mux())
+
+	// shared with the guard so that failed attempts count towards the limit
+	var limiter *clientRateLimiter
+	if a.Config.Server.RateLimit.Rate != nil {
+		limiter = newClientRateLimiter(&a.Config.Server.RateLimit)
+		handler = limitClientRequests(limiter, a.addressOfRequest, handler)
+	}
+
+	if a.Config.Server.Guard.Enabled {
+		handler = guardRequests(&a.Config.Server.Guard, limiter, a.addressOfRequest, handler)
+	}
+
+	if basePath := a.basePath(); basePath != "" {
+		handler = a.stripBasePath(basePath, handler)
+	}
+
+	return logRequests(handler)
+}
+
+// The path component of the base-url, which can also include the domain
+func (a *application) basePath() string {
+	parsed, err := url.Parse(a.Config.Server.BaseURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimRight(parsed.Path, "/")
+}
+
+// Requests are routed the same way regardless of whether the reverse proxy
+// strips the base path before forwarding them, the ones without it are left
+// as they are
+func (a *application) stripBasePath(basePath string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := strings.CutPrefix(r.URL.Path, basePath)
+		if !ok || (path != "" && path[0] != '/') {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if path == "" {
+			// unless there's a page of the same name
+			// and the prefix has already been stripped
+			slug := basePath[1:]
+			if _, exists := a.slugToPage[slug]; exists || a.dashboardApps[slug] != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		stripped := new(http.Request)
+		*stripped = *r
+		stripped.URL = new(url.URL)
+		*stripped.URL = *r.URL
+		stripped.URL.Path = path
+		stripped.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, basePath)
+
+		next.ServeHTTP(w, stripped)
+	})
+}
+
+func (a *application) mux() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /{$}", a.handlePageRequest)
 	mux.HandleFunc("GET /{page}", a.handlePageRequest)
 
 	mux.HandleFunc("GET /api/pages/{page}/content/{$}", a.handlePageContentRequest)
+	if a.Config.Server.LiveUpdates {
+		mux.HandleFunc("GET /api/pages/{page}/events", a.handlePageEventsRequest)
+	}
 
 	if !a.Config.Theme.DisablePicker {
 		mux.HandleFunc("POST /api/set-theme/{key}", a.handleThemeChangeRequest)
 	}
 
+	if !a.Config.CommandPalette.Disabled {
+		mux.HandleFunc("GET /api/command-palette", a.handleCommandPaletteRequest)
+	}
+
 	mux.HandleFunc("/api/widgets/{widget}/{path...}", a.handleWidgetRequest)
-	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("GET /api/v1/pages", a.handleAPIPagesRequest)
+	mux.HandleFunc("GET /api/v1/widgets/{id}", a.handleAPIWidgetRequest)
+	healthCheck := func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
-	})
+	}
+	mux.HandleFunc("GET /api/healthz", healthCheck)
+	mux.HandleFunc("GET /healthz", healthCheck)
 
-	if a.RequiresAuth {
+	if len(a.webhooks) > 0 {
+		mux.HandleFunc("POST /hooks/{id}", a.handleWebhookRequest)
+	}
+
+	if a.Config.Server.Metrics.Enabled && a.Config.Server.Metrics.Address == "" {
+		mux.HandleFunc("GET /metrics", handleMetricsRequest)
+	}
+
+	if a.RequiresAuth && a.parent != nil {
+		mux.HandleFunc("GET /logout", a.handleLogoutRequest)
+		mux.HandleFunc("POST /logout", a.handleLogoutRequest)
+	} else if a.RequiresAuth {
 		mux.HandleFunc("GET /login", a.handleLoginPageRequest)
 		mux.HandleFunc("GET /logout", a.handleLogoutRequest)
+		mux.HandleFunc("POST /logout", a.handleLogoutRequest)
 		mux.HandleFunc("POST /api/authenticate", a.handleAuthenticationAttempt)
 	}
 
+	assetCacheControlValue := fmt.Sprintf(
+		"public, max-age=%d",
+		int(STATIC_ASSETS_CACHE_DURATION.Seconds()),
+	)
+
 	mux.Handle(
 		fmt.Sprintf("GET /static/%s/{path...}", staticFSHash),
 		http.StripPrefix(
 			"/static/"+staticFSHash,
-			fileServerWithCache(http.FS(staticFS), STATIC_ASSETS_CACHE_DURATION),
+			serveStaticPrecompressed(
+				staticFS,
+				assetCacheControlValue,
+				fileServerWithCache(http.FS(staticFS), STATIC_ASSETS_CACHE_DURATION),
+			),
 		),
 	)
 
-	assetCacheControlValue := fmt.Sprintf(
-		"public, max-age=%d",
-		int(STATIC_ASSETS_CACHE_DURATION.Seconds()),
-	)
-
 	mux.HandleFunc(fmt.Sprintf("GET /static/%s/css/bundle.css", staticFSHash), func(w http.ResponseWriter, r *http.Request) {
+		if acceptsGzip(r) {
+			compressed, _ := staticAssetsCompressed.get("css/bundle.css", func() ([]byte, error) {
+				return bundledCSSContents, nil
+			})
+			writePrecompressed(w, "text/css; charset=utf-8", assetCacheControlValue, compressed)
+			return
+		}
+
 		w.Header().Add("Cache-Control", assetCacheControlValue)
 		w.Header().Add("Content-Type", "text/css; charset=utf-8")
 		w.Write(bundledCSSContents)
 	})
 
-	mux.HandleFunc("GET /manifest.json", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Cache-Control", assetCacheControlValue)
-		w.Header().Add("Content-Type", "application/json")
-		w.Write(a.parsedManifest)
-	})
+	// the old path is kept for documents which were cached before the rename
+	for _, path := range []string{"manifest.webmanifest", "manifest.json"} {
+		mux.HandleFunc("GET /"+path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Cache-Control", assetCacheControlValue)
+			w.Header().Add("Content-Type", "application/manifest+json")
+			w.Write(a.parsedManifest)
+		})
+	}
+
+	if a.parent == nil {
+		mux.HandleFunc("GET /service-worker.js", func(w http.ResponseWriter, r *http.Request) {
+			// browsers check for updates to the worker on their own, it
+			// only has to not be served from the HTTP cache
+			w.Header().Add("Cache-Control", "no-cache")
+			w.Header().Add("Content-Type", "text/javascript; charset=utf-8")
+			w.Write(a.parsedServiceWorker)
+		})
+	}
 
-	var absAssetsPath string
 	if a.Config.Server.AssetsPath != "" {
-		absAssetsPath, _ = filepath.Abs(a.Config.Server.AssetsPath)
 		assetsFS := fileServerWithCache(http.Dir(a.Config.Server.AssetsPath), 2*time.Hour)
 		mux.Handle("/assets/{path...}", http.StripPrefix("/assets/", assetsFS))
 	}
 
+	for slug, dashboardApp := range a.dashboardApps {
+		mux.Handle("/"+slug+"/", http.StripPrefix("/"+slug, dashboardApp.mux()))
+		mux.Handle("GET /"+slug, http.RedirectHandler(dashboardApp.Config.Server.BaseURL+"/", http.StatusSeeOther))
+	}
+
+	return mux
+}
+
+// The handler is passed in rather than created here so that it can be swapped
+// out when the config changes without having to restart the server
+func (a *application) server(handler http.Handler) (func() error, func() error) {
+	var absAssetsPath string
+	if a.Config.Server.AssetsPath != "" {
+		absAssetsPath, _ = filepath.Abs(a.Config.Server.AssetsPath)
+	}
+
 	server := http.Server{
-		Addr:    fmt.Sprintf("%s:%d", a.Config.Server.Host, a.Config.Server.Port),
-		Handler: mux,
+		Addr:         fmt.Sprintf("%s:%d", a.Config.Server.Host, a.Config.Server.Port),
+		Handler:      handler,
+		ReadTimeout:  time.Duration(a.Config.Server.ReadTimeout),
+		WriteTimeout: time.Duration(a.Config.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(a.Config.Server.IdleTimeout),
 	}
 
-	start := func() error {
-		log.Printf("Starting server on %s:%d (base-url: \"%s\", assets-path: \"%s\")\n",
+	tlsOptions := &a.Config.Server.TLS
+	var redirectServer *http.Server
+	var tlsErr error
+
+	if tlsOptions.enabled() {
+		// the certificate gets loaded again in case it changed since the config was loaded
+		var httpHandler http.Handler
+		httpHandler, tlsErr = tlsOptions.configure(&server, a.Config.Server.Port)
+
+		if tlsErr == nil && tlsOptions.RedirectHTTP != "" {
+			redirectServer = &http.Server{
+				Addr:              tlsOptions.RedirectHTTP,
+				Handler:           httpHandler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+		}
+	}
+
+	startMain := func() error {
+		log.Printf("Starting server on %s:%d%s (base-url: \"%s\", assets-path: \"%s\")\n",
 			a.Config.Server.Host,
 			a.Config.Server.Port,
+			ternary(tlsOptions.enabled(), " with TLS", ""),
 			a.Config.Server.BaseURL,
 			absAssetsPath,
 		)
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if !tlsOptions.enabled() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		}
+
+		if tlsErr != nil {
+			return tlsErr
+		}
+
+		if redirectServer != nil {
+			go func() {
+				log.Printf("Redirecting plain HTTP on %s to HTTPS\n", redirectServer.Addr)
+				if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Printf("Failed to start HTTP redirect server: %v", err)
+				}
+			}()
+		}
+
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			return err
 		}
 
 		return nil
 	}
 
+	var metricsServer *http.Server
+	if a.Config.Server.Metrics.Enabled && a.Config.Server.Metrics.Address != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.HandleFunc("GET /metrics", handleMetricsRequest)
+		metricsServer = &http.Server{
+			Addr:    a.Config.Server.Metrics.Address,
+			Handler: metricsMux,
+		}
+	}
+
+	start := func() error {
+		if metricsServer != nil {
+			go func() {
+				log.Printf("Starting metrics server on %s\n", metricsServer.Addr)
+				if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Printf("Failed to start metrics server: %v", err)
+				}
+			}()
+		}
+
+		return startMain()
+	}
+
+	// Stops accepting new connections and waits for the requests which are
+	// already being handled to finish, for up to the shutdown timeout
 	stop := func() error {
-		return server.Close()
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+
+		if redirectServer != nil {
+			redirectServer.Close()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.Config.Server.ShutdownTimeout))
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Requests still in progress after %s, closing their connections", time.Duration(a.Config.Server.ShutdownTimeout))
+			return server.Close()
+		}
+
+		return nil
 	}
 
 	return start, stop
@@ -0,0 +1,204 @@
+package glance
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Options for the requests made by widgets, which can be set both globally
+// and on individual widgets. The options of a widget replace the global ones
+// rather than being merged with them
+type httpClientOptions struct {
+	Proxy              proxyOptionsField `yaml:"proxy"`
+	CAFile             string            `yaml:"ca-file"`
+	InsecureSkipVerify bool              `yaml:"insecure-skip-verify"`
+	RateLimit          *rateLimitOptions `yaml:"rate-limit"`
+	client             *http.Client      `yaml:"-"`
+	insecureClient     *http.Client      `yaml:"-"`
+}
+
+func (o *httpClientOptions) isSet() bool {
+	return o.Proxy.parsedURL != nil || o.CAFile != "" || o.InsecureSkipVerify
+}
+
+func (o *httpClientOptions) tlsConfig(allowInsecure bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: allowInsecure || o.InsecureSkipVerify || o.Proxy.AllowInsecure,
+	}
+
+	if o.CAFile != "" {
+		contents, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca-file: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(contents) {
+			return nil, fmt.Errorf("ca-file %s does not contain any PEM encoded certificates", o.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func (o *httpClientOptions) newTransport(allowInsecure bool) (*http.Transport, error) {
+	tlsConfig, err := o.tlsConfig(allowInsecure)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: 10,
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	if o.Proxy.parsedURL != nil {
+		transport.Proxy = http.ProxyURL(o.Proxy.parsedURL)
+	}
+
+	return transport, nil
+}
+
+// Widgets which don't set any options keep using the default clients
+func (o *httpClientOptions) initClients(name string) error {
+	if o.RateLimit != nil {
+		return errors.New("rate-limit can only be set within the top level http-client")
+	}
+
+	if !o.isSet() {
+		return nil
+	}
+
+	if o.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled, connections can be intercepted without any indication", "for", name)
+	}
+
+	timeout := defaultClientTimeout
+	if o.Proxy.Timeout > 0 {
+		timeout = time.Duration(o.Proxy.Timeout)
+	}
+
+	transport, err := o.newTransport(false)
+	if err != nil {
+		return err
+	}
+
+	insecureTransport, err := o.newTransport(true)
+	if err != nil {
+		return err
+	}
+
+	o.client = &http.Client{Timeout: timeout, Transport: newLimitedTransport(transport)}
+	o.insecureClient = &http.Client{Timeout: timeout, Transport: newLimitedTransport(insecureTransport)}
+
+	return nil
+}
+
+// Safe to call on a nil value, in which case the default clients get used
+func (o *httpClientOptions) httpClient(allowInsecure bool) *http.Client {
+	if o == nil || o.client == nil {
+		return ternary(allowInsecure, defaultInsecureHTTPClient, defaultHTTPClient)
+	}
+
+	return ternary(allowInsecure, o.insecureClient, o.client)
+}
+
+// Makes it possible for the global options to apply to the default clients
+// without having to replace them, since they're referenced all over the place
+type switchableTransport struct {
+	fallback *http.Transport
+	current  atomic.Pointer[http.Transport]
+}
+
+func newSwitchableTransport(fallback *http.Transport) *switchableTransport {
+	return &switchableTransport{fallback: fallback}
+}
+
+// Passing nil switches back to the fallback transport
+func (t *switchableTransport) set(transport *http.Transport) {
+	t.current.Store(transport)
+}
+
+func (t *switchableTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if current := t.current.Load(); current != nil {
+		return current.RoundTrip(request)
+	}
+
+	return t.fallback.RoundTrip(request)
+}
+
+var (
+	defaultTransport = newSwitchableTransport(&http.Transport{
+		MaxIdleConnsPerHost: 10,
+		Proxy:               http.ProxyFromEnvironment,
+	})
+	defaultInsecureTransport = newSwitchableTransport(&http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		Proxy:           http.ProxyFromEnvironment,
+	})
+)
+
+// What the top level http-client options apply to the default clients, kept
+// separate so that they only get applied once the application that they're a
+// part of has been created successfully
+type globalHTTPClientState struct {
+	rateLimit         *rateLimitOptions
+	transport         *http.Transport
+	insecureTransport *http.Transport
+}
+
+func newGlobalHTTPClientState(options *httpClientOptions) (*globalHTTPClientState, error) {
+	state := &globalHTTPClientState{rateLimit: options.RateLimit}
+
+	if options.RateLimit != nil {
+		if err := options.RateLimit.initialize(); err != nil {
+			return nil, fmt.Errorf("http-client: rate-limit: %v", err)
+		}
+	}
+
+	if !options.isSet() {
+		return state, nil
+	}
+
+	if options.Proxy.Timeout > 0 {
+		return nil, errors.New("http-client: the proxy timeout can only be set on widgets")
+	}
+
+	if options.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled for all widgets, connections can be intercepted without any indication")
+	}
+
+	var err error
+	state.transport, err = options.newTransport(false)
+	if err != nil {
+		return nil, fmt.Errorf("http-client: %v", err)
+	}
+
+	state.insecureTransport, err = options.newTransport(true)
+	if err != nil {
+		return nil, fmt.Errorf("http-client: %v", err)
+	}
+
+	return state, nil
+}
+
+// Nil transports switch the default clients back to their fallbacks
+func (s *globalHTTPClientState) apply() {
+	outboundRateLimiter.configure(s.rateLimit)
+	defaultTransport.set(s.transport)
+	defaultInsecureTransport.set(s.insecureTransport)
+}
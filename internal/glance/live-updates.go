@@ -0,0 +1,358 @@
+package glance
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the widgets of pages which have clients listening for updates
+// get checked for whether they need to be updated
+const liveUpdatesCheckInterval = 5 * time.Second
+
+// Widgets with a visible-when condition are only re-evaluated this often
+// since the conditions don't have a finer resolution than a minute
+const liveUpdatesVisibilityCheckInterval = time.Minute
+
+// Keeps a connection from being closed by proxies for being idle
+const liveUpdatesKeepAliveInterval = 30 * time.Second
+
+// Clients which fall this many events behind get disconnected, after which
+// they reconnect and catch up on everything they missed at once
+const liveUpdatesClientBufferSize = 32
+
+type liveUpdateEvent struct {
+	// Either widget, when the data is the new HTML of a single widget, or
+	// reload when the whole page has to be loaded again
+	name string
+	// The data version of the page at the time of the event
+	version uint64
+	data    []byte
+}
+
+// The widgets of a page only get updated while someone is viewing it, which
+// is what the updates are checked for in between requests for the page
+type pageLiveUpdates struct {
+	mu          sync.Mutex
+	subscribers map[chan liveUpdateEvent]struct{}
+	running     bool
+
+	// Guarded by the lock of the page rather than the above
+	sentHashes          map[uint64][sha256.Size]byte
+	lastVisibilityCheck time.Time
+}
+
+type liveWidgetUpdate struct {
+	ID   uint64 `json:"id"`
+	HTML string `json:"html"`
+}
+
+func (a *application) handlePageEventsRequest(w http.ResponseWriter, r *http.Request) {
+	page, exists := a.slugToPage[r.PathValue("page")]
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	if _, authorized := a.authorizedUsername(w, r); !authorized {
+		a.respondUnauthorized(w, r, showUnauthorizedJSON)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	// the server's write timeout would otherwise end the stream
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Live updates are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	// browsers send the id of the last event they received when reconnecting,
+	// the first time around it's the version of the page content they loaded
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+
+	events := a.subscribeToPage(page)
+	defer page.live.unsubscribe(events)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// nginx buffers responses unless told otherwise
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range a.liveUpdatesSince(page, since) {
+		a.writeLiveUpdateEvent(w, &event)
+	}
+	controller.Flush()
+
+	keepAlive := time.NewTicker(liveUpdatesKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		// the client reconnects on its own and gets told to reload
+		// once it reaches the application which replaced this one
+		case <-a.liveUpdatesStopped:
+			return
+		case event, open := <-events:
+			if !open {
+				return
+			}
+
+			a.writeLiveUpdateEvent(w, &event)
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (a *application) writeLiveUpdateEvent(w http.ResponseWriter, event *liveUpdateEvent) {
+	fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event.name, a.livePageVersion(event.version), event.data)
+}
+
+// The versions of pages start over whenever the config is reloaded or the
+// server restarts, so they're prefixed with when the application was created
+func (a *application) livePageVersion(version uint64) string {
+	return strconv.FormatInt(a.CreatedAt.UnixNano(), 36) + "-" + strconv.FormatUint(version, 10)
+}
+
+// Returns the events which a client that has seen the page at the given
+// version missed. Since it isn't known which widgets changed in between,
+// all of them get sent, unless widgets have been shown or hidden since or
+// the version is from another application, in which case the page has to
+// be reloaded
+func (a *application) liveUpdatesSince(p *page, since string) []liveUpdateEvent {
+	if since == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	instance, versionString, _ := strings.Cut(since, "-")
+	version, err := strconv.ParseUint(versionString, 10, 64)
+	if err != nil || instance+"-0" != a.livePageVersion(0) || version < p.layoutVersion {
+		return []liveUpdateEvent{{name: "reload", version: p.dataVersion}}
+	}
+
+	if version >= p.dataVersion {
+		return nil
+	}
+
+	var events []liveUpdateEvent
+
+	p.forEachVisibleWidget(func(widget widget) {
+		if event, ok := p.renderLiveUpdate(widget); ok {
+			events = append(events, event)
+		}
+	})
+
+	return events
+}
+
+func (p *page) forEachVisibleWidget(fn func(widget)) {
+	for _, widget := range p.HeadWidgets {
+		if !widget.IsHidden() {
+			fn(widget)
+		}
+	}
+
+	for c := range p.Columns {
+		for _, widget := range p.Columns[c].Widgets {
+			if !widget.IsHidden() {
+				fn(widget)
+			}
+		}
+	}
+}
+
+// Must be called while holding the lock of the page
+func (p *page) renderLiveUpdate(widget widget) (liveUpdateEvent, bool) {
+	data, err := json.Marshal(liveWidgetUpdate{
+		ID:   widget.GetID(),
+		HTML: string(widget.Render()),
+	})
+	if err != nil {
+		return liveUpdateEvent{}, false
+	}
+
+	return liveUpdateEvent{name: "widget", version: p.dataVersion, data: data}, true
+}
+
+func (a *application) subscribeToPage(p *page) chan liveUpdateEvent {
+	events := make(chan liveUpdateEvent, liveUpdatesClientBufferSize)
+
+	p.live.mu.Lock()
+	defer p.live.mu.Unlock()
+
+	if p.live.subscribers == nil {
+		p.live.subscribers = make(map[chan liveUpdateEvent]struct{})
+	}
+	p.live.subscribers[events] = struct{}{}
+
+	if !p.live.running {
+		p.live.running = true
+		go a.runLiveUpdates(p)
+	}
+
+	return events
+}
+
+func (l *pageLiveUpdates) unsubscribe(events chan liveUpdateEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subscribers, events)
+}
+
+func (l *pageLiveUpdates) broadcast(event liveUpdateEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for events := range l.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(l.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Runs for as long as the page has clients listening for updates
+func (a *application) runLiveUpdates(p *page) {
+	ticker := time.NewTicker(liveUpdatesCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.liveUpdatesStopped:
+			return
+		case <-ticker.C:
+		}
+
+		p.live.mu.Lock()
+		if len(p.live.subscribers) == 0 {
+			p.live.running = false
+			p.live.mu.Unlock()
+			return
+		}
+		p.live.mu.Unlock()
+
+		for _, event := range a.checkForLiveUpdates(p) {
+			p.live.broadcast(event)
+		}
+	}
+}
+
+func (a *application) checkForLiveUpdates(p *page) []liveUpdateEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := a.now()
+	if !p.hasOutdatedWidgets(now) {
+		return nil
+	}
+
+	updated, visibilityChanged := p.updateOutdatedWidgets(widgetUpdatesCtx, now)
+	if visibilityChanged {
+		return []liveUpdateEvent{{name: "reload", version: p.dataVersion}}
+	}
+
+	if p.live.sentHashes == nil {
+		p.live.sentHashes = make(map[uint64][sha256.Size]byte)
+	}
+
+	var events []liveUpdateEvent
+
+	for _, widget := range updated {
+		// the ones that failed are left as they are on the page
+		// until they either recover or the page gets reloaded
+		if widget.updateError() != nil {
+			continue
+		}
+
+		event, ok := p.renderLiveUpdate(widget)
+		if !ok {
+			continue
+		}
+
+		// most updates don't change anything, such as when the
+		// upstream has nothing new or the update failed again
+		hash := sha256.Sum256(event.data)
+		if p.live.sentHashes[widget.GetID()] == hash {
+			continue
+		}
+		p.live.sentHashes[widget.GetID()] = hash
+
+		events = append(events, event)
+	}
+
+	if len(events) > 0 {
+		slog.Debug("Sending live updates", "page", p.Slug, "widgets", len(events))
+	}
+
+	return events
+}
+
+// Checked before updating the page so that widgets which don't need an
+// update don't get counted as cache hits every few seconds
+func (p *page) hasOutdatedWidgets(now time.Time) bool {
+	checkVisibility := now.Sub(p.live.lastVisibilityCheck) >= liveUpdatesVisibilityCheckInterval
+	outdated := false
+
+	p.forEachWidget(func(widget widget) {
+		if outdated {
+			return
+		}
+
+		if checkVisibility && widget.hasVisibilityCondition() {
+			outdated = true
+		} else if !widget.IsHidden() && widget.requiresUpdate(&now) {
+			outdated = true
+		}
+	})
+
+	if checkVisibility {
+		p.live.lastVisibilityCheck = now
+	}
+
+	return outdated
+}
+
+func (p *page) forEachWidget(fn func(widget)) {
+	for _, widget := range p.HeadWidgets {
+		fn(widget)
+	}
+
+	for c := range p.Columns {
+		for _, widget := range p.Columns[c].Widgets {
+			fn(widget)
+		}
+	}
+}
+
+// Ends the streams of all clients listening for updates and stops checking
+// for them, used when the config changes or the server is shutting down
+func (a *application) stopLiveUpdates() {
+	a.stopLiveUpdatesOnce.Do(func() {
+		close(a.liveUpdatesStopped)
+	})
+}
@@ -0,0 +1,164 @@
+package glance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// The logger that writes through the log package, which is what the text
+// format uses so that the output stays the same as it's always been
+var textLogger = slog.Default()
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+func validateLogOptions(server *serverConfig) error {
+	if _, exists := logLevels[server.LogLevel]; server.LogLevel != "" && !exists {
+		return fmt.Errorf("log-level must be one of debug, info, warn or error, got %s", server.LogLevel)
+	}
+
+	if server.LogFormat != "" && server.LogFormat != "text" && server.LogFormat != "json" {
+		return fmt.Errorf("log-format must be either text or json, got %s", server.LogFormat)
+	}
+
+	return nil
+}
+
+// Applies to the whole process, calling it again when the config gets
+// reloaded switches the level and format of all logs from then on
+func configureLogging(server *serverConfig) {
+	level, exists := logLevels[server.LogLevel]
+	if !exists {
+		level = slog.LevelInfo
+	}
+
+	if server.LogFormat != "json" {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+		slog.SetDefault(textLogger)
+		slog.SetLogLoggerLevel(level)
+		return
+	}
+
+	// lines written through the log package end up in the handler as well,
+	// at the level set here
+	slog.SetLogLoggerLevel(slog.LevelInfo)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+type requestIDContextKey struct{}
+
+const requestIDHeader = "X-Request-ID"
+
+// Returns the default logger with the ID of the request that the context
+// belongs to attached, if there is one
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return slog.Default().With("request_id", id)
+	}
+
+	return slog.Default()
+}
+
+// Widget updates don't use the context of the request that triggered them
+// since they shouldn't get canceled if the client goes away, this carries
+// over only the ID of the request so their logs can be tied back to it
+func withRequestIDFrom(ctx context.Context, r *http.Request) context.Context {
+	if id, ok := r.Context().Value(requestIDContextKey{}).(string); ok {
+		return context.WithValue(ctx, requestIDContextKey{}, id)
+	}
+
+	return ctx
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// IDs set by a reverse proxy in front of the server get reused so that
+// logs from both can be correlated
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+
+	return strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+	}) == -1
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id))
+
+		start := time.Now()
+		sw := &statusRecordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		logger := loggerFromContext(r.Context())
+		if !logger.Enabled(r.Context(), slog.LevelDebug) {
+			return
+		}
+
+		logger.Debug("Handled request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"bytes", sw.written,
+			"duration", time.Since(start).Round(time.Microsecond),
+		)
+	})
+}
+
+type statusRecordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int
+	wroteHeader bool
+}
+
+func (w *statusRecordingResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecordingResponseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(data)
+	w.written += n
+	return n, err
+}
+
+func (w *statusRecordingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusRecordingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,11 +1,18 @@
 package glance
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -28,35 +35,53 @@ func Main() int {
 			return 1
 		}
 
-		if err := serveApp(options.configPath); err != nil {
+		if err := serveApp(options.configPath, options.overlayPaths, options.restrictIncludes, options.noWatch); err != nil {
 			fmt.Println(err)
 			return 1
 		}
 	case cliIntentConfigValidate:
-		contents, _, err := parseYAMLIncludes(options.configPath)
+		contents, _, err := parseConfigFiles(options.configPath, options.overlayPaths, options.restrictIncludes)
 		if err != nil {
 			fmt.Printf("Could not parse config file: %v\n", err)
 			return 1
 		}
 
-		if _, err := newConfigFromYAML(contents); err != nil {
-			fmt.Printf("Config file is invalid: %v\n", err)
+		if _, err := newConfigFromYAML(contents, filepath.Dir(options.configPath)); err != nil {
+			var errs configErrors
+			if errors.As(err, &errs) {
+				fmt.Printf("Config file is invalid, found %d errors:\n", len(errs))
+				for _, err := range errs {
+					fmt.Printf("  %v\n", err)
+				}
+			} else {
+				fmt.Printf("Config file is invalid: %v\n", err)
+			}
 			return 1
 		}
+
+		fmt.Println("Config file is valid")
 	case cliIntentConfigPrint:
-		contents, _, err := parseYAMLIncludes(options.configPath)
+		contents, _, err := parseConfigFiles(options.configPath, options.overlayPaths, options.restrictIncludes)
 		if err != nil {
 			fmt.Printf("Could not parse config file: %v\n", err)
 			return 1
 		}
 
 		fmt.Println(string(contents))
+	case cliIntentConfigMigrate:
+		return cliMigrateConfig(options.configPath, options.overlayPaths, options.restrictIncludes)
 	case cliIntentSensorsPrint:
 		return cliSensorsPrint()
 	case cliIntentMountpointInfo:
 		return cliMountpointInfo(options.args[1])
+	case cliIntentFeedDiscover:
+		return cliFeedDiscover(options.args[1])
 	case cliIntentDiagnose:
 		runDiagnostic()
+	case cliIntentAgent:
+		return cliAgent(options.args[1:])
+	case cliIntentRender:
+		return cliRender(options.configPath, options.overlayPaths, options.restrictIncludes)
 	case cliIntentSecretMake:
 		key, err := makeAuthSecretKey(AUTH_SECRET_KEY_LENGTH)
 		if err != nil {
@@ -90,22 +115,45 @@ func Main() int {
 	return 0
 }
 
-func serveApp(configPath string) error {
+type swappableHandler struct {
+	current atomic.Pointer[http.Handler]
+}
+
+func (h *swappableHandler) swap(handler http.Handler) {
+	h.current.Store(&handler)
+}
+
+func (h *swappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.current.Load()).ServeHTTP(w, r)
+}
+
+func serveApp(configPath string, overlayPaths []string, restrictIncludes bool, noWatch bool) error {
 	// TODO: refactor if this gets any more complex, the current implementation is
 	// difficult to reason about due to all of the callbacks and simultaneous operations,
 	// use a single goroutine and a channel to initiate synchronous changes to the server
 	exitChannel := make(chan struct{})
 	hadValidConfigOnStartup := false
+	var stopServerMu sync.Mutex
 	var stopServer func() error
+	var runningServerConfig serverConfig
+	var runningApp *application
+	handler := &swappableHandler{}
 
 	onChange := func(newContents []byte) {
+		stopServerMu.Lock()
+		defer stopServerMu.Unlock()
+
 		if stopServer != nil {
 			log.Println("Config file changed, reloading...")
 		}
 
-		config, err := newConfigFromYAML(newContents)
+		config, err := newConfigFromYAML(newContents, filepath.Dir(configPath))
 		if err != nil {
-			log.Printf("Config has errors: %v", err)
+			if hadValidConfigOnStartup {
+				log.Printf("Config has errors, continuing to use the previous config: %v", err)
+			} else {
+				log.Printf("Config has errors: %v", err)
+			}
 
 			if !hadValidConfigOnStartup {
 				close(exitChannel)
@@ -114,6 +162,8 @@ func serveApp(configPath string) error {
 			return
 		}
 
+		configureLogging(&config.Server)
+
 		app, err := newApplication(config)
 		if err != nil {
 			log.Printf("Failed to create application: %v", err)
@@ -129,16 +179,34 @@ func serveApp(configPath string) error {
 			hadValidConfigOnStartup = true
 		}
 
+		// requests that are already being handled finish using the previous
+		// application while all new ones use the new one, except for the
+		// streams of live updates which get ended so that they reconnect
+		app.applyGlobalState()
+		handler.swap(app.handler())
+		if runningApp != nil {
+			runningApp.stopLiveUpdates()
+			runningApp.releaseGlobalState()
+		}
+		runningApp = app
+
 		if stopServer != nil {
+			if !app.Config.Server.requiresRestart(&runningServerConfig) {
+				log.Println("Config reloaded successfully")
+				return
+			}
+
+			log.Println("Server config changed, restarting server...")
 			if err := stopServer(); err != nil {
 				log.Printf("Error while trying to stop server: %v", err)
 			}
 		}
 
-		go func() {
-			var startServer func() error
-			startServer, stopServer = app.server()
+		runningServerConfig = app.Config.Server
+		var startServer func() error
+		startServer, stopServer = app.server(handler)
 
+		go func() {
 			if err := startServer(); err != nil {
 				log.Printf("Failed to start server: %v", err)
 			}
@@ -149,34 +217,95 @@ func serveApp(configPath string) error {
 		log.Printf("Error watching config files: %v", err)
 	}
 
-	configContents, configIncludes, err := parseYAMLIncludes(configPath)
+	configContents, configIncludes, err := parseConfigFiles(configPath, overlayPaths, restrictIncludes)
 	if err != nil {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
-	stopWatching, err := configFilesWatcher(configPath, configContents, configIncludes, onChange, onErr)
-	if err == nil {
-		defer stopWatching()
-	} else {
-		log.Printf("Error starting file watcher, config file changes will require a manual restart. (%v)", err)
+	if noWatch {
+		return serveAppWithoutWatching(configContents, filepath.Dir(configPath))
+	}
 
-		config, err := newConfigFromYAML(configContents)
-		if err != nil {
-			return fmt.Errorf("validating config file: %w", err)
+	stopWatching, err := configFilesWatcher(configPath, overlayPaths, restrictIncludes, configContents, configIncludes, onChange, onErr)
+	if err != nil {
+		log.Printf("Error starting file watcher, config file changes will require a manual restart. (%v)", err)
+		return serveAppWithoutWatching(configContents, filepath.Dir(configPath))
+	}
+	defer stopWatching()
+
+	signals, stopListeningForSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopListeningForSignals()
+
+	select {
+	case <-exitChannel:
+	case <-signals.Done():
+		// a second signal exits immediately
+		stopListeningForSignals()
+		stopServerMu.Lock()
+		defer stopServerMu.Unlock()
+		// otherwise the server waits for the streams to end on their own
+		if runningApp != nil {
+			runningApp.stopLiveUpdates()
 		}
+		shutdown(stopServer)
+	}
 
-		app, err := newApplication(config)
-		if err != nil {
-			return fmt.Errorf("creating application: %w", err)
+	return nil
+}
+
+func shutdown(stopServer func() error) {
+	log.Println("Shutting down, no longer accepting new connections")
+
+	// canceled first so that requests waiting on widgets which are stuck
+	// on an upstream don't hold up the server for the whole timeout
+	log.Println("Canceling widget updates which are still in progress")
+	cancelWidgetUpdates()
+
+	if stopServer != nil {
+		if err := stopServer(); err != nil {
+			log.Printf("Error while stopping server: %v", err)
 		}
+	}
+
+	log.Println("Shutdown complete")
+}
 
-		startServer, _ := app.server()
-		if err := startServer(); err != nil {
+func serveAppWithoutWatching(configContents []byte, configDir string) error {
+	config, err := newConfigFromYAML(configContents, configDir)
+	if err != nil {
+		return fmt.Errorf("validating config file: %w", err)
+	}
+
+	configureLogging(&config.Server)
+
+	app, err := newApplication(config)
+	if err != nil {
+		return fmt.Errorf("creating application: %w", err)
+	}
+	app.applyGlobalState()
+
+	startServer, stopServer := app.server(app.handler())
+
+	signals, stopListeningForSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopListeningForSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- startServer()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
 			return fmt.Errorf("starting server: %w", err)
 		}
+	case <-signals.Done():
+		stopListeningForSignals()
+		// otherwise the server waits for the streams to end on their own
+		app.stopLiveUpdates()
+		shutdown(stopServer)
 	}
 
-	<-exitChannel
 	return nil
 }
 
@@ -0,0 +1,126 @@
+package glance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Same as the default buckets of the Prometheus client libraries
+var widgetUpdateDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type widgetTypeMetrics struct {
+	updates         uint64
+	errors          uint64
+	cacheHits       uint64
+	durationSum     float64
+	durationBuckets []uint64
+}
+
+// Kept outside of the application so that counters don't get reset
+// whenever the config gets reloaded
+var widgetMetrics = struct {
+	mu     sync.Mutex
+	byType map[string]*widgetTypeMetrics
+}{
+	byType: make(map[string]*widgetTypeMetrics),
+}
+
+func widgetMetricsForType(widgetType string) *widgetTypeMetrics {
+	metrics, exists := widgetMetrics.byType[widgetType]
+	if !exists {
+		metrics = &widgetTypeMetrics{
+			durationBuckets: make([]uint64, len(widgetUpdateDurationBuckets)),
+		}
+		widgetMetrics.byType[widgetType] = metrics
+	}
+
+	return metrics
+}
+
+func recordWidgetCacheHit(ctx context.Context, widget widget) {
+	loggerFromContext(ctx).Debug("Widget cache hit", "widget", widget.GetType(), "widget_id", widget.GetID())
+
+	widgetMetrics.mu.Lock()
+	defer widgetMetrics.mu.Unlock()
+
+	widgetMetricsForType(widget.GetType()).cacheHits++
+}
+
+// Every update counts as a cache miss
+func recordWidgetUpdate(ctx context.Context, widget widget, duration time.Duration) {
+	seconds := duration.Seconds()
+	logger := loggerFromContext(ctx).With("widget", widget.GetType(), "widget_id", widget.GetID(), "duration", duration.Round(time.Millisecond))
+
+	if err := widget.updateError(); err != nil {
+		logger.Warn("Widget update failed", "error", err)
+	} else {
+		logger.Debug("Widget updated")
+	}
+
+	widgetMetrics.mu.Lock()
+	defer widgetMetrics.mu.Unlock()
+
+	metrics := widgetMetricsForType(widget.GetType())
+	metrics.updates++
+	metrics.durationSum += seconds
+
+	if widget.updateError() != nil {
+		metrics.errors++
+	}
+
+	for i, bucket := range widgetUpdateDurationBuckets {
+		if seconds <= bucket {
+			metrics.durationBuckets[i]++
+		}
+	}
+}
+
+// Writes the metrics in the Prometheus text exposition format
+func handleMetricsRequest(w http.ResponseWriter, _ *http.Request) {
+	widgetMetrics.mu.Lock()
+	types := make([]string, 0, len(widgetMetrics.byType))
+	for widgetType := range widgetMetrics.byType {
+		types = append(types, widgetType)
+	}
+	slices.Sort(types)
+
+	var b strings.Builder
+
+	writeHeader := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	writeHeader("glance_widget_update_duration_seconds", "histogram", "Time spent updating widgets.")
+	for _, widgetType := range types {
+		metrics := widgetMetrics.byType[widgetType]
+		for i, bucket := range widgetUpdateDurationBuckets {
+			fmt.Fprintf(&b, "glance_widget_update_duration_seconds_bucket{type=%q,le=%q} %d\n",
+				widgetType, strconv.FormatFloat(bucket, 'f', -1, 64), metrics.durationBuckets[i])
+		}
+		fmt.Fprintf(&b, "glance_widget_update_duration_seconds_bucket{type=%q,le=\"+Inf\"} %d\n", widgetType, metrics.updates)
+		fmt.Fprintf(&b, "glance_widget_update_duration_seconds_sum{type=%q} %s\n", widgetType, strconv.FormatFloat(metrics.durationSum, 'g', -1, 64))
+		fmt.Fprintf(&b, "glance_widget_update_duration_seconds_count{type=%q} %d\n", widgetType, metrics.updates)
+	}
+
+	writeHeader("glance_widget_update_errors_total", "counter", "Number of widget updates that resulted in an error.")
+	for _, widgetType := range types {
+		fmt.Fprintf(&b, "glance_widget_update_errors_total{type=%q} %d\n", widgetType, widgetMetrics.byType[widgetType].errors)
+	}
+
+	writeHeader("glance_widget_cache_requests_total", "counter", "Number of times widgets were requested, by whether the cached data could be used.")
+	for _, widgetType := range types {
+		metrics := widgetMetrics.byType[widgetType]
+		fmt.Fprintf(&b, "glance_widget_cache_requests_total{type=%q,result=\"hit\"} %d\n", widgetType, metrics.cacheHits)
+		fmt.Fprintf(&b, "glance_widget_cache_requests_total{type=%q,result=\"miss\"} %d\n", widgetType, metrics.updates)
+	}
+	widgetMetrics.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
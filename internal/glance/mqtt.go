@@ -0,0 +1,568 @@
+package glance
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal MQTT 3.1.1 client which only subscribes, messages are received
+// with a QoS of 0 and only the latest one of each topic is kept around
+
+const (
+	mqttKeepAlive          = 60 * time.Second
+	mqttMaxPacketSize      = 1 << 20
+	mqttMaxReconnectDelay  = time.Minute
+	mqttMaxTopics          = 1000
+	mqttPacketConnect      = 1
+	mqttPacketConnack      = 2
+	mqttPacketPublish      = 3
+	mqttPacketPuback       = 4
+	mqttPacketSubscribe    = 8
+	mqttPacketSuback       = 9
+	mqttPacketPingreq      = 12
+	mqttConnectCleanStart  = 0x02
+	mqttConnectHasPassword = 0x40
+	mqttConnectHasUsername = 0x80
+)
+
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad username or password",
+	5: "not authorized",
+}
+
+type mqttBrokerOptions struct {
+	address   string
+	tlsConfig *tls.Config
+	username  string
+	password  string
+	clientID  string
+}
+
+// Returns the host:port of the broker and whether it uses TLS, the scheme
+// can be either of mqtt/tcp or mqtts/ssl/tls
+func parseMQTTBrokerURL(raw string) (string, bool, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "mqtt://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", false, err
+	}
+
+	var useTLS bool
+	var defaultPort string
+
+	switch parsed.Scheme {
+	case "mqtt", "tcp":
+		defaultPort = "1883"
+	case "mqtts", "ssl", "tls":
+		useTLS, defaultPort = true, "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported scheme %s, must be one of mqtt, mqtts, tcp, ssl or tls", parsed.Scheme)
+	}
+
+	if parsed.Hostname() == "" {
+		return "", false, errors.New("missing host")
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = defaultPort
+	}
+
+	return net.JoinHostPort(parsed.Hostname(), port), useTLS, nil
+}
+
+type mqttMessage struct {
+	Payload    []byte
+	ReceivedAt time.Time
+}
+
+type mqttClient struct {
+	key     string
+	options mqttBrokerOptions
+	// guarded by the lock of mqttClients
+	refs int
+	// closed once the last reference gets released
+	stop chan struct{}
+
+	mu        sync.Mutex
+	filters   []string
+	messages  map[string]mqttMessage
+	started   bool
+	stopped   bool
+	connected bool
+	conn      net.Conn
+	connErr   error
+	lastID    uint16
+	// packets get written both by the reading loop and the pinger
+	writeMu sync.Mutex
+}
+
+var errMQTTClientStopped = errors.New("client stopped")
+
+// All widgets which connect to the same broker with the same credentials
+// share a single connection, which is kept for as long as any of them use it,
+// including the widgets of the application that replaces theirs on reload
+var mqttClients = struct {
+	sync.Mutex
+	byKey map[string]*mqttClient
+}{byKey: make(map[string]*mqttClient)}
+
+// Each call must be matched by a call to release once the client is no
+// longer used
+func acquireMQTTClient(key string, options mqttBrokerOptions) *mqttClient {
+	mqttClients.Lock()
+	defer mqttClients.Unlock()
+
+	if client, exists := mqttClients.byKey[key]; exists {
+		client.refs++
+		return client
+	}
+
+	if options.clientID == "" {
+		b := make([]byte, 6)
+		rand.Read(b)
+		options.clientID = "glance-" + hex.EncodeToString(b)
+	}
+
+	client := &mqttClient{
+		key:      key,
+		options:  options,
+		refs:     1,
+		stop:     make(chan struct{}),
+		messages: make(map[string]mqttMessage),
+	}
+	mqttClients.byKey[key] = client
+
+	return client
+}
+
+// Disconnects from the broker once the last reference is released
+func (c *mqttClient) release() {
+	mqttClients.Lock()
+	c.refs--
+	if c.refs > 0 {
+		mqttClients.Unlock()
+		return
+	}
+	delete(mqttClients.byKey, c.key)
+	mqttClients.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	close(c.stop)
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// Adds the filters that aren't already subscribed to and starts connecting
+// to the broker if the client isn't already
+func (c *mqttClient) subscribe(filters []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return
+	}
+
+	var added []string
+	for _, filter := range filters {
+		if !slices.Contains(c.filters, filter) && !slices.Contains(added, filter) {
+			added = append(added, filter)
+		}
+	}
+	c.filters = append(c.filters, added...)
+
+	if !c.started {
+		c.started = true
+		go c.run()
+		return
+	}
+
+	if c.connected && len(added) > 0 {
+		if err := c.writeSubscribe(c.conn, added); err != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// Returns the latest message of the topic, for filters with wildcards it's
+// the latest one out of all of the matching topics
+func (c *mqttClient) latest(filter string) (mqttMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !strings.ContainsAny(filter, "+#") {
+		message, exists := c.messages[filter]
+		return message, exists
+	}
+
+	var latest mqttMessage
+	var found bool
+	for topic, message := range c.messages {
+		if mqttTopicMatches(filter, topic) && (!found || message.ReceivedAt.After(latest.ReceivedAt)) {
+			latest, found = message, true
+		}
+	}
+
+	return latest, found
+}
+
+// Returns the reason why the client isn't connected, or nil if it is or
+// hasn't had a chance to connect yet
+func (c *mqttClient) status() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connected {
+		return nil
+	}
+
+	return c.connErr
+}
+
+func (c *mqttClient) run() {
+	delay := time.Second
+
+	for {
+		start := time.Now()
+		err := c.session()
+
+		c.mu.Lock()
+		c.connected = false
+		c.conn = nil
+		c.connErr = err
+		stopped := c.stopped
+		c.mu.Unlock()
+
+		if stopped {
+			return
+		}
+
+		slog.Warn("MQTT connection lost, reconnecting", "broker", c.options.address, "error", err)
+
+		// back off only when the connection can't be established or drops
+		// right away, a connection that has been up for a while is retried quickly
+		if time.Since(start) > mqttMaxReconnectDelay {
+			delay = time.Second
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, mqttMaxReconnectDelay)
+	}
+}
+
+func (c *mqttClient) session() error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if c.options.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.options.address, c.options.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", c.options.address)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	if err := c.writeConnect(conn); err != nil {
+		return err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	packetType, body, err := readMQTTPacket(reader)
+	if err != nil {
+		return fmt.Errorf("waiting for connack: %v", err)
+	}
+
+	if packetType>>4 != mqttPacketConnack || len(body) < 2 {
+		return errors.New("broker did not acknowledge the connection")
+	}
+
+	if body[1] != 0 {
+		if reason, exists := mqttConnackErrors[body[1]]; exists {
+			return fmt.Errorf("connection refused: %s", reason)
+		}
+		return fmt.Errorf("connection refused with code %d", body[1])
+	}
+
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return errMQTTClientStopped
+	}
+	c.conn = conn
+	c.connected = true
+	c.connErr = nil
+	if len(c.filters) > 0 {
+		err = c.writeSubscribe(conn, c.filters)
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(mqttKeepAlive / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if c.writePacket(conn, mqttPacketPingreq<<4, nil) != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(mqttKeepAlive * 3 / 2))
+
+		packetType, body, err := readMQTTPacket(reader)
+		if err != nil {
+			return err
+		}
+
+		switch packetType >> 4 {
+		case mqttPacketPublish:
+			if err := c.handlePublish(conn, packetType&0x0f, body); err != nil {
+				return err
+			}
+		case mqttPacketSuback:
+			for _, code := range body[min(2, len(body)):] {
+				if code == 0x80 {
+					slog.Warn("MQTT broker rejected a subscription", "broker", c.options.address)
+				}
+			}
+		}
+	}
+}
+
+func (c *mqttClient) handlePublish(conn net.Conn, flags byte, body []byte) error {
+	topic, rest, ok := readMQTTString(body)
+	if !ok {
+		return errors.New("malformed publish packet")
+	}
+
+	qos := (flags >> 1) & 0x03
+	if qos > 0 {
+		if len(rest) < 2 {
+			return errors.New("malformed publish packet")
+		}
+
+		packetID := rest[:2]
+		rest = rest[2:]
+
+		// the subscriptions are made with a QoS of 0 so brokers shouldn't
+		// send anything higher, acknowledged anyway for the ones that do
+		if qos == 1 {
+			if err := c.writePacket(conn, mqttPacketPuback<<4, packetID); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.mu.Lock()
+	if _, exists := c.messages[topic]; !exists && len(c.messages) >= mqttMaxTopics {
+		c.evictOldestMessage()
+	}
+	c.messages[topic] = mqttMessage{Payload: rest, ReceivedAt: time.Now()}
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Keeps filters with wildcards such as # from making the messages grow without
+// bound, must be called with mu held
+func (c *mqttClient) evictOldestMessage() {
+	var oldestTopic string
+	var oldest time.Time
+
+	for topic, message := range c.messages {
+		if oldestTopic == "" || message.ReceivedAt.Before(oldest) {
+			oldestTopic, oldest = topic, message.ReceivedAt
+		}
+	}
+
+	delete(c.messages, oldestTopic)
+}
+
+func (c *mqttClient) writeConnect(conn net.Conn) error {
+	var flags byte = mqttConnectCleanStart
+	if c.options.username != "" {
+		flags |= mqttConnectHasUsername
+	}
+	if c.options.password != "" {
+		flags |= mqttConnectHasPassword
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(mqttKeepAlive/time.Second))
+	body = appendMQTTString(body, c.options.clientID)
+
+	if c.options.username != "" {
+		body = appendMQTTString(body, c.options.username)
+	}
+	if c.options.password != "" {
+		body = appendMQTTString(body, c.options.password)
+	}
+
+	return c.writePacket(conn, mqttPacketConnect<<4, body)
+}
+
+// Must be called with mu held or before the connection is shared
+func (c *mqttClient) writeSubscribe(conn net.Conn, filters []string) error {
+	c.lastID++
+	if c.lastID == 0 {
+		c.lastID = 1
+	}
+
+	body := binary.BigEndian.AppendUint16(nil, c.lastID)
+	for _, filter := range filters {
+		body = appendMQTTString(body, filter)
+		body = append(body, 0)
+	}
+
+	return c.writePacket(conn, mqttPacketSubscribe<<4|0x02, body)
+}
+
+func (c *mqttClient) writePacket(conn net.Conn, header byte, body []byte) error {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if length == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := conn.Write(packet)
+	return err
+}
+
+// Returns the first byte of the fixed header which includes the type of the
+// packet in its upper 4 bits, along with the rest of the packet
+func readMQTTPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var length, multiplier int = 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed packet length")
+		}
+
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if length > mqttMaxPacketSize {
+		return 0, nil, fmt.Errorf("packet of %d bytes is too large", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header, body, nil
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readMQTTString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+
+	length := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+length {
+		return "", nil, false
+	}
+
+	return string(b[2 : 2+length]), b[2+length:], true
+}
+
+// + matches exactly one level and # matches any number of levels at the end
+func mqttTopicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+
+		if i >= len(topicLevels) {
+			return false
+		}
+
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
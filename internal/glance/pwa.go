@@ -0,0 +1,154 @@
+package glance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Not a template, the values it needs get prepended to it as a variable
+var serviceWorkerScript = func() []byte {
+	script, err := fs.ReadFile(templateFS, "service-worker.js")
+	if err != nil {
+		panic(err)
+	}
+
+	return script
+}()
+
+func (a *application) initAppIcons() error {
+	branding := &a.Config.Branding
+
+	if len(branding.AppIcons) == 0 {
+		branding.AppIcons = []appIcon{{URL: branding.AppIconURL, Sizes: "512x512"}}
+	}
+
+	for i := range branding.AppIcons {
+		icon := &branding.AppIcons[i]
+
+		if icon.URL == "" {
+			return fmt.Errorf("app icon #%d is missing a url", i+1)
+		}
+
+		icon.URL = a.resolveUserDefinedAssetPath(icon.URL)
+
+		if icon.Type == "" {
+			icon.Type = appIconTypeFromURL(icon.URL)
+		}
+
+		if icon.Sizes == "" {
+			icon.Sizes = ternary(icon.Type == "image/svg+xml", "any", "512x512")
+		}
+
+		// iOS doesn't support SVG icons and doesn't mask them on its own,
+		// so only the ones which are usable as they are get linked
+		if icon.Type != "image/svg+xml" && icon.Purpose != "maskable" {
+			branding.AppleTouchIcons = append(branding.AppleTouchIcons, *icon)
+		}
+	}
+
+	return nil
+}
+
+func appIconTypeFromURL(url string) string {
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+
+	switch strings.ToLower(path.Ext(url)) {
+	case ".svg":
+		return "image/svg+xml"
+	case ".webp":
+		return "image/webp"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".ico":
+		return "image/x-icon"
+	}
+
+	return "image/png"
+}
+
+type webAppManifest struct {
+	Name            string               `json:"name"`
+	ShortName       string               `json:"short_name"`
+	Display         string               `json:"display"`
+	BackgroundColor string               `json:"background_color"`
+	ThemeColor      string               `json:"theme_color"`
+	Scope           string               `json:"scope"`
+	StartURL        string               `json:"start_url"`
+	Icons           []webAppManifestIcon `json:"icons"`
+}
+
+type webAppManifestIcon struct {
+	Src     string `json:"src"`
+	Type    string `json:"type"`
+	Sizes   string `json:"sizes"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+func (a *application) buildManifest() ([]byte, error) {
+	branding := &a.Config.Branding
+
+	manifest := webAppManifest{
+		Name:            branding.AppName,
+		ShortName:       branding.AppShortName,
+		Display:         "standalone",
+		BackgroundColor: branding.AppBackgroundColor,
+		ThemeColor:      branding.AppThemeColor,
+		Scope:           a.rootBaseURL() + "/",
+		StartURL:        a.rootBaseURL() + "/",
+		Icons:           make([]webAppManifestIcon, len(branding.AppIcons)),
+	}
+
+	for i, icon := range branding.AppIcons {
+		manifest.Icons[i] = webAppManifestIcon{
+			Src:     icon.URL,
+			Type:    icon.Type,
+			Sizes:   icon.Sizes,
+			Purpose: icon.Purpose,
+		}
+	}
+
+	return json.MarshalIndent(manifest, "", "    ")
+}
+
+// Only the app shell gets cached, which is the pages themselves along with the
+// static assets, the content of the widgets is loaded separately through the
+// API and always goes to the server so that it's never shown while stale
+func (a *application) buildServiceWorker() ([]byte, error) {
+	precache := []string{a.StaticAssetPath("css/bundle.css")}
+
+	for _, pattern := range []string{"js/*.js", "fonts/*.woff2", "*.png", "*.svg"} {
+		files, err := fs.Glob(staticFS, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			precache = append(precache, a.StaticAssetPath(file))
+		}
+	}
+
+	shell, err := json.Marshal(map[string]any{
+		// a new config can change the pages, which gets the
+		// browser to install the worker again
+		"version":      staticFSHash + "-" + strconv.FormatInt(a.CreatedAt.Unix(), 10),
+		"staticPrefix": a.rootBaseURL() + "/static/" + staticFSHash + "/",
+		"precache":     precache,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	script := make([]byte, 0, len(shell)+len(serviceWorkerScript)+32)
+	script = append(script, "const glanceShell = "...)
+	script = append(script, shell...)
+	script = append(script, ";\n\n"...)
+	script = append(script, serviceWorkerScript...)
+
+	return script, nil
+}
@@ -0,0 +1,218 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var staticRenderTemplate = mustParseTemplate("render.html")
+
+// Widgets that haven't finished updating by then render their error state
+const renderTimeout = time.Minute
+
+var renderFontPattern = regexp.MustCompile(`url\('\.\./fonts/([^']+\.woff2)'\)`)
+
+// The elements that the browser would fill in with the time relative to now,
+// in a snapshot they're relative to when it was rendered instead
+var renderRelativeTimePattern = regexp.MustCompile(`(data-dynamic-relative-time="(\d+)"[^>]*>)(</)`)
+
+type renderTemplateData struct {
+	App        *application
+	Theme      *themeProperties
+	CSS        template.CSS
+	Pages      []renderedPage
+	RenderedAt time.Time
+}
+
+type renderedPage struct {
+	Page    *page
+	Content template.HTML
+}
+
+// Updates all widgets once and writes the pages one after another as a single
+// HTML document which doesn't depend on the server or any scripts, all logs
+// go to stderr so that stdout only has the document
+func cliRender(configPath string, overlayPaths []string, restrictIncludes bool) int {
+	contents, _, err := parseConfigFiles(configPath, overlayPaths, restrictIncludes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not parse config file: %v\n", err)
+		return 1
+	}
+
+	config, err := newConfigFromYAML(contents, filepath.Dir(configPath))
+	if err != nil {
+		var errs configErrors
+		if errors.As(err, &errs) {
+			fmt.Fprintf(os.Stderr, "Config file is invalid, found %d errors:\n", len(errs))
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "  %v\n", err)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Config file is invalid: %v\n", err)
+		}
+		return 1
+	}
+
+	configureLogging(&config.Server)
+
+	// spreading out the updates is only useful for a long running server
+	config.Server.InitialUpdateJitter = 0
+
+	app, err := newApplication(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not initialize application: %v\n", err)
+		return 1
+	}
+
+	app.applyGlobalState()
+
+	document, err := app.renderStatic()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not render pages: %v\n", err)
+		return 1
+	}
+
+	os.Stdout.Write(document)
+	return 0
+}
+
+func (a *application) renderStatic() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(widgetUpdatesCtx, renderTimeout)
+	defer cancel()
+
+	now := a.now()
+	pages := make([]renderedPage, len(a.Config.Pages))
+	errs := make([]error, len(a.Config.Pages))
+	var wg sync.WaitGroup
+
+	for p := range a.Config.Pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			page := &a.Config.Pages[p]
+			page.mu.Lock()
+			defer page.mu.Unlock()
+
+			page.updateOutdatedWidgets(ctx, now)
+
+			var content bytes.Buffer
+			errs[p] = pageContentTemplate.Execute(&content, templateData{Page: page})
+			pages[p] = renderedPage{Page: page, Content: template.HTML(content.String())}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	css, err := renderInlinedCSS()
+	if err != nil {
+		return nil, err
+	}
+
+	var document bytes.Buffer
+	err = staticRenderTemplate.Execute(&document, renderTemplateData{
+		App:        a,
+		Theme:      &a.Config.Theme.themeProperties,
+		CSS:        css,
+		Pages:      pages,
+		RenderedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return renderRelativeTimePattern.ReplaceAllFunc(document.Bytes(), func(match []byte) []byte {
+		groups := renderRelativeTimePattern.FindSubmatch(match)
+		timestamp, _ := strconv.ParseInt(string(groups[2]), 10, 64)
+
+		var text string
+		if bytes.Contains(groups[1], []byte(`data-relative-time-format="uptime"`)) {
+			text = "live " + formatUptime(now.Sub(time.Unix(timestamp, 0)))
+		} else {
+			text = formatRelativeTime(now.Sub(time.Unix(timestamp, 0)))
+		}
+
+		return append(append(append([]byte(nil), groups[1]...), text...), groups[3]...)
+	}), nil
+}
+
+// The bundled CSS with the fonts it references embedded as data URLs
+func renderInlinedCSS() (template.CSS, error) {
+	var fontErr error
+
+	css := renderFontPattern.ReplaceAllFunc(bundledCSSContents, func(match []byte) []byte {
+		name := renderFontPattern.FindSubmatch(match)[1]
+
+		font, err := readAllFromStaticFS("fonts/" + string(name))
+		if err != nil {
+			fontErr = err
+			return match
+		}
+
+		return []byte("url('data:font/woff2;base64," + base64.StdEncoding.EncodeToString(font) + "')")
+	})
+
+	if fontErr != nil {
+		return "", fmt.Errorf("inlining fonts: %v", fontErr)
+	}
+
+	return template.CSS(css), nil
+}
+
+const (
+	renderDay   = 24 * time.Hour
+	renderMonth = renderDay * 304 / 10
+	renderYear  = 365 * renderDay
+)
+
+// Same formats as the ones used on the client
+func formatRelativeTime(d time.Duration) string {
+	var prefix string
+	if d < 0 {
+		d, prefix = -d, "in "
+	}
+
+	const day, month, year = renderDay, renderMonth, renderYear
+
+	switch {
+	case d < time.Minute:
+		return prefix + "1m"
+	case d < time.Hour:
+		return prefix + strconv.Itoa(int(d/time.Minute)) + "m"
+	case d < day:
+		return prefix + strconv.Itoa(int(d/time.Hour)) + "h"
+	case d < month:
+		return prefix + strconv.Itoa(int(d/day)) + "d"
+	case d < year:
+		return prefix + strconv.Itoa(int(d/month)) + "mo"
+	}
+
+	return prefix + strconv.Itoa(int(d/year)) + "y"
+}
+
+func formatUptime(d time.Duration) string {
+	d = max(0, d)
+
+	switch {
+	case d < time.Hour:
+		return strconv.Itoa(max(1, int(d/time.Minute))) + "m"
+	case d < renderDay:
+		return fmt.Sprintf("%dh %dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	}
+
+	return fmt.Sprintf("%dd %dh", int(d/renderDay), int(d%renderDay/time.Hour))
+}
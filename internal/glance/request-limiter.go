@@ -0,0 +1,365 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits how many requests widgets can have in flight at once, both in total
+// and for each host, shared by all of the HTTP clients that widgets use
+var outboundRequestLimiter = &requestLimiter{}
+
+// How long to wait at most before the first update of a widget, spreading
+// out the initial requests of the widgets on a page
+var initialUpdateJitter atomic.Int64
+
+// Canceled when shutting down, which aborts the requests that widgets are
+// still making regardless of which context the requests were made with
+var widgetUpdatesCtx, cancelWidgetUpdates = context.WithCancel(context.Background())
+
+type requestLimiter struct {
+	mu      sync.Mutex
+	total   chan struct{}
+	perHost int
+	hosts   map[string]chan struct{}
+}
+
+// A limit of 0 means no limit, requests which are already in flight still
+// count towards the limits that were in place when they were made
+func (l *requestLimiter) configure(total, perHost int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total = nil
+	if total > 0 {
+		l.total = make(chan struct{}, total)
+	}
+
+	l.perHost = perHost
+	l.hosts = nil
+	if perHost > 0 {
+		l.hosts = make(map[string]chan struct{})
+	}
+}
+
+func (l *requestLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	total := l.total
+	var forHost chan struct{}
+	if l.hosts != nil {
+		forHost = l.hosts[host]
+		if forHost == nil {
+			forHost = make(chan struct{}, l.perHost)
+			l.hosts[host] = forHost
+		}
+	}
+	l.mu.Unlock()
+
+	// the host slot gets taken first so that requests waiting on a
+	// busy host don't hold up requests to other hosts
+	if forHost != nil {
+		select {
+		case forHost <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if total != nil {
+		select {
+		case total <- struct{}{}:
+		case <-ctx.Done():
+			if forHost != nil {
+				<-forHost
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		if total != nil {
+			<-total
+		}
+		if forHost != nil {
+			<-forHost
+		}
+	}, nil
+}
+
+// Limits how often requests can be made to each host, shared by all of the
+// HTTP clients that widgets use so that widgets which use the same provider
+// don't exceed its limits collectively
+var outboundRateLimiter = &rateLimiter{}
+
+type rateLimitOptions struct {
+	// Applies to every host which doesn't have its own rate, unlimited if not set
+	Rate    *requestRateField                `yaml:"rate"`
+	Burst   int                              `yaml:"burst"`
+	MaxWait durationField                    `yaml:"max-wait"`
+	Hosts   map[string]*hostRateLimitOptions `yaml:"hosts"`
+}
+
+type hostRateLimitOptions struct {
+	Rate  requestRateField `yaml:"rate"`
+	Burst int              `yaml:"burst"`
+}
+
+func (o *hostRateLimitOptions) UnmarshalYAML(node *yaml.Node) error {
+	type hostRateLimitOptionsAlias hostRateLimitOptions
+
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&o.Rate)
+	}
+
+	if err := node.Decode((*hostRateLimitOptionsAlias)(o)); err != nil {
+		return err
+	}
+
+	if o.Rate.Requests == 0 {
+		return fmt.Errorf("line %d: rate is required", node.Line)
+	}
+
+	return nil
+}
+
+const (
+	defaultRateLimitBurst   = 5
+	defaultRateLimitMaxWait = 10 * time.Second
+)
+
+func (o *rateLimitOptions) initialize() error {
+	if o.Burst < 0 {
+		return errors.New("burst must be a positive number")
+	} else if o.Burst == 0 {
+		o.Burst = defaultRateLimitBurst
+	}
+
+	if o.MaxWait == 0 {
+		o.MaxWait = durationField(defaultRateLimitMaxWait)
+	}
+
+	hosts := make(map[string]*hostRateLimitOptions, len(o.Hosts))
+	for host, options := range o.Hosts {
+		if options.Burst < 0 {
+			return fmt.Errorf("burst of host %s must be a positive number", host)
+		} else if options.Burst == 0 {
+			options.Burst = o.Burst
+		}
+
+		hosts[strings.ToLower(host)] = options
+	}
+	o.Hosts = hosts
+
+	return nil
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	options *rateLimitOptions
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	rate      requestRateField
+	perSecond float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+	// throttling is logged at most once a minute for each host
+	lastLogged time.Time
+}
+
+// Passing nil removes the limits, the state of the buckets is reset
+// regardless since the limits they were created with may have changed
+func (l *rateLimiter) configure(options *rateLimitOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.options = options
+	l.buckets = make(map[string]*tokenBucket)
+}
+
+// Must be called with the lock held, returns nil if the host has no limit
+func (l *rateLimiter) bucketFor(host string, now time.Time) *tokenBucket {
+	if bucket, exists := l.buckets[host]; exists {
+		return bucket
+	}
+
+	var rate requestRateField
+	var burst int
+
+	if options, exists := l.options.Hosts[host]; exists {
+		rate, burst = options.Rate, options.Burst
+	} else if l.options.Rate != nil {
+		rate, burst = *l.options.Rate, l.options.Burst
+	} else {
+		l.buckets[host] = nil
+		return nil
+	}
+
+	bucket := &tokenBucket{
+		rate:      rate,
+		perSecond: rate.perSecond(),
+		burst:     float64(burst),
+		tokens:    float64(burst),
+		last:      now,
+	}
+	l.buckets[host] = bucket
+
+	return bucket
+}
+
+// Takes a token right away, even when there are none left yet, and returns
+// how long to wait before it can be used
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.perSecond * float64(time.Second))
+}
+
+// Blocks until a request can be made to the host, or fails right away if that
+// would take longer than the max wait or the time left before the request
+// times out
+func (l *rateLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+
+	if l.options == nil {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	bucket := l.bucketFor(host, now)
+	if bucket == nil {
+		l.mu.Unlock()
+		return nil
+	}
+
+	delay := bucket.reserve(now)
+	if delay == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	maxWait := time.Duration(l.options.MaxWait)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = min(maxWait, deadline.Sub(now))
+	}
+
+	shouldLog := now.Sub(bucket.lastLogged) >= time.Minute
+	if shouldLog {
+		bucket.lastLogged = now
+	}
+
+	if delay > maxWait {
+		bucket.tokens++
+		l.mu.Unlock()
+
+		if shouldLog {
+			slog.Warn("Rate limit for host exceeded, requests are failing", "host", host, "rate", bucket.rate.String(), "wait", delay.Round(time.Millisecond))
+		}
+
+		return fmt.Errorf("rate limit of %s for %s exceeded, the request would have had to wait for %s", bucket.rate, host, delay.Round(time.Millisecond))
+	}
+
+	l.mu.Unlock()
+
+	if shouldLog {
+		slog.Info("Throttling requests to host", "host", host, "rate", bucket.rate.String(), "wait", delay.Round(time.Millisecond))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		bucket.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+type limitedTransport struct {
+	base http.RoundTripper
+}
+
+func newLimitedTransport(base http.RoundTripper) *limitedTransport {
+	return &limitedTransport{base: base}
+}
+
+// The slot is released as soon as the response headers arrive rather than
+// once the body is closed, so that a body which never gets closed can't
+// permanently take up a slot
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(request.Context())
+	stopCancelingOnShutdown := context.AfterFunc(widgetUpdatesCtx, cancel)
+	done := func() {
+		stopCancelingOnShutdown()
+		cancel()
+	}
+
+	// waiting on the rate limit happens before taking up a slot so that
+	// throttled requests don't hold up requests to other hosts
+	if err := outboundRateLimiter.wait(ctx, strings.ToLower(request.URL.Hostname())); err != nil {
+		done()
+		return nil, err
+	}
+
+	release, err := outboundRequestLimiter.acquire(ctx, request.URL.Host)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	defer release()
+
+	response, err := t.base.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	response.Body = &cancelOnCloseBody{ReadCloser: response.Body, cancel: done}
+	return response, nil
+}
+
+// The context of the request has to outlive the round trip since it also
+// covers reading the body, so it only gets released once the body is closed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func waitForInitialUpdateJitter() {
+	jitter := time.Duration(initialUpdateJitter.Load())
+	if jitter <= 0 {
+		return
+	}
+
+	time.Sleep(rand.N(jitter))
+}
@@ -0,0 +1,232 @@
+package glance
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Plain HTTP is used unless either a certificate or ACME is set up, so setups
+// with a reverse proxy in front of Glance aren't affected
+type serverTLSOptions struct {
+	CertFile string `yaml:"cert-file"`
+	KeyFile  string `yaml:"key-file"`
+	ACME     *struct {
+		Hosts        []string `yaml:"hosts"`
+		Email        string   `yaml:"email"`
+		CacheDir     string   `yaml:"cache-dir"`
+		DirectoryURL string   `yaml:"directory-url"`
+	} `yaml:"acme"`
+	// The address of a second listener which redirects to HTTPS, such as :80
+	RedirectHTTP string `yaml:"redirect-http"`
+}
+
+func (o *serverTLSOptions) enabled() bool {
+	return o.CertFile != "" || o.ACME != nil
+}
+
+// The certificate gets loaded here so that a missing or invalid one gets
+// reported along with the other config errors rather than once the server
+// is already starting
+func (o *serverTLSOptions) validate(dataPath string) error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("server tls cert-file and key-file must be set together")
+	}
+
+	if o.CertFile != "" && o.ACME != nil {
+		return errors.New("server tls can use either a cert-file and key-file or acme, not both")
+	}
+
+	if o.RedirectHTTP != "" && !o.enabled() {
+		return errors.New("server tls redirect-http requires either a cert-file and key-file or acme to be set")
+	}
+
+	if o.RedirectHTTP != "" {
+		if _, _, err := net.SplitHostPort(o.RedirectHTTP); err != nil {
+			return fmt.Errorf("server tls redirect-http must be an address such as :80: %v", err)
+		}
+	}
+
+	if o.CertFile != "" {
+		certificate, err := loadTLSCertificate(o.CertFile, o.KeyFile)
+		if err != nil {
+			return err
+		}
+
+		if leaf := certificate.Leaf; leaf != nil && time.Now().After(leaf.NotAfter) {
+			slog.Warn("Server TLS certificate has expired", "cert_file", o.CertFile, "expired_at", leaf.NotAfter)
+		}
+	}
+
+	if o.ACME != nil {
+		if len(o.ACME.Hosts) == 0 {
+			return errors.New("server tls acme hosts must contain at least one host, certificates are only requested for the listed hosts")
+		}
+
+		for i, host := range o.ACME.Hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host == "" || strings.ContainsAny(host, ":/") {
+				return fmt.Errorf("server tls acme host %q must be a domain without a port or scheme", o.ACME.Hosts[i])
+			}
+			o.ACME.Hosts[i] = host
+		}
+
+		if o.ACME.CacheDir == "" {
+			if dataPath == "" {
+				return errors.New("server tls acme cache-dir must be set when the server data-path isn't, otherwise certificates would be requested anew on every restart")
+			}
+
+			o.ACME.CacheDir = filepath.Join(dataPath, "acme")
+		}
+	}
+
+	return nil
+}
+
+func loadTLSCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server tls certificate from %s and key from %s: %v", certFile, keyFile, err)
+	}
+
+	if certificate.Leaf == nil && len(certificate.Certificate) > 0 {
+		certificate.Leaf, _ = x509.ParseCertificate(certificate.Certificate[0])
+	}
+
+	return &certificate, nil
+}
+
+// Certificates which get renewed by another tool, such as certbot, get picked
+// up without a restart. The files are checked for changes at most once a minute
+type reloadingCertificate struct {
+	certFile, keyFile string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTimes    [2]time.Time
+	lastChecked time.Time
+}
+
+func newReloadingCertificate(certFile, keyFile string) (*reloadingCertificate, error) {
+	c := &reloadingCertificate{certFile: certFile, keyFile: keyFile}
+
+	modTimes, err := c.currentModTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.certificate, err = loadTLSCertificate(certFile, keyFile); err != nil {
+		return nil, err
+	}
+
+	c.modTimes, c.lastChecked = modTimes, time.Now()
+
+	return c, nil
+}
+
+func (c *reloadingCertificate) currentModTimes() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+
+	for i, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("loading server tls certificate: %v", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+func (c *reloadingCertificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastChecked) < time.Minute {
+		return c.certificate, nil
+	}
+	c.lastChecked = time.Now()
+
+	modTimes, err := c.currentModTimes()
+	if err != nil || modTimes == c.modTimes {
+		return c.certificate, nil
+	}
+
+	// the previous certificate keeps being used if the new one is invalid,
+	// which can happen when this runs halfway through the files being replaced
+	certificate, err := loadTLSCertificate(c.certFile, c.keyFile)
+	if err != nil {
+		slog.Error("Failed to reload server TLS certificate, continuing to use the previous one", "error", err)
+		return c.certificate, nil
+	}
+
+	slog.Info("Reloaded server TLS certificate", "cert_file", c.certFile)
+	c.certificate, c.modTimes = certificate, modTimes
+
+	return c.certificate, nil
+}
+
+// Sets up the TLS config of the server. The returned handler is the one
+// to use for the plain HTTP listener, which also answers ACME challenges
+func (o *serverTLSOptions) configure(server *http.Server, httpsPort uint16) (http.Handler, error) {
+	redirect := redirectToHTTPS(httpsPort)
+
+	if o.ACME != nil {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(o.ACME.CacheDir),
+			HostPolicy: autocert.HostWhitelist(o.ACME.Hosts...),
+			Email:      o.ACME.Email,
+		}
+
+		if o.ACME.DirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: o.ACME.DirectoryURL}
+		}
+
+		server.TLSConfig = manager.TLSConfig()
+		return manager.HTTPHandler(redirect), nil
+	}
+
+	certificate, err := newReloadingCertificate(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificate.get,
+	}
+
+	return redirect, nil
+}
+
+func redirectToHTTPS(httpsPort uint16) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(int(httpsPort)))
+		} else if strings.Contains(host, ":") {
+			// IPv6 addresses without a port still need their brackets
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,271 @@
+import { elem } from "./templating.js";
+import { openURLInNewTab } from "./utils.js";
+
+const maxResults = 50;
+const kindLabels = {
+    page: "Page",
+    dashboard: "Dashboard",
+    widget: "Widget",
+    bookmark: "Bookmark",
+};
+
+let palette = null;
+
+function isWordStart(text, index) {
+    if (index == 0) return true;
+
+    const previous = text[index - 1];
+    if (/[\s\-_/.:]/.test(previous)) return true;
+
+    // the H in GitHub
+    return previous == previous.toLowerCase() && text[index] != text[index].toLowerCase();
+}
+
+// Every character of the query has to appear in the text in the same order.
+// Characters at the start of words and runs of consecutive characters score
+// higher, so that "gh" ranks "GitHub" above "Lighthouse"
+function fuzzyScore(query, text) {
+    const lowerText = text.toLowerCase();
+    let score = lowerText.includes(query) ? 4 : 0;
+    let streak = 0;
+    let from = 0;
+
+    for (const char of query) {
+        const index = lowerText.indexOf(char, from);
+        if (index == -1) return -1;
+
+        streak = index == from && from > 0 ? streak + 1 : 0;
+        score += 1 + streak * 2;
+
+        if (isWordStart(text, index)) score += 8;
+
+        from = index + 1;
+    }
+
+    // shorter texts win when everything else is equal
+    return score - text.length * 0.01;
+}
+
+function matchItems(items, query) {
+    query = query.toLowerCase().replace(/\s+/g, "");
+    if (query.length == 0) return items.slice(0, maxResults);
+
+    const matches = [];
+
+    for (const item of items) {
+        const titleScore = fuzzyScore(query, item.title);
+        const contextScore = item.context ? fuzzyScore(query, item.context) : -1;
+        const score = titleScore >= 0 ? titleScore : contextScore >= 0 ? contextScore / 2 : -1;
+
+        if (score >= 0) matches.push({ item, score });
+    }
+
+    // the sort is stable, so ties keep the order of the index
+    matches.sort((a, b) => b.score - a.score);
+
+    return matches.slice(0, maxResults).map(match => match.item);
+}
+
+function createPalette(revealWidget) {
+    const input = elem("input")
+        .classes("command-palette-input")
+        .attrs({
+            type: "text",
+            placeholder: "Jump to a page, widget or bookmark...",
+            autocomplete: "off",
+            spellcheck: "false",
+            role: "combobox",
+            "aria-expanded": "true",
+            "aria-controls": "command-palette-results",
+            "aria-label": "Search pages, widgets and bookmarks",
+        });
+
+    const results = elem("ul")
+        .classes("command-palette-results")
+        .attrs({ id: "command-palette-results", role: "listbox" });
+
+    const status = elem().classes("command-palette-status");
+
+    const dialog = elem()
+        .classes("command-palette")
+        .attrs({ role: "dialog", "aria-modal": "true", "aria-label": "Command palette" });
+    dialog.append(input, status, results);
+
+    const backdrop = elem().classes("command-palette-backdrop");
+    backdrop.append(dialog);
+
+    let items = [];
+    let shown = [];
+    let active = 0;
+    let previouslyFocused = null;
+    let loadController = null;
+
+    const setActive = (index) => {
+        if (results.children[active] !== undefined) {
+            results.children[active].classList.remove("command-palette-result-active");
+            results.children[active].setAttribute("aria-selected", "false");
+        }
+
+        active = index;
+        const element = results.children[active];
+
+        if (element === undefined) {
+            input.removeAttribute("aria-activedescendant");
+            return;
+        }
+
+        element.classList.add("command-palette-result-active");
+        element.setAttribute("aria-selected", "true");
+        element.scrollIntoView({ block: "nearest" });
+        input.setAttribute("aria-activedescendant", element.id);
+    };
+
+    const render = () => {
+        shown = matchItems(items, input.value);
+        results.innerHTML = "";
+
+        for (let i = 0; i < shown.length; i++) {
+            const item = shown[i];
+            const result = elem("li")
+                .classes("command-palette-result")
+                .attrs({ id: "command-palette-result-" + i, role: "option", "aria-selected": "false" });
+
+            result.append(
+                elem("span").classes("command-palette-result-kind").text(kindLabels[item.kind] ?? item.kind),
+                elem("span").classes("command-palette-result-title", "text-truncate").text(item.title),
+            );
+
+            if (item.context) {
+                result.append(elem("span").classes("command-palette-result-context", "text-truncate").text(item.context));
+            }
+
+            result.addEventListener("mousemove", () => { if (active != i) setActive(i); });
+            result.addEventListener("click", (event) => activate(item, event.ctrlKey || event.metaKey));
+            result.addEventListener("auxclick", (event) => {
+                if (event.button == 1) activate(item, true);
+            });
+
+            results.append(result);
+        }
+
+        if (items.length > 0) {
+            status.text(shown.length == 0 ? "No matches" : "").showIf(shown.length == 0);
+        }
+
+        active = 0;
+        setActive(0);
+    };
+
+    const load = async () => {
+        loadController?.abort();
+        const controller = loadController = new AbortController();
+
+        if (items.length == 0) status.text("Loading...").show();
+
+        try {
+            const response = await fetch(`${pageData.baseURL}/api/command-palette`, { signal: controller.signal });
+            if (!response.ok) throw new Error(`unexpected status ${response.status}`);
+
+            const data = await response.json();
+            if (controller.signal.aborted) return;
+
+            items = Array.isArray(data.items) ? data.items : [];
+            status.hide();
+            render();
+        } catch (e) {
+            if (controller.signal.aborted) return;
+            if (items.length == 0) status.text("Could not load the list of pages, widgets and bookmarks").show();
+        }
+    };
+
+    const isOpen = () => backdrop.isConnected;
+
+    const close = () => {
+        if (!isOpen()) return;
+
+        loadController?.abort();
+        backdrop.remove();
+        document.documentElement.classList.remove("command-palette-open");
+
+        if (previouslyFocused !== null && previouslyFocused.isConnected) previouslyFocused.focus();
+        previouslyFocused = null;
+    };
+
+    const open = () => {
+        if (isOpen()) return;
+
+        previouslyFocused = document.activeElement;
+        document.body.append(backdrop);
+        document.documentElement.classList.add("command-palette-open");
+
+        input.value = "";
+        render();
+        input.focus();
+        load();
+    };
+
+    const activate = (item, newTab) => {
+        if (item === undefined) return;
+
+        // widgets on the current page get scrolled to instead of reloading it
+        if (item.kind == "widget" && !newTab && document.querySelector(`[data-widget-id="${item.widget_id}"]`) !== null) {
+            close();
+            history.replaceState(null, "", "#widget-" + item.widget_id);
+            revealWidget(item.widget_id);
+            return;
+        }
+
+        close();
+
+        if (newTab || item.target == "_blank") {
+            openURLInNewTab(item.url);
+        } else if (item.target) {
+            window.open(item.url, item.target);
+        } else {
+            location.href = item.url;
+        }
+    };
+
+    input.addEventListener("input", render);
+    input.addEventListener("keydown", (event) => {
+        event.stopPropagation();
+
+        if (event.key == "Escape") {
+            event.preventDefault();
+            close();
+        } else if (event.key == "ArrowDown" || event.key == "ArrowUp") {
+            event.preventDefault();
+            if (shown.length == 0) return;
+
+            const step = event.key == "ArrowDown" ? 1 : -1;
+            setActive((active + step + shown.length) % shown.length);
+        } else if (event.key == "Enter") {
+            event.preventDefault();
+            activate(shown[active], event.ctrlKey || event.metaKey);
+        } else if (event.key.toLowerCase() == "k" && (event.ctrlKey || event.metaKey)) {
+            event.preventDefault();
+            close();
+        }
+    });
+
+    backdrop.addEventListener("mousedown", (event) => {
+        if (event.target === backdrop) close();
+    });
+
+    input.addEventListener("blur", (event) => {
+        // clicking on a result moves the focus away from the input for a moment
+        if (event.relatedTarget === null || !dialog.contains(event.relatedTarget)) {
+            requestAnimationFrame(() => { if (isOpen() && !dialog.contains(document.activeElement)) close(); });
+        }
+    });
+
+    results.addEventListener("mousedown", (event) => event.preventDefault());
+
+    return { open, close, isOpen };
+}
+
+export function toggleCommandPalette(revealWidget) {
+    if (palette === null) palette = createPalette(revealWidget);
+
+    palette.isOpen() ? palette.close() : palette.open();
+}
@@ -1,8 +1,8 @@
 
 import { clamp } from "./utils.js";
 
-export function setupMasonries() {
-    const masonryContainers = document.getElementsByClassName("masonry");
+export function setupMasonries(root = document) {
+    const masonryContainers = root.getElementsByClassName("masonry");
 
     for (let i = 0; i < masonryContainers.length; i++) {
         const container = masonryContainers[i];
@@ -13,35 +13,79 @@ export function setupMasonries() {
         };
 
         const items = Array.from(container.children);
+        const singleColumnItemsCount = items.filter(item => (Number(item.dataset.span) || 1) === 1).length;
         let previousColumnsCount = 0;
 
         const render = function() {
             const columnsCount = clamp(
                 Math.floor(container.offsetWidth / options.minColumnWidth),
                 1,
-                Math.min(options.maxColumns, items.length)
+                Math.min(options.maxColumns, Math.max(1, singleColumnItemsCount, ...items.map(item => Number(item.dataset.span) || 1)))
             );
 
             if (columnsCount === previousColumnsCount) {
                 return;
-            } else {
-                container.textContent = "";
-                previousColumnsCount = columnsCount;
             }
 
-            const columnsFragment = document.createDocumentFragment();
+            // measured before being moved around, at the width of whatever the
+            // previous layout was, so it's only an estimate of their final height
+            const heights = items.map(item => item.offsetHeight);
 
-            for (let i = 0; i < columnsCount; i++) {
-                const column = document.createElement("div");
-                column.className = "masonry-column";
-                columnsFragment.append(column);
-            }
+            container.textContent = "";
+            previousColumnsCount = columnsCount;
+            container.style.setProperty("--masonry-columns", columnsCount);
+
+            const fragment = document.createDocumentFragment();
+            let columns = null;
+            let columnHeights = null;
+            let columnItems = null;
 
             for (let i = 0; i < items.length; i++) {
-                columnsFragment.children[i % columnsCount].appendChild(items[i]);
+                const span = Math.min(Number(items[i].dataset.span) || 1, columnsCount);
+
+                // widgets which span more than a single column get a row of their
+                // own, the ones around them are balanced between the columns
+                if (span > 1) {
+                    items[i].style.setProperty("--masonry-span", span);
+                    fragment.append(items[i]);
+                    columns = null;
+                    continue;
+                }
+
+                if (columns === null) {
+                    const row = document.createElement("div");
+                    row.className = "masonry-row";
+                    columns = [];
+                    columnHeights = [];
+                    columnItems = [];
+
+                    for (let c = 0; c < columnsCount; c++) {
+                        const column = document.createElement("div");
+                        column.className = "masonry-column";
+                        row.append(column);
+                        columns.push(column);
+                        columnHeights.push(0);
+                        columnItems.push(0);
+                    }
+
+                    fragment.append(row);
+                }
+
+                // the shortest column gets the next widget, falling back to the one
+                // with the fewest widgets when the heights are the same or unknown
+                let target = 0;
+                for (let c = 1; c < columnsCount; c++) {
+                    if (columnHeights[c] < columnHeights[target] || (columnHeights[c] === columnHeights[target] && columnItems[c] < columnItems[target])) {
+                        target = c;
+                    }
+                }
+
+                columns[target].appendChild(items[i]);
+                columnHeights[target] += heights[i];
+                columnItems[target]++;
             }
 
-            container.append(columnsFragment);
+            container.append(fragment);
         };
 
         const observer = new ResizeObserver(() => requestAnimationFrame(render));
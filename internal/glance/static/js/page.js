@@ -9,11 +9,20 @@ async function fetchPageContent(pageData) {
     const response = await fetch(`${pageData.baseURL}/api/pages/${pageData.slug}/content/`);
     const content = await response.text();
 
-    return content;
+    return { content, version: response.headers.get("X-Page-Version") };
 }
 
-function setupCarousels() {
-    const carouselElements = document.getElementsByClassName("carousel-container");
+// Same as querySelectorAll, except the root itself is included if it matches,
+// so that setting up a single widget also covers its outermost element
+function queryAll(root, selector) {
+    const elements = Array.from(root.querySelectorAll(selector));
+    if (root !== document && root.matches(selector)) elements.unshift(root);
+
+    return elements;
+}
+
+function setupCarousels(root = document) {
+    const carouselElements = queryAll(root, ".carousel-container");
 
     if (carouselElements.length == 0) {
         return;
@@ -81,6 +90,19 @@ function timestampToRelativeTime(timestamp) {
     return prefix + Math.floor(delta / yearInSeconds) + "y";
 }
 
+function timestampToUptime(timestamp) {
+    const delta = Math.max(0, Math.round((Date.now() / 1000) - timestamp));
+
+    if (delta < hourInSeconds) {
+        return Math.max(1, Math.floor(delta / minuteInSeconds)) + "m";
+    }
+    if (delta < dayInSeconds) {
+        return Math.floor(delta / hourInSeconds) + "h " + Math.floor((delta % hourInSeconds) / minuteInSeconds) + "m";
+    }
+
+    return Math.floor(delta / dayInSeconds) + "d " + Math.floor((delta % dayInSeconds) / hourInSeconds) + "h";
+}
+
 function updateRelativeTimeForElements(elements)
 {
     for (let i = 0; i < elements.length; i++)
@@ -91,12 +113,17 @@ function updateRelativeTimeForElements(elements)
         if (timestamp === undefined)
             continue
 
+        if (element.dataset.relativeTimeFormat == "uptime") {
+            element.textContent = "live " + timestampToUptime(timestamp);
+            continue;
+        }
+
         element.textContent = timestampToRelativeTime(timestamp);
     }
 }
 
-function setupSearchBoxes() {
-    const searchWidgets = document.getElementsByClassName("search");
+function setupSearchBoxes(root = document) {
+    const searchWidgets = queryAll(root, ".search");
 
     if (searchWidgets.length == 0) {
         return;
@@ -109,50 +136,221 @@ function setupSearchBoxes() {
         const newTab = widget.dataset.newTab === "true";
         const inputElement = widget.getElementsByClassName("search-input")[0];
         const bangElement = widget.getElementsByClassName("search-bang")[0];
+        const hintElement = widget.getElementsByClassName("search-bangs-hint")[0];
         const bangs = widget.querySelectorAll(".search-bangs > input");
         const bangsMap = {};
         const kbdElement = widget.getElementsByTagName("kbd")[0];
+        const suggestionsElement = widget.getElementsByClassName("search-suggestions")[0];
+        const suggestionsUrl = widget.dataset.suggestionsUrl === undefined ? null
+            : (widget.dataset.suggestionsProxied !== undefined ? pageData.baseURL : "") + widget.dataset.suggestionsUrl;
         let currentBang = null;
         let lastQuery = "";
+        let suggestionsTimeout = null;
+        let suggestionsController = null;
+        let activeSuggestion = -1;
 
         for (let j = 0; j < bangs.length; j++) {
             const bang = bangs[j];
             bangsMap[bang.dataset.shortcut] = bang;
         }
 
+        const hideHint = () => {
+            if (hintElement !== undefined) hintElement.hidden = true;
+        };
+
+        const completeBang = (bang) => {
+            inputElement.value = bang.dataset.shortcut + " ";
+            changeCurrentBang(bang);
+            hideHint();
+        };
+
+        // lists the bangs whose shortcut starts with what has been typed so far,
+        // only while the first word is being typed and doesn't match a bang yet
+        const updateHint = (value) => {
+            if (hintElement === undefined) return;
+
+            const typed = value.trimStart();
+            if (typed.length == 0 || typed.includes(" ") || typed in bangsMap) {
+                hideHint();
+                return;
+            }
+
+            const matching = [];
+            for (let j = 0; j < bangs.length; j++) {
+                if (bangs[j].dataset.shortcut.startsWith(typed)) matching.push(bangs[j]);
+            }
+
+            if (matching.length == 0) {
+                hideHint();
+                return;
+            }
+
+            hintElement.replaceChildren(...matching.map((bang, index) => {
+                const item = document.createElement("li");
+                const shortcut = document.createElement("span");
+                const title = document.createElement("span");
+
+                shortcut.className = "search-bangs-hint-shortcut";
+                shortcut.textContent = bang.dataset.shortcut;
+                title.textContent = bang.dataset.title;
+                item.append(shortcut, title);
+
+                if (index == 0) item.classList.add("active");
+                item.addEventListener("mousedown", (event) => {
+                    event.preventDefault();
+                    completeBang(bang);
+                });
+
+                return item;
+            }));
+
+            hintElement.matching = matching;
+            hintElement.hidden = false;
+        };
+
+        const suggestionsVisible = () => suggestionsElement !== undefined && !suggestionsElement.hidden;
+
+        const cancelSuggestions = () => {
+            clearTimeout(suggestionsTimeout);
+            if (suggestionsController !== null) suggestionsController.abort();
+            suggestionsController = null;
+        };
+
+        const hideSuggestions = () => {
+            cancelSuggestions();
+            activeSuggestion = -1;
+            if (suggestionsElement !== undefined) suggestionsElement.hidden = true;
+        };
+
+        const setActiveSuggestion = (index) => {
+            const items = suggestionsElement.children;
+            if (activeSuggestion >= 0) items[activeSuggestion].classList.remove("active");
+            activeSuggestion = index;
+            if (index >= 0) items[index].classList.add("active");
+        };
+
+        const showSuggestions = (suggestions) => {
+            if (suggestions.length == 0) {
+                hideSuggestions();
+                return;
+            }
+
+            activeSuggestion = -1;
+            suggestionsElement.replaceChildren(...suggestions.map((suggestion) => {
+                const item = document.createElement("li");
+                item.textContent = suggestion;
+                item.addEventListener("mousedown", (event) => {
+                    event.preventDefault();
+                    search(suggestion, event);
+                });
+
+                return item;
+            }));
+
+            suggestionsElement.hidden = false;
+        };
+
+        // suggestions are only shown for the default search engine and not while
+        // the bangs hint is visible, since both would otherwise overlap
+        const updateSuggestions = () => {
+            if (suggestionsUrl === null) return;
+            cancelSuggestions();
+
+            const query = inputElement.value.trim();
+            if (query.length == 0 || currentBang != null || (hintElement !== undefined && !hintElement.hidden)) {
+                hideSuggestions();
+                return;
+            }
+
+            suggestionsTimeout = setTimeout(async () => {
+                const controller = suggestionsController = new AbortController();
+
+                try {
+                    const response = await fetch(
+                        suggestionsUrl.replace("!QUERY!", encodeURIComponent(query)),
+                        { signal: controller.signal }
+                    );
+                    const data = await response.json();
+
+                    if (controller.signal.aborted || !Array.isArray(data) || !Array.isArray(data[1])) return;
+                    showSuggestions(data[1].filter(suggestion => typeof suggestion == "string").slice(0, 8));
+                } catch (e) {
+                    // failing to get suggestions shouldn't get in the way of searching
+                }
+            }, 250);
+        };
+
+        const search = (input, event) => {
+            let query;
+            let searchUrlTemplate;
+
+            if (currentBang != null) {
+                query = input.slice(currentBang.dataset.shortcut.length + 1);
+                searchUrlTemplate = currentBang.dataset.url;
+            } else {
+                query = input;
+                searchUrlTemplate = defaultSearchUrl;
+            }
+            if (query.length == 0 && currentBang == null) {
+                return;
+            }
+
+            const url = searchUrlTemplate.replace("!QUERY!", encodeURIComponent(query));
+
+            if (newTab && !event.ctrlKey || !newTab && event.ctrlKey) {
+                window.open(url, target).focus();
+            } else {
+                window.location.href = url;
+            }
+
+            lastQuery = query;
+            inputElement.value = "";
+            hideHint();
+            hideSuggestions();
+        };
+
         const handleKeyDown = (event) => {
             if (event.key == "Escape") {
+                if (hintElement !== undefined && !hintElement.hidden) {
+                    hideHint();
+                    return;
+                }
+
+                if (suggestionsVisible()) {
+                    hideSuggestions();
+                    return;
+                }
+
                 inputElement.blur();
                 return;
             }
 
-            if (event.key == "Enter") {
-                const input = inputElement.value.trim();
-                let query;
-                let searchUrlTemplate;
+            if (event.key == "Tab" && hintElement !== undefined && !hintElement.hidden) {
+                event.preventDefault();
+                completeBang(hintElement.matching[0]);
+                return;
+            }
 
-                if (currentBang != null) {
-                    query = input.slice(currentBang.dataset.shortcut.length + 1);
-                    searchUrlTemplate = currentBang.dataset.url;
+            if (event.key == "Enter") {
+                if (suggestionsVisible() && activeSuggestion >= 0) {
+                    search(suggestionsElement.children[activeSuggestion].textContent, event);
                 } else {
-                    query = input;
-                    searchUrlTemplate = defaultSearchUrl;
-                }
-                if (query.length == 0 && currentBang == null) {
-                    return;
+                    search(inputElement.value.trim(), event);
                 }
 
-                const url = searchUrlTemplate.replace("!QUERY!", encodeURIComponent(query));
+                return;
+            }
+
+            if (suggestionsVisible() && (event.key == "ArrowDown" || event.key == "ArrowUp")) {
+                event.preventDefault();
+                const count = suggestionsElement.children.length;
 
-                if (newTab && !event.ctrlKey || !newTab && event.ctrlKey) {
-                    window.open(url, target).focus();
+                if (event.key == "ArrowDown") {
+                    setActiveSuggestion(activeSuggestion + 1 < count ? activeSuggestion + 1 : -1);
                 } else {
-                    window.location.href = url;
+                    setActiveSuggestion(activeSuggestion == -1 ? count - 1 : activeSuggestion - 1);
                 }
 
-                lastQuery = query;
-                inputElement.value = "";
-
                 return;
             }
 
@@ -168,19 +366,19 @@ function setupSearchBoxes() {
         }
 
         const handleInput = (event) => {
+            updateHint(event.target.value);
             const value = event.target.value.trim();
+            const words = value.split(" ");
+
             if (value in bangsMap) {
                 changeCurrentBang(bangsMap[value]);
-                return;
-            }
-
-            const words = value.split(" ");
-            if (words.length >= 2 && words[0] in bangsMap) {
+            } else if (words.length >= 2 && words[0] in bangsMap) {
                 changeCurrentBang(bangsMap[words[0]]);
-                return;
+            } else {
+                changeCurrentBang(null);
             }
 
-            changeCurrentBang(null);
+            updateSuggestions();
         };
 
         inputElement.addEventListener("focus", () => {
@@ -188,6 +386,8 @@ function setupSearchBoxes() {
             document.addEventListener("input", handleInput);
         });
         inputElement.addEventListener("blur", () => {
+            hideHint();
+            hideSuggestions();
             document.removeEventListener("keydown", handleKeyDown);
             document.removeEventListener("input", handleInput);
         });
@@ -206,15 +406,129 @@ function setupSearchBoxes() {
     }
 }
 
-function setupDynamicRelativeTime() {
-    const elements = document.querySelectorAll("[data-dynamic-relative-time]");
+function setupStaleSinceIndicators(root = document) {
+    const elements = queryAll(root, "[data-stale-since]");
+
+    for (const element of elements) {
+        const staleSince = new Date(parseInt(element.dataset.staleSince, 10) * 1000);
+        element.textContent = "stale since " + staleSince.toLocaleTimeString([], { hour: "2-digit", minute: "2-digit" });
+    }
+}
+
+// Each alert only notifies once per browser session, until the price goes
+// back within the thresholds, so that reloading the page doesn't repeat it
+function setupMarketAlerts(root = document) {
+    const lists = queryAll(root, "[data-market-alert-notifications]");
+    if (lists.length == 0 || !("Notification" in window)) return;
+
+    const notify = (rows) => {
+        for (const row of rows) {
+            const text = `${row.dataset.marketSymbol} is ${row.dataset.marketAlert} its alert threshold at ${row.dataset.marketPrice}`;
+            new Notification(row.dataset.marketSymbol, { body: text, tag: "market-alert-" + row.dataset.marketSymbol });
+        }
+    };
+
+    for (const list of lists) {
+        const pending = [];
+
+        for (const row of list.children) {
+            const key = `market-alert-${row.dataset.marketSymbol}`;
+            const alert = row.dataset.marketAlert;
+
+            if (alert === undefined) {
+                sessionStorage.removeItem(key);
+                continue;
+            }
+
+            if (sessionStorage.getItem(key) === alert) continue;
+
+            sessionStorage.setItem(key, alert);
+            pending.push(row);
+        }
+
+        if (pending.length == 0) continue;
+
+        if (Notification.permission === "granted") {
+            notify(pending);
+        } else if (Notification.permission === "default") {
+            Notification.requestPermission().then(permission => {
+                if (permission === "granted") notify(pending);
+            }).catch(() => {});
+        }
+    }
+}
+
+// A single audio element is shared by all of the episodes on the page
+// so that playing one stops whichever was playing before it
+let podcastAudio = null;
+
+function setupPodcastPlayers(root = document) {
+    const buttons = queryAll(root, "[data-podcast-audio]");
+    if (buttons.length == 0) return;
+
+    const progressOf = (button) => button.closest(".rss-podcast-controls").nextElementSibling;
+
+    const setPlaying = (button, playing) => {
+        button.classList.toggle("playing", playing);
+        button.title = playing ? "Pause" : "Play";
+        button.setAttribute("aria-label", button.title);
+    };
+
+    if (podcastAudio === null) {
+        const audio = new Audio();
+        audio.preload = "none";
+        podcastAudio = { audio, current: null };
+
+        audio.addEventListener("play", () => setPlaying(podcastAudio.current, true));
+        audio.addEventListener("pause", () => setPlaying(podcastAudio.current, false));
+        audio.addEventListener("ended", () => setPlaying(podcastAudio.current, false));
+        audio.addEventListener("timeupdate", () => {
+            if (!audio.duration) return;
+            progressOf(podcastAudio.current).style.setProperty("--podcast-progress", (audio.currentTime / audio.duration).toFixed(4));
+        });
+    }
+
+    const audio = podcastAudio.audio;
+
+    for (const button of buttons) {
+        button.addEventListener("click", () => {
+            if (podcastAudio.current === button) {
+                if (audio.paused) audio.play().catch(() => {});
+                else audio.pause();
+                return;
+            }
+
+            if (podcastAudio.current !== null) setPlaying(podcastAudio.current, false);
+
+            podcastAudio.current = button;
+            progressOf(button).classList.add("started");
+            audio.src = button.dataset.podcastAudio;
+            audio.play().catch(() => {
+                if (podcastAudio.current === button) setPlaying(button, false);
+            });
+        });
+    }
+}
+
+// Elements of widgets which get replaced by live updates are dropped
+// and the ones of their replacements are added to the same list
+let dynamicRelativeTimeElements = null;
+
+function setupDynamicRelativeTime(root = document) {
+    const newElements = queryAll(root, "[data-dynamic-relative-time]");
+    updateRelativeTimeForElements(newElements);
+
+    if (dynamicRelativeTimeElements !== null) {
+        dynamicRelativeTimeElements = dynamicRelativeTimeElements.filter((element) => element.isConnected).concat(newElements);
+        return;
+    }
+
+    dynamicRelativeTimeElements = newElements;
     const updateInterval = 60 * 1000;
     let lastUpdateTime = Date.now();
 
-    updateRelativeTimeForElements(elements);
-
     const updateElementsAndTimestamp = () => {
-        updateRelativeTimeForElements(elements);
+        updateRelativeTimeForElements(dynamicRelativeTimeElements);
         lastUpdateTime = Date.now();
     };
 
@@ -248,8 +562,8 @@ function setupDynamicRelativeTime() {
     });
 }
 
-function setupGroups() {
-    const groups = document.getElementsByClassName("widget-type-group");
+function setupGroups(root = document) {
+    const groups = queryAll(root, ".widget-type-group");
 
     if (groups.length == 0) {
         return;
@@ -308,8 +622,93 @@ function setupGroups() {
     }
 }
 
-function setupLazyImages() {
-    const images = document.querySelectorAll("img[loading=lazy]");
+// The source only gets set once the iframe is close to being visible, rather
+// than relying on loading=lazy, so that the placeholder can be shown until
+// it loads regardless of whether the browser supports lazy loading iframes
+function setupIframes(root = document) {
+    const iframes = queryAll(root, "iframe[data-iframe-src]");
+    if (iframes.length == 0) return;
+
+    const load = (iframe) => {
+        iframe.addEventListener("load", () => {
+            iframe.parentElement.classList.add("iframe-loaded");
+        }, { once: true });
+
+        iframe.src = iframe.dataset.iframeSrc;
+        iframe.removeAttribute("data-iframe-src");
+    };
+
+    if (!("IntersectionObserver" in window)) {
+        for (const iframe of iframes) load(iframe);
+        return;
+    }
+
+    const observer = new IntersectionObserver((entries) => {
+        for (const entry of entries) {
+            if (!entry.isIntersecting) continue;
+            observer.unobserve(entry.target);
+            load(entry.target);
+        }
+    }, { rootMargin: "200px" });
+
+    for (const iframe of iframes) observer.observe(iframe);
+}
+
+// Cells with a data-sort-value get compared numerically using it, all
+// other cells get compared by their text
+function setupTables(root = document) {
+    const tables = queryAll(root, ".table-sortable");
+
+    for (const table of tables) {
+        const headers = table.querySelectorAll("th");
+        const tbody = table.querySelector("tbody");
+
+        const cellValue = (row, index) => {
+            const cell = row.children[index];
+            if (cell.dataset.sortValue !== undefined) {
+                return parseFloat(cell.dataset.sortValue);
+            }
+
+            return cell.textContent.trim().toLowerCase();
+        };
+
+        for (let i = 0; i < headers.length; i++) {
+            const header = headers[i];
+            const button = header.querySelector(".table-sort-button");
+            if (button === null) continue;
+
+            button.addEventListener("click", () => {
+                const direction = header.getAttribute("aria-sort") == "ascending" ? "descending" : "ascending";
+                const multiplier = direction == "ascending" ? 1 : -1;
+
+                for (const other of headers) other.setAttribute("aria-sort", "none");
+                header.setAttribute("aria-sort", direction);
+
+                const rows = Array.from(tbody.children);
+                rows.sort((a, b) => {
+                    const aValue = cellValue(a, i);
+                    const bValue = cellValue(b, i);
+
+                    // rows without a numeric value always go last
+                    if (typeof aValue != typeof bValue) {
+                        return typeof aValue == "number" ? -1 : 1;
+                    }
+
+                    if (typeof aValue == "number") {
+                        return (aValue - bValue) * multiplier;
+                    }
+
+                    return aValue.localeCompare(bValue) * multiplier;
+                });
+
+                tbody.append(...rows);
+            });
+        }
+    }
+}
+
+function setupLazyImages(root = document) {
+    const images = queryAll(root, "img[loading=lazy]");
 
     if (images.length == 0) {
         return;
@@ -383,8 +782,8 @@ function attachExpandToggleButton(collapsibleContainer) {
 };
 
 
-function setupCollapsibleLists() {
-    const collapsibleLists = document.querySelectorAll(".list.collapsible-container");
+function setupCollapsibleLists(root = document) {
+    const collapsibleLists = queryAll(root, ".list.collapsible-container");
 
     if (collapsibleLists.length == 0) {
         return;
@@ -417,8 +816,8 @@ function setupCollapsibleLists() {
     }
 }
 
-function setupCollapsibleGrids() {
-    const collapsibleGridElements = document.querySelectorAll(".cards-grid.collapsible-container");
+function setupCollapsibleGrids(root = document) {
+    const collapsibleGridElements = queryAll(root, ".cards-grid.collapsible-container");
 
     if (collapsibleGridElements.length == 0) {
         return;
@@ -493,21 +892,34 @@ function setupCollapsibleGrids() {
 }
 
 const contentReadyCallbacks = [];
+let contentReady = false;
 
+// Widgets which get set up after the page is ready, such as through
+// live updates, have their callbacks run right away
 function afterContentReady(callback) {
+    if (contentReady) {
+        callback();
+        return;
+    }
+
     contentReadyCallbacks.push(callback);
 }
 
 const weekDayNames = ['Sunday', 'Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday'];
 const monthNames = ['January', 'February', 'March', 'April', 'May', 'June', 'July', 'August', 'September', 'October', 'November', 'December'];
 
-function makeSettableTimeElement(element, hourFormat) {
+function makeSettableTimeElement(element, hourFormat, showSeconds) {
     const fragment = document.createDocumentFragment();
     const hour = document.createElement('span');
     const minute = document.createElement('span');
+    const second = document.createElement('span');
     const amPm = document.createElement('span');
     fragment.append(hour, document.createTextNode(':'), minute);
 
+    if (showSeconds) {
+        fragment.append(document.createTextNode(':'), second);
+    }
+
     if (hourFormat == '12h') {
         fragment.append(document.createTextNode(' '), amPm);
     }
@@ -526,6 +938,11 @@ function makeSettableTimeElement(element, hourFormat) {
 
         const minutes = date.getMinutes();
         minute.textContent = minutes < 10 ? '0' + minutes : minutes;
+
+        if (showSeconds) {
+            const seconds = date.getSeconds();
+            second.textContent = seconds < 10 ? '0' + seconds : seconds;
+        }
     };
 };
 
@@ -570,8 +987,8 @@ function zoneDiffText(diffInMinutes) {
     return { text: `${sign}${hours}h~`, title: `${hours} hour${hourSuffix} and ${minutes} minutes ${signText}` };
 }
 
-function setupClocks() {
-    const clocks = document.getElementsByClassName('clock');
+function setupClocks(root = document) {
+    const clocks = queryAll(root, '.clock');
 
     if (clocks.length == 0) {
         return;
@@ -582,6 +999,7 @@ function setupClocks() {
     for (var i = 0; i < clocks.length; i++) {
         const clock = clocks[i];
         const hourFormat = clock.dataset.hourFormat;
+        const showSeconds = clock.dataset.showSeconds !== undefined;
         const localTimeContainer = clock.querySelector('[data-local-time]');
         const localDateElement = localTimeContainer.querySelector('[data-date]');
         const localWeekdayElement = localTimeContainer.querySelector('[data-weekday]');
@@ -590,7 +1008,8 @@ function setupClocks() {
 
         const setLocalTime = makeSettableTimeElement(
             localTimeContainer.querySelector('[data-time]'),
-            hourFormat
+            hourFormat,
+            showSeconds
         );
 
         updateCallbacks.push((now) => {
@@ -603,15 +1022,22 @@ function setupClocks() {
         for (var z = 0; z < timeZoneContainers.length; z++) {
             const timeZoneContainer = timeZoneContainers[z];
             const diffElement = timeZoneContainer.querySelector('[data-time-diff]');
+            const dateElement = timeZoneContainer.querySelector('[data-date]');
 
             const setZoneTime = makeSettableTimeElement(
                 timeZoneContainer.querySelector('[data-time]'),
-                hourFormat
+                timeZoneContainer.dataset.hourFormat || hourFormat,
+                showSeconds
             );
 
             updateCallbacks.push((now) => {
                 const { time, diffInMinutes } = timeInZone(now, timeZoneContainer.dataset.timeInZone);
                 setZoneTime(time);
+
+                if (dateElement !== null) {
+                    dateElement.textContent = weekDayNames[time.getDay()] + ', ' + time.getDate() + ' ' + monthNames[time.getMonth()];
+                }
+
                 const { text, title } = zoneDiffText(diffInMinutes);
                 diffElement.textContent = text;
                 diffElement.title = title;
@@ -620,19 +1046,23 @@ function setupClocks() {
     }
 
     const updateClocks = () => {
+        // replaced by a live update
+        if (!clocks.some((clock) => clock.isConnected)) return;
+
         const now = new Date();
 
         for (var i = 0; i < updateCallbacks.length; i++)
             updateCallbacks[i](now);
 
-        setTimeout(updateClocks, (60 - now.getSeconds()) * 1000);
+        // aligned to the start of each second
+        setTimeout(updateClocks, 1000 - now.getMilliseconds());
     };
 
     updateClocks();
 }
 
-async function setupCalendars() {
-    const elems = document.getElementsByClassName("calendar");
+async function setupCalendars(root = document) {
+    const elems = queryAll(root, ".calendar");
     if (elems.length == 0) return;
 
     // TODO: implement prefetching, currently loads as a nasty waterfall of requests
@@ -642,8 +1072,8 @@ async function setupCalendars() {
         calendar.default(elems[i]);
 }
 
-async function setupTodos() {
-    const elems = Array.from(document.getElementsByClassName("todo"));
+async function setupTodos(root = document) {
+    const elems = queryAll(root, ".todo");
     if (elems.length == 0) return;
 
     const todo = await import ('./todo.js');
@@ -653,8 +1083,87 @@ async function setupTodos() {
     }
 }
 
-function setupTruncatedElementTitles() {
-    const elements = document.querySelectorAll(".text-truncate, .single-line-titles .title, .text-truncate-2-lines, .text-truncate-3-lines");
+// Changes get saved once typing stops for a moment, as well as right away
+// when the note loses focus or the page gets hidden
+function setupNotes(root = document) {
+    const notes = queryAll(root, ".notes");
+    const saveDelay = 1000;
+
+    for (let i = 0; i < notes.length; i++) {
+        const note = notes[i];
+        const textarea = note.querySelector("textarea");
+        if (textarea === null) continue;
+
+        const mimic = note.querySelector(".auto-scaling-textarea-mimic");
+        const status = note.querySelector(".notes-status");
+        const saveURL = `${pageData.baseURL}/api/widgets/${encodeURIComponent(note.dataset.notesId)}/save`;
+        let savedContent = textarea.value;
+        let saveTimeout = null;
+        let saving = false;
+
+        const setStatus = (text, error = null) => {
+            status.textContent = text;
+            status.title = error === null ? "" : error;
+            status.classList.toggle("error", error !== null);
+        };
+
+        const save = async (keepalive = false) => {
+            clearTimeout(saveTimeout);
+            saveTimeout = null;
+
+            const content = textarea.value;
+            if (content === savedContent) {
+                setStatus("Saved");
+                return;
+            }
+
+            // changes made while saving get saved once it's done
+            if (saving) {
+                saveTimeout = setTimeout(save, saveDelay);
+                return;
+            }
+
+            saving = true;
+            setStatus("Saving...");
+
+            try {
+                const response = await fetch(saveURL, {
+                    method: "POST",
+                    headers: { "Content-Type": "application/json" },
+                    body: JSON.stringify({ content }),
+                    keepalive,
+                });
+
+                if (!response.ok) throw new Error(await response.text());
+
+                savedContent = content;
+                setStatus(textarea.value === savedContent ? "Saved" : "Unsaved changes");
+            } catch (error) {
+                setStatus("Failed to save", error.message);
+            } finally {
+                saving = false;
+            }
+        };
+
+        textarea.addEventListener("input", () => {
+            mimic.textContent = textarea.value + " ";
+            setStatus("Unsaved changes");
+            clearTimeout(saveTimeout);
+            saveTimeout = setTimeout(save, saveDelay);
+        });
+
+        textarea.addEventListener("blur", () => {
+            if (saveTimeout !== null) save();
+        });
+
+        document.addEventListener("visibilitychange", () => {
+            if (document.visibilityState == "hidden" && saveTimeout !== null) save(true);
+        });
+    }
+}
+
+function setupTruncatedElementTitles(root = document) {
+    const elements = queryAll(root, ".text-truncate, .single-line-titles .title, .text-truncate-2-lines, .text-truncate-3-lines");
 
     if (elements.length == 0) {
         return;
@@ -667,26 +1176,36 @@ function setupTruncatedElementTitles() {
     }
 }
 
-async function changeTheme(key, onChanged) {
+// The styles of all themes are included in the page, the request only saves
+// the choice so that the pages loaded after it get rendered with the theme
+function changeTheme(key, onChanged) {
     const themeStyleElem = find("#theme-style");
+    const theme = pageData.themes[key];
+    if (theme === undefined) return;
 
-    const response = await fetch(`${pageData.baseURL}/api/set-theme/${key}`, {
-        method: "POST",
-    });
-
-    if (response.status != 200) {
-        alert("Failed to set theme: " + response.statusText);
-        return;
-    }
-    const newThemeStyle = await response.text();
+    fetch(`${pageData.baseURL}/api/set-theme/${key}`, { method: "POST" })
+        .then((response) => {
+            if (response.status != 200) alert("Failed to save theme: " + response.statusText);
+        })
+        .catch(() => {});
 
     const tempStyle = elem("style")
         .html("* { transition: none !important; }")
         .appendTo(document.head);
 
-    themeStyleElem.html(newThemeStyle);
+    themeStyleElem.html(theme.css);
     document.documentElement.setAttribute("data-theme", key);
-    document.documentElement.setAttribute("data-scheme", response.headers.get("X-Scheme"));
+
+    const scheme = theme.scheme;
+    if (scheme == "auto") {
+        document.documentElement.setAttribute(
+            "data-scheme",
+            window.matchMedia("(prefers-color-scheme: light)").matches ? "light" : "dark"
+        );
+    } else {
+        document.documentElement.setAttribute("data-scheme", scheme);
+    }
+
     typeof onChanged == "function" && onChanged();
     setTimeout(() => { tempStyle.remove(); }, 10);
 }
@@ -743,20 +1262,180 @@ function initThemePicker() {
     })
 }
 
+// Reloads the page on an interval so that it stays up to date without any
+// interaction, but only once the server is reachable so that a temporary
+// outage doesn't leave the display stuck on the browser's error page
+function setupKioskRefresh() {
+    const retryInterval = 30 * 1000;
+
+    const refresh = async () => {
+        try {
+            const response = await fetch(`${pageData.baseURL}/api/healthz`, { cache: "no-store" });
+            if (response.ok) {
+                location.reload();
+                return;
+            }
+        } catch {}
+
+        setTimeout(refresh, retryInterval);
+    };
+
+    setTimeout(refresh, pageData.kioskRefreshInterval);
+}
+
+// Sets up a widget which replaced an older version of itself after the page
+// was already set up, the rest of the page is left as it is
+async function setupWidget(element) {
+    setupPopovers(element);
+    setupClocks(element);
+    await setupCalendars(element);
+    await setupTodos(element);
+    setupNotes(element);
+    setupCarousels(element);
+    setupSearchBoxes(element);
+    setupCollapsibleLists(element);
+    setupCollapsibleGrids(element);
+    setupGroups(element);
+    setupMasonries(element);
+    setupDynamicRelativeTime(element);
+    setupStaleSinceIndicators(element);
+    setupMarketAlerts(element);
+    setupPodcastPlayers(element);
+    setupLazyImages(element);
+    setupIframes(element);
+    setupTables(element);
+    setupTruncatedElementTitles(element);
+}
+
+// Widgets within groups are only visible while their tab is selected, so
+// the tabs of all of the groups that the widget is in get selected first
+function revealWidget(id) {
+    const element = document.querySelector(`[data-widget-id="${id}"]`);
+    if (element === null) return false;
+
+    for (let panel = element.closest(".widget-group-content"); panel !== null; panel = panel.parentElement.closest(".widget-group-content")) {
+        if (!panel.classList.contains("widget-group-content-current")) {
+            document.getElementById(panel.getAttribute("aria-labelledby"))?.click();
+        }
+    }
+
+    element.scrollIntoView({ behavior: "smooth", block: "center" });
+
+    // restarts the animation if the widget was revealed a moment ago
+    element.classList.remove("widget-revealed");
+    void element.offsetWidth;
+    element.classList.add("widget-revealed");
+    element.addEventListener("animationend", () => element.classList.remove("widget-revealed"), { once: true });
+
+    return true;
+}
+
+// The palette itself only gets loaded the first time it's opened
+function setupCommandPalette() {
+    if (pageData.commandPalette !== true) return;
+
+    let palette = null;
+
+    document.addEventListener("keydown", async (event) => {
+        if (event.key === undefined || event.key.toLowerCase() != "k") return;
+        if (!(event.ctrlKey || event.metaKey) || event.altKey || event.shiftKey) return;
+
+        event.preventDefault();
+
+        if (palette === null) palette = import("./command-palette.js");
+        (await palette).toggleCommandPalette(revealWidget);
+    });
+}
+
+// Widgets which are being interacted with, such as ones with a focused input,
+// an open popover or a podcast that's playing, would lose their state if they
+// got replaced, so their updates are held back until the interaction ends
+function isWidgetBusy(element) {
+    return element.contains(document.activeElement)
+        || element.querySelector(".popover-active, [data-podcast-audio].playing") !== null;
+}
+
+function setupLiveUpdates(version) {
+    const pendingUpdates = new Map();
+    const busyCheckInterval = 5 * 1000;
+    let busyCheckTimeout = null;
+
+    const replaceWidget = (id, html) => {
+        const element = document.querySelector(`[data-widget-id="${id}"]`);
+        if (element === null) return true;
+
+        if (isWidgetBusy(element)) return false;
+
+        const template = document.createElement("template");
+        template.innerHTML = html;
+        const replacement = template.content.firstElementChild;
+        if (replacement === null) return true;
+
+        element.replaceWith(replacement);
+        setupWidget(replacement);
+
+        return true;
+    };
+
+    const applyPendingUpdates = () => {
+        busyCheckTimeout = null;
+
+        for (const [id, html] of pendingUpdates) {
+            if (replaceWidget(id, html)) pendingUpdates.delete(id);
+        }
+
+        if (pendingUpdates.size > 0) busyCheckTimeout = setTimeout(applyPendingUpdates, busyCheckInterval);
+    };
+
+    const events = new EventSource(`${pageData.baseURL}/api/pages/${pageData.slug}/events?since=${encodeURIComponent(version)}`);
+
+    events.addEventListener("widget", (event) => {
+        const update = JSON.parse(event.data);
+        pendingUpdates.set(update.id, update.html);
+
+        if (busyCheckTimeout === null) applyPendingUpdates();
+    });
+
+    events.addEventListener("reload", () => {
+        events.close();
+        location.reload();
+    });
+
+    // the browser keeps reconnecting on its own unless the server responded
+    // with an error, such as after live updates were disabled in the config
+    events.addEventListener("error", () => {
+        if (events.readyState != EventSource.CLOSED) return;
+
+        if (pageData.kioskRefreshInterval !== undefined) {
+            setupKioskRefresh();
+        }
+    });
+}
+
 async function setupPage() {
     initThemePicker();
+    setupCommandPalette();
 
     const pageElement = document.getElementById("page");
     const pageContentElement = document.getElementById("page-content");
     const pageContent = await fetchPageContent(pageData);
+    const liveUpdates = pageData.liveUpdates === true
+        && window.EventSource !== undefined
+        && pageContent.version !== null;
+
+    // live updates keep the page up to date without having to reload it
+    if (pageData.kioskRefreshInterval !== undefined && !liveUpdates) {
+        setupKioskRefresh();
+    }
 
-    pageContentElement.innerHTML = pageContent;
+    pageContentElement.innerHTML = pageContent.content;
 
     try {
         setupPopovers();
         setupClocks()
         await setupCalendars();
         await setupTodos();
+        setupNotes();
         setupCarousels();
         setupSearchBoxes();
         setupCollapsibleLists();
@@ -764,14 +1443,31 @@ async function setupPage() {
         setupGroups();
         setupMasonries();
         setupDynamicRelativeTime();
+        setupStaleSinceIndicators();
+        setupMarketAlerts();
+        setupPodcastPlayers();
         setupLazyImages();
+        setupIframes();
+        setupTables();
     } finally {
         pageElement.classList.add("content-ready");
         pageElement.setAttribute("aria-busy", "false");
+        contentReady = true;
 
         for (let i = 0; i < contentReadyCallbacks.length; i++) {
             contentReadyCallbacks[i]();
         }
+        contentReadyCallbacks.length = 0;
+
+        if (liveUpdates) {
+            setupLiveUpdates(pageContent.version);
+        }
+
+        // set when jumping to a widget on another page from the command palette
+        const revealedWidget = location.hash.match(/^#widget-(\d+)$/);
+        if (revealedWidget !== null) {
+            revealWidget(revealedWidget[1]);
+        }
 
         setTimeout(() => {
             setupTruncatedElementTitles();
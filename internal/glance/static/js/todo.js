@@ -7,8 +7,10 @@ const trashIconSvg = `<svg fill="currentColor" xmlns="http://www.w3.org/2000/svg
 </svg>`;
 
 export default function(element) {
+    const initialItems = element.dataset.todoItems ? JSON.parse(element.dataset.todoItems) : [];
+
     element.swapWith(
-        Todo(element.dataset.todoId)
+        Todo(element.dataset.todoId, initialItems, element.dataset.todoResetAtMidnight !== undefined)
     )
 }
 
@@ -37,16 +39,42 @@ function inputMarginAnim(entrance = true) {
     }
 }
 
-function loadFromLocalStorage(id) {
-    return JSON.parse(localStorage.getItem(`todo-${id}`) || "[]");
+// The items from the config are only used when nothing has been stored yet,
+// after that the list is entirely up to whoever is using it
+function loadFromLocalStorage(id, initialItems) {
+    const stored = localStorage.getItem(`todo-${id}`);
+    if (stored === null) return initialItems.map(text => ({ text, checked: false }));
+
+    return JSON.parse(stored);
 }
 
 function saveToLocalStorage(id, data) {
     localStorage.setItem(`todo-${id}`, JSON.stringify(data));
 }
 
+function currentLocalDate() {
+    const now = new Date();
+    return `${now.getFullYear()}-${now.getMonth() + 1}-${now.getDate()}`;
+}
+
+// Returns true if the list hasn't been reset yet today and marks it as reset
+function claimDailyReset(id) {
+    const key = `todo-${id}-last-reset`;
+    const today = currentLocalDate();
+    if (localStorage.getItem(key) === today) return false;
+
+    localStorage.setItem(key, today);
+    return true;
+}
+
+function msUntilNextMidnight() {
+    const midnight = new Date();
+    midnight.setHours(24, 0, 0, 0);
+    return midnight - Date.now();
+}
+
 function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
-    let item, input, inputArea;
+    let item, checkbox, input, inputArea;
 
     const serializeable = {
         text: unserialize.text || "",
@@ -54,7 +82,7 @@ function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
     };
 
     item = elem().classes("todo-item", "flex", "gap-10", "items-center").append(
-        elem("input")
+        checkbox = elem("input")
             .classes("todo-item-checkbox", "shrink-0")
             .styles({ marginTop: "-0.1rem" })
             .attrs({ type: "checkbox" })
@@ -97,11 +125,12 @@ function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
     input.component.setValue(serializeable.text);
     return item.component({
         focusInput: () => inputArea.focus(),
-        serialize: () => serializeable
+        serialize: () => serializeable,
+        uncheck: () => checkbox.checked = serializeable.checked = false,
     });
 }
 
-function Todo(id) {
+function Todo(id, initialItems, resetAtMidnight) {
     let items, input, inputArea, inputContainer, lastAddedItem;
     let queuedForRemoval = 0;
     let reorderable;
@@ -179,12 +208,33 @@ function Todo(id) {
         }
     };
 
+    let initialData = loadFromLocalStorage(id, initialItems);
+
+    // lists which haven't been stored yet have nothing to reset
+    const resetOnLoad = resetAtMidnight && claimDailyReset(id) && localStorage.getItem(`todo-${id}`) !== null;
+    if (resetOnLoad) initialData = initialData.map(data => ({ ...data, checked: false }));
+
     items = elem()
         .classes("todo-items")
         .append(
-            ...loadFromLocalStorage(id).map(data => newItem(data))
+            ...initialData.map(data => newItem(data))
         );
 
+    if (resetOnLoad) saveItems();
+
+    if (resetAtMidnight) {
+        const resetAndReschedule = () => {
+            if (claimDailyReset(id)) {
+                for (const item of items.children) item.component.uncheck();
+                saveItems();
+            }
+
+            setTimeout(resetAndReschedule, msUntilNextMidnight());
+        };
+
+        setTimeout(resetAndReschedule, msUntilNextMidnight());
+    }
+
     return fragment().append(
         inputContainer = elem()
             .classes("todo-input", "flex", "gap-10", "items-center")
@@ -0,0 +1,81 @@
+// glanceShell is prepended by the server, see pwa.go
+
+const CACHE_PREFIX = "glance-shell:" + self.registration.scope + ":";
+const STATIC_CACHE_NAME = CACHE_PREFIX + glanceShell.version;
+const PAGES_CACHE_NAME = STATIC_CACHE_NAME + ":pages";
+
+self.addEventListener("install", (event) => {
+    event.waitUntil(
+        caches.open(STATIC_CACHE_NAME)
+            .then((cache) => cache.addAll(glanceShell.precache))
+            .then(() => self.skipWaiting())
+    );
+});
+
+self.addEventListener("activate", (event) => {
+    event.waitUntil(
+        caches.keys()
+            .then((keys) => Promise.all(keys
+                .filter((key) => key.startsWith(CACHE_PREFIX) && key !== STATIC_CACHE_NAME && key !== PAGES_CACHE_NAME)
+                .map((key) => caches.delete(key))
+            ))
+            .then(() => self.clients.claim())
+    );
+});
+
+self.addEventListener("fetch", (event) => {
+    const request = event.request;
+    if (request.method !== "GET") return;
+
+    const url = new URL(request.url);
+    if (url.origin !== self.location.origin) return;
+
+    // the path of static assets changes along with their contents
+    if (url.pathname.startsWith(glanceShell.staticPrefix)) {
+        event.respondWith(cacheFirst(request));
+        return;
+    }
+
+    // everything else, including the API which the content of the
+    // widgets comes from, isn't touched and always goes to the server
+    if (request.mode !== "navigate") return;
+
+    if (url.pathname.endsWith("/logout")) {
+        event.waitUntil(caches.delete(PAGES_CACHE_NAME));
+        return;
+    }
+
+    event.respondWith(networkFirst(request));
+});
+
+async function cacheFirst(request) {
+    const cached = await caches.match(request, { cacheName: STATIC_CACHE_NAME });
+    if (cached) return cached;
+
+    const response = await fetch(request);
+    if (response.ok) {
+        const cache = await caches.open(STATIC_CACHE_NAME);
+        await cache.put(request, response.clone());
+    }
+
+    return response;
+}
+
+async function networkFirst(request) {
+    try {
+        const response = await fetch(request);
+
+        // redirects are left out so that the login page
+        // doesn't get cached in place of a page
+        if (response.ok && !response.redirected) {
+            const cache = await caches.open(PAGES_CACHE_NAME);
+            await cache.put(request, response.clone());
+        }
+
+        return response;
+    } catch (error) {
+        const cached = await caches.match(request, { cacheName: PAGES_CACHE_NAME, ignoreSearch: true });
+        if (cached) return cached;
+        throw error;
+    }
+}
@@ -5,18 +5,23 @@ import (
 	"html/template"
 	"net/http"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	themeStyleTemplate         = mustParseTemplate("theme-style.gotmpl")
-	themePresetPreviewTemplate = mustParseTemplate("theme-preset-preview.html")
+	themeStyleTemplate             = mustParseTemplate("theme-style.gotmpl")
+	themePresetPreviewTemplate     = mustParseTemplate("theme-preset-preview.html")
+	themeAutoPresetPreviewTemplate = mustParseTemplate("theme-auto-preset-preview.html")
 )
 
+const themeAutoKey = "auto"
+
 func (a *application) handleThemeChangeRequest(w http.ResponseWriter, r *http.Request) {
 	themeKey := r.PathValue("key")
 
 	properties, exists := a.Config.Theme.Presets.Get(themeKey)
-	if !exists && themeKey != "default" {
+	if !exists && themeKey != "default" && themeKey != themeAutoKey {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -25,19 +30,65 @@ func (a *application) handleThemeChangeRequest(w http.ResponseWriter, r *http.Re
 		properties = &a.Config.Theme.themeProperties
 	}
 
+	if themeKey == themeAutoKey && a.Config.Theme.AutoProperties != nil {
+		properties = a.Config.Theme.AutoProperties
+	} else if themeKey == themeAutoKey {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:     "theme",
 		Value:    themeKey,
-		Path:     a.Config.Server.BaseURL + "/",
+		Path:     a.rootBaseURL() + "/",
 		SameSite: http.SameSiteLaxMode,
 		Expires:  time.Now().Add(2 * 365 * 24 * time.Hour),
 	})
 
 	w.Header().Set("Content-Type", "text/css")
-	w.Header().Set("X-Scheme", ternary(properties.Light, "light", "dark"))
+	if themeKey == themeAutoKey {
+		w.Header().Set("X-Scheme", themeAutoKey)
+	} else {
+		w.Header().Set("X-Scheme", ternary(properties.Light, "light", "dark"))
+	}
 	w.Write([]byte(properties.CSS))
 }
 
+// The CSS of a theme which can be picked, all of them are included in pages
+// so that switching between them doesn't have to wait for the server
+type themeStyle struct {
+	CSS    string `json:"css"`
+	Scheme string `json:"scheme"`
+}
+
+func (a *application) initThemeStyles() {
+	theme := &a.Config.Theme
+	a.themeStyles = make(map[string]themeStyle)
+
+	a.themeStyles["default"] = themeStyle{
+		CSS:    string(theme.CSS),
+		Scheme: ternary(theme.Light, "light", "dark"),
+	}
+
+	for key, properties := range theme.Presets.Items() {
+		a.themeStyles[key] = themeStyle{
+			CSS:    string(properties.CSS),
+			Scheme: ternary(properties.Light, "light", "dark"),
+		}
+	}
+
+	if theme.AutoProperties != nil {
+		a.themeStyles[themeAutoKey] = themeStyle{
+			CSS:    string(theme.AutoProperties.CSS),
+			Scheme: themeAutoKey,
+		}
+	}
+}
+
+func (a *application) ThemeStyles() map[string]themeStyle {
+	return a.themeStyles
+}
+
 type themeProperties struct {
 	BackgroundColor          *hslColorField `yaml:"background-color"`
 	PrimaryColor             *hslColorField `yaml:"primary-color"`
@@ -51,6 +102,68 @@ type themeProperties struct {
 	CSS                  template.CSS  `yaml:"-"`
 	PreviewHTML          template.HTML `yaml:"-"`
 	BackgroundColorAsHex string        `yaml:"-"`
+	// only set for the auto theme
+	LightBackgroundColorAsHex string `yaml:"-"`
+}
+
+func newDefaultLightTheme() *themeProperties {
+	return &themeProperties{
+		Light:                    true,
+		BackgroundColor:          &hslColorField{240, 13, 95},
+		PrimaryColor:             &hslColorField{230, 100, 30},
+		NegativeColor:            &hslColorField{0, 70, 50},
+		ContrastMultiplier:       1.3,
+		TextSaturationMultiplier: 0.5,
+	}
+}
+
+// Either `auto: true`, which follows the system's preference between the
+// default-light preset and the default theme, or an object specifying which
+// presets to use for each scheme
+type themeAutoField struct {
+	Enabled bool   `yaml:"-"`
+	Light   string `yaml:"light"`
+	Dark    string `yaml:"dark"`
+}
+
+func (f *themeAutoField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&f.Enabled)
+	}
+
+	type alias themeAutoField
+	if err := node.Decode((*alias)(f)); err != nil {
+		return err
+	}
+
+	f.Enabled = true
+	return nil
+}
+
+// Combines two themes into one that switches between them based on the
+// prefers-color-scheme media query, the data-scheme attribute which some
+// styles depend on is kept in sync through JS within document.html
+func newAutoTheme(light, dark *themeProperties) (*themeProperties, error) {
+	t := &themeProperties{
+		Key:                       themeAutoKey,
+		BackgroundColorAsHex:      dark.BackgroundColorAsHex,
+		LightBackgroundColorAsHex: light.BackgroundColorAsHex,
+		CSS: template.CSS(
+			"@media (prefers-color-scheme: light) {" + string(light.CSS) + "}\n" +
+				"@media (prefers-color-scheme: dark) {" + string(dark.CSS) + "}",
+		),
+	}
+
+	previewHTML, err := executeTemplateToString(themeAutoPresetPreviewTemplate, struct {
+		Light *themeProperties
+		Dark  *themeProperties
+	}{light, dark})
+	if err != nil {
+		return nil, fmt.Errorf("compiling auto theme preview: %v", err)
+	}
+	t.PreviewHTML = template.HTML(previewHTML)
+
+	return t, nil
 }
 
 func (t *themeProperties) init() error {
@@ -41,6 +41,17 @@ func extractDomainFromUrl(u string) string {
 	return strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
 }
 
+// Subdomains are matched as well, so example.com matches blog.example.com
+func isDomainInList(domain string, list []string) bool {
+	for _, entry := range list {
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func svgPolylineCoordsFromYValues(width float64, height float64, values []float64) string {
 	if len(values) < 2 {
 		return ""
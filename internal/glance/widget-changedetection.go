@@ -1,26 +1,55 @@
 package glance
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
 )
 
 var changeDetectionWidgetTemplate = mustParseTemplate("change-detection.html", "widget-base.html")
 
 type changeDetectionWidget struct {
 	widgetBase       `yaml:",inline"`
-	ChangeDetections changeDetectionWatchList `yaml:"-"`
-	WatchUUIDs       []string                 `yaml:"watches"`
-	InstanceURL      string                   `yaml:"instance-url"`
-	Token            string                   `yaml:"token"`
-	Limit            int                      `yaml:"limit"`
-	CollapseAfter    int                      `yaml:"collapse-after"`
+	ChangeDetections changeDetectionWatchList    `yaml:"-"`
+	WatchUUIDs       []string                    `yaml:"watches"`
+	InstanceURL      string                      `yaml:"instance-url"`
+	Token            string                      `yaml:"token"`
+	URLs             []changeDetectionURLRequest `yaml:"urls"`
+	Limit            int                         `yaml:"limit"`
+	CollapseAfter    int                         `yaml:"collapse-after"`
+
+	// previous state of the URLs watched by the widget itself
+	snapshotsMu sync.Mutex
+	Snapshots   map[string]*changeDetectionSnapshot `yaml:"-"`
+}
+
+type changeDetectionURLRequest struct {
+	URL      string            `yaml:"url"`
+	Title    string            `yaml:"title"`
+	Selector string            `yaml:"selector"`
+	Regex    string            `yaml:"regex"`
+	Headers  map[string]string `yaml:"headers"`
+
+	regex *regexp.Regexp
+}
+
+func (r *changeDetectionURLRequest) key() string {
+	return r.URL + "\n" + r.Selector + "\n" + r.Regex
 }
 
 func (widget *changeDetectionWidget) initialize() error {
@@ -38,10 +67,51 @@ func (widget *changeDetectionWidget) initialize() error {
 		widget.InstanceURL = "https://www.changedetection.io"
 	}
 
+	if len(widget.URLs) > 0 && len(widget.WatchUUIDs) > 0 {
+		return errors.New("urls and watches can not be used together")
+	}
+
+	for i := range widget.URLs {
+		request := &widget.URLs[i]
+
+		if request.URL == "" {
+			return fmt.Errorf("url #%d is missing a url", i+1)
+		}
+
+		if request.Selector != "" {
+			if _, err := cascadia.ParseGroup(request.Selector); err != nil {
+				return fmt.Errorf("parsing selector of %s: %v", request.URL, err)
+			}
+		}
+
+		if request.Regex != "" {
+			regex, err := regexp.Compile(request.Regex)
+			if err != nil {
+				return fmt.Errorf("parsing regex of %s: %v", request.URL, err)
+			}
+			request.regex = regex
+		}
+	}
+
 	return nil
 }
 
 func (widget *changeDetectionWidget) update(ctx context.Context) {
+	if len(widget.URLs) > 0 {
+		watches, err := widget.checkURLsForChanges()
+
+		if !widget.canContinueUpdateAfterHandlingErr(err) {
+			return
+		}
+
+		if len(watches) > widget.Limit {
+			watches = watches[:widget.Limit]
+		}
+
+		widget.ChangeDetections = watches
+		return
+	}
+
 	if len(widget.WatchUUIDs) == 0 {
 		uuids, err := fetchWatchUUIDsFromChangeDetection(widget.InstanceURL, string(widget.Token))
 
@@ -65,6 +135,13 @@ func (widget *changeDetectionWidget) update(ctx context.Context) {
 	widget.ChangeDetections = watches
 }
 
+func (widget *changeDetectionWidget) dataModel() any {
+	return &struct {
+		Watches   *changeDetectionWatchList
+		Snapshots *map[string]*changeDetectionSnapshot
+	}{&widget.ChangeDetections, &widget.Snapshots}
+}
+
 func (widget *changeDetectionWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, changeDetectionWidgetTemplate)
 }
@@ -75,6 +152,9 @@ type changeDetectionWatch struct {
 	LastChanged  time.Time
 	DiffURL      string
 	PreviousHash string
+	// only set for the URLs watched by the widget itself, since
+	// there's no page with the diff to link to
+	DiffSummary string
 }
 
 type changeDetectionWatchList []changeDetectionWatch
@@ -195,3 +275,209 @@ func fetchWatchesFromChangeDetection(instanceURL string, requestedWatchIDs []str
 
 	return watches, nil
 }
+
+// The snapshot text is capped so that huge pages don't take up a large
+// amount of memory, changes past the cap still get detected by the hash
+const changeDetectionMaxSnapshotLength = 64 * 1024
+
+type changeDetectionSnapshot struct {
+	Hash        string
+	Text        string
+	LastChanged time.Time
+	DiffSummary string
+}
+
+func (widget *changeDetectionWidget) checkURLsForChanges() (changeDetectionWatchList, error) {
+	job := newJob(widget.checkURLForChanges, widget.URLs).withWorkers(10)
+	watches, errs, err := workerPoolDo(job)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed int
+	results := make(changeDetectionWatchList, 0, len(watches))
+
+	for i := range watches {
+		if errs[i] != nil {
+			failed++
+			slog.Error("Failed to check URL for changes", "url", widget.URLs[i].URL, "error", errs[i])
+			continue
+		}
+
+		results = append(results, watches[i])
+	}
+
+	if len(results) == 0 {
+		return nil, errNoContent
+	}
+
+	results.sortByNewest()
+
+	if failed > 0 {
+		return results, fmt.Errorf("%w: could not check %d URLs", errPartialContent, failed)
+	}
+
+	return results, nil
+}
+
+func (widget *changeDetectionWidget) checkURLForChanges(request changeDetectionURLRequest) (changeDetectionWatch, error) {
+	text, err := fetchChangeDetectionText(widget.httpClient(false), &request)
+	if err != nil {
+		return changeDetectionWatch{}, err
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	hash := hex.EncodeToString(sum[:])
+
+	if len(text) > changeDetectionMaxSnapshotLength {
+		text = text[:changeDetectionMaxSnapshotLength]
+	}
+
+	widget.snapshotsMu.Lock()
+	if widget.Snapshots == nil {
+		widget.Snapshots = make(map[string]*changeDetectionSnapshot)
+	}
+
+	snapshot, exists := widget.Snapshots[request.key()]
+	if !exists {
+		snapshot = &changeDetectionSnapshot{
+			Hash:        hash,
+			Text:        text,
+			LastChanged: time.Now(),
+			DiffSummary: "no changes yet",
+		}
+		widget.Snapshots[request.key()] = snapshot
+	} else if snapshot.Hash != hash {
+		snapshot.DiffSummary = summarizeChangeDetectionDiff(snapshot.Text, text)
+		snapshot.Hash = hash
+		snapshot.Text = text
+		snapshot.LastChanged = time.Now()
+	}
+	watch := changeDetectionWatch{
+		Title:        request.Title,
+		URL:          request.URL,
+		LastChanged:  snapshot.LastChanged,
+		PreviousHash: snapshot.Hash[:8],
+		DiffSummary:  snapshot.DiffSummary,
+	}
+	widget.snapshotsMu.Unlock()
+
+	if watch.Title == "" {
+		watch.Title = strings.TrimPrefix(strings.Trim(stripURLScheme(request.URL), "/"), "www.")
+	}
+
+	return watch, nil
+}
+
+var changeDetectionWhitespacePattern = regexp.MustCompile(`[^\S\n]+`)
+
+func fetchChangeDetectionText(client requestDoer, request *changeDetectionURLRequest) (string, error) {
+	httpRequest, err := http.NewRequest("GET", request.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	setBrowserUserAgentHeader(httpRequest)
+	for key, value := range request.Headers {
+		httpRequest.Header.Set(key, value)
+	}
+
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", response.StatusCode, request.URL)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	contentType := response.Header.Get("Content-Type")
+
+	if strings.Contains(contentType, "html") || request.Selector != "" {
+		document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			return "", fmt.Errorf("parsing HTML: %v", err)
+		}
+
+		// scripts and styles often contain values which change on every
+		// request and aren't part of what's shown on the page anyway
+		document.Find("script, style, noscript").Remove()
+
+		selection := document.Find("body")
+		if request.Selector != "" {
+			selection = document.Find(request.Selector)
+			if selection.Length() == 0 {
+				return "", fmt.Errorf("selector %s did not match anything", request.Selector)
+			}
+		}
+
+		parts := make([]string, 0, selection.Length())
+		selection.Each(func(_ int, s *goquery.Selection) {
+			parts = append(parts, s.Text())
+		})
+		text = strings.Join(parts, "\n")
+	} else {
+		text = string(body)
+	}
+
+	lines := strings.Split(text, "\n")
+	normalized := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(changeDetectionWhitespacePattern.ReplaceAllString(line, " "))
+		if line != "" {
+			normalized = append(normalized, line)
+		}
+	}
+	text = strings.Join(normalized, "\n")
+
+	if request.regex != nil {
+		matches := request.regex.FindAllStringSubmatch(text, -1)
+		if len(matches) == 0 {
+			return "", fmt.Errorf("regex %s did not match anything", request.Regex)
+		}
+
+		parts := make([]string, 0, len(matches))
+		for _, match := range matches {
+			// only the first group is used when the regex has any
+			parts = append(parts, match[min(1, len(match)-1)])
+		}
+		text = strings.Join(parts, "\n")
+	}
+
+	return text, nil
+}
+
+// Counts the lines which were added and removed, ignoring the order of lines
+func summarizeChangeDetectionDiff(previous, current string) string {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(previous, "\n") {
+		counts[line]++
+	}
+
+	added := 0
+	for _, line := range strings.Split(current, "\n") {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			added++
+		}
+	}
+
+	removed := 0
+	for _, count := range counts {
+		removed += count
+	}
+
+	if added == 0 && removed == 0 {
+		return "lines reordered"
+	}
+
+	return fmt.Sprintf("+%d -%d lines", added, removed)
+}
@@ -12,11 +12,17 @@ var clockWidgetTemplate = mustParseTemplate("clock.html", "widget-base.html")
 type clockWidget struct {
 	widgetBase `yaml:",inline"`
 	cachedHTML template.HTML `yaml:"-"`
-	HourFormat string        `yaml:"hour-format"`
-	Timezones  []struct {
-		Timezone string `yaml:"timezone"`
-		Label    string `yaml:"label"`
-	} `yaml:"timezones"`
+	HourFormat  string          `yaml:"hour-format"`
+	ShowSeconds bool            `yaml:"show-seconds"`
+	Timezones   []clockTimezone `yaml:"timezones"`
+}
+
+type clockTimezone struct {
+	Timezone string `yaml:"timezone"`
+	Label    string `yaml:"label"`
+	// Defaults to the hour format of the widget
+	HourFormat string `yaml:"hour-format"`
+	ShowDate   bool   `yaml:"show-date"`
 }
 
 func (widget *clockWidget) initialize() error {
@@ -29,12 +35,20 @@ func (widget *clockWidget) initialize() error {
 	}
 
 	for t := range widget.Timezones {
-		if widget.Timezones[t].Timezone == "" {
+		timezone := &widget.Timezones[t]
+
+		if timezone.Timezone == "" {
 			return errors.New("missing timezone value")
 		}
 
-		if _, err := time.LoadLocation(widget.Timezones[t].Timezone); err != nil {
-			return fmt.Errorf("invalid timezone '%s': %v", widget.Timezones[t].Timezone, err)
+		if _, err := time.LoadLocation(timezone.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone '%s': %v", timezone.Timezone, err)
+		}
+
+		if timezone.HourFormat == "" {
+			timezone.HourFormat = widget.HourFormat
+		} else if timezone.HourFormat != "12h" && timezone.HourFormat != "24h" {
+			return fmt.Errorf("hour-format of timezone '%s' must be either 12h or 24h", timezone.Timezone)
 		}
 	}
 
@@ -2,6 +2,7 @@ package glance
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 )
@@ -10,8 +11,16 @@ type containerWidgetBase struct {
 	Widgets widgets `yaml:"widgets"`
 }
 
+func (widget *containerWidgetBase) children() widgets {
+	return widget.Widgets
+}
+
 func (widget *containerWidgetBase) _initializeWidgets() error {
 	for i := range widget.Widgets {
+		if widget.Widgets[i].hasVisibilityCondition() {
+			return formatWidgetInitError(errors.New("visible-when can't be used on widgets within a container, set it on the container instead"), widget.Widgets[i])
+		}
+
 		if err := widget.Widgets[i].initialize(); err != nil {
 			return formatWidgetInitError(err, widget.Widgets[i])
 		}
@@ -28,13 +37,14 @@ func (widget *containerWidgetBase) _update(ctx context.Context) {
 		widget := widget.Widgets[w]
 
 		if !widget.requiresUpdate(&now) {
+			recordWidgetCacheHit(ctx, widget)
 			continue
 		}
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			widget.update(ctx)
+			updateWidget(ctx, widget)
 		}()
 	}
 
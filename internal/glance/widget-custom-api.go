@@ -33,6 +33,7 @@ type CustomAPIRequest struct {
 	BodyType           string               `yaml:"body-type"`
 	Body               any                  `yaml:"body"`
 	SkipJSONValidation bool                 `yaml:"skip-json-validation"`
+	clientOptions      *httpClientOptions   `yaml:"-"`
 	bodyReader         io.ReadSeeker        `yaml:"-"`
 	httpRequest        *http.Request        `yaml:"-"`
 }
@@ -54,11 +55,13 @@ func (widget *customAPIWidget) initialize() error {
 	if err := widget.CustomAPIRequest.initialize(); err != nil {
 		return fmt.Errorf("initializing primary request: %v", err)
 	}
+	widget.CustomAPIRequest.clientOptions = &widget.httpClientOptions
 
 	for key := range widget.Subrequests {
 		if err := widget.Subrequests[key].initialize(); err != nil {
 			return fmt.Errorf("initializing subrequest %q: %v", key, err)
 		}
+		widget.Subrequests[key].clientOptions = &widget.httpClientOptions
 	}
 
 	if widget.Template == "" {
@@ -75,6 +78,11 @@ func (widget *customAPIWidget) initialize() error {
 	return nil
 }
 
+func (widget *customAPIWidget) setProviders(providers *widgetProviders) {
+	widget.widgetBase.setProviders(providers)
+	widget.compiledTemplate = localizeCustomTemplate(widget.compiledTemplate, widget.formatter())
+}
+
 func (widget *customAPIWidget) update(ctx context.Context) {
 	compiledHTML, err := fetchAndRenderCustomAPIRequest(
 		widget.CustomAPIRequest, widget.Subrequests, widget.Options, widget.compiledTemplate,
@@ -136,11 +144,17 @@ func (req *CustomAPIRequest) initialize() error {
 		return nil
 	}
 
+	req.Method = strings.ToUpper(req.Method)
+
 	if req.Body != nil {
 		if req.Method == "" {
 			req.Method = http.MethodPost
 		}
 
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			return fmt.Errorf("body cannot be sent with a %s request", req.Method)
+		}
+
 		if req.BodyType == "" {
 			req.BodyType = "json"
 		}
@@ -170,7 +184,7 @@ func (req *CustomAPIRequest) initialize() error {
 		req.Method = http.MethodGet
 	}
 
-	httpReq, err := http.NewRequest(strings.ToUpper(req.Method), req.URL, req.bodyReader)
+	httpReq, err := http.NewRequest(req.Method, req.URL, req.bodyReader)
 	if err != nil {
 		return err
 	}
@@ -240,7 +254,7 @@ func fetchCustomAPIResponse(ctx context.Context, req *CustomAPIRequest) (*custom
 		req.bodyReader.Seek(0, io.SeekStart)
 	}
 
-	client := ternary(req.AllowInsecure, defaultInsecureHTTPClient, defaultHTTPClient)
+	client := req.clientOptions.httpClient(req.AllowInsecure)
 	resp, err := client.Do(req.httpRequest.WithContext(ctx))
 	if err != nil {
 		return nil, err
@@ -415,6 +429,145 @@ func (r *decoratedGJSONResult) Get(key string) *decoratedGJSONResult {
 	return &decoratedGJSONResult{r.Result.Get(key)}
 }
 
+// Evaluates a JSONPath-style expression such as $.data.items[0].name against
+// the result. Wildcards ([*] or .*) collect every match into an array. An
+// invalid path evaluates to a string containing the error so that it shows up
+// where the value would have been rendered instead of failing the widget.
+func customAPIJSONPath(path string, r decoratedGJSONResult) *decoratedGJSONResult {
+	segments, err := parseCustomAPIJSONPath(path)
+	if err != nil {
+		message := fmt.Sprintf("invalid path %q: %v", path, err)
+		return &decoratedGJSONResult{gjson.Result{Type: gjson.String, Str: message, Raw: strconv.Quote(message)}}
+	}
+
+	current := []gjson.Result{r.Result}
+	hasWildcard := false
+
+	for _, segment := range segments {
+		next := make([]gjson.Result, 0, len(current))
+
+		for i := range current {
+			switch {
+			case segment.wildcard:
+				current[i].ForEach(func(_, value gjson.Result) bool {
+					next = append(next, value)
+					return true
+				})
+			case segment.isIndex:
+				if !current[i].IsArray() {
+					continue
+				}
+
+				items := current[i].Array()
+				index := ternary(segment.index < 0, len(items)+segment.index, segment.index)
+				if index >= 0 && index < len(items) {
+					next = append(next, items[index])
+				}
+			default:
+				if !current[i].IsObject() {
+					continue
+				}
+
+				if value, exists := current[i].Map()[segment.key]; exists {
+					next = append(next, value)
+				}
+			}
+		}
+
+		if segment.wildcard {
+			hasWildcard = true
+		}
+
+		current = next
+	}
+
+	if !hasWildcard {
+		if len(current) == 0 {
+			return &decoratedGJSONResult{gjson.Result{}}
+		}
+
+		return &decoratedGJSONResult{current[0]}
+	}
+
+	raw := make([]string, len(current))
+	for i := range current {
+		raw[i] = current[i].Raw
+	}
+
+	return &decoratedGJSONResult{gjson.Parse("[" + strings.Join(raw, ",") + "]")}
+}
+
+type customAPIJSONPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseCustomAPIJSONPath(path string) ([]customAPIJSONPathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	segments := make([]customAPIJSONPathSegment, 0, 4)
+
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+
+			key := path[:end]
+			if key == "" {
+				return nil, errors.New("empty key")
+			}
+
+			path = path[end:]
+			if key == "*" {
+				segments = append(segments, customAPIJSONPathSegment{wildcard: true})
+			} else {
+				segments = append(segments, customAPIJSONPathSegment{key: key})
+			}
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, errors.New("missing closing bracket")
+			}
+
+			inner := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+
+			if inner == "*" {
+				segments = append(segments, customAPIJSONPathSegment{wildcard: true})
+				continue
+			}
+
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, customAPIJSONPathSegment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", inner)
+			}
+
+			segments = append(segments, customAPIJSONPathSegment{index: index, isIndex: true})
+		default:
+			if len(segments) == 0 {
+				// allow omitting the leading dot, e.g. data.items[0]
+				path = "." + path
+				continue
+			}
+
+			return nil, fmt.Errorf("unexpected character %q", path[0])
+		}
+	}
+
+	return segments, nil
+}
+
 func customAPIDoMathOp[T int | float64](a, b T, op string) T {
 	switch op {
 	case "add":
@@ -635,6 +788,16 @@ var customAPITemplateFuncs = func() template.FuncMap {
 			}
 			return out
 		},
+		"jsonPath": customAPIJSONPath,
+		"pluck": func(key string, results []decoratedGJSONResult) []string {
+			out := make([]string, 0, len(results))
+			for _, result := range results {
+				if value := result.Get(key); value.Result.Exists() {
+					out = append(out, value.String(""))
+				}
+			}
+			return out
+		},
 		"newRequest": func(url string) *CustomAPIRequest {
 			return &CustomAPIRequest{
 				URL: url,
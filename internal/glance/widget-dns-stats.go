@@ -10,7 +10,9 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,16 +32,19 @@ type dnsStatsWidget struct {
 	TimeLabels      [8]string `yaml:"-"`
 	Stats           *dnsStats `yaml:"-"`
 	piholeSessionID string    `yaml:"-"`
-
-	HourFormat     string `yaml:"hour-format"`
-	HideGraph      bool   `yaml:"hide-graph"`
-	HideTopDomains bool   `yaml:"hide-top-domains"`
-	Service        string `yaml:"service"`
-	AllowInsecure  bool   `yaml:"allow-insecure"`
-	URL            string `yaml:"url"`
-	Token          string `yaml:"token"`
-	Username       string `yaml:"username"`
-	Password       string `yaml:"password"`
+	// only used when authenticating with a username and password
+	technitiumSessionToken string `yaml:"-"`
+
+	HourFormat      string `yaml:"hour-format"`
+	HideGraph       bool   `yaml:"hide-graph"`
+	HideTopDomains  bool   `yaml:"hide-top-domains"`
+	TopDomainsLimit int    `yaml:"top-domains-limit"`
+	Service         string `yaml:"service"`
+	AllowInsecure   bool   `yaml:"allow-insecure"`
+	URL             string `yaml:"url"`
+	Token           string `yaml:"token"`
+	Username        string `yaml:"username"`
+	Password        string `yaml:"password"`
 }
 
 const (
@@ -47,6 +52,7 @@ const (
 	dnsServicePihole     = "pihole"
 	dnsServiceTechnitium = "technitium"
 	dnsServicePiholeV6   = "pihole-v6"
+	dnsServiceBlocky     = "blocky"
 )
 
 func makeDNSWidgetTimeLabels(format string) [8]string {
@@ -72,13 +78,27 @@ func (widget *dnsStatsWidget) initialize() error {
 		withTitleURL(titleURL).
 		withCacheDuration(10 * time.Minute)
 
+	if widget.TopDomainsLimit <= 0 {
+		widget.TopDomainsLimit = 5
+	}
+
 	switch widget.Service {
 	case dnsServiceAdguard:
 	case dnsServicePiholeV6:
 	case dnsServicePihole:
 	case dnsServiceTechnitium:
+		if widget.Token == "" && (widget.Username == "" || widget.Password == "") {
+			return errors.New("either token or username and password are required for technitium")
+		}
+	case dnsServiceBlocky:
+		// blocky only exposes counters through its prometheus
+		// metrics, so there's nothing to plot a graph with
+		widget.HideGraph = true
 	default:
-		return fmt.Errorf("service must be one of: %s, %s, %s, %s", dnsServiceAdguard, dnsServicePihole, dnsServicePiholeV6, dnsServiceTechnitium)
+		return fmt.Errorf(
+			"service must be one of: %s, %s, %s, %s, %s",
+			dnsServiceAdguard, dnsServicePihole, dnsServicePiholeV6, dnsServiceTechnitium, dnsServiceBlocky,
+		)
 	}
 
 	return nil
@@ -90,11 +110,26 @@ func (widget *dnsStatsWidget) update(ctx context.Context) {
 
 	switch widget.Service {
 	case dnsServiceAdguard:
-		stats, err = fetchAdguardStats(widget.URL, widget.AllowInsecure, widget.Username, widget.Password, widget.HideGraph)
+		stats, err = fetchAdguardStats(widget.URL, widget.AllowInsecure, widget.Username, widget.Password, widget.HideGraph, widget.TopDomainsLimit)
 	case dnsServicePihole:
-		stats, err = fetchPihole5Stats(widget.URL, widget.AllowInsecure, widget.Token, widget.HideGraph)
+		stats, err = fetchPihole5Stats(widget.URL, widget.AllowInsecure, widget.Token, widget.HideGraph, widget.TopDomainsLimit)
 	case dnsServiceTechnitium:
-		stats, err = fetchTechnitiumStats(widget.URL, widget.AllowInsecure, widget.Token, widget.HideGraph)
+		var newSessionToken string
+		stats, newSessionToken, err = fetchTechnitiumStats(
+			widget.URL,
+			widget.AllowInsecure,
+			widget.Token,
+			widget.Username,
+			widget.Password,
+			widget.technitiumSessionToken,
+			widget.HideGraph,
+			widget.TopDomainsLimit,
+		)
+		if err == nil {
+			widget.technitiumSessionToken = newSessionToken
+		}
+	case dnsServiceBlocky:
+		stats, err = fetchBlockyStats(widget.URL, widget.AllowInsecure, widget.Token)
 	case dnsServicePiholeV6:
 		var newSessionID string
 		stats, newSessionID, err = fetchPiholeStats(
@@ -104,6 +139,7 @@ func (widget *dnsStatsWidget) update(ctx context.Context) {
 			widget.piholeSessionID,
 			!widget.HideGraph,
 			!widget.HideTopDomains,
+			widget.TopDomainsLimit,
 		)
 		if err == nil {
 			widget.piholeSessionID = newSessionID
@@ -158,7 +194,7 @@ type adguardStatsResponse struct {
 	TopBlockedDomains []map[string]int `json:"top_blocked_domains"`
 }
 
-func fetchAdguardStats(instanceURL string, allowInsecure bool, username, password string, noGraph bool) (*dnsStats, error) {
+func fetchAdguardStats(instanceURL string, allowInsecure bool, username, password string, noGraph bool, topDomainsLimit int) (*dnsStats, error) {
 	requestURL := strings.TrimRight(instanceURL, "/") + "/control/stats"
 
 	request, err := http.NewRequest("GET", requestURL, nil)
@@ -174,7 +210,7 @@ func fetchAdguardStats(instanceURL string, allowInsecure bool, username, passwor
 		return nil, err
 	}
 
-	var topBlockedDomainsCount = min(len(responseJson.TopBlockedDomains), 5)
+	var topBlockedDomainsCount = min(len(responseJson.TopBlockedDomains), topDomainsLimit)
 
 	stats := &dnsStats{
 		TotalQueries:      responseJson.TotalQueries,
@@ -311,13 +347,13 @@ func (p *pihole5TopBlockedDomains) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func fetchPihole5Stats(instanceURL string, allowInsecure bool, token string, noGraph bool) (*dnsStats, error) {
+func fetchPihole5Stats(instanceURL string, allowInsecure bool, token string, noGraph bool, topDomainsLimit int) (*dnsStats, error) {
 	if token == "" {
 		return nil, errors.New("missing API token")
 	}
 
 	requestURL := strings.TrimRight(instanceURL, "/") +
-		"/admin/api.php?summaryRaw&topItems&overTimeData10mins&auth=" + token
+		"/admin/api.php?summaryRaw&topItems=" + strconv.Itoa(topDomainsLimit) + "&overTimeData10mins&auth=" + token
 
 	request, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
@@ -351,7 +387,7 @@ func fetchPihole5Stats(instanceURL string, allowInsecure bool, token string, noG
 			return domains[a].PercentBlocked > domains[b].PercentBlocked
 		})
 
-		stats.TopBlockedDomains = domains[:min(len(domains), 5)]
+		stats.TopBlockedDomains = domains[:min(len(domains), topDomainsLimit)]
 	}
 
 	if noGraph {
@@ -416,6 +452,7 @@ func fetchPiholeStats(
 	sessionID string,
 	includeGraph bool,
 	includeTopDomains bool,
+	topDomainsLimit int,
 ) (*dnsStats, string, error) {
 	instanceURL = strings.TrimRight(instanceURL, "/")
 	var client = ternary(allowInsecure, defaultInsecureHTTPClient, defaultHTTPClient)
@@ -515,7 +552,7 @@ func fetchPiholeStats(
 	var topDomainsErr error
 
 	if includeTopDomains {
-		topDomainsRequest, _ := http.NewRequestWithContext(ctx, "GET", instanceURL+"/api/stats/top_domains?blocked=true", nil)
+		topDomainsRequest, _ := http.NewRequestWithContext(ctx, "GET", instanceURL+"/api/stats/top_domains?blocked=true&count="+strconv.Itoa(topDomainsLimit), nil)
 		topDomainsRequest.Header.Set("x-ftl-sid", sessionID)
 
 		wg.Add(1)
@@ -609,7 +646,7 @@ func fetchPiholeStats(
 		sort.Slice(domains, func(a, b int) bool {
 			return domains[a].PercentBlocked > domains[b].PercentBlocked
 		})
-		stats.TopBlockedDomains = domains[:min(len(domains), 5)]
+		stats.TopBlockedDomains = domains[:min(len(domains), topDomainsLimit)]
 	}
 
 	return stats, sessionID, ternary(partialContent, errPartialContent, nil)
@@ -684,7 +721,9 @@ func checkPiholeSessionIDIsValid(instanceURL string, client *http.Client, sessio
 }
 
 type technitiumStatsResponse struct {
-	Response struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"errorMessage"`
+	Response     struct {
 		Stats struct {
 			TotalQueries   int `json:"totalQueries"`
 			BlockedQueries int `json:"totalBlocked"`
@@ -704,31 +743,67 @@ type technitiumStatsResponse struct {
 	} `json:"response"`
 }
 
-func fetchTechnitiumStats(instanceUrl string, allowInsecure bool, token string, noGraph bool) (*dnsStats, error) {
-	if token == "" {
-		return nil, errors.New("missing API token")
+// A static API token is used when provided, otherwise a session token is
+// obtained by logging in with the username and password and reused until
+// the server reports it as no longer valid
+func fetchTechnitiumStats(
+	instanceUrl string,
+	allowInsecure bool,
+	token string,
+	username string,
+	password string,
+	sessionToken string,
+	noGraph bool,
+	topDomainsLimit int,
+) (*dnsStats, string, error) {
+	instanceUrl = strings.TrimRight(instanceUrl, "/")
+	var client = ternary(allowInsecure, defaultInsecureHTTPClient, defaultHTTPClient)
+
+	usingSession := token == ""
+	if usingSession && sessionToken == "" {
+		newSessionToken, err := fetchTechnitiumSessionToken(instanceUrl, client, username, password)
+		if err != nil {
+			return nil, "", fmt.Errorf("logging in: %v", err)
+		}
+		sessionToken = newSessionToken
 	}
 
-	requestURL := strings.TrimRight(instanceUrl, "/") + "/api/dashboard/stats/get?token=" + token + "&type=LastDay"
+	fetchStats := func() (technitiumStatsResponse, error) {
+		request, err := http.NewRequest(
+			"GET",
+			instanceUrl+"/api/dashboard/stats/get?type=LastDay&token="+url.QueryEscape(ternary(usingSession, sessionToken, token)),
+			nil,
+		)
+		if err != nil {
+			return technitiumStatsResponse{}, err
+		}
+
+		return decodeJsonFromRequest[technitiumStatsResponse](client, request)
+	}
 
-	request, err := http.NewRequest("GET", requestURL, nil)
+	responseJson, err := fetchStats()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	var client requestDoer
-	if !allowInsecure {
-		client = defaultHTTPClient
-	} else {
-		client = defaultInsecureHTTPClient
+	if responseJson.Status == "invalid-token" && usingSession {
+		newSessionToken, err := fetchTechnitiumSessionToken(instanceUrl, client, username, password)
+		if err != nil {
+			return nil, "", fmt.Errorf("renewing session: %v", err)
+		}
+		sessionToken = newSessionToken
+
+		responseJson, err = fetchStats()
+		if err != nil {
+			return nil, "", err
+		}
 	}
 
-	responseJson, err := decodeJsonFromRequest[technitiumStatsResponse](client, request)
-	if err != nil {
-		return nil, err
+	if responseJson.Status != "" && responseJson.Status != "ok" {
+		return nil, "", fmt.Errorf("stats request returned status %s: %s", responseJson.Status, responseJson.ErrorMessage)
 	}
 
-	var topBlockedDomainsCount = min(len(responseJson.Response.TopBlockedDomains), 5)
+	var topBlockedDomainsCount = min(len(responseJson.Response.TopBlockedDomains), topDomainsLimit)
 
 	stats := &dnsStats{
 		TotalQueries:      responseJson.Response.Stats.TotalQueries,
@@ -738,7 +813,7 @@ func fetchTechnitiumStats(instanceUrl string, allowInsecure bool, token string,
 	}
 
 	if stats.TotalQueries <= 0 {
-		return stats, nil
+		return stats, sessionToken, nil
 	}
 
 	stats.BlockedPercent = int(float64(responseJson.Response.Stats.BlockedQueries) / float64(responseJson.Response.Stats.TotalQueries) * 100)
@@ -751,17 +826,19 @@ func fetchTechnitiumStats(instanceUrl string, allowInsecure bool, token string,
 			continue
 		}
 
-		stats.TopBlockedDomains = append(stats.TopBlockedDomains, dnsStatsBlockedDomain{
+		blockedDomain := dnsStatsBlockedDomain{
 			Domain: firstDomain,
-		})
+		}
 
 		if stats.BlockedQueries > 0 {
-			stats.TopBlockedDomains[i].PercentBlocked = int(float64(domain.Count) / float64(responseJson.Response.Stats.BlockedQueries) * 100)
+			blockedDomain.PercentBlocked = int(float64(domain.Count) / float64(responseJson.Response.Stats.BlockedQueries) * 100)
 		}
+
+		stats.TopBlockedDomains = append(stats.TopBlockedDomains, blockedDomain)
 	}
 
 	if noGraph {
-		return stats, nil
+		return stats, sessionToken, nil
 	}
 
 	var queriesSeries, blockedSeries []int
@@ -816,5 +893,152 @@ func fetchTechnitiumStats(instanceUrl string, allowInsecure bool, token string,
 		stats.Series[i].PercentTotal = int(float64(stats.Series[i].Queries) / float64(maxQueriesInSeries) * 100)
 	}
 
+	return stats, sessionToken, nil
+}
+
+func fetchTechnitiumSessionToken(instanceURL string, client *http.Client, username, password string) (string, error) {
+	form := url.Values{}
+	form.Set("user", username)
+	form.Set("pass", password)
+	form.Set("includeInfo", "false")
+
+	request, err := http.NewRequest("POST", instanceURL+"/api/user/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("creating login request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	type loginResponseJson struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"errorMessage"`
+		Token        string `json:"token"`
+	}
+
+	response, err := decodeJsonFromRequest[loginResponseJson](client, request)
+	if err != nil {
+		return "", err
+	}
+
+	if response.Status != "ok" || response.Token == "" {
+		return "", fmt.Errorf("login request returned status %s: %s", response.Status, response.ErrorMessage)
+	}
+
+	return response.Token, nil
+}
+
+// Blocky doesn't have an API for statistics, only prometheus metrics which
+// count from when it was last started rather than over the past 24 hours
+// and note neither the time nor the domain of queries
+func fetchBlockyStats(instanceURL string, allowInsecure bool, token string) (*dnsStats, error) {
+	request, err := http.NewRequest("GET", strings.TrimRight(instanceURL, "/")+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	var client = ternary(allowInsecure, defaultInsecureHTTPClient, defaultHTTPClient)
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", response.StatusCode, request.URL)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var total, blocked, domains float64
+	foundQueries := false
+
+	for _, line := range strings.Split(string(body), "\n") {
+		name, labels, value, ok := parseBlockyMetricLine(line)
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "blocky_query_total":
+			total += value
+			foundQueries = true
+		case "blocky_response_total":
+			if labels["response_type"] == "BLOCKED" {
+				blocked += value
+			}
+		// renamed from blacklist in v0.24
+		case "blocky_denylist_cache_entries", "blocky_blacklist_cache":
+			domains += value
+		}
+	}
+
+	if !foundQueries {
+		return nil, errors.New("no query metrics found, make sure prometheus is enabled in blocky's config")
+	}
+
+	stats := &dnsStats{
+		TotalQueries:   int(total),
+		BlockedQueries: int(blocked),
+		DomainsBlocked: int(domains),
+	}
+
+	if total > 0 {
+		stats.BlockedPercent = int(blocked / total * 100)
+	}
+
 	return stats, nil
 }
+
+// Only handles what blocky outputs, samples look like:
+// blocky_response_total{reason="BLOCKED (ads)",response_code="NOERROR",response_type="BLOCKED"} 12
+func parseBlockyMetricLine(line string) (string, map[string]string, float64, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, 0, false
+	}
+
+	var name, rest string
+	labels := make(map[string]string)
+
+	if i := strings.IndexByte(line, '{'); i != -1 {
+		end := strings.LastIndexByte(line, '}')
+		if end < i {
+			return "", nil, 0, false
+		}
+
+		name = line[:i]
+		rest = line[end+1:]
+
+		for _, pair := range strings.Split(line[i+1:end], "\",") {
+			key, value, found := strings.Cut(pair, "=")
+			if !found {
+				continue
+			}
+			labels[strings.TrimLeft(key, ",")] = strings.Trim(value, "\"")
+		}
+	} else {
+		var found bool
+		name, rest, found = strings.Cut(line, " ")
+		if !found {
+			return "", nil, 0, false
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, false
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, false
+	}
+
+	return name, labels, value, true
+}
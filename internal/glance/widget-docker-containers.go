@@ -22,8 +22,12 @@ type dockerContainersWidget struct {
 	Category             string                       `yaml:"category"`
 	SockPath             string                       `yaml:"sock-path"`
 	FormatContainerNames bool                         `yaml:"format-container-names"`
+	GroupByProject       bool                         `yaml:"group-by-project"`
 	Containers           dockerContainerList          `yaml:"-"`
+	Groups               []dockerContainerGroup       `yaml:"-"`
 	LabelOverrides       map[string]map[string]string `yaml:"containers"`
+	CheckUpdates         dockerUpdateCheckOptions     `yaml:"check-updates"`
+	updateChecker        *dockerUpdateChecker         `yaml:"-"`
 }
 
 func (widget *dockerContainersWidget) initialize() error {
@@ -33,12 +37,21 @@ func (widget *dockerContainersWidget) initialize() error {
 		widget.SockPath = "/var/run/docker.sock"
 	}
 
+	if widget.CheckUpdates.Enabled {
+		widget.updateChecker = newDockerUpdateChecker(&widget.CheckUpdates, widget.httpClient(false))
+	}
+
 	return nil
 }
 
 func (widget *dockerContainersWidget) update(ctx context.Context) {
+	docker, err := newDockerClient(widget.SockPath)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
 	containers, err := fetchDockerContainers(
-		widget.SockPath,
+		docker,
 		widget.HideByDefault,
 		widget.Category,
 		widget.RunningOnly,
@@ -49,8 +62,16 @@ func (widget *dockerContainersWidget) update(ctx context.Context) {
 		return
 	}
 
+	if widget.updateChecker != nil {
+		widget.updateChecker.markUpdates(ctx, docker, containers)
+	}
+
 	containers.sortByStateIconThenTitle()
 	widget.Containers = containers
+
+	if widget.GroupByProject {
+		widget.Groups = containers.groupByProject()
+	}
 }
 
 func (widget *dockerContainersWidget) Render() template.HTML {
@@ -67,28 +88,65 @@ const (
 	dockerContainerLabelID          = "glance.id"
 	dockerContainerLabelParent      = "glance.parent"
 	dockerContainerLabelCategory    = "glance.category"
+
+	dockerContainerLabelComposeProject = "com.docker.compose.project"
 )
 
 const (
-	dockerContainerStateIconOK     = "ok"
-	dockerContainerStateIconPaused = "paused"
-	dockerContainerStateIconWarn   = "warn"
-	dockerContainerStateIconOther  = "other"
+	dockerContainerStateIconOK        = "ok"
+	dockerContainerStateIconPaused    = "paused"
+	dockerContainerStateIconWarn      = "warn"
+	dockerContainerStateIconUnhealthy = "unhealthy"
+	dockerContainerStateIconOther     = "other"
 )
 
 var dockerContainerStateIconPriorities = map[string]int{
-	dockerContainerStateIconWarn:   0,
-	dockerContainerStateIconOther:  1,
-	dockerContainerStateIconPaused: 2,
-	dockerContainerStateIconOK:     3,
+	dockerContainerStateIconWarn:      0,
+	dockerContainerStateIconUnhealthy: 1,
+	dockerContainerStateIconOther:     2,
+	dockerContainerStateIconPaused:    3,
+	dockerContainerStateIconOK:        4,
 }
 
+const (
+	dockerContainerHealthHealthy   = "healthy"
+	dockerContainerHealthUnhealthy = "unhealthy"
+	dockerContainerHealthStarting  = "starting"
+)
+
 type dockerContainerJsonResponse struct {
-	Names  []string              `json:"Names"`
-	Image  string                `json:"Image"`
-	State  string                `json:"State"`
-	Status string                `json:"Status"`
-	Labels dockerContainerLabels `json:"Labels"`
+	Names   []string              `json:"Names"`
+	Image   string                `json:"Image"`
+	ImageID string                `json:"ImageID"`
+	State   string                `json:"State"`
+	Status  string                `json:"Status"`
+	Labels  dockerContainerLabels `json:"Labels"`
+	// Only included in the list response by newer versions of the API,
+	// otherwise the same value has to be derived from Status
+	Health *struct {
+		Status string `json:"Status"`
+	} `json:"Health"`
+}
+
+// Equivalent to State.Health.Status from the inspect endpoint, which would
+// otherwise require an additional request per container
+func (c *dockerContainerJsonResponse) healthStatus() string {
+	if c.Health != nil && c.Health.Status != "" && c.Health.Status != "none" {
+		return strings.ToLower(c.Health.Status)
+	}
+
+	status := strings.ToLower(c.Status)
+
+	switch {
+	case strings.Contains(status, "(unhealthy)"):
+		return dockerContainerHealthUnhealthy
+	case strings.Contains(status, "(health: starting)"):
+		return dockerContainerHealthStarting
+	case strings.Contains(status, "(healthy)"):
+		return dockerContainerHealthHealthy
+	default:
+		return ""
+	}
 }
 
 type dockerContainerLabels map[string]string
@@ -120,11 +178,61 @@ type dockerContainer struct {
 	StateIcon   string
 	Description string
 	Icon        customIconField
+	Health      string
+	Project     string
 	Children    dockerContainerList
+	// Only set when checking for updates is enabled
+	UpdateAvailable bool
+	imageID         string
 }
 
 type dockerContainerList []dockerContainer
 
+type dockerContainerGroup struct {
+	Name       string
+	StateIcon  string
+	Containers dockerContainerList
+}
+
+// Expects the containers to already be sorted, the order is preserved within
+// each group and groups are sorted by name with ungrouped containers last
+func (containers dockerContainerList) groupByProject() []dockerContainerGroup {
+	groups := make([]dockerContainerGroup, 0, 4)
+	indexes := make(map[string]int)
+	p := &dockerContainerStateIconPriorities
+
+	for i := range containers {
+		container := &containers[i]
+
+		index, exists := indexes[container.Project]
+		if !exists {
+			index = len(groups)
+			indexes[container.Project] = index
+			groups = append(groups, dockerContainerGroup{
+				Name:      container.Project,
+				StateIcon: container.StateIcon,
+			})
+		}
+
+		group := &groups[index]
+		group.Containers = append(group.Containers, *container)
+
+		if (*p)[container.StateIcon] < (*p)[group.StateIcon] {
+			group.StateIcon = container.StateIcon
+		}
+	}
+
+	sort.SliceStable(groups, func(a, b int) bool {
+		if (groups[a].Name == "") != (groups[b].Name == "") {
+			return groups[b].Name == ""
+		}
+
+		return strings.ToLower(groups[a].Name) < strings.ToLower(groups[b].Name)
+	})
+
+	return groups
+}
+
 func (containers dockerContainerList) sortByStateIconThenTitle() {
 	p := &dockerContainerStateIconPriorities
 
@@ -137,7 +245,16 @@ func (containers dockerContainerList) sortByStateIconThenTitle() {
 	})
 }
 
-func dockerContainerStateToStateIcon(state string) string {
+func dockerContainerStateToStateIcon(state, health string) string {
+	if state == "running" {
+		switch health {
+		case dockerContainerHealthUnhealthy:
+			return dockerContainerStateIconUnhealthy
+		case dockerContainerHealthStarting:
+			return dockerContainerStateIconOther
+		}
+	}
+
 	switch state {
 	case "running":
 		return dockerContainerStateIconOK
@@ -151,14 +268,14 @@ func dockerContainerStateToStateIcon(state string) string {
 }
 
 func fetchDockerContainers(
-	socketPath string,
+	docker *dockerClient,
 	hideByDefault bool,
 	category string,
 	runningOnly bool,
 	formatNames bool,
 	labelOverrides map[string]map[string]string,
 ) (dockerContainerList, error) {
-	containers, err := fetchDockerContainersFromSource(socketPath, category, runningOnly, labelOverrides)
+	containers, err := fetchDockerContainersFromSource(docker, category, runningOnly, labelOverrides)
 	if err != nil {
 		return nil, fmt.Errorf("fetching containers: %w", err)
 	}
@@ -175,9 +292,12 @@ func fetchDockerContainers(
 			Description: container.Labels.getOrDefault(dockerContainerLabelDescription, ""),
 			SameTab:     stringToBool(container.Labels.getOrDefault(dockerContainerLabelSameTab, "false")),
 			Image:       container.Image,
+			imageID:     container.ImageID,
 			State:       strings.ToLower(container.State),
 			StateText:   strings.ToLower(container.Status),
 			Icon:        newCustomIconField(container.Labels.getOrDefault(dockerContainerLabelIcon, "si:docker")),
+			Health:      container.healthStatus(),
+			Project:     container.Labels.getOrDefault(dockerContainerLabelComposeProject, ""),
 		}
 
 		if idValue := container.Labels.getOrDefault(dockerContainerLabelID, ""); idValue != "" {
@@ -187,7 +307,7 @@ func fetchDockerContainers(
 					dc.Children = append(dc.Children, dockerContainer{
 						Name:      deriveDockerContainerName(child, formatNames),
 						StateText: child.Status,
-						StateIcon: dockerContainerStateToStateIcon(strings.ToLower(child.State)),
+						StateIcon: dockerContainerStateToStateIcon(strings.ToLower(child.State), child.healthStatus()),
 					})
 				}
 			}
@@ -197,14 +317,15 @@ func fetchDockerContainers(
 
 		stateIconSupersededByChild := false
 		for i := range dc.Children {
-			if dc.Children[i].StateIcon == dockerContainerStateIconWarn {
-				dc.StateIcon = dockerContainerStateIconWarn
+			if dc.Children[i].StateIcon == dockerContainerStateIconWarn ||
+				dc.Children[i].StateIcon == dockerContainerStateIconUnhealthy {
+				dc.StateIcon = dc.Children[i].StateIcon
 				stateIconSupersededByChild = true
 				break
 			}
 		}
 		if !stateIconSupersededByChild {
-			dc.StateIcon = dockerContainerStateToStateIcon(dc.State)
+			dc.StateIcon = dockerContainerStateToStateIcon(dc.State, dc.Health)
 		}
 
 		dockerContainers = append(dockerContainers, dc)
@@ -278,18 +399,14 @@ func isDockerContainerHidden(container *dockerContainerJsonResponse, hideByDefau
 	return hideByDefault
 }
 
+// Talks to the Docker API either through its socket or over TCP
+type dockerClient struct {
+	client   *http.Client
+	hostname string
+}
 
-func fetchDockerContainersFromSource(
-	source string,
-	category string,
-	runningOnly bool,
-	labelOverrides map[string]map[string]string,
-) ([]dockerContainerJsonResponse, error) {
-	var hostname string
-
-	var client *http.Client
+func newDockerClient(source string) (*dockerClient, error) {
 	if strings.HasPrefix(source, "tcp://") || strings.HasPrefix(source, "http://") {
-		client = &http.Client{}
 		parsed, err := url.Parse(source)
 		if err != nil {
 			return nil, fmt.Errorf("parsing URL: %w", err)
@@ -300,29 +417,41 @@ func fetchDockerContainersFromSource(
 			port = "80"
 		}
 
-		hostname = parsed.Hostname() + ":" + port
-	} else {
-		hostname = "docker"
-		client = &http.Client{
+		return &dockerClient{client: &http.Client{}, hostname: parsed.Hostname() + ":" + port}, nil
+	}
+
+	return &dockerClient{
+		hostname: "docker",
+		client: &http.Client{
 			Transport: &http.Transport{
 				DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
 					return net.Dial("unix", source)
 				},
 			},
-		}
-	}
+		},
+	}, nil
+}
 
+func (c *dockerClient) url(path string) string {
+	return "http://" + c.hostname + path
+}
 
+func fetchDockerContainersFromSource(
+	docker *dockerClient,
+	category string,
+	runningOnly bool,
+	labelOverrides map[string]map[string]string,
+) ([]dockerContainerJsonResponse, error) {
 	fetchAll := ternary(runningOnly, "false", "true")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	request, err := http.NewRequestWithContext(ctx, "GET", "http://"+hostname+"/containers/json?all="+fetchAll, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", docker.url("/containers/json?all="+fetchAll), nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	response, err := client.Do(request)
+	response, err := docker.client.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("sending request to socket: %w", err)
 	}
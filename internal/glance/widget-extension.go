@@ -10,7 +10,10 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
+
+	nethtml "golang.org/x/net/html"
 )
 
 var extensionWidgetTemplate = mustParseTemplate("extension.html", "widget-base.html")
@@ -43,7 +46,7 @@ func (widget *extensionWidget) initialize() error {
 }
 
 func (widget *extensionWidget) update(ctx context.Context) {
-	extension, err := fetchExtension(extensionRequestOptions{
+	extension, err := fetchExtension(widget.httpClient(false), extensionRequestOptions{
 		URL:                 widget.URL,
 		FallbackContentType: widget.FallbackContentType,
 		Parameters:          widget.Parameters,
@@ -51,7 +54,12 @@ func (widget *extensionWidget) update(ctx context.Context) {
 		AllowHtml:           widget.AllowHtml,
 	})
 
-	widget.canContinueUpdateAfterHandlingErr(err)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		// rendered again so that the error gets shown, along
+		// with the previous content if it's still available
+		widget.cachedHTML = widget.renderTemplate(widget, extensionWidgetTemplate)
+		return
+	}
 
 	widget.Extension = extension
 
@@ -110,13 +118,130 @@ func convertExtensionContent(options extensionRequestOptions, content []byte, co
 			return template.HTML(content)
 		}
 
-		fallthrough
+		return sanitizeExtensionHTML(string(content))
 	default:
 		return template.HTML("<pre>" + html.EscapeString(string(content)) + "</pre>")
 	}
 }
 
-func fetchExtension(options extensionRequestOptions) (extension, error) {
+var extensionSanitizedAllowedTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "blockquote": true, "br": true, "code": true,
+	"dd": true, "del": true, "details": true, "div": true, "dl": true, "dt": true,
+	"em": true, "figcaption": true, "figure": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "hr": true, "i": true, "img": true, "ins": true,
+	"kbd": true, "li": true, "mark": true, "ol": true, "p": true, "pre": true, "q": true,
+	"s": true, "small": true, "span": true, "strong": true, "sub": true, "summary": true,
+	"sup": true, "table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+	"thead": true, "time": true, "tr": true, "u": true, "ul": true,
+}
+
+// These get removed along with everything inside of them, all other tags
+// which aren't allowed get removed while keeping their contents
+var extensionSanitizedDroppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "textarea": true, "select": true, "svg": true,
+	"math": true, "title": true, "head": true, "form": true,
+}
+
+// Only the data attributes of features which can't be used to inject HTML
+var extensionSanitizedAllowedAttributes = map[string]bool{
+	"class": true, "title": true, "alt": true, "href": true, "src": true, "width": true,
+	"height": true, "colspan": true, "rowspan": true, "datetime": true, "loading": true,
+	"open": true, "data-dynamic-relative-time": true, "data-collapse-after": true,
+}
+
+func isSafeExtensionURL(value string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// Reduces the HTML of extensions which aren't trusted to a subset of tags
+// and attributes that is enough for building simple widgets using the
+// existing classes, without allowing scripts, styles or embedded content
+func sanitizeExtensionHTML(content string) template.HTML {
+	var builder strings.Builder
+	tokenizer := nethtml.NewTokenizer(strings.NewReader(content))
+	droppedTag := ""
+	droppedDepth := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == nethtml.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+
+		if droppedTag != "" {
+			if token.Data == droppedTag {
+				if tokenType == nethtml.StartTagToken {
+					droppedDepth++
+				} else if tokenType == nethtml.EndTagToken {
+					droppedDepth--
+				}
+			}
+
+			if droppedDepth == 0 {
+				droppedTag = ""
+			}
+			continue
+		}
+
+		switch tokenType {
+		case nethtml.TextToken:
+			builder.WriteString(html.EscapeString(token.Data))
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if extensionSanitizedDroppedTags[token.Data] {
+				if tokenType == nethtml.StartTagToken {
+					droppedTag = token.Data
+					droppedDepth = 1
+				}
+				continue
+			}
+
+			if !extensionSanitizedAllowedTags[token.Data] {
+				continue
+			}
+
+			attributes := token.Attr[:0]
+			for _, attribute := range token.Attr {
+				if attribute.Namespace != "" || !extensionSanitizedAllowedAttributes[attribute.Key] {
+					continue
+				}
+
+				if (attribute.Key == "href" || attribute.Key == "src") && !isSafeExtensionURL(attribute.Val) {
+					continue
+				}
+
+				attributes = append(attributes, attribute)
+			}
+
+			if token.Data == "a" {
+				attributes = append(attributes, nethtml.Attribute{Key: "rel", Val: "noreferrer"})
+			}
+
+			token.Attr = attributes
+			builder.WriteString(token.String())
+		case nethtml.EndTagToken:
+			if extensionSanitizedAllowedTags[token.Data] {
+				builder.WriteString(token.String())
+			}
+		}
+	}
+
+	return template.HTML(builder.String())
+}
+
+func fetchExtension(client requestDoer, options extensionRequestOptions) (extension, error) {
 	request, _ := http.NewRequest("GET", options.URL, nil)
 	if len(options.Parameters) > 0 {
 		request.URL.RawQuery = options.Parameters.toQueryString()
@@ -126,7 +251,7 @@ func fetchExtension(options extensionRequestOptions) (extension, error) {
 		request.Header.Add(key, value)
 	}
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := client.Do(request)
 	if err != nil {
 		slog.Error("Failed fetching extension", "url", options.URL, "error", err)
 		return extension{}, fmt.Errorf("%w: request failed: %w", errNoContent, err)
@@ -134,6 +259,10 @@ func fetchExtension(options extensionRequestOptions) (extension, error) {
 
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK {
+		return extension{}, fmt.Errorf("%w: unexpected status code %d", errNoContent, response.StatusCode)
+	}
+
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
 		slog.Error("Failed reading response body of extension", "url", options.URL, "error", err)
@@ -0,0 +1,188 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var graphqlWidgetTemplate = mustParseTemplate("graphql.html", "widget-base.html")
+
+type graphqlWidget struct {
+	widgetBase       `yaml:",inline"`
+	URL              string             `yaml:"url"`
+	AllowInsecure    bool               `yaml:"allow-insecure"`
+	Headers          map[string]string  `yaml:"headers"`
+	BearerToken      string             `yaml:"bearer-token"`
+	Query            string             `yaml:"query"`
+	OperationName    string             `yaml:"operation-name"`
+	Variables        map[string]any     `yaml:"variables"`
+	Options          customAPIOptions   `yaml:"options"`
+	Template         string             `yaml:"template"`
+	Frameless        bool               `yaml:"frameless"`
+	request          *CustomAPIRequest  `yaml:"-"`
+	compiledTemplate *template.Template `yaml:"-"`
+
+	CompiledHTML template.HTML  `yaml:"-"`
+	QueryErrors  []graphqlError `yaml:"-"`
+}
+
+type graphqlError struct {
+	Message string
+	// Where in the data the error happened, such as repository.issues.0
+	Path string
+}
+
+func (widget *graphqlWidget) initialize() error {
+	widget.withTitle("GraphQL").withCacheDuration(1 * time.Hour)
+
+	if widget.URL == "" {
+		return errors.New("url is required")
+	}
+
+	if strings.TrimSpace(widget.Query) == "" {
+		return errors.New("query is required")
+	}
+
+	if widget.Template == "" {
+		return errors.New("template is required")
+	}
+
+	body := map[string]any{"query": widget.Query}
+	if len(widget.Variables) > 0 {
+		body["variables"] = widget.Variables
+	}
+	if widget.OperationName != "" {
+		body["operationName"] = widget.OperationName
+	}
+
+	headers := make(map[string]string, len(widget.Headers)+2)
+	headers["Accept"] = "application/graphql-response+json, application/json"
+	for key, value := range widget.Headers {
+		headers[key] = value
+	}
+	if widget.BearerToken != "" {
+		headers["Authorization"] = "Bearer " + widget.BearerToken
+	}
+
+	widget.request = &CustomAPIRequest{
+		URL:           widget.URL,
+		AllowInsecure: widget.AllowInsecure,
+		Headers:       headers,
+		Method:        http.MethodPost,
+		BodyType:      "json",
+		Body:          body,
+		clientOptions: &widget.httpClientOptions,
+	}
+
+	if err := widget.request.initialize(); err != nil {
+		return fmt.Errorf("initializing request: %v", err)
+	}
+
+	compiledTemplate, err := template.New("").Funcs(customAPITemplateFuncs).Parse(widget.Template)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+
+	widget.compiledTemplate = compiledTemplate
+
+	return nil
+}
+
+func (widget *graphqlWidget) setProviders(providers *widgetProviders) {
+	widget.widgetBase.setProviders(providers)
+	widget.compiledTemplate = localizeCustomTemplate(widget.compiledTemplate, widget.formatter())
+}
+
+func (widget *graphqlWidget) update(ctx context.Context) {
+	compiledHTML, queryErrors, err := widget.fetchAndRender(ctx)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.CompiledHTML = compiledHTML
+	widget.QueryErrors = queryErrors
+}
+
+func (widget *graphqlWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, graphqlWidgetTemplate)
+}
+
+// Servers respond with errors alongside the data when only some of the fields
+// could be resolved, in which case the data gets rendered with the errors shown
+// above it, and without any data when the query itself failed
+func (widget *graphqlWidget) fetchAndRender(ctx context.Context) (template.HTML, []graphqlError, error) {
+	response, err := fetchCustomAPIResponse(ctx, widget.request)
+	if err != nil {
+		return "", nil, err
+	}
+
+	data := response.JSON.Result.Get("data")
+	queryErrors := parseGraphQLErrors(response.JSON.Result.Get("errors"))
+
+	if !data.Exists() || data.Type == gjson.Null {
+		if len(queryErrors) > 0 {
+			return "", nil, fmt.Errorf("query failed: %s", queryErrors[0].Message)
+		}
+
+		if response.Response.StatusCode < 200 || response.Response.StatusCode >= 300 {
+			return "", nil, fmt.Errorf("unexpected status code %d from %s", response.Response.StatusCode, widget.URL)
+		}
+
+		return "", nil, errors.New("response has no data")
+	}
+
+	var templateBuffer strings.Builder
+	err = widget.compiledTemplate.Execute(&templateBuffer, &customAPITemplateData{
+		customAPIResponseData: &customAPIResponseData{
+			JSON:     decoratedGJSONResult{data},
+			Response: response.Response,
+		},
+		Options: widget.Options,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(queryErrors) > 0 {
+		err = fmt.Errorf("%w: %d of the fields could not be resolved", errPartialContent, len(queryErrors))
+	}
+
+	return template.HTML(templateBuffer.String()), queryErrors, err
+}
+
+func parseGraphQLErrors(result gjson.Result) []graphqlError {
+	if !result.IsArray() {
+		return nil
+	}
+
+	var queryErrors []graphqlError
+
+	for _, entry := range result.Array() {
+		queryError := graphqlError{Message: entry.Get("message").String()}
+		if queryError.Message == "" {
+			queryError.Message = "unknown error"
+		}
+
+		var path []string
+		for _, segment := range entry.Get("path").Array() {
+			if segment.Type == gjson.Number {
+				path = append(path, strconv.FormatInt(segment.Int(), 10))
+			} else {
+				path = append(path, segment.String())
+			}
+		}
+		queryError.Path = strings.Join(path, ".")
+
+		queryErrors = append(queryErrors, queryError)
+	}
+
+	return queryErrors
+}
@@ -40,7 +40,8 @@ func (widget *groupWidget) update(ctx context.Context) {
 }
 
 func (widget *groupWidget) setProviders(providers *widgetProviders) {
-	widget.containerWidgetBase._setProviders(providers)
+	widget.widgetBase.setProviders(providers)
+	widget.containerWidgetBase._setProviders(widget.Providers)
 }
 
 func (widget *groupWidget) requiresUpdate(now *time.Time) bool {
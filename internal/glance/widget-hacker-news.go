@@ -19,6 +19,9 @@ type hackerNewsWidget struct {
 	ExtraSortBy         string        `yaml:"extra-sort-by"`
 	CollapseAfter       int           `yaml:"collapse-after"`
 	CommentsUrlTemplate string        `yaml:"comments-url-template"`
+	MinPoints           int           `yaml:"min-points"`
+	MinComments         int           `yaml:"min-comments"`
+	HideDomains         []string      `yaml:"hide-domains"`
 	ShowThumbnails      bool          `yaml:"-"`
 }
 
@@ -40,16 +43,30 @@ func (widget *hackerNewsWidget) initialize() error {
 		widget.SortBy = "top"
 	}
 
+	for i := range widget.HideDomains {
+		widget.HideDomains[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(widget.HideDomains[i])), "www.")
+	}
+
 	return nil
 }
 
+func (widget *hackerNewsWidget) hasFilters() bool {
+	return widget.MinPoints > 0 || widget.MinComments > 0 || len(widget.HideDomains) > 0
+}
+
 func (widget *hackerNewsWidget) update(ctx context.Context) {
-	posts, err := fetchHackerNewsPosts(widget.SortBy, 40, widget.CommentsUrlTemplate)
+	// more posts get fetched when filtering in order to
+	// still have enough of them left to reach the limit
+	posts, err := fetchHackerNewsPosts(widget.SortBy, ternary(widget.hasFilters(), 100, 40), widget.CommentsUrlTemplate)
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
 	}
 
+	if widget.hasFilters() {
+		posts = widget.filterPosts(posts)
+	}
+
 	if widget.ExtraSortBy == "engagement" {
 		posts.calculateEngagement()
 		posts.sortByEngagement()
@@ -62,6 +79,31 @@ func (widget *hackerNewsWidget) update(ctx context.Context) {
 	widget.Posts = posts
 }
 
+func (widget *hackerNewsWidget) filterPosts(posts forumPostList) forumPostList {
+	filtered := make(forumPostList, 0, len(posts))
+
+	for i := range posts {
+		post := &posts[i]
+
+		if post.Score < widget.MinPoints || post.CommentCount < widget.MinComments {
+			continue
+		}
+
+		// posts such as Ask HN don't link anywhere and have no domain
+		if post.TargetUrlDomain != "" && isDomainInList(post.TargetUrlDomain, widget.HideDomains) {
+			continue
+		}
+
+		filtered = append(filtered, *post)
+	}
+
+	return filtered
+}
+
+func (widget *hackerNewsWidget) dataModel() any {
+	return &widget.Posts
+}
+
 func (widget *hackerNewsWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, forumPostsTemplate)
 }
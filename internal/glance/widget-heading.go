@@ -0,0 +1,32 @@
+package glance
+
+import (
+	"errors"
+	"html/template"
+)
+
+var headingWidgetTemplate = mustParseTemplate("heading.html")
+
+// Only a title between other widgets, never gets updated since
+// there's nothing for it to fetch
+type headingWidget struct {
+	widgetBase  `yaml:",inline"`
+	Subtitle    string `yaml:"subtitle"`
+	Separator   bool   `yaml:"separator"`
+	HideInKiosk bool   `yaml:"hide-in-kiosk"`
+	HideInPrint bool   `yaml:"hide-in-print"`
+}
+
+func (widget *headingWidget) initialize() error {
+	widget.withTitle("").withError(nil)
+
+	if widget.Title == "" && widget.Subtitle == "" {
+		return errors.New("either a title or a subtitle is required")
+	}
+
+	return nil
+}
+
+func (widget *headingWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, headingWidgetTemplate)
+}
@@ -0,0 +1,322 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var heatmapWidgetTemplate = mustParseTemplate("heatmap.html", "widget-base.html")
+
+// Layouts tried in order when no date-format is set
+var heatmapDateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+}
+
+type heatmapWidget struct {
+	widgetBase        `yaml:",inline"`
+	*CustomAPIRequest `yaml:",inline"`
+	Data              string    `yaml:"data"`
+	DateField         string    `yaml:"date-field"`
+	CountField        string    `yaml:"count-field"`
+	DateFormat        string    `yaml:"date-format"`
+	Weeks             int       `yaml:"weeks"`
+	Steps             int       `yaml:"steps"`
+	Thresholds        []float64 `yaml:"thresholds"`
+	Unit              string    `yaml:"unit"`
+	FirstDayOfWeek    string    `yaml:"first-day-of-week"`
+	firstDay          time.Weekday
+
+	Grid       [][]heatmapDay      `yaml:"-"`
+	Months     []heatmapMonthLabel `yaml:"-"`
+	Total      float64             `yaml:"-"`
+	ActiveDays int                 `yaml:"-"`
+}
+
+type heatmapDay struct {
+	Date   time.Time
+	Count  float64
+	Level  int
+	Steps  int
+	Future bool
+}
+
+type heatmapMonthLabel struct {
+	// 1-based so that it can be used as the grid column directly
+	Column int
+	Name   string
+}
+
+func (widget *heatmapWidget) initialize() error {
+	widget.withTitle("Heatmap").withCacheDuration(1 * time.Hour)
+
+	if widget.CustomAPIRequest == nil || widget.URL == "" {
+		return errors.New("url is required")
+	}
+
+	if err := widget.CustomAPIRequest.initialize(); err != nil {
+		return fmt.Errorf("initializing request: %v", err)
+	}
+	widget.CustomAPIRequest.clientOptions = &widget.httpClientOptions
+
+	if widget.DateField == "" {
+		widget.DateField = "date"
+	}
+
+	if widget.CountField == "" {
+		widget.CountField = "count"
+	}
+
+	if widget.Weeks <= 0 {
+		widget.Weeks = 26
+	} else if widget.Weeks > 106 {
+		return errors.New("weeks can be at most 106")
+	}
+
+	if len(widget.Thresholds) > 0 {
+		if !slices.IsSorted(widget.Thresholds) {
+			return errors.New("thresholds must be in ascending order")
+		}
+		widget.Steps = len(widget.Thresholds)
+	} else if widget.Steps <= 0 {
+		widget.Steps = 4
+	} else if widget.Steps > 10 {
+		return errors.New("steps can be at most 10")
+	}
+
+	if widget.FirstDayOfWeek == "" {
+		widget.FirstDayOfWeek = "monday"
+	} else if _, ok := calendarWeekdaysToInt[widget.FirstDayOfWeek]; !ok {
+		return errors.New("invalid first day of week")
+	}
+	widget.firstDay = calendarWeekdaysToInt[widget.FirstDayOfWeek]
+
+	return nil
+}
+
+func (widget *heatmapWidget) update(ctx context.Context) {
+	counts, err := widget.fetchCounts(ctx)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.buildGrid(counts, time.Now())
+}
+
+func (widget *heatmapWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, heatmapWidgetTemplate)
+}
+
+// Returns the sum of the counts of each day, keyed by the date in the
+// 2006-01-02 format
+func (widget *heatmapWidget) fetchCounts(ctx context.Context) (map[string]float64, error) {
+	response, err := fetchCustomAPIResponse(ctx, widget.CustomAPIRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Response.StatusCode < 200 || response.Response.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code %d from %s", response.Response.StatusCode, widget.URL)
+	}
+
+	series := response.JSON.Result
+	if widget.Data != "" {
+		series = series.Get(widget.Data)
+	}
+
+	counts := make(map[string]float64)
+	var skipped int
+
+	add := func(date, count gjson.Result) {
+		day, ok := widget.parseDate(date)
+		if !ok || !count.Exists() {
+			skipped++
+			return
+		}
+
+		counts[day] += count.Float()
+	}
+
+	switch {
+	// {"2025-01-01": 3, ...}
+	case series.IsObject():
+		series.ForEach(func(date, count gjson.Result) bool {
+			add(date, count)
+			return true
+		})
+	case series.IsArray():
+		for _, item := range series.Array() {
+			// [["2025-01-01", 3], ...]
+			if item.IsArray() {
+				pair := item.Array()
+				if len(pair) < 2 {
+					skipped++
+					continue
+				}
+				add(pair[0], pair[1])
+				continue
+			}
+
+			// [{"date": "2025-01-01", "count": 3}, ...]
+			add(item.Get(widget.DateField), item.Get(widget.CountField))
+		}
+	default:
+		return nil, fmt.Errorf("%w: expected the data to be an object or an array", errNoContent)
+	}
+
+	if len(counts) == 0 && skipped > 0 {
+		return nil, fmt.Errorf("%w: could not read the date or count of any of the %d items", errNoContent, skipped)
+	}
+
+	if skipped > 0 {
+		return counts, fmt.Errorf("%w: could not read the date or count of %d items", errPartialContent, skipped)
+	}
+
+	return counts, nil
+}
+
+func (widget *heatmapWidget) parseDate(value gjson.Result) (string, bool) {
+	var date time.Time
+
+	if value.Type == gjson.Number {
+		timestamp := value.Int()
+		// anything this large can't be a timestamp in seconds
+		// for the foreseeable future, so it must be in milliseconds
+		if timestamp > 100_000_000_000 {
+			date = time.UnixMilli(timestamp)
+		} else {
+			date = time.Unix(timestamp, 0)
+		}
+
+		return date.Local().Format("2006-01-02"), true
+	}
+
+	raw := strings.TrimSpace(value.String())
+	if raw == "" {
+		return "", false
+	}
+
+	if widget.DateFormat != "" {
+		parsed, err := time.ParseInLocation(widget.DateFormat, raw, time.Local)
+		if err != nil {
+			return "", false
+		}
+		return parsed.Local().Format("2006-01-02"), true
+	}
+
+	for _, layout := range heatmapDateLayouts {
+		if parsed, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+			return parsed.Local().Format("2006-01-02"), true
+		}
+	}
+
+	return "", false
+}
+
+// The last column is the current week, days after today are still part of
+// the grid so that all columns have the same number of rows
+func (widget *heatmapWidget) buildGrid(counts map[string]float64, now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	daysIntoWeek := (int(today.Weekday()) - int(widget.firstDay) + 7) % 7
+	start := today.AddDate(0, 0, -daysIntoWeek-(widget.Weeks-1)*7)
+
+	grid := make([][]heatmapDay, widget.Weeks)
+	months := make([]heatmapMonthLabel, 0, widget.Weeks/4+1)
+	var total, highest float64
+	var activeDays int
+
+	for week := range grid {
+		grid[week] = make([]heatmapDay, 7)
+
+		for weekday := range grid[week] {
+			date := start.AddDate(0, 0, week*7+weekday)
+			count := math.Max(0, counts[date.Format("2006-01-02")])
+			future := date.After(today)
+
+			if future {
+				count = 0
+			}
+
+			grid[week][weekday] = heatmapDay{Date: date, Count: count, Steps: widget.Steps, Future: future}
+
+			total += count
+			if count > 0 {
+				activeDays++
+			}
+			highest = math.Max(highest, count)
+
+			// labels are placed on the first week that starts in a new month,
+			// the first column only gets one if there's room before the next
+			if date.Day() == 1 || (week == 0 && weekday == 0 && date.Day() <= 10) {
+				months = append(months, heatmapMonthLabel{Column: week + 1, Name: date.Format("Jan")})
+			}
+		}
+	}
+
+	for week := range grid {
+		for weekday := range grid[week] {
+			grid[week][weekday].Level = widget.levelOf(grid[week][weekday].Count, highest)
+		}
+	}
+
+	widget.Grid = grid
+	widget.Months = months
+	widget.Total = total
+	widget.ActiveDays = activeDays
+}
+
+func (widget *heatmapWidget) levelOf(count, highest float64) int {
+	if count <= 0 {
+		return 0
+	}
+
+	if len(widget.Thresholds) > 0 {
+		level := 0
+		for _, threshold := range widget.Thresholds {
+			if count >= threshold {
+				level++
+			}
+		}
+		return level
+	}
+
+	return min(widget.Steps, max(1, int(math.Ceil(count/highest*float64(widget.Steps)))))
+}
+
+func (widget *heatmapWidget) TotalText() string {
+	return formatHeatmapCount(widget.Total)
+}
+
+func (d heatmapDay) Intensity() string {
+	if d.Level == 0 {
+		return "0"
+	}
+
+	// the lowest level is still clearly distinguishable from no activity
+	return strconv.FormatFloat(0.25+0.75*float64(d.Level-1)/float64(max(1, d.Steps-1)), 'f', 2, 64)
+}
+
+func (d heatmapDay) Label(unit string) string {
+	count := formatHeatmapCount(d.Count)
+	if unit != "" {
+		count += " " + unit
+	}
+
+	return count + " on " + d.Date.Format("Mon, Jan 2 2006")
+}
+
+func formatHeatmapCount(count float64) string {
+	return strconv.FormatFloat(math.Round(count*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,843 @@
+package glance
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var icsWidgetTemplate = mustParseTemplate("ics.html", "widget-base.html")
+
+type icsWidget struct {
+	widgetBase    `yaml:",inline"`
+	Calendars     []icsCalendarRequest `yaml:"calendars"`
+	Days          int                  `yaml:"days"`
+	Limit         int                  `yaml:"limit"`
+	Timezone      string               `yaml:"timezone"`
+	AllowInsecure bool                 `yaml:"allow-insecure"`
+	location      *time.Location       `yaml:"-"`
+
+	EventDays       []icsEventDay `yaml:"-"`
+	FailedCalendars []string      `yaml:"-"`
+}
+
+type icsCalendarRequest struct {
+	URL     string            `yaml:"url"`
+	Name    string            `yaml:"name"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+func (widget *icsWidget) initialize() error {
+	widget.withTitle("Events").withCacheDuration(1 * time.Hour)
+
+	if len(widget.Calendars) == 0 {
+		return errors.New("no calendars specified")
+	}
+
+	for i := range widget.Calendars {
+		if widget.Calendars[i].URL == "" {
+			return fmt.Errorf("calendar #%d is missing a url", i+1)
+		}
+	}
+
+	if widget.Days <= 0 {
+		widget.Days = 14
+	}
+
+	if widget.Limit <= 0 {
+		widget.Limit = 20
+	}
+
+	if widget.Timezone != "" {
+		location, err := time.LoadLocation(widget.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone '%s': %v", widget.Timezone, err)
+		}
+
+		widget.location = location
+	}
+
+	return nil
+}
+
+func (widget *icsWidget) update(ctx context.Context) {
+	// events are shown in the timezone of the dashboard unless the widget has its own
+	location := widget.location
+	if location == nil {
+		location = widget.dashboardLocation()
+	}
+
+	now := time.Now().In(location)
+	windowStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	windowEnd := windowStart.AddDate(0, 0, widget.Days)
+	client := widget.httpClient(widget.AllowInsecure)
+
+	job := newJob(func(request icsCalendarRequest) ([]icsEvent, error) {
+		return fetchICSCalendarEvents(client, request, location, windowStart, windowEnd)
+	}, widget.Calendars).withWorkers(10)
+
+	calendars, errs, err := workerPoolDo(job)
+	if err != nil {
+		widget.canContinueUpdateAfterHandlingErr(fmt.Errorf("%w: %v", errNoContent, err))
+		return
+	}
+
+	events := make([]icsEvent, 0, 32)
+	failed := make([]string, 0)
+
+	for i := range calendars {
+		if errs[i] != nil {
+			name := ternary(widget.Calendars[i].Name != "", widget.Calendars[i].Name, widget.Calendars[i].URL)
+			failed = append(failed, name)
+			slog.Error("Failed to get ICS calendar", "url", widget.Calendars[i].URL, "error", errs[i])
+			continue
+		}
+
+		events = append(events, calendars[i]...)
+	}
+
+	if len(failed) == len(widget.Calendars) {
+		err = errNoContent
+	} else if len(failed) > 0 {
+		err = fmt.Errorf("%w: could not get %d calendar(s): %s", errPartialContent, len(failed), strings.Join(failed, ", "))
+	}
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].Start.Before(events[b].Start)
+	})
+
+	if len(events) > widget.Limit {
+		events = events[:widget.Limit]
+	}
+
+	widget.EventDays = groupICSEventsByDay(events, now)
+	widget.FailedCalendars = failed
+}
+
+func (widget *icsWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, icsWidgetTemplate)
+}
+
+type icsEvent struct {
+	Summary  string
+	Location string
+	URL      string
+	Calendar string
+	Start    time.Time
+	End      time.Time
+	AllDay   bool
+}
+
+type icsEventDay struct {
+	Label  string
+	Events []icsEvent
+}
+
+// Events spanning multiple days are only listed under the day they start on,
+// or today if they started before the window
+func groupICSEventsByDay(events []icsEvent, now time.Time) []icsEventDay {
+	days := make([]icsEventDay, 0, len(events))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i := range events {
+		start := events[i].Start.In(now.Location())
+		if start.Before(today) {
+			start = today
+		}
+
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, now.Location())
+		var label string
+
+		switch {
+		case day.Equal(today):
+			label = "Today"
+		case day.Equal(today.AddDate(0, 0, 1)):
+			label = "Tomorrow"
+		default:
+			label = day.Format("Monday, January 2")
+		}
+
+		if len(days) == 0 || days[len(days)-1].Label != label {
+			days = append(days, icsEventDay{Label: label})
+		}
+
+		days[len(days)-1].Events = append(days[len(days)-1].Events, events[i])
+	}
+
+	return days
+}
+
+func fetchICSCalendarEvents(client requestDoer, request icsCalendarRequest, location *time.Location, windowStart, windowEnd time.Time) ([]icsEvent, error) {
+	req, err := http.NewRequest("GET", request.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", glanceUserAgentString)
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, request.URL)
+	}
+
+	calendar, err := parseICSCalendar(resp.Body, location)
+	if err != nil {
+		return nil, err
+	}
+
+	events := calendar.eventsBetween(windowStart, windowEnd)
+	for i := range events {
+		events[i].Calendar = request.Name
+	}
+
+	return events, nil
+}
+
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+type icsComponent struct {
+	properties map[string][]icsProperty
+}
+
+func (c *icsComponent) first(name string) (icsProperty, bool) {
+	if properties := c.properties[name]; len(properties) > 0 {
+		return properties[0], true
+	}
+
+	return icsProperty{}, false
+}
+
+func (c *icsComponent) text(name string) string {
+	property, _ := c.first(name)
+	return unescapeICSText(property.value)
+}
+
+type icsCalendar struct {
+	events   []*icsComponent
+	location *time.Location
+}
+
+// Only VEVENT components are kept, everything else such as alarms, todos and
+// timezone definitions is skipped. Timezones are resolved through the TZID
+// parameter, which in practice almost always holds an IANA name.
+func parseICSCalendar(r io.Reader, location *time.Location) (*icsCalendar, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &icsCalendar{location: location}
+	var current *icsComponent
+	nesting := make([]string, 0, 4)
+	sawCalendar := false
+
+	for _, line := range lines {
+		property, ok := parseICSContentLine(line)
+		if !ok {
+			continue
+		}
+
+		switch property.name {
+		case "BEGIN":
+			component := strings.ToUpper(property.value)
+			nesting = append(nesting, component)
+
+			if component == "VCALENDAR" {
+				sawCalendar = true
+			} else if component == "VEVENT" && len(nesting) == 2 {
+				current = &icsComponent{properties: make(map[string][]icsProperty)}
+			}
+		case "END":
+			if len(nesting) == 0 {
+				return nil, fmt.Errorf("unexpected END:%s", property.value)
+			}
+
+			if nesting[len(nesting)-1] == "VEVENT" && len(nesting) == 2 && current != nil {
+				calendar.events = append(calendar.events, current)
+				current = nil
+			}
+
+			nesting = nesting[:len(nesting)-1]
+		default:
+			if current != nil && len(nesting) == 2 {
+				current.properties[property.name] = append(current.properties[property.name], property)
+			}
+		}
+	}
+
+	if !sawCalendar {
+		return nil, errors.New("response is not an iCalendar feed")
+	}
+
+	return calendar, nil
+}
+
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lines := make([]string, 0, 256)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// Parses NAME;PARAM=VALUE;PARAM="QUOTED:VALUE":VALUE
+func parseICSContentLine(line string) (icsProperty, bool) {
+	inQuotes := false
+	separator := -1
+
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' {
+			inQuotes = !inQuotes
+		} else if line[i] == ':' && !inQuotes {
+			separator = i
+			break
+		}
+	}
+
+	if separator == -1 {
+		return icsProperty{}, false
+	}
+
+	property := icsProperty{value: line[separator+1:]}
+	parts := strings.Split(line[:separator], ";")
+	property.name = strings.ToUpper(parts[0])
+
+	if len(parts) > 1 {
+		property.params = make(map[string]string, len(parts)-1)
+		for _, param := range parts[1:] {
+			key, value, _ := strings.Cut(param, "=")
+			property.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return property, property.name != ""
+}
+
+var icsTextUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescapeICSText(s string) string {
+	return icsTextUnescaper.Replace(s)
+}
+
+// Returns the parsed time and whether it's a date without a time
+func parseICSTime(property icsProperty, fallback *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(property.value)
+
+	if property.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, fallback)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	location := fallback
+	if tzid := property.params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+// Parses the subset of ISO 8601 durations used by iCalendar, such as P1W,
+// PT1H30M or -P1DT12H
+func parseICSDuration(value string) (time.Duration, error) {
+	original := value
+	sign := time.Duration(1)
+
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	}
+	value = strings.TrimPrefix(value, "+")
+
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", original)
+	}
+	value = value[1:]
+
+	var total time.Duration
+	inTime := false
+	number := ""
+
+	for _, r := range value {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9':
+			number += string(r)
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", original)
+			}
+			number = ""
+
+			switch {
+			case r == 'W' && !inTime:
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				total += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", original)
+			}
+		}
+	}
+
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", original)
+	}
+
+	return sign * total, nil
+}
+
+func (calendar *icsCalendar) eventsBetween(windowStart, windowEnd time.Time) []icsEvent {
+	// overridden occurrences of recurring events, keyed by UID and the
+	// original start time of the occurrence they replace
+	overrides := make(map[string]struct{})
+	for _, component := range calendar.events {
+		property, ok := component.first("RECURRENCE-ID")
+		if !ok {
+			continue
+		}
+
+		if t, _, err := parseICSTime(property, calendar.location); err == nil {
+			overrides[component.text("UID")+"|"+strconv.FormatInt(t.Unix(), 10)] = struct{}{}
+		}
+	}
+
+	events := make([]icsEvent, 0, 16)
+
+	for _, component := range calendar.events {
+		if strings.EqualFold(component.text("STATUS"), "CANCELLED") {
+			continue
+		}
+
+		startProperty, ok := component.first("DTSTART")
+		if !ok {
+			continue
+		}
+
+		start, allDay, err := parseICSTime(startProperty, calendar.location)
+		if err != nil {
+			continue
+		}
+
+		var duration time.Duration
+		if endProperty, ok := component.first("DTEND"); ok {
+			if end, _, err := parseICSTime(endProperty, calendar.location); err == nil {
+				duration = end.Sub(start)
+			}
+		} else if durationProperty, ok := component.first("DURATION"); ok {
+			duration, _ = parseICSDuration(durationProperty.value)
+		} else if allDay {
+			duration = 24 * time.Hour
+		}
+
+		event := icsEvent{
+			Summary:  component.text("SUMMARY"),
+			Location: component.text("LOCATION"),
+			URL:      component.text("URL"),
+			AllDay:   allDay,
+		}
+
+		var starts []time.Time
+		rruleProperty, isRecurring := component.first("RRULE")
+		_, isOverride := component.first("RECURRENCE-ID")
+
+		if isRecurring && !isOverride {
+			rule, err := parseICSRecurrenceRule(rruleProperty.value, calendar.location)
+			if err != nil {
+				slog.Warn("Skipping ICS event with unsupported recurrence rule", "summary", event.Summary, "error", err)
+				continue
+			}
+
+			excluded := make(map[int64]struct{})
+			for _, property := range component.properties["EXDATE"] {
+				for _, value := range strings.Split(property.value, ",") {
+					property.value = value
+					if t, _, err := parseICSTime(property, calendar.location); err == nil {
+						excluded[t.Unix()] = struct{}{}
+					}
+				}
+			}
+
+			uid := component.text("UID")
+			for _, occurrence := range rule.expand(start, windowStart.Add(-duration), windowEnd) {
+				if _, ok := excluded[occurrence.Unix()]; ok {
+					continue
+				}
+
+				if _, ok := overrides[uid+"|"+strconv.FormatInt(occurrence.Unix(), 10)]; ok {
+					continue
+				}
+
+				starts = append(starts, occurrence)
+			}
+		} else {
+			starts = []time.Time{start}
+		}
+
+		for _, occurrenceStart := range starts {
+			occurrenceEnd := occurrenceStart.Add(duration)
+
+			// events without a duration are kept if they start exactly when the window does
+			if !occurrenceStart.Before(windowEnd) || !occurrenceEnd.After(windowStart) && occurrenceStart.Before(windowStart) {
+				continue
+			}
+
+			event.Start = occurrenceStart.In(windowStart.Location())
+			event.End = occurrenceEnd.In(windowStart.Location())
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+type icsWeekday struct {
+	weekday time.Weekday
+	// 0 means every occurrence within the period, negative counts from the end
+	ordinal int
+}
+
+type icsRecurrenceRule struct {
+	frequency  string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []icsWeekday
+	byMonthDay []int
+	byMonth    []time.Month
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseICSRecurrenceRule(value string, location *time.Location) (*icsRecurrenceRule, error) {
+	rule := &icsRecurrenceRule{interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		key, value, _ := strings.Cut(part, "=")
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.frequency = strings.ToUpper(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid interval %q", value)
+			}
+			rule.interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid count %q", value)
+			}
+			rule.count = count
+		case "UNTIL":
+			until, _, err := parseICSTime(icsProperty{value: value}, location)
+			if err != nil {
+				return nil, fmt.Errorf("invalid until %q", value)
+			}
+			if len(value) == 8 {
+				// the whole day is included when only a date is given
+				until = until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			rule.until = until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				day = strings.ToUpper(strings.TrimSpace(day))
+				if len(day) < 2 {
+					return nil, fmt.Errorf("invalid day %q", day)
+				}
+
+				weekday, ok := icsWeekdays[day[len(day)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid day %q", day)
+				}
+
+				ordinal := 0
+				if len(day) > 2 {
+					n, err := strconv.Atoi(day[:len(day)-2])
+					if err != nil {
+						return nil, fmt.Errorf("invalid day %q", day)
+					}
+					ordinal = n
+				}
+
+				rule.byDay = append(rule.byDay, icsWeekday{weekday: weekday, ordinal: ordinal})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid month day %q", day)
+				}
+				rule.byMonthDay = append(rule.byMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid month %q", month)
+				}
+				rule.byMonth = append(rule.byMonth, time.Month(n))
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	switch rule.frequency {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, errors.New("missing frequency")
+	default:
+		return nil, fmt.Errorf("unsupported frequency %q", rule.frequency)
+	}
+
+	return rule, nil
+}
+
+// Upper bound on the number of periods walked through, which is enough
+// for a daily event started decades ago
+const icsMaxRecurrencePeriods = 50_000
+
+// Returns the start times of all occurrences that start within [from, to).
+// Occurrences before the window still count towards COUNT and, as with every
+// other client, DTSTART is always the first occurrence even if the rule
+// wouldn't otherwise match it.
+func (rule *icsRecurrenceRule) expand(start, from, to time.Time) []time.Time {
+	occurrences := make([]time.Time, 0, 8)
+	generated := 1
+
+	if !start.Before(from) && start.Before(to) {
+		occurrences = append(occurrences, start)
+	}
+
+	for period := 0; period < icsMaxRecurrencePeriods; period++ {
+		candidates := rule.candidatesForPeriod(start, period)
+		if candidates == nil {
+			break
+		}
+
+		for _, candidate := range candidates {
+			if !candidate.After(start) {
+				continue
+			}
+
+			if !candidate.Before(to) || (!rule.until.IsZero() && candidate.After(rule.until)) {
+				return occurrences
+			}
+
+			generated++
+			if rule.count > 0 && generated > rule.count {
+				return occurrences
+			}
+
+			if !candidate.Before(from) {
+				occurrences = append(occurrences, candidate)
+			}
+		}
+	}
+
+	return occurrences
+}
+
+func (rule *icsRecurrenceRule) candidatesForPeriod(start time.Time, period int) []time.Time {
+	step := period * rule.interval
+	hour, minute, second := start.Clock()
+	location := start.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, location)
+	}
+
+	candidates := make([]time.Time, 0, 4)
+
+	switch rule.frequency {
+	case "DAILY":
+		day := at(start.Year(), start.Month(), start.Day()+step)
+		if rule.matchesDayFilters(day) {
+			candidates = append(candidates, day)
+		}
+	case "WEEKLY":
+		weekStart := at(start.Year(), start.Month(), start.Day()-int((start.Weekday()+6)%7)+step*7)
+		if len(rule.byDay) == 0 {
+			candidates = append(candidates, at(start.Year(), start.Month(), start.Day()+step*7))
+			break
+		}
+
+		for offset := 0; offset < 7; offset++ {
+			day := at(weekStart.Year(), weekStart.Month(), weekStart.Day()+offset)
+			if rule.matchesWeekday(day.Weekday()) && (len(rule.byMonth) == 0 || slices.Contains(rule.byMonth, day.Month())) {
+				candidates = append(candidates, day)
+			}
+		}
+	case "MONTHLY":
+		month := at(start.Year(), start.Month()+time.Month(step), 1)
+		candidates = rule.candidatesInMonth(month.Year(), month.Month(), start.Day(), at)
+		if len(rule.byMonth) > 0 && !slices.Contains(rule.byMonth, month.Month()) {
+			candidates = candidates[:0]
+		}
+	case "YEARLY":
+		year := start.Year() + step
+		months := rule.byMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+
+		for _, month := range months {
+			candidates = append(candidates, rule.candidatesInMonth(year, month, start.Day(), at)...)
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].Before(candidates[b])
+	})
+
+	return candidates
+}
+
+func (rule *icsRecurrenceRule) candidatesInMonth(year int, month time.Month, defaultDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	candidates := make([]time.Time, 0, 4)
+
+	if len(rule.byMonthDay) > 0 {
+		for _, day := range rule.byMonthDay {
+			if day < 0 {
+				day = daysInMonth + day + 1
+			}
+
+			if day >= 1 && day <= daysInMonth {
+				candidates = append(candidates, at(year, month, day))
+			}
+		}
+
+		return candidates
+	}
+
+	if len(rule.byDay) > 0 {
+		for _, weekday := range rule.byDay {
+			matching := make([]int, 0, 5)
+			for day := 1; day <= daysInMonth; day++ {
+				if time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday() == weekday.weekday {
+					matching = append(matching, day)
+				}
+			}
+
+			switch {
+			case weekday.ordinal == 0:
+				for _, day := range matching {
+					candidates = append(candidates, at(year, month, day))
+				}
+			case weekday.ordinal > 0 && weekday.ordinal <= len(matching):
+				candidates = append(candidates, at(year, month, matching[weekday.ordinal-1]))
+			case weekday.ordinal < 0 && -weekday.ordinal <= len(matching):
+				candidates = append(candidates, at(year, month, matching[len(matching)+weekday.ordinal]))
+			}
+		}
+
+		return candidates
+	}
+
+	// months that don't have the day, such as the 31st, are skipped
+	if defaultDay <= daysInMonth {
+		candidates = append(candidates, at(year, month, defaultDay))
+	}
+
+	return candidates
+}
+
+func (rule *icsRecurrenceRule) matchesWeekday(weekday time.Weekday) bool {
+	for i := range rule.byDay {
+		if rule.byDay[i].weekday == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rule *icsRecurrenceRule) matchesDayFilters(day time.Time) bool {
+	if len(rule.byDay) > 0 && !rule.matchesWeekday(day.Weekday()) {
+		return false
+	}
+
+	if len(rule.byMonth) > 0 && !slices.Contains(rule.byMonth, day.Month()) {
+		return false
+	}
+
+	if len(rule.byMonthDay) > 0 {
+		daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for _, monthDay := range rule.byMonthDay {
+			if monthDay == day.Day() || monthDay < 0 && daysInMonth+monthDay+1 == day.Day() {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+package glance
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICSRecurringEventsAreExpandedWithinWindow(t *testing.T) {
+	feed := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:standup",
+		"SUMMARY:Stand\\, up",
+		"DTSTART;TZID=Europe/Berlin:20240101T090000",
+		"DURATION:PT15M",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE",
+		"EXDATE;TZID=Europe/Berlin:20240313T090000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20240318T090000",
+		"SUMMARY:Moved stand up",
+		"DTSTART;TZID=Europe/Berlin:20240318T100000",
+		"DTEND;TZID=Europe/Berlin:20240318T101500",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:review",
+		"SUMMARY:Monthly",
+		"  review",
+		"DTSTART:20231231T120000Z",
+		"DTEND:20231231T130000Z",
+		"RRULE:FREQ=MONTHLY;BYDAY=3FR;COUNT=3",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Holiday",
+		"DTSTART;VALUE=DATE:20240315",
+		"DTEND;VALUE=DATE:20240316",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Timezone database is not available: %v", err)
+	}
+
+	calendar, err := parseICSCalendar(strings.NewReader(feed), location)
+	if err != nil {
+		t.Fatalf("Failed to parse calendar: %v", err)
+	}
+
+	windowStart := time.Date(2024, 3, 11, 0, 0, 0, 0, location)
+	windowEnd := windowStart.AddDate(0, 0, 8)
+
+	events := calendar.eventsBetween(windowStart, windowEnd)
+	sort.Slice(events, func(a, b int) bool {
+		return events[a].Start.Before(events[b].Start)
+	})
+
+	got := make([]string, 0, len(events))
+	for _, event := range events {
+		got = append(got, event.Start.Format("01-02 15:04")+" "+event.Summary)
+	}
+
+	// the monthly review would also fall on the third friday of March but
+	// COUNT is used up by DTSTART and the third fridays of January and February
+	expected := []string{
+		"03-11 09:00 Stand, up",
+		"03-15 00:00 Holiday",
+		"03-18 10:00 Moved stand up",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected events:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -5,15 +5,21 @@ import (
 	"fmt"
 	"html/template"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 var iframeWidgetTemplate = mustParseTemplate("iframe.html", "widget-base.html")
 
 type iframeWidget struct {
-	widgetBase `yaml:",inline"`
-	cachedHTML template.HTML `yaml:"-"`
-	Source     string        `yaml:"source"`
-	Height     int           `yaml:"height"`
+	widgetBase  `yaml:",inline"`
+	cachedHTML  template.HTML `yaml:"-"`
+	Source      string        `yaml:"source"`
+	Height      int           `yaml:"height"`
+	AspectRatio string        `yaml:"aspect-ratio"`
+	Sandbox     *string       `yaml:"sandbox"`
+	// Only set when the aspect ratio is, in the form that CSS expects
+	AspectRatioCSS string `yaml:"-"`
 }
 
 func (widget *iframeWidget) initialize() error {
@@ -27,12 +33,20 @@ func (widget *iframeWidget) initialize() error {
 		return fmt.Errorf("parsing URL: %v", err)
 	}
 
-	if widget.Height == 50 {
+	if widget.Height == 0 {
 		widget.Height = 300
 	} else if widget.Height < 50 {
 		widget.Height = 50
 	}
 
+	if widget.AspectRatio != "" {
+		ratio, err := parseIframeAspectRatio(widget.AspectRatio)
+		if err != nil {
+			return err
+		}
+		widget.AspectRatioCSS = ratio
+	}
+
 	widget.cachedHTML = widget.renderTemplate(widget, iframeWidgetTemplate)
 
 	return nil
@@ -41,3 +55,23 @@ func (widget *iframeWidget) initialize() error {
 func (widget *iframeWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+// Accepts either a width and height such as 16:9 or 16/9, or a single number
+func parseIframeAspectRatio(value string) (string, error) {
+	width, height, hasHeight := strings.Cut(strings.ReplaceAll(value, ":", "/"), "/")
+	if !hasHeight {
+		height = "1"
+	}
+
+	parsedWidth, err := strconv.ParseFloat(strings.TrimSpace(width), 64)
+	if err != nil || parsedWidth <= 0 {
+		return "", fmt.Errorf("invalid aspect-ratio %s, must be in the form of 16:9", value)
+	}
+
+	parsedHeight, err := strconv.ParseFloat(strings.TrimSpace(height), 64)
+	if err != nil || parsedHeight <= 0 {
+		return "", fmt.Errorf("invalid aspect-ratio %s, must be in the form of 16:9", value)
+	}
+
+	return strconv.FormatFloat(parsedWidth, 'f', -1, 64) + " / " + strconv.FormatFloat(parsedHeight, 'f', -1, 64), nil
+}
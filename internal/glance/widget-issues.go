@@ -0,0 +1,605 @@
+package glance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var issuesWidgetTemplate = mustParseTemplate("issues.html", "widget-base.html")
+
+type issuesWidget struct {
+	widgetBase     `yaml:",inline"`
+	Repositories   []*issuesRequest  `yaml:"repositories"`
+	Token          string            `yaml:"token"`
+	GitLabToken    string            `yaml:"gitlab-token"`
+	Kind           string            `yaml:"kind"`
+	Labels         []string          `yaml:"labels"`
+	Assignee       string            `yaml:"assignee"`
+	Author         string            `yaml:"author"`
+	Limit          int               `yaml:"limit"`
+	CollapseAfter  int               `yaml:"collapse-after"`
+	ShowSourceIcon bool              `yaml:"show-source-icon"`
+	Issues         []repositoryIssue `yaml:"-"`
+
+	rateLimits forgeRateLimits
+}
+
+type issuesRequest struct {
+	Repository string `yaml:"repository"`
+	Provider   string `yaml:"provider"`
+	BaseURL    string `yaml:"base-url"`
+	Token      string `yaml:"token"`
+
+	source releaseSource
+	token  *string
+}
+
+type repositoryIssue struct {
+	Source        releaseSource
+	SourceIconURL string
+	Repository    string
+	Number        int
+	Title         string
+	Author        string
+	URL           string
+	IsPullRequest bool
+	IsDraft       bool
+	TimeCreated   time.Time
+	TimeUpdated   time.Time
+}
+
+// The last known API quota of a host, shared by all repositories on it since
+// the quota applies to the token rather than the repository
+type forgeRateLimit struct {
+	Remaining int
+	Limit     int
+	Reset     time.Time
+}
+
+// Keeps track of the quotas of the hosts a widget makes requests to
+type forgeRateLimits struct {
+	mu     sync.Mutex
+	byHost map[string]*forgeRateLimit
+}
+
+var issueKinds = []string{"all", "issues", "pull-requests"}
+
+// Below this many remaining requests a notice is shown on the widget
+const forgeRateLimitLowThreshold = 10
+
+func (widget *issuesWidget) initialize() error {
+	widget.withTitle("Issues").withCacheDuration(30 * time.Minute)
+
+	if len(widget.Repositories) == 0 {
+		return errors.New("at least one repository is required")
+	}
+
+	if widget.Kind == "" {
+		widget.Kind = "all"
+	} else if !slices.Contains(issueKinds, widget.Kind) {
+		return fmt.Errorf("kind must be one of %s", strings.Join(issueKinds, ", "))
+	}
+
+	if widget.Limit <= 0 {
+		widget.Limit = 10
+	}
+
+	if widget.CollapseAfter == 0 || widget.CollapseAfter < -1 {
+		widget.CollapseAfter = 5
+	}
+
+	for _, r := range widget.Repositories {
+		if r.Token != "" {
+			r.token = &r.Token
+		} else if r.source == releaseSourceGithub && widget.Token != "" {
+			r.token = &widget.Token
+		} else if r.source == releaseSourceGitlab && widget.GitLabToken != "" {
+			r.token = &widget.GitLabToken
+		}
+	}
+
+	return nil
+}
+
+func (widget *issuesWidget) update(ctx context.Context) {
+	issues, err := widget.fetchIssues(ctx)
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	if len(issues) > widget.Limit {
+		issues = issues[:widget.Limit]
+	}
+
+	for i := range issues {
+		issues[i].SourceIconURL = widget.Providers.assetResolver("icons/" + string(issues[i].Source) + ".svg")
+	}
+
+	widget.Issues = issues
+
+	if widget.Notice == nil {
+		if warning := widget.rateLimits.lowWarning(); warning != nil {
+			widget.withNotice(warning)
+		}
+	}
+}
+
+func (widget *issuesWidget) dataModel() any {
+	return &widget.Issues
+}
+
+func (widget *issuesWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, issuesWidgetTemplate)
+}
+
+func (r *issuesRequest) UnmarshalYAML(node *yaml.Node) error {
+	type issuesRequestAlias issuesRequest
+	var repository string
+
+	if err := node.Decode(&repository); err != nil {
+		if err := node.Decode((*issuesRequestAlias)(r)); err != nil {
+			return fmt.Errorf("could not unmarshal repository into string or struct: %v", err)
+		}
+	} else {
+		r.Repository = repository
+	}
+
+	if r.Repository == "" {
+		return errors.New("repository is required")
+	}
+
+	repository, source, err := parseRepositoryWithProvider(r.Repository, r.Provider)
+	if err != nil {
+		return err
+	}
+
+	if source == releaseSourceDockerHub {
+		return fmt.Errorf("repository %s: dockerhub has no issues", repository)
+	}
+
+	r.Repository = repository
+	r.source = source
+	r.BaseURL = strings.TrimRight(r.BaseURL, "/")
+
+	if r.source == releaseSourceGitea && r.BaseURL == "" {
+		return fmt.Errorf("base-url is required for gitea repository %s", r.Repository)
+	}
+
+	return nil
+}
+
+func (r *issuesRequest) baseURL() string {
+	return forgeBaseURL(r.source, r.BaseURL)
+}
+
+// The base URL of the API of a source, unless a self-hosted instance is used
+func forgeBaseURL(source releaseSource, baseURL string) string {
+	if baseURL != "" {
+		return baseURL
+	}
+
+	switch source {
+	case releaseSourceGithub:
+		return "https://api.github.com"
+	case releaseSourceGitlab:
+		return "https://gitlab.com"
+	case releaseSourceCodeberg:
+		return "https://codeberg.org"
+	}
+
+	return ""
+}
+
+func (widget *issuesWidget) fetchIssues(ctx context.Context) ([]repositoryIssue, error) {
+	job := newJob(func(request *issuesRequest) ([]repositoryIssue, error) {
+		return widget.fetchIssuesTask(ctx, request)
+	}, widget.Repositories).withWorkers(10)
+
+	results, errs, err := workerPoolDo(job)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed int
+	var firstErr error
+	issues := make([]repositoryIssue, 0, widget.Limit)
+
+	for i := range results {
+		if errs[i] != nil {
+			failed++
+			firstErr = ternary(firstErr == nil, errs[i], firstErr)
+			slog.Error("Failed to fetch issues", "source", widget.Repositories[i].source, "repository", widget.Repositories[i].Repository, "error", errs[i])
+			continue
+		}
+
+		issues = append(issues, results[i]...)
+	}
+
+	if failed == len(results) {
+		return nil, fmt.Errorf("%w: %v", errNoContent, firstErr)
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].TimeUpdated.After(issues[j].TimeUpdated)
+	})
+
+	if failed > 0 {
+		return issues, fmt.Errorf("%w: could not get issues of %d repositories", errPartialContent, failed)
+	}
+
+	return issues, nil
+}
+
+func (widget *issuesWidget) fetchIssuesTask(ctx context.Context, request *issuesRequest) ([]repositoryIssue, error) {
+	if err := widget.rateLimits.exceeded(request.baseURL()); err != nil {
+		return nil, err
+	}
+
+	switch request.source {
+	case releaseSourceGithub:
+		return widget.fetchGithubIssues(ctx, request)
+	case releaseSourceGitlab:
+		return widget.fetchGitLabIssues(ctx, request)
+	case releaseSourceGitea, releaseSourceCodeberg:
+		return widget.fetchGiteaIssues(ctx, request)
+	}
+
+	return nil, errors.New("unsupported source")
+}
+
+// When the quota has run out there's no point in making requests
+// that are guaranteed to fail until it resets
+func (l *forgeRateLimits) exceeded(host string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, exists := l.byHost[host]
+	if exists && limit.Remaining == 0 && time.Now().Before(limit.Reset) {
+		return fmt.Errorf("API rate limit of %s exceeded, resets at %s", host, limit.Reset.Format("15:04"))
+	}
+
+	return nil
+}
+
+// Decodes the response while keeping track of the quota reported through its
+// headers. GitHub uses the X-RateLimit prefix, GitLab uses none
+func (l *forgeRateLimits) decode(request *http.Request, host string, v any) error {
+	_, err := l.decodeWithHeader(request, host, v)
+	return err
+}
+
+// Same as decode, also returning the headers of the response for the APIs
+// which report totals through them
+func (l *forgeRateLimits) decodeWithHeader(request *http.Request, host string, v any) (http.Header, error) {
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	limit := parseForgeRateLimit(response.Header)
+	if limit != nil {
+		l.mu.Lock()
+		if l.byHost == nil {
+			l.byHost = make(map[string]*forgeRateLimit)
+		}
+		l.byHost[host] = limit
+		l.mu.Unlock()
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		if limit != nil && limit.Remaining == 0 && (response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusTooManyRequests) {
+			return nil, fmt.Errorf("API rate limit of %s exceeded, resets at %s", host, limit.Reset.Format("15:04"))
+		}
+
+		truncatedBody, _ := limitStringLength(string(body), 256)
+		return nil, fmt.Errorf("unexpected status code %d from %s, response: %s", response.StatusCode, request.URL, truncatedBody)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, fmt.Errorf("decoding response: %v", err)
+	}
+
+	return response.Header, nil
+}
+
+func parseForgeRateLimit(header http.Header) *forgeRateLimit {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, err := strconv.Atoi(header.Get(prefix + "Remaining"))
+		if err != nil {
+			continue
+		}
+
+		limit := &forgeRateLimit{Remaining: remaining}
+		limit.Limit, _ = strconv.Atoi(header.Get(prefix + "Limit"))
+
+		if reset, err := strconv.ParseInt(header.Get(prefix+"Reset"), 10, 64); err == nil {
+			limit.Reset = time.Unix(reset, 0)
+		}
+
+		return limit
+	}
+
+	return nil
+}
+
+func (l *forgeRateLimits) lowWarning() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for host, limit := range l.byHost {
+		if limit.Remaining >= forgeRateLimitLowThreshold || time.Now().After(limit.Reset) {
+			continue
+		}
+
+		return fmt.Errorf("only %d of %d API requests to %s remaining until %s", limit.Remaining, limit.Limit, host, limit.Reset.Format("15:04"))
+	}
+
+	return nil
+}
+
+type githubIssueResponseJson struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	HtmlUrl   string `json:"html_url"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Draft     bool   `json:"draft"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// The issues endpoint also returns pull requests, which unlike the pulls
+// endpoint makes it possible to filter them by labels and assignee
+func (widget *issuesWidget) fetchGithubIssues(ctx context.Context, request *issuesRequest) ([]repositoryIssue, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("sort", "updated")
+	query.Set("direction", "desc")
+	// issues and pull requests are filtered out afterwards, so more are
+	// requested to make up for the ones that get left out
+	query.Set("per_page", strconv.Itoa(ternary(widget.Kind == "all", widget.Limit, min(100, widget.Limit*3))))
+
+	if len(widget.Labels) > 0 {
+		query.Set("labels", strings.Join(widget.Labels, ","))
+	}
+	if widget.Assignee != "" {
+		query.Set("assignee", widget.Assignee)
+	}
+	if widget.Author != "" {
+		query.Set("creator", widget.Author)
+	}
+
+	host := request.baseURL()
+	httpRequest, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/repos/%s/issues?%s", host, request.Repository, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest.Header.Set("Accept", "application/vnd.github+json")
+	if request.token != nil {
+		httpRequest.Header.Set("Authorization", "Bearer "+(*request.token))
+	}
+
+	var responses []githubIssueResponseJson
+	if err := widget.rateLimits.decode(httpRequest, host, &responses); err != nil {
+		return nil, err
+	}
+
+	issues := make([]repositoryIssue, 0, len(responses))
+	for i := range responses {
+		response := &responses[i]
+		isPullRequest := response.PullRequest != nil
+
+		if !widget.includesKind(isPullRequest) {
+			continue
+		}
+
+		issues = append(issues, repositoryIssue{
+			Source:        releaseSourceGithub,
+			Repository:    request.Repository,
+			Number:        response.Number,
+			Title:         response.Title,
+			Author:        response.User.Login,
+			URL:           response.HtmlUrl,
+			IsPullRequest: isPullRequest,
+			IsDraft:       response.Draft,
+			TimeCreated:   parseRFC3339Time(response.CreatedAt),
+			TimeUpdated:   parseRFC3339Time(response.UpdatedAt),
+		})
+	}
+
+	return issues, nil
+}
+
+type gitlabIssueResponseJson struct {
+	IID       int    `json:"iid"`
+	Title     string `json:"title"`
+	WebUrl    string `json:"web_url"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Draft     bool   `json:"draft"`
+	Author    struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// Issues and merge requests have separate endpoints on GitLab which
+// accept the same filters
+func (widget *issuesWidget) fetchGitLabIssues(ctx context.Context, request *issuesRequest) ([]repositoryIssue, error) {
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("order_by", "updated_at")
+	query.Set("sort", "desc")
+	query.Set("per_page", strconv.Itoa(widget.Limit))
+
+	if len(widget.Labels) > 0 {
+		query.Set("labels", strings.Join(widget.Labels, ","))
+	}
+	if widget.Assignee != "" {
+		query.Set("assignee_username", widget.Assignee)
+	}
+	if widget.Author != "" {
+		query.Set("author_username", widget.Author)
+	}
+
+	host := request.baseURL()
+	var issues []repositoryIssue
+
+	for _, isMergeRequest := range []bool{false, true} {
+		if !widget.includesKind(isMergeRequest) {
+			continue
+		}
+
+		httpRequest, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(
+			"%s/api/v4/projects/%s/%s?%s",
+			host,
+			url.QueryEscape(request.Repository),
+			ternary(isMergeRequest, "merge_requests", "issues"),
+			query.Encode(),
+		), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if request.token != nil {
+			httpRequest.Header.Set("PRIVATE-TOKEN", *request.token)
+		}
+
+		var responses []gitlabIssueResponseJson
+		if err := widget.rateLimits.decode(httpRequest, host, &responses); err != nil {
+			return nil, err
+		}
+
+		for i := range responses {
+			response := &responses[i]
+			issues = append(issues, repositoryIssue{
+				Source:        releaseSourceGitlab,
+				Repository:    request.Repository,
+				Number:        response.IID,
+				Title:         response.Title,
+				Author:        response.Author.Username,
+				URL:           response.WebUrl,
+				IsPullRequest: isMergeRequest,
+				IsDraft:       response.Draft,
+				TimeCreated:   parseRFC3339Time(response.CreatedAt),
+				TimeUpdated:   parseRFC3339Time(response.UpdatedAt),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+type giteaIssueResponseJson struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	HtmlUrl   string `json:"html_url"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	PullRequest *struct {
+		Draft bool `json:"draft"`
+	} `json:"pull_request"`
+}
+
+// Used for both Codeberg and self-hosted Gitea and Forgejo instances
+// since they all share the same API
+func (widget *issuesWidget) fetchGiteaIssues(ctx context.Context, request *issuesRequest) ([]repositoryIssue, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("limit", strconv.Itoa(widget.Limit))
+
+	switch widget.Kind {
+	case "issues":
+		query.Set("type", "issues")
+	case "pull-requests":
+		query.Set("type", "pulls")
+	}
+
+	if len(widget.Labels) > 0 {
+		query.Set("labels", strings.Join(widget.Labels, ","))
+	}
+	if widget.Assignee != "" {
+		query.Set("assigned_by", widget.Assignee)
+	}
+	if widget.Author != "" {
+		query.Set("created_by", widget.Author)
+	}
+
+	host := request.baseURL()
+	httpRequest, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/repos/%s/issues?%s", host, request.Repository, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.token != nil {
+		httpRequest.Header.Set("Authorization", "token "+(*request.token))
+	}
+
+	var responses []giteaIssueResponseJson
+	if err := widget.rateLimits.decode(httpRequest, host, &responses); err != nil {
+		return nil, err
+	}
+
+	issues := make([]repositoryIssue, 0, len(responses))
+	for i := range responses {
+		response := &responses[i]
+		isPullRequest := response.PullRequest != nil
+
+		if !widget.includesKind(isPullRequest) {
+			continue
+		}
+
+		issues = append(issues, repositoryIssue{
+			Source:        request.source,
+			Repository:    request.Repository,
+			Number:        response.Number,
+			Title:         response.Title,
+			Author:        response.User.Login,
+			URL:           response.HtmlUrl,
+			IsPullRequest: isPullRequest,
+			IsDraft:       isPullRequest && response.PullRequest.Draft,
+			TimeCreated:   parseRFC3339Time(response.CreatedAt),
+			TimeUpdated:   parseRFC3339Time(response.UpdatedAt),
+		})
+	}
+
+	return issues, nil
+}
+
+func (widget *issuesWidget) includesKind(isPullRequest bool) bool {
+	switch widget.Kind {
+	case "issues":
+		return !isPullRequest
+	case "pull-requests":
+		return isPullRequest
+	}
+
+	return true
+}
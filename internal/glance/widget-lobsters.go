@@ -58,6 +58,10 @@ func (widget *lobstersWidget) update(ctx context.Context) {
 	widget.Posts = posts
 }
 
+func (widget *lobstersWidget) dataModel() any {
+	return &widget.Posts
+}
+
 func (widget *lobstersWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, forumPostsTemplate)
 }
@@ -70,6 +74,7 @@ type lobstersPostResponseJson struct {
 	CommentCount int      `json:"comment_count"`
 	CommentsURL  string   `json:"comments_url"`
 	Tags         []string `json:"tags"`
+	Description  string   `json:"description_plain"`
 }
 
 type lobstersFeedResponseJson []lobstersPostResponseJson
@@ -110,29 +115,9 @@ func fetchLobstersPostsFromFeed(feedUrl string) (forumPostList, error) {
 }
 
 func fetchLobstersPosts(customURL string, instanceURL string, sortBy string, tags []string) (forumPostList, error) {
-	var feedUrl string
-
-	if customURL != "" {
-		feedUrl = customURL
-	} else {
-		if instanceURL != "" {
-			instanceURL = strings.TrimRight(instanceURL, "/") + "/"
-		} else {
-			instanceURL = "https://lobste.rs/"
-		}
-
-		if sortBy == "hot" {
-			sortBy = "hottest"
-		} else if sortBy == "new" {
-			sortBy = "newest"
-		}
-
-		if len(tags) == 0 {
-			feedUrl = instanceURL + sortBy + ".json"
-		} else {
-			tags := strings.Join(tags, ",")
-			feedUrl = instanceURL + "t/" + tags + ".json"
-		}
+	feedUrl := customURL
+	if feedUrl == "" {
+		feedUrl = lobstersFeedURL(instanceURL, sortBy, tags)
 	}
 
 	posts, err := fetchLobstersPostsFromFeed(feedUrl)
@@ -142,3 +127,21 @@ func fetchLobstersPosts(customURL string, instanceURL string, sortBy string, tag
 
 	return posts, nil
 }
+
+func lobstersFeedURL(instanceURL string, sortBy string, tags []string) string {
+	if instanceURL != "" {
+		instanceURL = strings.TrimRight(instanceURL, "/") + "/"
+	} else {
+		instanceURL = "https://lobste.rs/"
+	}
+
+	if len(tags) > 0 {
+		return instanceURL + "t/" + strings.Join(tags, ",") + ".json"
+	}
+
+	if sortBy == "new" {
+		return instanceURL + "newest.json"
+	}
+
+	return instanceURL + "hottest.json"
+}
@@ -2,39 +2,89 @@ package glance
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log/slog"
 	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tidwall/gjson"
 )
 
 var marketsWidgetTemplate = mustParseTemplate("markets.html", "widget-base.html")
 
 type marketsWidget struct {
 	widgetBase         `yaml:",inline"`
-	StocksRequests     []marketRequest `yaml:"stocks"`
-	MarketRequests     []marketRequest `yaml:"markets"`
-	ChartLinkTemplate  string          `yaml:"chart-link-template"`
-	SymbolLinkTemplate string          `yaml:"symbol-link-template"`
-	Sort               string          `yaml:"sort-by"`
-	Markets            marketList      `yaml:"-"`
+	MarketRequests     []marketRequest       `yaml:"markets"`
+	ChartLinkTemplate  string                `yaml:"chart-link-template"`
+	SymbolLinkTemplate string                `yaml:"symbol-link-template"`
+	Sort               string                `yaml:"sort-by"`
+	ChartTimeframe     string                `yaml:"chart-timeframe"`
+	ChartPoints        int                   `yaml:"chart-points"`
+	AlertNotifications bool                  `yaml:"alert-notifications"`
+	Provider           string                `yaml:"provider"`
+	CustomProvider     *customMarketProvider `yaml:"custom-provider"`
+	Markets            marketList            `yaml:"-"`
 }
 
 func (widget *marketsWidget) initialize() error {
 	widget.withTitle("Markets").withCacheDuration(time.Hour)
 
-	// legacy support, remove in v0.10.0
-	if len(widget.MarketRequests) == 0 {
-		widget.MarketRequests = widget.StocksRequests
+	if widget.ChartTimeframe == "" {
+		widget.ChartTimeframe = "daily"
+	} else if widget.ChartTimeframe != "daily" && widget.ChartTimeframe != "intraday" {
+		return errors.New("chart-timeframe must be either daily or intraday")
+	}
+
+	if widget.ChartPoints < 0 {
+		return errors.New("chart-points cannot be negative")
+	} else if widget.ChartPoints == 0 {
+		widget.ChartPoints = ternary(widget.ChartTimeframe == "daily", marketChartDays, marketChartIntradayPoints)
+	}
+
+	if widget.Provider == "" {
+		widget.Provider = "yahoo"
+	} else if !slices.Contains(marketProviders, widget.Provider) {
+		return fmt.Errorf("provider must be one of %s", strings.Join(marketProviders, ", "))
+	}
+
+	if widget.CustomProvider != nil {
+		if err := widget.CustomProvider.initialize(); err != nil {
+			return fmt.Errorf("custom-provider: %v", err)
+		}
 	}
 
 	for i := range widget.MarketRequests {
 		m := &widget.MarketRequests[i]
 
+		if m.Provider == "" {
+			m.Provider = widget.Provider
+		} else if !slices.Contains(marketProviders, m.Provider) {
+			return fmt.Errorf("market %s: provider must be one of %s", m.Symbol, strings.Join(marketProviders, ", "))
+		}
+
+		if m.CustomProvider == nil {
+			m.CustomProvider = widget.CustomProvider
+		} else if err := m.CustomProvider.initialize(); err != nil {
+			return fmt.Errorf("market %s: custom-provider: %v", m.Symbol, err)
+		}
+
+		if m.Provider == "custom" && m.CustomProvider == nil {
+			return fmt.Errorf("market %s: custom-provider is required when using the custom provider", m.Symbol)
+		}
+
+		if m.Provider == "coingecko" && m.QuoteCurrency == "" {
+			m.QuoteCurrency = "usd"
+		}
+
 		if widget.ChartLinkTemplate != "" && m.ChartLink == "" {
 			m.ChartLink = strings.ReplaceAll(widget.ChartLinkTemplate, "{SYMBOL}", m.Symbol)
 		}
@@ -42,13 +92,17 @@ func (widget *marketsWidget) initialize() error {
 		if widget.SymbolLinkTemplate != "" && m.SymbolLink == "" {
 			m.SymbolLink = strings.ReplaceAll(widget.SymbolLinkTemplate, "{SYMBOL}", m.Symbol)
 		}
+
+		if m.AlertAbove != nil && m.AlertBelow != nil && *m.AlertBelow >= *m.AlertAbove {
+			return fmt.Errorf("market %s: alert-below must be lower than alert-above", m.Symbol)
+		}
 	}
 
 	return nil
 }
 
 func (widget *marketsWidget) update(ctx context.Context) {
-	markets, err := fetchMarketsDataFromYahoo(widget.MarketRequests)
+	markets, err := fetchMarketsData(widget.httpClientOptions.httpClient(false), widget.MarketRequests, widget.ChartTimeframe, widget.ChartPoints)
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
@@ -63,15 +117,27 @@ func (widget *marketsWidget) update(ctx context.Context) {
 	widget.Markets = markets
 }
 
+func (widget *marketsWidget) dataModel() any {
+	return &widget.Markets
+}
+
 func (widget *marketsWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, marketsWidgetTemplate)
 }
 
+var marketProviders = []string{"yahoo", "coingecko", "custom"}
+
 type marketRequest struct {
-	CustomName string `yaml:"name"`
-	Symbol     string `yaml:"symbol"`
-	ChartLink  string `yaml:"chart-link"`
-	SymbolLink string `yaml:"symbol-link"`
+	CustomName    string   `yaml:"name"`
+	Symbol        string   `yaml:"symbol"`
+	ChartLink     string   `yaml:"chart-link"`
+	SymbolLink    string   `yaml:"symbol-link"`
+	AlertAbove    *float64 `yaml:"alert-above"`
+	AlertBelow    *float64 `yaml:"alert-below"`
+	Provider      string   `yaml:"provider"`
+	QuoteCurrency string   `yaml:"currency"`
+	// excluded from the cached data since the headers can contain API keys
+	CustomProvider *customMarketProvider `yaml:"custom-provider" json:"-"`
 }
 
 type market struct {
@@ -82,6 +148,20 @@ type market struct {
 	PriceHint      int
 	PercentChange  float64
 	SvgChartPoints string
+	// Either above or below when the price has crossed one of the thresholds
+	Alert string
+}
+
+func (r *marketRequest) alertForPrice(price float64) string {
+	if r.AlertAbove != nil && price >= *r.AlertAbove {
+		return "above"
+	}
+
+	if r.AlertBelow != nil && price <= *r.AlertBelow {
+		return "below"
+	}
+
+	return ""
 }
 
 type marketList []market
@@ -118,76 +198,78 @@ type marketResponseJson struct {
 	} `json:"chart"`
 }
 
-// TODO: allow changing chart time frame
 const marketChartDays = 21
 
-func fetchMarketsDataFromYahoo(marketRequests []marketRequest) (marketList, error) {
-	requests := make([]*http.Request, 0, len(marketRequests))
+// a full trading day at 15 minute intervals
+const marketChartIntradayPoints = 26
+
+// What each provider returns before being turned into a market, so that
+// everything from the currency symbol to the alerts works the same way
+// regardless of where the data came from
+type marketQuote struct {
+	name          string
+	currency      string
+	price         float64
+	priceHint     int
+	percentChange float64
+	prices        []float64
+}
+
+func fetchMarketsData(client requestDoer, marketRequests []marketRequest, timeframe string, chartPoints int) (marketList, error) {
+	fetchQuote := func(request *marketRequest) (marketQuote, error) {
+		switch request.Provider {
+		case "coingecko":
+			return fetchMarketQuoteFromCoinGecko(client, request, timeframe, chartPoints)
+		case "custom":
+			return fetchMarketQuoteFromCustomProvider(client, request)
+		default:
+			return fetchMarketQuoteFromYahoo(client, request, timeframe)
+		}
+	}
 
+	requests := make([]*marketRequest, len(marketRequests))
 	for i := range marketRequests {
-		request, _ := http.NewRequest("GET", fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s?range=1mo&interval=1d", marketRequests[i].Symbol), nil)
-		setBrowserUserAgentHeader(request)
-		requests = append(requests, request)
+		requests[i] = &marketRequests[i]
 	}
 
-	job := newJob(decodeJsonFromRequestTask[marketResponseJson](defaultHTTPClient), requests)
-	responses, errs, err := workerPoolDo(job)
+	quotes, errs, err := workerPoolDo(newJob(fetchQuote, requests))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errNoContent, err)
 	}
 
-	markets := make(marketList, 0, len(responses))
+	markets := make(marketList, 0, len(quotes))
 	var failed int
 
-	for i := range responses {
+	for i := range quotes {
 		if errs[i] != nil {
 			failed++
-			slog.Error("Failed to fetch market data", "symbol", marketRequests[i].Symbol, "error", errs[i])
-			continue
-		}
-
-		response := responses[i]
-
-		if len(response.Chart.Result) == 0 {
-			failed++
-			slog.Error("Market response contains no data", "symbol", marketRequests[i].Symbol)
+			slog.Error("Failed to fetch market data", "symbol", marketRequests[i].Symbol, "provider", marketRequests[i].Provider, "error", errs[i])
 			continue
 		}
 
-		result := &response.Chart.Result[0]
-		prices := result.Indicators.Quote[0].Close
-
-		if len(prices) > marketChartDays {
-			prices = prices[len(prices)-marketChartDays:]
-		}
-
-		previous := result.Meta.RegularMarketPrice
+		quote := &quotes[i]
+		prices := quote.prices
 
-		if len(prices) >= 2 && prices[len(prices)-2] != 0 {
-			previous = prices[len(prices)-2]
+		if len(prices) > chartPoints {
+			prices = prices[len(prices)-chartPoints:]
 		}
 
 		points := svgPolylineCoordsFromYValues(100, 50, maybeCopySliceWithoutZeroValues(prices))
 
-		currency, exists := currencyToSymbol[strings.ToUpper(result.Meta.Currency)]
+		currency, exists := currencyToSymbol[strings.ToUpper(quote.currency)]
 		if !exists {
-			currency = result.Meta.Currency
+			currency = quote.currency
 		}
 
 		markets = append(markets, market{
-			marketRequest: marketRequests[i],
-			Price:         result.Meta.RegularMarketPrice,
-			Currency:      currency,
-			PriceHint:     result.Meta.PriceHint,
-			Name: ternary(marketRequests[i].CustomName == "",
-				result.Meta.ShortName,
-				marketRequests[i].CustomName,
-			),
-			PercentChange: percentChange(
-				result.Meta.RegularMarketPrice,
-				previous,
-			),
+			marketRequest:  marketRequests[i],
+			Price:          quote.price,
+			Currency:       currency,
+			PriceHint:      quote.priceHint,
+			Name:           ternary(marketRequests[i].CustomName == "", quote.name, marketRequests[i].CustomName),
+			PercentChange:  quote.percentChange,
 			SvgChartPoints: points,
+			Alert:          marketRequests[i].alertForPrice(quote.price),
 		})
 	}
 
@@ -202,6 +284,212 @@ func fetchMarketsDataFromYahoo(marketRequests []marketRequest) (marketList, erro
 	return markets, nil
 }
 
+func fetchMarketQuoteFromYahoo(client requestDoer, marketRequest *marketRequest, timeframe string) (marketQuote, error) {
+	query := ternary(timeframe == "intraday", "range=1d&interval=15m", "range=1mo&interval=1d")
+	request, _ := http.NewRequest("GET", fmt.Sprintf("https://query1.finance.yahoo.com/v8/finance/chart/%s?%s", marketRequest.Symbol, query), nil)
+	setBrowserUserAgentHeader(request)
+
+	response, err := decodeJsonFromRequest[marketResponseJson](client, request)
+	if err != nil {
+		return marketQuote{}, err
+	}
+
+	if len(response.Chart.Result) == 0 {
+		return marketQuote{}, errors.New("response contains no data")
+	}
+
+	result := &response.Chart.Result[0]
+
+	// intraday history can be unavailable, such as for symbols which
+	// haven't traded yet today, in which case only the price is shown
+	var prices []float64
+	if len(result.Indicators.Quote) > 0 {
+		prices = result.Indicators.Quote[0].Close
+	}
+
+	previous := result.Meta.RegularMarketPrice
+
+	if timeframe == "intraday" {
+		if result.Meta.ChartPreviousClose != 0 {
+			previous = result.Meta.ChartPreviousClose
+		}
+	} else if len(prices) >= 2 && prices[len(prices)-2] != 0 {
+		previous = prices[len(prices)-2]
+	}
+
+	return marketQuote{
+		name:          result.Meta.ShortName,
+		currency:      result.Meta.Currency,
+		price:         result.Meta.RegularMarketPrice,
+		priceHint:     result.Meta.PriceHint,
+		percentChange: percentChange(result.Meta.RegularMarketPrice, previous),
+		prices:        prices,
+	}, nil
+}
+
+type coinGeckoMarketChartResponseJson struct {
+	// pairs of a timestamp in milliseconds and a price
+	Prices [][2]float64 `json:"prices"`
+}
+
+// The symbol is the ID of the coin as seen in the URL of its page on CoinGecko,
+// such as bitcoin, rather than its ticker
+func fetchMarketQuoteFromCoinGecko(client requestDoer, marketRequest *marketRequest, timeframe string, chartPoints int) (marketQuote, error) {
+	query := url.Values{}
+	query.Set("vs_currency", strings.ToLower(marketRequest.QuoteCurrency))
+
+	if timeframe == "intraday" {
+		// last 24 hours at 5 minute intervals
+		query.Set("days", "1")
+	} else {
+		query.Set("days", strconv.Itoa(chartPoints))
+		query.Set("interval", "daily")
+	}
+
+	request, _ := http.NewRequest(
+		"GET",
+		"https://api.coingecko.com/api/v3/coins/"+url.PathEscape(marketRequest.Symbol)+"/market_chart?"+query.Encode(),
+		nil,
+	)
+	request.Header.Set("User-Agent", glanceUserAgentString)
+
+	response, err := decodeJsonFromRequest[coinGeckoMarketChartResponseJson](client, request)
+	if err != nil {
+		return marketQuote{}, err
+	}
+
+	if len(response.Prices) == 0 {
+		return marketQuote{}, errors.New("response contains no prices")
+	}
+
+	prices := make([]float64, 0, len(response.Prices))
+
+	if timeframe == "intraday" {
+		// keep the same 15 minute intervals as the other providers, counting
+		// back from the latest price so that it's always included
+		for i := len(response.Prices) - 1; i >= 0; i -= 3 {
+			prices = append(prices, response.Prices[i][1])
+		}
+		slices.Reverse(prices)
+	} else {
+		for i := range response.Prices {
+			prices = append(prices, response.Prices[i][1])
+		}
+	}
+
+	price := response.Prices[len(response.Prices)-1][1]
+	previous := price
+
+	// crypto trades around the clock, so the change for intraday is over the
+	// last 24 hours and for daily it's since the last daily price
+	if timeframe == "intraday" {
+		previous = response.Prices[0][1]
+	} else if len(response.Prices) >= 2 {
+		previous = response.Prices[len(response.Prices)-2][1]
+	}
+
+	return marketQuote{
+		currency:      marketRequest.QuoteCurrency,
+		price:         price,
+		priceHint:     priceHintFromPrice(price),
+		percentChange: percentChange(price, previous),
+		prices:        prices,
+	}, nil
+}
+
+type customMarketProvider struct {
+	URL               string            `yaml:"url"`
+	Headers           map[string]string `yaml:"headers"`
+	PricePath         string            `yaml:"price-path"`
+	ChangePath        string            `yaml:"change-path"`
+	PreviousPricePath string            `yaml:"previous-price-path"`
+	NamePath          string            `yaml:"name-path"`
+	CurrencyPath      string            `yaml:"currency-path"`
+	ChartPath         string            `yaml:"chart-path"`
+}
+
+func (p *customMarketProvider) initialize() error {
+	if p.URL == "" {
+		return errors.New("url is required")
+	}
+
+	if p.PricePath == "" {
+		return errors.New("price-path is required")
+	}
+
+	return nil
+}
+
+func fetchMarketQuoteFromCustomProvider(client requestDoer, marketRequest *marketRequest) (marketQuote, error) {
+	provider := marketRequest.CustomProvider
+
+	request, err := http.NewRequest("GET", strings.ReplaceAll(provider.URL, "{SYMBOL}", url.PathEscape(marketRequest.Symbol)), nil)
+	if err != nil {
+		return marketQuote{}, err
+	}
+
+	request.Header.Set("User-Agent", glanceUserAgentString)
+	for key, value := range provider.Headers {
+		request.Header.Set(key, value)
+	}
+
+	body, err := decodeJsonFromRequest[json.RawMessage](client, request)
+	if err != nil {
+		return marketQuote{}, err
+	}
+
+	get := func(path string) gjson.Result {
+		if path == "" {
+			return gjson.Result{}
+		}
+
+		return gjson.GetBytes(body, path)
+	}
+
+	priceResult := get(provider.PricePath)
+	if !priceResult.Exists() {
+		return marketQuote{}, fmt.Errorf("price-path %q did not match anything in the response", provider.PricePath)
+	}
+
+	quote := marketQuote{
+		name:     get(provider.NamePath).String(),
+		currency: marketRequest.QuoteCurrency,
+		price:    priceResult.Float(),
+	}
+	quote.priceHint = priceHintFromPrice(quote.price)
+
+	if currency := get(provider.CurrencyPath).String(); currency != "" {
+		quote.currency = currency
+	}
+
+	for _, point := range get(provider.ChartPath).Array() {
+		quote.prices = append(quote.prices, point.Float())
+	}
+
+	if change := get(provider.ChangePath); change.Exists() {
+		quote.percentChange = change.Float()
+	} else if previous := get(provider.PreviousPricePath); previous.Exists() {
+		quote.percentChange = percentChange(quote.price, previous.Float())
+	} else if len(quote.prices) >= 2 {
+		quote.percentChange = percentChange(quote.price, quote.prices[len(quote.prices)-2])
+	}
+
+	return quote, nil
+}
+
+// Unlike Yahoo, the other providers don't say how many decimals a price should
+// be shown with, which matters for coins that are worth a fraction of a cent
+func priceHintFromPrice(price float64) int {
+	switch abs := math.Abs(price); {
+	case abs == 0 || abs >= 1:
+		return 2
+	case abs >= 0.01:
+		return 4
+	default:
+		return 8
+	}
+}
+
 var currencyToSymbol = map[string]string{
 	"USD": "$",
 	"EUR": "€",
@@ -1,13 +1,27 @@
 package glance
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -28,15 +42,42 @@ type monitorWidget struct {
 		StatusText         string          `yaml:"-"`
 		StatusStyle        string          `yaml:"-"`
 		AltStatusCodes     []int           `yaml:"alt-status-codes"`
+		History            []monitorSample `yaml:"-"`
 	} `yaml:"sites"`
-	Style           string `yaml:"style"`
-	ShowFailingOnly bool   `yaml:"show-failing-only"`
-	HasFailing      bool   `yaml:"-"`
+	Style           string                     `yaml:"style"`
+	ShowFailingOnly bool                       `yaml:"show-failing-only"`
+	HistorySize     int                        `yaml:"history"`
+	HasFailing      bool                       `yaml:"-"`
+	history         map[string][]monitorSample `yaml:"-"`
+}
+
+type monitorSample struct {
+	ResponseTime time.Duration
+	// one of ok, error or timeout
+	State string
+	// percentage relative to the slowest successful sample in the window
+	Height int
 }
 
 func (widget *monitorWidget) initialize() error {
 	widget.withTitle("Monitor").withCacheDuration(5 * time.Minute)
 
+	if widget.HistorySize < 0 {
+		return errors.New("history cannot be negative")
+	}
+
+	if widget.HistorySize > 0 {
+		widget.history = make(map[string][]monitorSample, len(widget.Sites))
+	}
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+
+		if err := site.initialize(&widget.httpClientOptions, site.AltStatusCodes); err != nil {
+			return fmt.Errorf("site %q: %v", site.Title, err)
+		}
+	}
+
 	return nil
 }
 
@@ -60,7 +101,8 @@ func (widget *monitorWidget) update(ctx context.Context) {
 		status := &statuses[i]
 		site.Status = status
 
-		if !slices.Contains(site.AltStatusCodes, status.Code) && (status.Code >= 400 || status.Error != nil) {
+		ok := status.Error == nil && status.AssertionFailure == ""
+		if !ok {
 			widget.HasFailing = true
 		}
 
@@ -70,11 +112,52 @@ func (widget *monitorWidget) update(ctx context.Context) {
 			site.URL = site.DefaultURL
 		}
 
-		site.StatusText = statusCodeToText(status.Code, site.AltStatusCodes)
-		site.StatusStyle = statusCodeToStyle(status.Code, site.AltStatusCodes)
+		site.StatusStyle = ternary(ok, "ok", "error")
+		site.StatusText = site.statusText(status)
+
+		// sites with the same URL can still be checked differently, such as
+		// through another method or expected status, so each has its own
+		if widget.HistorySize > 0 {
+			site.History = widget.recordSample(strconv.Itoa(i)+" "+site.checkedURL(), status, site.StatusStyle == "ok")
+		}
 	}
 }
 
+func (widget *monitorWidget) recordSample(key string, status *siteStatus, ok bool) []monitorSample {
+	sample := monitorSample{ResponseTime: status.ResponseTime, State: "ok"}
+
+	if status.TimedOut {
+		sample.State = "timeout"
+	} else if !ok {
+		sample.State = "error"
+	}
+
+	samples := append(widget.history[key], sample)
+	if len(samples) > widget.HistorySize {
+		samples = samples[len(samples)-widget.HistorySize:]
+	}
+
+	var slowest time.Duration
+	for i := range samples {
+		if samples[i].State == "ok" {
+			slowest = max(slowest, samples[i].ResponseTime)
+		}
+	}
+
+	for i := range samples {
+		if samples[i].State != "ok" || slowest == 0 {
+			samples[i].Height = 100
+			continue
+		}
+
+		samples[i].Height = max(10, int(samples[i].ResponseTime*100/slowest))
+	}
+
+	widget.history[key] = samples
+
+	return samples
+}
+
 func (widget *monitorWidget) Render() template.HTML {
 	if widget.Style == "compact" {
 		return widget.renderTemplate(widget, monitorWidgetCompactTemplate)
@@ -83,10 +166,7 @@ func (widget *monitorWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, monitorWidgetTemplate)
 }
 
-func statusCodeToText(status int, altStatusCodes []int) string {
-	if status == 200 || slices.Contains(altStatusCodes, status) {
-		return "OK"
-	}
+func statusCodeToText(status int) string {
 	if status == 404 {
 		return "Not Found"
 	}
@@ -106,15 +186,53 @@ func statusCodeToText(status int, altStatusCodes []int) string {
 	return strconv.Itoa(status)
 }
 
-func statusCodeToStyle(status int, altStatusCodes []int) string {
-	if status == 200 || slices.Contains(altStatusCodes, status) {
-		return "ok"
+var expectedStatusPattern = regexp.MustCompile(`^[1-5](?:\d\d|xx)$`)
+
+// Either a single status code or a list of them, each of which can also be a
+// class of status codes such as 2xx
+type expectedStatusField []string
+
+func (f *expectedStatusField) UnmarshalYAML(node *yaml.Node) error {
+	var values []string
+
+	if node.Kind == yaml.ScalarNode {
+		values = []string{node.Value}
+	} else if err := node.Decode(&values); err != nil {
+		return err
+	}
+
+	for i := range values {
+		value := strings.ToLower(strings.TrimSpace(values[i]))
+
+		if !expectedStatusPattern.MatchString(value) {
+			return fmt.Errorf("line %d: invalid expected-status %q, must be a status code such as 200 or a class such as 2xx", node.Line, values[i])
+		}
+
+		values[i] = value
+	}
+
+	*f = values
+	return nil
+}
+
+func (f expectedStatusField) matches(code int) bool {
+	codeString := strconv.Itoa(code)
+
+	for _, expected := range f {
+		if expected == codeString || (strings.HasSuffix(expected, "xx") && expected[0] == codeString[0] && len(codeString) == 3) {
+			return true
+		}
 	}
 
-	return "error"
+	return false
+}
+
+func (f expectedStatusField) String() string {
+	return strings.Join(f, ", ")
 }
 
 type SiteStatusRequest struct {
+	Method        string        `yaml:"method"`
 	DefaultURL    string        `yaml:"url"`
 	CheckURL      string        `yaml:"check-url"`
 	AllowInsecure bool          `yaml:"allow-insecure"`
@@ -123,6 +241,12 @@ type SiteStatusRequest struct {
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
 	} `yaml:"basic-auth"`
+	ExpectedStatus       expectedStatusField `yaml:"expected-status"`
+	ExpectedBodyContains string              `yaml:"expected-body-contains"`
+	ExpectedBodyRegex    string              `yaml:"expected-body-regex"`
+	expectedBodyRegex    *regexp.Regexp      `yaml:"-"`
+	altStatusCodes       []int               `yaml:"-"`
+	clientOptions        *httpClientOptions  `yaml:"-"`
 }
 
 type siteStatus struct {
@@ -130,17 +254,123 @@ type siteStatus struct {
 	TimedOut     bool
 	ResponseTime time.Duration
 	Error        error
+	// Set when the site was reachable but the response didn't match what
+	// was expected of it
+	AssertionFailure string
 }
 
-func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
-	var url string
-	if statusRequest.CheckURL != "" {
-		url = statusRequest.CheckURL
-	} else {
-		url = statusRequest.DefaultURL
+func (r *SiteStatusRequest) initialize(clientOptions *httpClientOptions, altStatusCodes []int) error {
+	r.Method = strings.ToLower(r.Method)
+	r.clientOptions = clientOptions
+
+	switch r.Method {
+	case "":
+		r.Method = "http"
+	case "http", "tcp", "icmp":
+	default:
+		return fmt.Errorf("unsupported method %q, must be one of http, tcp or icmp", r.Method)
+	}
+
+	hasAssertions := len(r.ExpectedStatus) > 0 || r.ExpectedBodyContains != "" || r.ExpectedBodyRegex != ""
+	if hasAssertions && r.Method != "http" {
+		return errors.New("expected-status, expected-body-contains and expected-body-regex can only be used with the http method")
+	}
+
+	r.altStatusCodes = altStatusCodes
+
+	if r.ExpectedBodyRegex != "" {
+		regex, err := regexp.Compile(r.ExpectedBodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid expected-body-regex: %v", err)
+		}
+		r.expectedBodyRegex = regex
+	}
+
+	return nil
+}
+
+func (r *SiteStatusRequest) statusText(status *siteStatus) string {
+	switch {
+	case status.Error == nil && status.AssertionFailure == "":
+		return "OK"
+	case r.Method != "http":
+		return "Unreachable"
+	case status.Error == nil && r.isExpectedStatus(status.Code):
+		// the status matched but the content of the response didn't
+		return "Unexpected Content"
+	default:
+		return statusCodeToText(status.Code)
+	}
+}
+
+func (r *SiteStatusRequest) checkedURL() string {
+	return ternary(r.CheckURL != "", r.CheckURL, r.DefaultURL)
+}
+
+// Any 2xx status is expected by default, the alt-status-codes are
+// accepted in addition to either
+func (r *SiteStatusRequest) isExpectedStatus(code int) bool {
+	if slices.Contains(r.altStatusCodes, code) {
+		return true
+	}
+
+	if len(r.ExpectedStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+
+	return r.ExpectedStatus.matches(code)
+}
+
+func (r *SiteStatusRequest) expectedStatusText() string {
+	if len(r.ExpectedStatus) == 0 {
+		return "2xx"
+	}
+
+	return r.ExpectedStatus.String()
+}
+
+// Pages larger than this only have their beginning checked
+const siteStatusBodySizeLimit = 1024 * 1024
+
+// Returns the reason the response doesn't match the assertions of the request,
+// or an empty string if it does
+func (r *SiteStatusRequest) checkResponse(response *http.Response) (string, error) {
+	if !r.isExpectedStatus(response.StatusCode) {
+		return fmt.Sprintf("Expected status %s, got %d", r.expectedStatusText(), response.StatusCode), nil
+	}
+
+	if r.ExpectedBodyContains == "" && r.expectedBodyRegex == nil {
+		return "", nil
 	}
 
+	body, err := io.ReadAll(io.LimitReader(response.Body, siteStatusBodySizeLimit))
+	if err != nil {
+		return "", fmt.Errorf("reading response body: %w", err)
+	}
+
+	if r.ExpectedBodyContains != "" && !bytes.Contains(body, []byte(r.ExpectedBodyContains)) {
+		return fmt.Sprintf("Response does not contain %q", r.ExpectedBodyContains), nil
+	}
+
+	if r.expectedBodyRegex != nil && !r.expectedBodyRegex.Match(body) {
+		return fmt.Sprintf("Response does not match %s", r.ExpectedBodyRegex), nil
+	}
+
+	return "", nil
+}
+
+func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
+	url := statusRequest.checkedURL()
+
 	timeout := ternary(statusRequest.Timeout > 0, time.Duration(statusRequest.Timeout), 3*time.Second)
+
+	switch statusRequest.Method {
+	case "tcp":
+		return fetchTCPSiteStatus(url, timeout), nil
+	case "icmp":
+		return fetchICMPSiteStatus(url, timeout), nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -158,11 +388,7 @@ func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
 	requestSentAt := time.Now()
 	var response *http.Response
 
-	if !statusRequest.AllowInsecure {
-		response, err = defaultHTTPClient.Do(request)
-	} else {
-		response, err = defaultInsecureHTTPClient.Do(request)
-	}
+	response, err = statusRequest.clientOptions.httpClient(statusRequest.AllowInsecure).Do(request)
 
 	status := siteStatus{ResponseTime: time.Since(requestSentAt)}
 
@@ -178,10 +404,157 @@ func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
 	defer response.Body.Close()
 
 	status.Code = response.StatusCode
+	status.AssertionFailure, err = statusRequest.checkResponse(response)
+	if err != nil {
+		status.TimedOut = errors.Is(err, context.DeadlineExceeded)
+		status.Error = err
+	}
 
 	return status, nil
 }
 
+// Accepts either a URL, in which case its host and port are used, or a plain
+// host with an optional port. When the port is missing it is inferred from the
+// scheme of the URL if possible.
+func siteStatusTargetAddress(target string, requirePort bool) (string, error) {
+	scheme := ""
+
+	if strings.Contains(target, "://") {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return "", err
+		}
+
+		scheme = parsed.Scheme
+		target = parsed.Host
+	}
+
+	if target == "" {
+		return "", errors.New("missing host")
+	}
+
+	if !requirePort {
+		if host, _, err := net.SplitHostPort(target); err == nil {
+			return host, nil
+		}
+
+		return strings.Trim(target, "[]"), nil
+	}
+
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target, nil
+	}
+
+	switch scheme {
+	case "http":
+		return net.JoinHostPort(strings.Trim(target, "[]"), "80"), nil
+	case "https":
+		return net.JoinHostPort(strings.Trim(target, "[]"), "443"), nil
+	}
+
+	return "", fmt.Errorf("missing port in address %q", target)
+}
+
+func fetchTCPSiteStatus(target string, timeout time.Duration) siteStatus {
+	address, err := siteStatusTargetAddress(target, true)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	dialStartedAt := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	status := siteStatus{ResponseTime: time.Since(dialStartedAt)}
+
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			status.TimedOut = true
+		}
+
+		status.Error = err
+		return status
+	}
+
+	conn.Close()
+
+	return status
+}
+
+// Sends a single echo request and waits for the matching reply. This requires
+// raw socket privileges, such as running as root or with CAP_NET_RAW.
+func fetchICMPSiteStatus(target string, timeout time.Duration) siteStatus {
+	host, err := siteStatusTargetAddress(target, false)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	ipAddr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	network, listenAddress, protocol := "ip4:icmp", "0.0.0.0", 1
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+
+	if ipAddr.IP.To4() == nil {
+		network, listenAddress, protocol = "ip6:ipv6-icmp", "::", 58
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	conn, err := icmp.ListenPacket(network, listenAddress)
+	if err != nil {
+		return siteStatus{Error: fmt.Errorf("opening icmp socket: %w", err)}
+	}
+	defer conn.Close()
+
+	echo := &icmp.Echo{
+		ID:   os.Getpid() & 0xffff,
+		Seq:  rand.IntN(0xffff),
+		Data: []byte("glance"),
+	}
+
+	request, err := (&icmp.Message{Type: requestType, Body: echo}).Marshal(nil)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	conn.SetDeadline(deadline)
+	sentAt := time.Now()
+
+	if _, err := conn.WriteTo(request, ipAddr); err != nil {
+		return siteStatus{Error: err}
+	}
+
+	buffer := make([]byte, 1500)
+
+	for {
+		n, peer, err := conn.ReadFrom(buffer)
+		if err != nil {
+			var netErr net.Error
+			status := siteStatus{ResponseTime: time.Since(sentAt), Error: err}
+			status.TimedOut = errors.As(err, &netErr) && netErr.Timeout()
+			return status
+		}
+
+		// the socket receives every icmp message sent to the host, so
+		// anything that isn't the reply to our request is ignored
+		if peerAddr, ok := peer.(*net.IPAddr); !ok || !peerAddr.IP.Equal(ipAddr.IP) {
+			continue
+		}
+
+		message, err := icmp.ParseMessage(protocol, buffer[:n])
+		if err != nil || message.Type != replyType {
+			continue
+		}
+
+		if reply, ok := message.Body.(*icmp.Echo); ok && reply.ID == echo.ID && reply.Seq == echo.Seq {
+			return siteStatus{ResponseTime: time.Since(sentAt)}
+		}
+	}
+}
+
 func fetchStatusForSites(requests []*SiteStatusRequest) ([]siteStatus, error) {
 	job := newJob(fetchSiteStatusTask, requests).withWorkers(20)
 	results, _, err := workerPoolDo(job)
@@ -0,0 +1,231 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var mqttWidgetTemplate = mustParseTemplate("mqtt.html", "widget-base.html")
+
+type mqttWidget struct {
+	widgetBase `yaml:",inline"`
+	Broker     string      `yaml:"broker"`
+	Username   string      `yaml:"username"`
+	Password   string      `yaml:"password"`
+	ClientID   string      `yaml:"client-id"`
+	Topics     []mqttTopic `yaml:"topics"`
+	Values     []mqttValue `yaml:"-"`
+	options    mqttBrokerOptions
+	clientKey  string
+	// set when the application starts being used, which can be while
+	// the widget is already updating in the background
+	clientMu       sync.Mutex
+	client         *mqttClient
+	clientReleased bool
+	subscribed     bool
+}
+
+type mqttTopic struct {
+	Topic    string `yaml:"topic"`
+	Title    string `yaml:"title"`
+	Unit     string `yaml:"unit"`
+	JSONPath string `yaml:"json-path"`
+}
+
+type mqttValue struct {
+	Title      string
+	Value      string
+	Unit       string
+	ReceivedAt time.Time
+	HasData    bool
+	Error      string
+}
+
+func (widget *mqttWidget) initialize() error {
+	widget.withTitle("MQTT").withCacheDuration(10 * time.Second)
+
+	if widget.Broker == "" {
+		return errors.New("broker is required")
+	}
+
+	address, useTLS, err := parseMQTTBrokerURL(widget.Broker)
+	if err != nil {
+		return fmt.Errorf("parsing broker: %v", err)
+	}
+
+	if len(widget.Topics) == 0 {
+		return errors.New("at least one topic is required")
+	}
+
+	for i := range widget.Topics {
+		topic := &widget.Topics[i]
+
+		if topic.Topic == "" {
+			return fmt.Errorf("topic #%d is missing a topic", i+1)
+		}
+
+		if strings.Contains(topic.Topic, "#") && !strings.HasSuffix(topic.Topic, "#") {
+			return fmt.Errorf("topic %s: # can only be used as the last level", topic.Topic)
+		}
+
+		if topic.Title == "" {
+			topic.Title = topic.Topic
+		}
+	}
+
+	widget.options = mqttBrokerOptions{
+		address:  address,
+		username: widget.Username,
+		password: widget.Password,
+		clientID: widget.ClientID,
+	}
+
+	if useTLS {
+		widget.options.tlsConfig, err = widget.httpClientOptions.tlsConfig(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	widget.clientKey = strings.Join([]string{
+		address,
+		fmt.Sprint(useTLS, widget.CAFile, widget.InsecureSkipVerify),
+		widget.Username,
+		widget.Password,
+		widget.ClientID,
+	}, "\x00")
+
+	return nil
+}
+
+// Called once the application of the widget starts being used, through
+// applyGlobalState, and released once it's been replaced. This is the only
+// place the client gets acquired, calling it again has no effect
+func (widget *mqttWidget) acquireClient() {
+	widget.clientMu.Lock()
+	defer widget.clientMu.Unlock()
+
+	if widget.client == nil {
+		widget.client = acquireMQTTClient(widget.clientKey, widget.options)
+	}
+}
+
+// The client is kept around so that requests which are still being handled
+// by the previous application don't fail, it just gets no new subscriptions
+func (widget *mqttWidget) releaseClient() {
+	widget.clientMu.Lock()
+	defer widget.clientMu.Unlock()
+
+	if widget.client != nil && !widget.clientReleased {
+		widget.clientReleased = true
+		widget.client.release()
+	}
+}
+
+func (widget *mqttWidget) update(ctx context.Context) {
+	widget.clientMu.Lock()
+	client := widget.client
+	widget.clientMu.Unlock()
+
+	if client == nil {
+		widget.canContinueUpdateAfterHandlingErr(fmt.Errorf("%w: not connected to %s yet", errNoContent, widget.options.address))
+		return
+	}
+
+	// connecting is left until the first update so that validating
+	// the config doesn't reach out to the broker
+	if !widget.subscribed {
+		widget.subscribed = true
+
+		filters := make([]string, len(widget.Topics))
+		for i := range widget.Topics {
+			filters[i] = widget.Topics[i].Topic
+		}
+		client.subscribe(filters)
+	}
+
+	values := make([]mqttValue, len(widget.Topics))
+	var received int
+
+	for i := range widget.Topics {
+		topic := &widget.Topics[i]
+		value := &values[i]
+		value.Title = topic.Title
+		value.Unit = topic.Unit
+
+		message, exists := client.latest(topic.Topic)
+		if !exists {
+			continue
+		}
+
+		received++
+		value.HasData = true
+		value.ReceivedAt = message.ReceivedAt
+
+		if topic.JSONPath == "" {
+			value.Value = strings.TrimSpace(string(message.Payload))
+			continue
+		}
+
+		if !gjson.ValidBytes(message.Payload) {
+			value.Error = "payload is not valid JSON"
+			continue
+		}
+
+		result := gjson.GetBytes(message.Payload, topic.JSONPath)
+		if !result.Exists() {
+			value.Error = "json-path " + topic.JSONPath + " does not exist in the payload"
+			continue
+		}
+
+		value.Value = result.String()
+	}
+
+	var err error
+	if connErr := client.status(); connErr != nil {
+		if received == 0 {
+			err = fmt.Errorf("%w: connecting to %s: %v", errNoContent, widget.options.address, connErr)
+		} else {
+			err = fmt.Errorf("%w: disconnected from %s, values may be outdated: %v", errPartialContent, widget.options.address, connErr)
+		}
+	}
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.Values = values
+}
+
+func (widget *mqttWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, mqttWidgetTemplate)
+}
+
+// Includes the widgets of the other dashboards and the ones within containers
+func (a *application) forEachMQTTWidget(fn func(*mqttWidget)) {
+	var visit func(ws widgets)
+	visit = func(ws widgets) {
+		for _, w := range ws {
+			if container, ok := w.(containerWidget); ok {
+				visit(container.children())
+			} else if widget, ok := w.(*mqttWidget); ok {
+				fn(widget)
+			}
+		}
+	}
+
+	for i := range a.Config.Pages {
+		a.Config.Pages[i].forEachWidget(func(w widget) { visit(widgets{w}) })
+	}
+
+	for _, dashboardApp := range a.dashboardApps {
+		dashboardApp.forEachMQTTWidget(fn)
+	}
+}
@@ -0,0 +1,215 @@
+package glance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var notesWidgetTemplate = mustParseTemplate("notes.html", "widget-base.html")
+
+// Larger notes than this get rejected when saving
+const noteSizeLimit = 512 * 1024
+
+type notesWidget struct {
+	widgetBase  `yaml:",inline"`
+	Placeholder string `yaml:"placeholder"`
+
+	// Read from the store every time the widget gets rendered, so that
+	// each page load shows the latest saved version. Rendering happens while
+	// the page is locked but saving doesn't, so both hold mu instead
+	mu           sync.Mutex
+	Note         note   `yaml:"-"`
+	LoadError    string `yaml:"-"`
+	RequiresAuth bool   `yaml:"-"`
+}
+
+func (widget *notesWidget) initialize() error {
+	widget.withTitle("Notes").withError(nil)
+
+	if widget.CustomID == "" {
+		return errors.New("id is required so that the note can still be found after the config changes")
+	}
+
+	if widget.Placeholder == "" {
+		widget.Placeholder = "Write something..."
+	}
+
+	return nil
+}
+
+func (widget *notesWidget) Render() template.HTML {
+	widget.mu.Lock()
+	defer widget.mu.Unlock()
+
+	widget.Note, widget.LoadError = note{}, ""
+
+	if widget.Providers != nil && widget.Providers.notes != nil {
+		widget.RequiresAuth = widget.Providers.requiresAuth
+
+		loaded, err := widget.Providers.notes.load(widget.CustomID)
+		if err != nil {
+			slog.Warn("Failed to load note", "id", widget.CustomID, "error", err)
+			widget.LoadError = "Could not load the saved note"
+		} else {
+			widget.Note = loaded
+		}
+	}
+
+	return widget.renderTemplate(widget, notesWidgetTemplate)
+}
+
+// Notes have nowhere to be saved to unless the data path is set, which is
+// only known once the widget has been given its providers
+func (a *application) checkNotesStorage() error {
+	if a.Config.Server.DataPath != "" {
+		return nil
+	}
+
+	for _, entry := range a.widgetByStableID {
+		if _, ok := entry.widget.(*notesWidget); ok {
+			return formatWidgetInitError(errors.New("the server data-path must be set for notes to be saved"), entry.widget)
+		}
+	}
+
+	return nil
+}
+
+type noteSaveRequest struct {
+	Content string `json:"content"`
+}
+
+type noteSaveResponse struct {
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (widget *notesWidget) handleRequest(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("path") != "save" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	// browsers can't send JSON to other origins without a preflight request,
+	// which keeps other sites from being able to overwrite the note
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "expected a JSON body", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var request noteSaveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, noteSizeLimit+1024)).Decode(&request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "note is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Content) > noteSizeLimit {
+		http.Error(w, "note is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	widget.mu.Lock()
+	saved, err := widget.Providers.notes.save(widget.CustomID, request.Content)
+	widget.mu.Unlock()
+	if err != nil {
+		slog.Error("Failed to save note", "id", widget.CustomID, "error", err)
+		http.Error(w, "could not save note", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(noteSaveResponse{UpdatedAt: saved.UpdatedAt})
+}
+
+type note struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Every note is kept in its own file which gets read whenever the note is
+// needed, so that instances of the application which get created when the
+// config is reloaded never end up with an outdated copy
+type diskNoteStore struct {
+	dir string
+}
+
+func newDiskNoteStore(dir string) (*diskNoteStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating notes directory: %v", err)
+	}
+
+	return &diskNoteStore{dir: dir}, nil
+}
+
+// IDs can contain anything, including characters which aren't allowed in
+// file names, the ID itself is also stored in the file for reference
+func (s *diskNoteStore) path(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, "note-"+hex.EncodeToString(hash[:16])+".json")
+}
+
+func (s *diskNoteStore) load(id string) (note, error) {
+	contents, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return note{ID: id}, nil
+	} else if err != nil {
+		return note{}, err
+	}
+
+	var loaded note
+	if err := json.Unmarshal(contents, &loaded); err != nil {
+		return note{}, fmt.Errorf("decoding note: %v", err)
+	}
+
+	return loaded, nil
+}
+
+func (s *diskNoteStore) save(id, content string) (note, error) {
+	saved := note{ID: id, Content: content, UpdatedAt: time.Now()}
+
+	contents, err := json.Marshal(saved)
+	if err != nil {
+		return note{}, err
+	}
+
+	path := s.path(id)
+
+	// same as with the widget cache, a crash midway through
+	// writing shouldn't leave behind a partial file
+	file, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return note{}, err
+	}
+
+	_, err = file.Write(contents)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return note{}, err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return note{}, err
+	}
+
+	return saved, nil
+}
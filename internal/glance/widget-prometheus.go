@@ -0,0 +1,350 @@
+package glance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var prometheusWidgetTemplate = mustParseTemplate("prometheus.html", "widget-base.html")
+
+type prometheusWidget struct {
+	widgetBase    `yaml:",inline"`
+	URL           string             `yaml:"url"`
+	Token         string             `yaml:"token"`
+	AllowInsecure bool               `yaml:"allow-insecure"`
+	Queries       []prometheusQuery  `yaml:"queries"`
+	Results       []prometheusResult `yaml:"-"`
+}
+
+type prometheusQuery struct {
+	Name      string   `yaml:"name"`
+	Query     string   `yaml:"query"`
+	Unit      string   `yaml:"unit"`
+	Decimals  *int     `yaml:"decimals"`
+	Aggregate string   `yaml:"aggregate"`
+	Warning   *float64 `yaml:"warning"`
+	Critical  *float64 `yaml:"critical"`
+}
+
+type prometheusResult struct {
+	Name  string
+	Value string
+	Unit  string
+	// Either warning or critical when the value has crossed that threshold
+	Status string
+	Error  string
+}
+
+var prometheusAggregates = []string{"first", "sum", "avg", "min", "max"}
+
+func (widget *prometheusWidget) initialize() error {
+	widget.withTitle("Prometheus").withCacheDuration(time.Minute)
+
+	if widget.URL == "" {
+		return errors.New("url is required")
+	}
+
+	widget.URL = strings.TrimRight(widget.URL, "/")
+
+	if len(widget.Queries) == 0 {
+		return errors.New("at least one query is required")
+	}
+
+	for i := range widget.Queries {
+		query := &widget.Queries[i]
+
+		if query.Query == "" {
+			return fmt.Errorf("query #%d is missing a query", i+1)
+		}
+
+		if query.Name == "" {
+			query.Name = query.Query
+		}
+
+		if query.Aggregate == "" {
+			query.Aggregate = "first"
+		} else if !slices.Contains(prometheusAggregates, query.Aggregate) {
+			return fmt.Errorf("query %s: aggregate must be one of %s", query.Name, strings.Join(prometheusAggregates, ", "))
+		}
+
+		if query.Decimals != nil && (*query.Decimals < 0 || *query.Decimals > 10) {
+			return fmt.Errorf("query %s: decimals must be between 0 and 10", query.Name)
+		}
+	}
+
+	return nil
+}
+
+func (widget *prometheusWidget) update(ctx context.Context) {
+	results, err := fetchPrometheusResults(ctx, widget.httpClient(widget.AllowInsecure), widget.URL, widget.Token, widget.Queries, widget.formatter())
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.Results = results
+}
+
+func (widget *prometheusWidget) dataModel() any {
+	return &widget.Results
+}
+
+func (widget *prometheusWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, prometheusWidgetTemplate)
+}
+
+// When the critical threshold is lower than the warning one, lower values
+// are considered worse instead of higher ones
+func (q *prometheusQuery) statusForValue(value float64) string {
+	lowerIsWorse := q.Warning != nil && q.Critical != nil && *q.Critical < *q.Warning
+
+	crossed := func(threshold *float64) bool {
+		if threshold == nil {
+			return false
+		}
+
+		return ternary(lowerIsWorse, value <= *threshold, value >= *threshold)
+	}
+
+	if crossed(q.Critical) {
+		return "critical"
+	}
+
+	if crossed(q.Warning) {
+		return "warning"
+	}
+
+	return ""
+}
+
+// Queries which fail are shown along with their error rather than failing
+// the whole widget, unless all of them fail
+func fetchPrometheusResults(ctx context.Context, client requestDoer, baseURL, token string, queries []prometheusQuery, formatter *localeFormatter) ([]prometheusResult, error) {
+	job := newJob(func(query prometheusQuery) (float64, error) {
+		return fetchPrometheusInstantQuery(ctx, client, baseURL, token, &query)
+	}, queries).withWorkers(4)
+
+	values, errs, err := workerPoolDo(job)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoContent, err)
+	}
+
+	results := make([]prometheusResult, len(queries))
+	var failed int
+
+	for i := range queries {
+		query := &queries[i]
+		results[i].Name = query.Name
+
+		if errs[i] != nil {
+			failed++
+			results[i].Error = errs[i].Error()
+			continue
+		}
+
+		results[i].Value, results[i].Unit = formatPrometheusValue(formatter, values[i], query.Unit, query.Decimals)
+		results[i].Status = query.statusForValue(values[i])
+	}
+
+	if failed == len(results) {
+		return nil, fmt.Errorf("%w: %v", errNoContent, errs[0])
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%w: %d of %d queries failed", errPartialContent, failed, len(results))
+	}
+
+	return results, nil
+}
+
+type prometheusQueryResponseJson struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type prometheusVectorSampleJson struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]any            `json:"value"`
+}
+
+func fetchPrometheusInstantQuery(ctx context.Context, client requestDoer, baseURL, token string, query *prometheusQuery) (float64, error) {
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		baseURL+"/api/v1/query?query="+url.QueryEscape(query.Query),
+		nil,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+
+	// errors with the query itself are returned as JSON along with a
+	// non-200 status code, so the body gets decoded regardless of it
+	var decoded prometheusQueryResponseJson
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		if response.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("unexpected status code %d", response.StatusCode)
+		}
+		return 0, fmt.Errorf("decoding response: %v", err)
+	}
+
+	if decoded.Status != "success" {
+		if decoded.Error == "" {
+			return 0, fmt.Errorf("unexpected status code %d", response.StatusCode)
+		}
+		return 0, errors.New(decoded.Error)
+	}
+
+	switch decoded.Data.ResultType {
+	case "scalar":
+		var sample [2]any
+		if err := json.Unmarshal(decoded.Data.Result, &sample); err != nil {
+			return 0, fmt.Errorf("decoding scalar result: %v", err)
+		}
+
+		return parsePrometheusSampleValue(sample)
+	case "vector":
+		var samples []prometheusVectorSampleJson
+		if err := json.Unmarshal(decoded.Data.Result, &samples); err != nil {
+			return 0, fmt.Errorf("decoding vector result: %v", err)
+		}
+
+		if len(samples) == 0 {
+			return 0, errors.New("query returned no data")
+		}
+
+		values := make([]float64, 0, len(samples))
+		for i := range samples {
+			value, err := parsePrometheusSampleValue(samples[i].Value)
+			if err != nil {
+				return 0, err
+			}
+			values = append(values, value)
+		}
+
+		return aggregatePrometheusValues(values, query.Aggregate), nil
+	default:
+		return 0, fmt.Errorf("unsupported result type %s, only instant vectors and scalars can be shown", decoded.Data.ResultType)
+	}
+}
+
+// Samples are encoded as [<unix time>, "<value>"]
+func parsePrometheusSampleValue(sample [2]any) (float64, error) {
+	encoded, ok := sample[1].(string)
+	if !ok {
+		return 0, errors.New("sample has no value")
+	}
+
+	value, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing sample value %s: %v", encoded, err)
+	}
+
+	return value, nil
+}
+
+func aggregatePrometheusValues(values []float64, aggregate string) float64 {
+	result := values[0]
+
+	switch aggregate {
+	case "sum", "avg":
+		result = 0
+		for _, value := range values {
+			result += value
+		}
+
+		if aggregate == "avg" {
+			result /= float64(len(values))
+		}
+	case "min":
+		for _, value := range values[1:] {
+			result = math.Min(result, value)
+		}
+	case "max":
+		for _, value := range values[1:] {
+			result = math.Max(result, value)
+		}
+	}
+
+	return result
+}
+
+// The units bytes, percent and seconds get special formatting, anything
+// else gets displayed as is after the value
+func formatPrometheusValue(formatter *localeFormatter, value float64, unit string, decimals *int) (string, string) {
+	if math.IsNaN(value) {
+		return "NaN", ""
+	}
+
+	if math.IsInf(value, 0) {
+		return ternary(value > 0, "∞", "-∞"), ""
+	}
+
+	format := func(value float64, defaultDecimals int) string {
+		precision := defaultDecimals
+		if decimals != nil {
+			precision = *decimals
+		}
+
+		return formatter.number(precision, value)
+	}
+
+	switch unit {
+	case "bytes":
+		value, unit := formatter.scaleBytes(value)
+		return format(value, ternary(unit == "B", 0, 1)), unit
+	case "percent":
+		return format(value, 1), "%"
+	case "seconds":
+		switch abs := math.Abs(value); {
+		case abs < 1:
+			return format(value*1000, 0), "ms"
+		case abs < 60:
+			return format(value, 1), "s"
+		case abs < 3600:
+			return format(value/60, 1), "m"
+		case abs < 86400:
+			return format(value/3600, 1), "h"
+		default:
+			return format(value/86400, 1), "d"
+		}
+	}
+
+	// whole numbers don't get decimals unless asked for
+	if decimals == nil && value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return format(value, 0), unit
+	}
+
+	return format(value, 2), unit
+}
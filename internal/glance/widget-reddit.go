@@ -20,20 +20,19 @@ var (
 
 type redditWidget struct {
 	widgetBase          `yaml:",inline"`
-	Posts               forumPostList     `yaml:"-"`
-	Subreddit           string            `yaml:"subreddit"`
-	Proxy               proxyOptionsField `yaml:"proxy"`
-	Style               string            `yaml:"style"`
-	ShowThumbnails      bool              `yaml:"show-thumbnails"`
-	ShowFlairs          bool              `yaml:"show-flairs"`
-	SortBy              string            `yaml:"sort-by"`
-	TopPeriod           string            `yaml:"top-period"`
-	Search              string            `yaml:"search"`
-	ExtraSortBy         string            `yaml:"extra-sort-by"`
-	CommentsURLTemplate string            `yaml:"comments-url-template"`
-	Limit               int               `yaml:"limit"`
-	CollapseAfter       int               `yaml:"collapse-after"`
-	RequestURLTemplate  string            `yaml:"request-url-template"`
+	Posts               forumPostList `yaml:"-"`
+	Subreddit           string        `yaml:"subreddit"`
+	Style               string        `yaml:"style"`
+	ShowThumbnails      bool          `yaml:"show-thumbnails"`
+	ShowFlairs          bool          `yaml:"show-flairs"`
+	SortBy              string        `yaml:"sort-by"`
+	TopPeriod           string        `yaml:"top-period"`
+	Search              string        `yaml:"search"`
+	ExtraSortBy         string        `yaml:"extra-sort-by"`
+	CommentsURLTemplate string        `yaml:"comments-url-template"`
+	Limit               int           `yaml:"limit"`
+	CollapseAfter       int           `yaml:"collapse-after"`
+	RequestURLTemplate  string        `yaml:"request-url-template"`
 
 	AppAuth struct {
 		Name   string `yaml:"name"`
@@ -109,6 +108,10 @@ func (widget *redditWidget) update(ctx context.Context) {
 	widget.Posts = posts
 }
 
+func (widget *redditWidget) dataModel() any {
+	return &widget.Posts
+}
+
 func (widget *redditWidget) Render() template.HTML {
 	if widget.Style == "horizontal-cards" {
 		return widget.renderTemplate(widget, redditWidgetHorizontalCardsTemplate)
@@ -202,8 +205,8 @@ func (widget *redditWidget) fetchSubredditPosts() (forumPostList, error) {
 
 	if widget.RequestURLTemplate != "" {
 		requestURL = strings.ReplaceAll(widget.RequestURLTemplate, "{REQUEST-URL}", requestURL)
-	} else if widget.Proxy.client != nil {
-		client = widget.Proxy.client
+	} else {
+		client = widget.httpClient(false)
 	}
 
 	request, err := http.NewRequest("GET", requestURL, nil)
@@ -214,6 +217,12 @@ func (widget *redditWidget) fetchSubredditPosts() (forumPostList, error) {
 
 	responseJson, err := decodeJsonFromRequest[subredditResponseJson](client, request)
 	if err != nil {
+		if app.enabled {
+			// the token may have been revoked or invalidated before its
+			// expiry, so force fetching a new one on the next update
+			app.accessToken = ""
+		}
+
 		return nil, err
 	}
 
@@ -295,14 +304,19 @@ func (widget *redditWidget) fetchNewAppAccessToken() error {
 	type tokenResponse struct {
 		AccessToken string `json:"access_token"`
 		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
 	}
 
-	client := ternary(widget.Proxy.client != nil, widget.Proxy.client, defaultHTTPClient)
-	response, err := decodeJsonFromRequest[tokenResponse](client, req)
+	response, err := decodeJsonFromRequest[tokenResponse](widget.httpClient(false), req)
 	if err != nil {
 		return err
 	}
 
+	// reddit responds with a 200 status code for some authentication failures
+	if response.AccessToken == "" {
+		return fmt.Errorf("no access token in response: %s", ternary(response.Error != "", response.Error, "unknown error"))
+	}
+
 	app.accessToken = response.AccessToken
 	app.tokenExpiresAt = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
 
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -42,7 +43,9 @@ func (widget *releasesWidget) initialize() error {
 	for i := range widget.Repositories {
 		r := widget.Repositories[i]
 
-		if r.source == releaseSourceGithub && widget.Token != "" {
+		if r.Token != "" {
+			r.token = &r.Token
+		} else if r.source == releaseSourceGithub && widget.Token != "" {
 			r.token = &widget.Token
 		} else if r.source == releaseSourceGitlab && widget.GitLabToken != "" {
 			r.token = &widget.GitLabToken
@@ -70,6 +73,10 @@ func (widget *releasesWidget) update(ctx context.Context) {
 	widget.Releases = releases
 }
 
+func (widget *releasesWidget) dataModel() any {
+	return &widget.Releases
+}
+
 func (widget *releasesWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, releasesWidgetTemplate)
 }
@@ -80,6 +87,7 @@ const (
 	releaseSourceCodeberg  releaseSource = "codeberg"
 	releaseSourceGithub    releaseSource = "github"
 	releaseSourceGitlab    releaseSource = "gitlab"
+	releaseSourceGitea     releaseSource = "gitea"
 	releaseSourceDockerHub releaseSource = "dockerhub"
 )
 
@@ -91,6 +99,9 @@ type appRelease struct {
 	NotesUrl      string
 	TimeReleased  time.Time
 	Downvotes     int
+	// set when no stable release was found among the most recent releases
+	// and pre-releases weren't requested to be included
+	OnlyPrereleases bool
 }
 
 type appReleaseList []appRelease
@@ -106,9 +117,14 @@ func (r appReleaseList) sortByNewest() appReleaseList {
 type releaseRequest struct {
 	IncludePreleases bool   `yaml:"include-prereleases"`
 	Repository       string `yaml:"repository"`
-
-	source releaseSource
-	token  *string
+	Regex            string `yaml:"regex"`
+	Provider         string `yaml:"provider"`
+	BaseURL          string `yaml:"base-url"`
+	Token            string `yaml:"token"`
+
+	source     releaseSource
+	token      *string
+	tagPattern *regexp.Regexp
 }
 
 func (r *releaseRequest) UnmarshalYAML(node *yaml.Node) error {
@@ -130,29 +146,56 @@ func (r *releaseRequest) UnmarshalYAML(node *yaml.Node) error {
 		}
 	}
 
-	parts := strings.SplitN(repository, ":", 2)
-	if len(parts) == 1 {
-		r.source = releaseSourceGithub
-	} else if len(parts) == 2 {
-		r.Repository = parts[1]
-
-		switch parts[0] {
-		case string(releaseSourceGithub):
-			r.source = releaseSourceGithub
-		case string(releaseSourceGitlab):
-			r.source = releaseSourceGitlab
-		case string(releaseSourceDockerHub):
-			r.source = releaseSourceDockerHub
-		case string(releaseSourceCodeberg):
-			r.source = releaseSourceCodeberg
-		default:
-			return errors.New("invalid source")
+	if r.Regex != "" {
+		pattern, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex for repository %s: %v", r.Repository, err)
 		}
+
+		r.tagPattern = pattern
+	}
+
+	repository, source, err := parseRepositoryWithProvider(r.Repository, r.Provider)
+	if err != nil {
+		return err
+	}
+	r.Repository = repository
+	r.source = source
+
+	r.BaseURL = strings.TrimRight(r.BaseURL, "/")
+
+	if r.source == releaseSourceGitea && r.BaseURL == "" {
+		return fmt.Errorf("base-url is required for gitea repository %s", r.Repository)
 	}
 
 	return nil
 }
 
+// The provider can either be set separately or as a prefix of the
+// repository, such as gitlab:owner/repo, defaulting to GitHub
+func parseRepositoryWithProvider(repository, provider string) (string, releaseSource, error) {
+	parts := strings.SplitN(repository, ":", 2)
+	if len(parts) == 2 && provider == "" {
+		repository = parts[1]
+		provider = parts[0]
+	}
+
+	switch provider {
+	case "", string(releaseSourceGithub):
+		return repository, releaseSourceGithub, nil
+	case string(releaseSourceGitlab):
+		return repository, releaseSourceGitlab, nil
+	case string(releaseSourceGitea), "forgejo":
+		return repository, releaseSourceGitea, nil
+	case string(releaseSourceDockerHub):
+		return repository, releaseSourceDockerHub, nil
+	case string(releaseSourceCodeberg):
+		return repository, releaseSourceCodeberg, nil
+	}
+
+	return "", "", errors.New("invalid source")
+}
+
 func fetchLatestReleases(requests []*releaseRequest) (appReleaseList, error) {
 	job := newJob(fetchLatestReleaseTask, requests).withWorkers(20)
 	results, errs, err := workerPoolDo(job)
@@ -189,8 +232,8 @@ func fetchLatestReleases(requests []*releaseRequest) (appReleaseList, error) {
 
 func fetchLatestReleaseTask(request *releaseRequest) (*appRelease, error) {
 	switch request.source {
-	case releaseSourceCodeberg:
-		return fetchLatestCodebergRelease(request)
+	case releaseSourceCodeberg, releaseSourceGitea:
+		return fetchLatestGiteaRelease(request)
 	case releaseSourceGithub:
 		return fetchLatestGithubRelease(request)
 	case releaseSourceGitlab:
@@ -206,58 +249,100 @@ type githubReleaseResponseJson struct {
 	TagName     string `json:"tag_name"`
 	PublishedAt string `json:"published_at"`
 	HtmlUrl     string `json:"html_url"`
+	Draft       bool   `json:"draft"`
+	Prerelease  bool   `json:"prerelease"`
 	Reactions   struct {
 		Downvotes int `json:"-1"`
 	} `json:"reactions"`
 }
 
-func fetchLatestGithubRelease(request *releaseRequest) (*appRelease, error) {
-	var requestURL string
-	if !request.IncludePreleases {
-		requestURL = fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", request.Repository)
-	} else {
-		requestURL = fmt.Sprintf("https://api.github.com/repos/%s/releases", request.Repository)
+// Returns the index of the newest release that passes the request's filters.
+// Drafts are always skipped, as are pre-releases unless they're included,
+// however if none of the given releases are stable the newest matching
+// pre-release is returned along with onlyPrereleases being set. Releases must
+// be sorted from newest to oldest.
+func (r *releaseRequest) pickRelease(count int, release func(int) (tag string, draft, prerelease bool)) (index int, onlyPrereleases bool) {
+	newestPrerelease := -1
+
+	for i := range count {
+		tag, draft, prerelease := release(i)
+		if draft || (r.tagPattern != nil && !r.tagPattern.MatchString(tag)) {
+			continue
+		}
+
+		if prerelease && !r.IncludePreleases {
+			if newestPrerelease == -1 {
+				newestPrerelease = i
+			}
+			continue
+		}
+
+		return i, false
 	}
 
-	httpRequest, err := http.NewRequest("GET", requestURL, nil)
+	return newestPrerelease, newestPrerelease != -1
+}
+
+func fetchLatestGithubRelease(request *releaseRequest) (*appRelease, error) {
+	// the releases/latest endpoint would be enough when not filtering, but
+	// then repositories that only publish pre-releases would show up as errors
+	responses, err := fetchGithubReleases[[]githubReleaseResponseJson](request, fmt.Sprintf(
+		"https://api.github.com/repos/%s/releases?per_page=%d",
+		request.Repository,
+		ternary(request.tagPattern != nil, 100, 30),
+	))
 	if err != nil {
 		return nil, err
 	}
 
-	if request.token != nil {
-		httpRequest.Header.Add("Authorization", "Bearer "+(*request.token))
-	}
+	index, onlyPrereleases := request.pickRelease(len(responses), func(i int) (string, bool, bool) {
+		return responses[i].TagName, responses[i].Draft, responses[i].Prerelease
+	})
 
-	var response githubReleaseResponseJson
+	if index == -1 {
+		return nil, fmt.Errorf("no matching releases found for repository %s", request.Repository)
+	}
 
-	if !request.IncludePreleases {
-		response, err = decodeJsonFromRequest[githubReleaseResponseJson](defaultHTTPClient, httpRequest)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		responses, err := decodeJsonFromRequest[[]githubReleaseResponseJson](defaultHTTPClient, httpRequest)
-		if err != nil {
-			return nil, err
-		}
+	response := &responses[index]
 
-		if len(responses) == 0 {
-			return nil, fmt.Errorf("no releases found for repository %s", request.Repository)
+	// only a page of releases is listed, so a stable release could still exist
+	// past a long run of pre-releases, errors here mean there likely isn't one
+	if onlyPrereleases {
+		latest, err := fetchGithubReleases[githubReleaseResponseJson](request, fmt.Sprintf(
+			"https://api.github.com/repos/%s/releases/latest",
+			request.Repository,
+		))
+		if err == nil && (request.tagPattern == nil || request.tagPattern.MatchString(latest.TagName)) {
+			response = &latest
+			onlyPrereleases = false
 		}
-
-		response = responses[0]
 	}
 
 	return &appRelease{
-		Source:       releaseSourceGithub,
-		Name:         request.Repository,
-		Version:      normalizeVersionFormat(response.TagName),
-		NotesUrl:     response.HtmlUrl,
-		TimeReleased: parseRFC3339Time(response.PublishedAt),
-		Downvotes:    response.Reactions.Downvotes,
+		Source:          releaseSourceGithub,
+		Name:            request.Repository,
+		Version:         normalizeVersionFormat(response.TagName),
+		NotesUrl:        response.HtmlUrl,
+		TimeReleased:    parseRFC3339Time(response.PublishedAt),
+		Downvotes:       response.Reactions.Downvotes,
+		OnlyPrereleases: onlyPrereleases,
 	}, nil
 }
 
+func fetchGithubReleases[T any](request *releaseRequest, requestURL string) (T, error) {
+	httpRequest, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		var result T
+		return result, err
+	}
+
+	if request.token != nil {
+		httpRequest.Header.Add("Authorization", "Bearer "+(*request.token))
+	}
+
+	return decodeJsonFromRequest[T](defaultHTTPClient, httpRequest)
+}
+
 type dockerHubRepositoryTagsResponse struct {
 	Results []dockerHubRepositoryTagResponse `json:"results"`
 }
@@ -349,9 +434,10 @@ func fetchLatestDockerHubRelease(request *releaseRequest) (*appRelease, error) {
 }
 
 type gitlabReleaseResponseJson struct {
-	TagName    string `json:"tag_name"`
-	ReleasedAt string `json:"released_at"`
-	Links      struct {
+	TagName         string `json:"tag_name"`
+	ReleasedAt      string `json:"released_at"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+	Links           struct {
 		Self string `json:"self"`
 	} `json:"_links"`
 }
@@ -360,8 +446,10 @@ func fetchLatestGitLabRelease(request *releaseRequest) (*appRelease, error) {
 	httpRequest, err := http.NewRequest(
 		"GET",
 		fmt.Sprintf(
-			"https://gitlab.com/api/v4/projects/%s/releases/permalink/latest",
+			"%s/api/v4/projects/%s/releases?per_page=%d",
+			ternary(request.BaseURL != "", request.BaseURL, "https://gitlab.com"),
 			url.QueryEscape(request.Repository),
+			ternary(request.tagPattern != nil, 100, 20),
 		),
 		nil,
 	)
@@ -373,32 +461,56 @@ func fetchLatestGitLabRelease(request *releaseRequest) (*appRelease, error) {
 		httpRequest.Header.Add("PRIVATE-TOKEN", *request.token)
 	}
 
-	response, err := decodeJsonFromRequest[gitlabReleaseResponseJson](defaultHTTPClient, httpRequest)
+	responses, err := decodeJsonFromRequest[[]gitlabReleaseResponseJson](defaultHTTPClient, httpRequest)
 	if err != nil {
 		return nil, err
 	}
 
+	// gitlab has no pre-releases, the closest thing being releases
+	// scheduled for the future
+	index, onlyPrereleases := request.pickRelease(len(responses), func(i int) (string, bool, bool) {
+		return responses[i].TagName, false, responses[i].UpcomingRelease
+	})
+
+	if index == -1 {
+		return nil, fmt.Errorf("no matching releases found for repository %s", request.Repository)
+	}
+
+	response := &responses[index]
+
 	return &appRelease{
-		Source:       releaseSourceGitlab,
-		Name:         request.Repository,
-		Version:      normalizeVersionFormat(response.TagName),
-		NotesUrl:     response.Links.Self,
-		TimeReleased: parseRFC3339Time(response.ReleasedAt),
+		Source:          releaseSourceGitlab,
+		Name:            request.Repository,
+		Version:         normalizeVersionFormat(response.TagName),
+		NotesUrl:        response.Links.Self,
+		TimeReleased:    parseRFC3339Time(response.ReleasedAt),
+		OnlyPrereleases: onlyPrereleases,
 	}, nil
 }
 
-type codebergReleaseResponseJson struct {
+type giteaReleaseResponseJson struct {
 	TagName     string `json:"tag_name"`
 	PublishedAt string `json:"published_at"`
 	HtmlUrl     string `json:"html_url"`
+	Draft       bool   `json:"draft"`
+	Prerelease  bool   `json:"prerelease"`
 }
 
-func fetchLatestCodebergRelease(request *releaseRequest) (*appRelease, error) {
+// Used for both Codeberg and self-hosted Gitea and Forgejo instances
+// since they all share the same API
+func fetchLatestGiteaRelease(request *releaseRequest) (*appRelease, error) {
+	baseURL := request.BaseURL
+	if request.source == releaseSourceCodeberg && baseURL == "" {
+		baseURL = "https://codeberg.org"
+	}
+
 	httpRequest, err := http.NewRequest(
 		"GET",
 		fmt.Sprintf(
-			"https://codeberg.org/api/v1/repos/%s/releases/latest",
+			"%s/api/v1/repos/%s/releases?limit=%d",
+			baseURL,
 			request.Repository,
+			ternary(request.tagPattern != nil, 50, 20),
 		),
 		nil,
 	)
@@ -406,16 +518,31 @@ func fetchLatestCodebergRelease(request *releaseRequest) (*appRelease, error) {
 		return nil, err
 	}
 
-	response, err := decodeJsonFromRequest[codebergReleaseResponseJson](defaultHTTPClient, httpRequest)
+	if request.token != nil {
+		httpRequest.Header.Add("Authorization", "token "+(*request.token))
+	}
+
+	responses, err := decodeJsonFromRequest[[]giteaReleaseResponseJson](defaultHTTPClient, httpRequest)
 	if err != nil {
 		return nil, err
 	}
 
+	index, onlyPrereleases := request.pickRelease(len(responses), func(i int) (string, bool, bool) {
+		return responses[i].TagName, responses[i].Draft, responses[i].Prerelease
+	})
+
+	if index == -1 {
+		return nil, fmt.Errorf("no matching releases found for repository %s", request.Repository)
+	}
+
+	response := &responses[index]
+
 	return &appRelease{
-		Source:       releaseSourceCodeberg,
-		Name:         request.Repository,
-		Version:      normalizeVersionFormat(response.TagName),
-		NotesUrl:     response.HtmlUrl,
-		TimeReleased: parseRFC3339Time(response.PublishedAt),
+		Source:          request.source,
+		Name:            request.Repository,
+		Version:         normalizeVersionFormat(response.TagName),
+		NotesUrl:        response.HtmlUrl,
+		TimeReleased:    parseRFC3339Time(response.PublishedAt),
+		OnlyPrereleases: onlyPrereleases,
 	}, nil
 }
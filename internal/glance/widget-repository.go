@@ -2,9 +2,13 @@ package glance
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,16 +19,42 @@ var repositoryWidgetTemplate = mustParseTemplate("repository.html", "widget-base
 type repositoryWidget struct {
 	widgetBase          `yaml:",inline"`
 	RequestedRepository string     `yaml:"repository"`
+	Provider            string     `yaml:"provider"`
+	BaseURL             string     `yaml:"base-url"`
 	Token               string     `yaml:"token"`
 	PullRequestsLimit   int        `yaml:"pull-requests-limit"`
 	IssuesLimit         int        `yaml:"issues-limit"`
 	CommitsLimit        int        `yaml:"commits-limit"`
 	Repository          repository `yaml:"-"`
+
+	source     releaseSource
+	rateLimits forgeRateLimits
 }
 
 func (widget *repositoryWidget) initialize() error {
 	widget.withTitle("Repository").withCacheDuration(1 * time.Hour)
 
+	if widget.RequestedRepository == "" {
+		return errors.New("repository is required")
+	}
+
+	repository, source, err := parseRepositoryWithProvider(widget.RequestedRepository, widget.Provider)
+	if err != nil {
+		return err
+	}
+
+	if source == releaseSourceDockerHub {
+		return fmt.Errorf("repository %s: dockerhub is not supported", repository)
+	}
+
+	widget.RequestedRepository = repository
+	widget.source = source
+	widget.BaseURL = strings.TrimRight(widget.BaseURL, "/")
+
+	if widget.source == releaseSourceGitea && widget.BaseURL == "" {
+		return fmt.Errorf("base-url is required for gitea repository %s", widget.RequestedRepository)
+	}
+
 	if widget.PullRequestsLimit == 0 || widget.PullRequestsLimit < -1 {
 		widget.PullRequestsLimit = 3
 	}
@@ -41,19 +71,23 @@ func (widget *repositoryWidget) initialize() error {
 }
 
 func (widget *repositoryWidget) update(ctx context.Context) {
-	details, err := fetchRepositoryDetailsFromGithub(
-		widget.RequestedRepository,
-		string(widget.Token),
-		widget.PullRequestsLimit,
-		widget.IssuesLimit,
-		widget.CommitsLimit,
-	)
+	details, err := widget.fetchRepository(ctx)
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
 	}
 
 	widget.Repository = details
+
+	if widget.Notice == nil {
+		if warning := widget.rateLimits.lowWarning(); warning != nil {
+			widget.withNotice(warning)
+		}
+	}
+}
+
+func (widget *repositoryWidget) dataModel() any {
+	return &widget.Repository
 }
 
 func (widget *repositoryWidget) Render() template.HTML {
@@ -61,34 +95,217 @@ func (widget *repositoryWidget) Render() template.HTML {
 }
 
 type repository struct {
+	Source           releaseSource
 	Name             string
+	URL              string
 	Stars            int
 	Forks            int
+	Archived         bool
+	HasIssues        bool
+	HasPullRequests  bool
+	LastCommitAt     time.Time
 	OpenPullRequests int
-	PullRequests     []githubTicket
+	PullRequestsURL  string
+	PullRequests     []repositoryTicket
 	OpenIssues       int
-	Issues           []githubTicket
+	IssuesURL        string
+	Issues           []repositoryTicket
 	LastCommits      int
-	Commits          []githubCommitDetails
+	CommitsURL       string
+	Commits          []repositoryCommit
+
+	// set when the repository has no commits yet, in which case
+	// they can't be requested
+	empty bool
 }
 
-type githubTicket struct {
+type repositoryTicket struct {
 	Number    int
 	CreatedAt time.Time
 	Title     string
+	URL       string
 }
 
-type githubCommitDetails struct {
+type repositoryCommit struct {
 	Sha       string
 	Author    string
 	CreatedAt time.Time
 	Message   string
+	URL       string
+}
+
+// The details are requested first since they tell whether the repository has
+// its issues or pull requests disabled, in which case requesting them would
+// fail, after which the rest are requested at the same time. Commits are
+// always requested for the time of the last one even when none are shown
+func (widget *repositoryWidget) fetchRepository(ctx context.Context) (repository, error) {
+	host := forgeBaseURL(widget.source, widget.BaseURL)
+
+	if err := widget.rateLimits.exceeded(host); err != nil {
+		return repository{}, fmt.Errorf("%w: %v", errNoContent, err)
+	}
+
+	details, err := widget.fetchRepositoryDetails(ctx, host)
+	if err != nil {
+		return repository{}, fmt.Errorf("%w: could not get repository details: %v", errNoContent, err)
+	}
+
+	details.Source = widget.source
+	details.PullRequests = []repositoryTicket{}
+	details.Issues = []repositoryTicket{}
+	details.Commits = []repositoryCommit{}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+
+	fail := func(what string, err error) {
+		slog.Error("Failed to fetch repository "+what, "source", widget.source, "repository", widget.RequestedRepository, "error", err)
+		mu.Lock()
+		failed = append(failed, what)
+		mu.Unlock()
+	}
+
+	if widget.PullRequestsLimit > 0 && details.HasPullRequests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tickets, total, err := widget.fetchRepositoryTickets(ctx, host, true, widget.PullRequestsLimit)
+			if err != nil {
+				fail("pull requests", err)
+				return
+			}
+
+			details.PullRequests = tickets
+			if total >= 0 {
+				details.OpenPullRequests = total
+			}
+		}()
+	}
+
+	// GitHub counts pull requests as issues, so the number of open
+	// issues can only be known by searching for them
+	if details.HasIssues && (widget.IssuesLimit > 0 || widget.source == releaseSourceGithub) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tickets, total, err := widget.fetchRepositoryTickets(ctx, host, false, max(1, widget.IssuesLimit))
+			if err != nil {
+				fail("issues", err)
+				return
+			}
+
+			if widget.IssuesLimit > 0 {
+				details.Issues = tickets
+			}
+			if total >= 0 {
+				details.OpenIssues = total
+			}
+		}()
+	}
+
+	if !details.empty {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			commits, err := widget.fetchRepositoryCommits(ctx, host, max(1, widget.CommitsLimit))
+			if err != nil {
+				fail("commits", err)
+				return
+			}
+
+			if len(commits) > 0 {
+				details.LastCommitAt = commits[0].CreatedAt
+			}
+			if widget.CommitsLimit > 0 {
+				details.Commits = commits
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return details, fmt.Errorf("%w: could not get %s", errPartialContent, strings.Join(failed, ", "))
+	}
+
+	return details, nil
+}
+
+func (widget *repositoryWidget) fetchRepositoryDetails(ctx context.Context, host string) (repository, error) {
+	switch widget.source {
+	case releaseSourceGithub:
+		return widget.fetchGithubRepositoryDetails(ctx, host)
+	case releaseSourceGitlab:
+		return widget.fetchGitLabRepositoryDetails(ctx, host)
+	case releaseSourceGitea, releaseSourceCodeberg:
+		return widget.fetchGiteaRepositoryDetails(ctx, host)
+	}
+
+	return repository{}, errors.New("unsupported source")
+}
+
+// Returns the newest open tickets along with the total number of open ones,
+// which is -1 when the API doesn't report it
+func (widget *repositoryWidget) fetchRepositoryTickets(ctx context.Context, host string, pullRequests bool, limit int) ([]repositoryTicket, int, error) {
+	switch widget.source {
+	case releaseSourceGithub:
+		return widget.fetchGithubRepositoryTickets(ctx, host, pullRequests, limit)
+	case releaseSourceGitlab:
+		return widget.fetchGitLabRepositoryTickets(ctx, host, pullRequests, limit)
+	case releaseSourceGitea, releaseSourceCodeberg:
+		return widget.fetchGiteaRepositoryTickets(ctx, host, pullRequests, limit)
+	}
+
+	return nil, 0, errors.New("unsupported source")
+}
+
+func (widget *repositoryWidget) fetchRepositoryCommits(ctx context.Context, host string, limit int) ([]repositoryCommit, error) {
+	switch widget.source {
+	case releaseSourceGithub:
+		return widget.fetchGithubRepositoryCommits(ctx, host, limit)
+	case releaseSourceGitlab:
+		return widget.fetchGitLabRepositoryCommits(ctx, host, limit)
+	case releaseSourceGitea, releaseSourceCodeberg:
+		return widget.fetchGiteaRepositoryCommits(ctx, host, limit)
+	}
+
+	return nil, errors.New("unsupported source")
+}
+
+func (widget *repositoryWidget) newRequest(ctx context.Context, requestURL string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if widget.Token == "" {
+		return request, nil
+	}
+
+	switch widget.source {
+	case releaseSourceGithub:
+		request.Header.Set("Authorization", "Bearer "+widget.Token)
+	case releaseSourceGitlab:
+		request.Header.Set("PRIVATE-TOKEN", widget.Token)
+	default:
+		request.Header.Set("Authorization", "token "+widget.Token)
+	}
+
+	return request, nil
+}
+
+func firstLineOfCommitMessage(message string) string {
+	return strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
 }
 
 type githubRepositoryResponseJson struct {
-	Name  string `json:"full_name"`
-	Stars int    `json:"stargazers_count"`
-	Forks int    `json:"forks_count"`
+	Name      string `json:"full_name"`
+	HtmlUrl   string `json:"html_url"`
+	Stars     int    `json:"stargazers_count"`
+	Forks     int    `json:"forks_count"`
+	Archived  bool   `json:"archived"`
+	HasIssues bool   `json:"has_issues"`
 }
 
 type githubTicketResponseJson struct {
@@ -97,12 +314,14 @@ type githubTicketResponseJson struct {
 		Number    int    `json:"number"`
 		CreatedAt string `json:"created_at"`
 		Title     string `json:"title"`
+		HtmlUrl   string `json:"html_url"`
 	} `json:"items"`
 }
 
 type gitHubCommitResponseJson struct {
-	Sha    string `json:"sha"`
-	Commit struct {
+	Sha     string `json:"sha"`
+	HtmlUrl string `json:"html_url"`
+	Commit  struct {
 		Author struct {
 			Name string `json:"name"`
 			Date string `json:"date"`
@@ -111,128 +330,321 @@ type gitHubCommitResponseJson struct {
 	} `json:"commit"`
 }
 
-func fetchRepositoryDetailsFromGithub(repo string, token string, maxPRs int, maxIssues int, maxCommits int) (repository, error) {
-	repositoryRequest, err := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s", repo), nil)
+func (widget *repositoryWidget) fetchGithubRepositoryDetails(ctx context.Context, host string) (repository, error) {
+	request, err := widget.newRequest(ctx, fmt.Sprintf("%s/repos/%s", host, widget.RequestedRepository))
 	if err != nil {
-		return repository{}, fmt.Errorf("%w: could not create request with repository: %v", errNoContent, err)
+		return repository{}, err
 	}
 
-	PRsRequest, _ := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/search/issues?q=is:pr+is:open+repo:%s&per_page=%d", repo, maxPRs), nil)
-	issuesRequest, _ := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/search/issues?q=is:issue+is:open+repo:%s&per_page=%d", repo, maxIssues), nil)
-	CommitsRequest, _ := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/commits?per_page=%d", repo, maxCommits), nil)
+	var response githubRepositoryResponseJson
+	if err := widget.rateLimits.decode(request, host, &response); err != nil {
+		return repository{}, err
+	}
 
-	if token != "" {
-		token = fmt.Sprintf("Bearer %s", token)
-		repositoryRequest.Header.Add("Authorization", token)
-		PRsRequest.Header.Add("Authorization", token)
-		issuesRequest.Header.Add("Authorization", token)
-		CommitsRequest.Header.Add("Authorization", token)
+	return repository{
+		Name:            response.Name,
+		URL:             response.HtmlUrl,
+		Stars:           response.Stars,
+		Forks:           response.Forks,
+		Archived:        response.Archived,
+		HasIssues:       response.HasIssues,
+		HasPullRequests: true,
+		PullRequestsURL: response.HtmlUrl + "/pulls",
+		IssuesURL:       response.HtmlUrl + "/issues",
+		CommitsURL:      response.HtmlUrl + "/commits",
+	}, nil
+}
+
+// The search API has its own, much lower, quota which is tracked separately
+func (widget *repositoryWidget) fetchGithubRepositoryTickets(ctx context.Context, host string, pullRequests bool, limit int) ([]repositoryTicket, int, error) {
+	searchHost := host + "/search"
+	if err := widget.rateLimits.exceeded(searchHost); err != nil {
+		return nil, 0, err
 	}
 
-	var repositoryResponse githubRepositoryResponseJson
-	var detailsErr error
-	var PRsResponse githubTicketResponseJson
-	var PRsErr error
-	var issuesResponse githubTicketResponseJson
-	var issuesErr error
-	var commitsResponse []gitHubCommitResponseJson
-	var CommitsErr error
-	var wg sync.WaitGroup
+	query := url.Values{}
+	query.Set("q", fmt.Sprintf("is:%s is:open repo:%s", ternary(pullRequests, "pr", "issue"), widget.RequestedRepository))
+	query.Set("per_page", strconv.Itoa(limit))
 
-	wg.Add(1)
-	go (func() {
-		defer wg.Done()
-		repositoryResponse, detailsErr = decodeJsonFromRequest[githubRepositoryResponseJson](defaultHTTPClient, repositoryRequest)
-	})()
+	request, err := widget.newRequest(ctx, fmt.Sprintf("%s/search/issues?%s", host, query.Encode()))
+	if err != nil {
+		return nil, 0, err
+	}
 
-	if maxPRs > 0 {
-		wg.Add(1)
-		go (func() {
-			defer wg.Done()
-			PRsResponse, PRsErr = decodeJsonFromRequest[githubTicketResponseJson](defaultHTTPClient, PRsRequest)
-		})()
+	var response githubTicketResponseJson
+	if err := widget.rateLimits.decode(request, searchHost, &response); err != nil {
+		return nil, 0, err
 	}
 
-	if maxIssues > 0 {
-		wg.Add(1)
-		go (func() {
-			defer wg.Done()
-			issuesResponse, issuesErr = decodeJsonFromRequest[githubTicketResponseJson](defaultHTTPClient, issuesRequest)
-		})()
+	tickets := make([]repositoryTicket, 0, len(response.Tickets))
+	for i := range response.Tickets {
+		tickets = append(tickets, repositoryTicket{
+			Number:    response.Tickets[i].Number,
+			CreatedAt: parseRFC3339Time(response.Tickets[i].CreatedAt),
+			Title:     response.Tickets[i].Title,
+			URL:       response.Tickets[i].HtmlUrl,
+		})
 	}
 
-	if maxCommits > 0 {
-		wg.Add(1)
-		go (func() {
-			defer wg.Done()
-			commitsResponse, CommitsErr = decodeJsonFromRequest[[]gitHubCommitResponseJson](defaultHTTPClient, CommitsRequest)
-		})()
+	return tickets, response.Count, nil
+}
+
+func (widget *repositoryWidget) fetchGithubRepositoryCommits(ctx context.Context, host string, limit int) ([]repositoryCommit, error) {
+	request, err := widget.newRequest(ctx, fmt.Sprintf("%s/repos/%s/commits?per_page=%d", host, widget.RequestedRepository, limit))
+	if err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
+	var responses []gitHubCommitResponseJson
+	if err := widget.rateLimits.decode(request, host, &responses); err != nil {
+		return nil, err
+	}
 
-	if detailsErr != nil {
-		return repository{}, fmt.Errorf("%w: could not get repository details: %s", errNoContent, detailsErr)
+	commits := make([]repositoryCommit, 0, len(responses))
+	for i := range responses {
+		commits = append(commits, repositoryCommit{
+			Sha:       responses[i].Sha,
+			Author:    responses[i].Commit.Author.Name,
+			CreatedAt: parseRFC3339Time(responses[i].Commit.Author.Date),
+			Message:   firstLineOfCommitMessage(responses[i].Commit.Message),
+			URL:       responses[i].HtmlUrl,
+		})
 	}
 
-	details := repository{
-		Name:         repositoryResponse.Name,
-		Stars:        repositoryResponse.Stars,
-		Forks:        repositoryResponse.Forks,
-		PullRequests: make([]githubTicket, 0, len(PRsResponse.Tickets)),
-		Issues:       make([]githubTicket, 0, len(issuesResponse.Tickets)),
-		Commits:      make([]githubCommitDetails, 0, len(commitsResponse)),
+	return commits, nil
+}
+
+type gitlabRepositoryResponseJson struct {
+	Name                 string `json:"path_with_namespace"`
+	WebUrl               string `json:"web_url"`
+	Stars                int    `json:"star_count"`
+	Forks                int    `json:"forks_count"`
+	Archived             bool   `json:"archived"`
+	EmptyRepo            bool   `json:"empty_repo"`
+	DefaultBranch        string `json:"default_branch"`
+	IssuesEnabled        bool   `json:"issues_enabled"`
+	MergeRequestsEnabled bool   `json:"merge_requests_enabled"`
+	// not included when the issues are disabled
+	OpenIssues int `json:"open_issues_count"`
+}
+
+type gitlabTicketResponseJson struct {
+	IID       int    `json:"iid"`
+	Title     string `json:"title"`
+	WebUrl    string `json:"web_url"`
+	CreatedAt string `json:"created_at"`
+}
+
+type gitlabCommitResponseJson struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	AuthorName string `json:"author_name"`
+	AuthoredAt string `json:"authored_date"`
+	WebUrl     string `json:"web_url"`
+}
+
+func (widget *repositoryWidget) gitlabProjectURL(host string) string {
+	return fmt.Sprintf("%s/api/v4/projects/%s", host, url.QueryEscape(widget.RequestedRepository))
+}
+
+func (widget *repositoryWidget) fetchGitLabRepositoryDetails(ctx context.Context, host string) (repository, error) {
+	request, err := widget.newRequest(ctx, widget.gitlabProjectURL(host))
+	if err != nil {
+		return repository{}, err
 	}
 
-	err = nil
+	var response gitlabRepositoryResponseJson
+	if err := widget.rateLimits.decode(request, host, &response); err != nil {
+		return repository{}, err
+	}
 
-	if maxPRs > 0 {
-		if PRsErr != nil {
-			err = fmt.Errorf("%w: could not get PRs: %s", errPartialContent, PRsErr)
-		} else {
-			details.OpenPullRequests = PRsResponse.Count
+	return repository{
+		Name:            response.Name,
+		URL:             response.WebUrl,
+		Stars:           response.Stars,
+		Forks:           response.Forks,
+		Archived:        response.Archived,
+		HasIssues:       response.IssuesEnabled,
+		HasPullRequests: response.MergeRequestsEnabled,
+		OpenIssues:      response.OpenIssues,
+		PullRequestsURL: response.WebUrl + "/-/merge_requests",
+		IssuesURL:       response.WebUrl + "/-/issues",
+		CommitsURL:      response.WebUrl + "/-/commits/" + response.DefaultBranch,
+		empty:           response.EmptyRepo,
+	}, nil
+}
 
-			for i := range PRsResponse.Tickets {
-				details.PullRequests = append(details.PullRequests, githubTicket{
-					Number:    PRsResponse.Tickets[i].Number,
-					CreatedAt: parseRFC3339Time(PRsResponse.Tickets[i].CreatedAt),
-					Title:     PRsResponse.Tickets[i].Title,
-				})
-			}
-		}
+// The total is reported through the X-Total header, which GitLab leaves
+// out when there are more than 10,000 results
+func (widget *repositoryWidget) fetchGitLabRepositoryTickets(ctx context.Context, host string, pullRequests bool, limit int) ([]repositoryTicket, int, error) {
+	request, err := widget.newRequest(ctx, fmt.Sprintf(
+		"%s/%s?state=opened&order_by=created_at&sort=desc&per_page=%d",
+		widget.gitlabProjectURL(host),
+		ternary(pullRequests, "merge_requests", "issues"),
+		limit,
+	))
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if maxIssues > 0 {
-		if issuesErr != nil {
-			// TODO: fix, overwriting the previous error
-			err = fmt.Errorf("%w: could not get issues: %s", errPartialContent, issuesErr)
-		} else {
-			details.OpenIssues = issuesResponse.Count
+	var responses []gitlabTicketResponseJson
+	header, err := widget.rateLimits.decodeWithHeader(request, host, &responses)
+	if err != nil {
+		return nil, 0, err
+	}
 
-			for i := range issuesResponse.Tickets {
-				details.Issues = append(details.Issues, githubTicket{
-					Number:    issuesResponse.Tickets[i].Number,
-					CreatedAt: parseRFC3339Time(issuesResponse.Tickets[i].CreatedAt),
-					Title:     issuesResponse.Tickets[i].Title,
-				})
-			}
-		}
+	tickets := make([]repositoryTicket, 0, len(responses))
+	for i := range responses {
+		tickets = append(tickets, repositoryTicket{
+			Number:    responses[i].IID,
+			CreatedAt: parseRFC3339Time(responses[i].CreatedAt),
+			Title:     responses[i].Title,
+			URL:       responses[i].WebUrl,
+		})
 	}
 
-	if maxCommits > 0 {
-		if CommitsErr != nil {
-			err = fmt.Errorf("%w: could not get commits: %s", errPartialContent, CommitsErr)
-		} else {
-			for i := range commitsResponse {
-				details.Commits = append(details.Commits, githubCommitDetails{
-					Sha:       commitsResponse[i].Sha,
-					Author:    commitsResponse[i].Commit.Author.Name,
-					CreatedAt: parseRFC3339Time(commitsResponse[i].Commit.Author.Date),
-					Message:   strings.SplitN(commitsResponse[i].Commit.Message, "\n\n", 2)[0],
-				})
-			}
-		}
+	total, err := strconv.Atoi(header.Get("X-Total"))
+	if err != nil {
+		total = -1
+	}
+
+	return tickets, total, nil
+}
+
+func (widget *repositoryWidget) fetchGitLabRepositoryCommits(ctx context.Context, host string, limit int) ([]repositoryCommit, error) {
+	request, err := widget.newRequest(ctx, fmt.Sprintf("%s/repository/commits?per_page=%d", widget.gitlabProjectURL(host), limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []gitlabCommitResponseJson
+	if err := widget.rateLimits.decode(request, host, &responses); err != nil {
+		return nil, err
+	}
+
+	commits := make([]repositoryCommit, 0, len(responses))
+	for i := range responses {
+		commits = append(commits, repositoryCommit{
+			Sha:       responses[i].ID,
+			Author:    responses[i].AuthorName,
+			CreatedAt: parseRFC3339Time(responses[i].AuthoredAt),
+			Message:   firstLineOfCommitMessage(responses[i].Title),
+			URL:       responses[i].WebUrl,
+		})
+	}
+
+	return commits, nil
+}
+
+type giteaRepositoryResponseJson struct {
+	Name             string `json:"full_name"`
+	HtmlUrl          string `json:"html_url"`
+	Stars            int    `json:"stars_count"`
+	Forks            int    `json:"forks_count"`
+	Archived         bool   `json:"archived"`
+	Empty            bool   `json:"empty"`
+	DefaultBranch    string `json:"default_branch"`
+	HasIssues        bool   `json:"has_issues"`
+	HasPullRequests  bool   `json:"has_pull_requests"`
+	OpenIssues       int    `json:"open_issues_count"`
+	OpenPullRequests int    `json:"open_pr_counter"`
+}
+
+type giteaTicketResponseJson struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	HtmlUrl   string `json:"html_url"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Used for both Codeberg and self-hosted Gitea and Forgejo instances
+// since they all share the same API
+func (widget *repositoryWidget) fetchGiteaRepositoryDetails(ctx context.Context, host string) (repository, error) {
+	request, err := widget.newRequest(ctx, fmt.Sprintf("%s/api/v1/repos/%s", host, widget.RequestedRepository))
+	if err != nil {
+		return repository{}, err
+	}
+
+	var response giteaRepositoryResponseJson
+	if err := widget.rateLimits.decode(request, host, &response); err != nil {
+		return repository{}, err
+	}
+
+	return repository{
+		Name:             response.Name,
+		URL:              response.HtmlUrl,
+		Stars:            response.Stars,
+		Forks:            response.Forks,
+		Archived:         response.Archived,
+		HasIssues:        response.HasIssues,
+		HasPullRequests:  response.HasPullRequests,
+		OpenIssues:       response.OpenIssues,
+		OpenPullRequests: response.OpenPullRequests,
+		PullRequestsURL:  response.HtmlUrl + "/pulls",
+		IssuesURL:        response.HtmlUrl + "/issues",
+		CommitsURL:       response.HtmlUrl + "/commits/branch/" + response.DefaultBranch,
+		empty:            response.Empty,
+	}, nil
+}
+
+// The totals are already known from the details of the repository
+func (widget *repositoryWidget) fetchGiteaRepositoryTickets(ctx context.Context, host string, pullRequests bool, limit int) ([]repositoryTicket, int, error) {
+	requestURL := fmt.Sprintf("%s/api/v1/repos/%s/issues?state=open&type=issues&limit=%d", host, widget.RequestedRepository, limit)
+	if pullRequests {
+		requestURL = fmt.Sprintf("%s/api/v1/repos/%s/pulls?state=open&limit=%d", host, widget.RequestedRepository, limit)
+	}
+
+	request, err := widget.newRequest(ctx, requestURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var responses []giteaTicketResponseJson
+	if err := widget.rateLimits.decode(request, host, &responses); err != nil {
+		return nil, 0, err
+	}
+
+	tickets := make([]repositoryTicket, 0, len(responses))
+	for i := range responses {
+		tickets = append(tickets, repositoryTicket{
+			Number:    responses[i].Number,
+			CreatedAt: parseRFC3339Time(responses[i].CreatedAt),
+			Title:     responses[i].Title,
+			URL:       responses[i].HtmlUrl,
+		})
+	}
+
+	return tickets, -1, nil
+}
+
+// The response has the same shape as GitHub's, the stats, verification and
+// files are left out since they're slow to compute and not shown
+func (widget *repositoryWidget) fetchGiteaRepositoryCommits(ctx context.Context, host string, limit int) ([]repositoryCommit, error) {
+	request, err := widget.newRequest(ctx, fmt.Sprintf(
+		"%s/api/v1/repos/%s/commits?limit=%d&stat=false&verification=false&files=false",
+		host,
+		widget.RequestedRepository,
+		limit,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []gitHubCommitResponseJson
+	if err := widget.rateLimits.decode(request, host, &responses); err != nil {
+		return nil, err
+	}
+
+	commits := make([]repositoryCommit, 0, len(responses))
+	for i := range responses {
+		commits = append(commits, repositoryCommit{
+			Sha:       responses[i].Sha,
+			Author:    responses[i].Commit.Author.Name,
+			CreatedAt: parseRFC3339Time(responses[i].Commit.Author.Date),
+			Message:   firstLineOfCommitMessage(responses[i].Commit.Message),
+			URL:       responses[i].HtmlUrl,
+		})
 	}
 
-	return details, err
+	return commits, nil
 }
@@ -1,22 +1,32 @@
 package glance
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"html/template"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/mmcdole/gofeed"
 	gofeedext "github.com/mmcdole/gofeed/extensions"
+	jsonfeed "github.com/mmcdole/gofeed/json"
+	nethtml "golang.org/x/net/html"
 )
 
 var (
@@ -24,6 +34,7 @@ var (
 	rssWidgetDetailedListTemplate     = mustParseTemplate("rss-detailed-list.html", "widget-base.html")
 	rssWidgetHorizontalCardsTemplate  = mustParseTemplate("rss-horizontal-cards.html", "widget-base.html")
 	rssWidgetHorizontalCards2Template = mustParseTemplate("rss-horizontal-cards-2.html", "widget-base.html")
+	rssWidgetPodcastTemplate          = mustParseTemplate("rss-podcast.html", "widget-base.html")
 )
 
 var feedParser = gofeed.NewParser()
@@ -38,12 +49,25 @@ type rssWidget struct {
 	CollapseAfter    int              `yaml:"collapse-after"`
 	SingleLineTitles bool             `yaml:"single-line-titles"`
 	PreserveOrder    bool             `yaml:"preserve-order"`
+	FetchFullContent bool             `yaml:"fetch-full-content"`
+	FullContentLen   int              `yaml:"full-content-length"`
+	Highlight        []string         `yaml:"highlight"`
+	Mute             []string         `yaml:"mute"`
+	WholeWords       bool             `yaml:"whole-words"`
 
 	Items          rssFeedItemList `yaml:"-"`
 	NoItemsMessage string          `yaml:"-"`
 
 	cachedFeedsMutex sync.Mutex
 	cachedFeeds      map[string]*cachedRSSFeed `yaml:"-"`
+	// The feeds found on pages which were configured as feed URLs
+	discoveredFeedURLs map[string]string `yaml:"-"`
+
+	fullContentMutex sync.Mutex
+	fullContent      map[string]*rssFullContent `yaml:"-"`
+
+	highlightPattern *regexp.Regexp
+	mutePattern      *regexp.Regexp
 }
 
 func (widget *rssWidget) initialize() error {
@@ -71,8 +95,52 @@ func (widget *rssWidget) initialize() error {
 		}
 	}
 
+	if widget.FetchFullContent {
+		for i := range widget.FeedRequests {
+			widget.FeedRequests[i].FetchFullContent = true
+		}
+	}
+
+	for i := range widget.FeedRequests {
+		request := &widget.FeedRequests[i]
+
+		if request.FetchFullContent && widget.Style != "detailed-list" {
+			return fmt.Errorf("fetch-full-content can only be used with the detailed-list style since it's the only one which shows descriptions")
+		}
+
+		switch request.Source {
+		case "", "rss":
+			if request.URL == "" {
+				return fmt.Errorf("feed %d is missing a url", i+1)
+			}
+		case "lobsters":
+			if request.SortBy == "" {
+				request.SortBy = "hot"
+			} else if request.SortBy != "hot" && request.SortBy != "new" {
+				return fmt.Errorf("sort-by of lobsters feeds must be either hot or new, got %s", request.SortBy)
+			}
+		default:
+			return fmt.Errorf("unknown feed source %s, must be either rss or lobsters", request.Source)
+		}
+	}
+
+	if widget.FullContentLen <= 0 {
+		widget.FullContentLen = 500
+	}
+
+	var err error
+	if widget.highlightPattern, err = compileFeedTermsPattern(widget.Highlight, widget.WholeWords); err != nil {
+		return fmt.Errorf("highlight: %v", err)
+	}
+
+	if widget.mutePattern, err = compileFeedTermsPattern(widget.Mute, widget.WholeWords); err != nil {
+		return fmt.Errorf("mute: %v", err)
+	}
+
 	widget.NoItemsMessage = "No items were returned from the feeds."
 	widget.cachedFeeds = make(map[string]*cachedRSSFeed)
+	widget.discoveredFeedURLs = make(map[string]string)
+	widget.fullContent = make(map[string]*rssFullContent)
 
 	return nil
 }
@@ -92,9 +160,14 @@ func (widget *rssWidget) update(ctx context.Context) {
 		items = items[:widget.Limit]
 	}
 
+	widget.populateFullContent(items)
 	widget.Items = items
 }
 
+func (widget *rssWidget) dataModel() any {
+	return &widget.Items
+}
+
 func (widget *rssWidget) Render() template.HTML {
 	if widget.Style == "horizontal-cards" {
 		return widget.renderTemplate(widget, rssWidgetHorizontalCardsTemplate)
@@ -108,6 +181,10 @@ func (widget *rssWidget) Render() template.HTML {
 		return widget.renderTemplate(widget, rssWidgetDetailedListTemplate)
 	}
 
+	if widget.Style == "podcast" {
+		return widget.renderTemplate(widget, rssWidgetPodcastTemplate)
+	}
+
 	return widget.renderTemplate(widget, rssWidgetTemplate)
 }
 
@@ -126,17 +203,61 @@ type rssFeedItem struct {
 	Categories  []string
 	Description string
 	PublishedAt time.Time
+	// Whether the description is a preview of the article itself
+	IsFullContent bool
+	// Set for podcast episodes and any other items with an audio enclosure
+	Audio *rssFeedItemAudio
+
+	fetchFullContent bool
+}
+
+type rssFeedItemAudio struct {
+	URL  string
+	Type string
+	// Both are zero when the feed doesn't include them
+	Length   int64
+	Duration time.Duration
+}
+
+func (a *rssFeedItemAudio) FormattedDuration() string {
+	if a.Duration <= 0 {
+		return ""
+	}
+
+	hours, minutes, seconds := int(a.Duration/time.Hour), int(a.Duration%time.Hour/time.Minute), int(a.Duration%time.Minute/time.Second)
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+func (a *rssFeedItemAudio) FormattedLength() string {
+	switch {
+	case a.Length <= 0:
+		return ""
+	case a.Length < 1_000_000:
+		return strconv.FormatInt(max(1, a.Length/1_000), 10) + " KB"
+	case a.Length < 1_000_000_000:
+		return strconv.FormatInt(a.Length/1_000_000, 10) + " MB"
+	}
+
+	return fmt.Sprintf("%.1f GB", float64(a.Length)/1_000_000_000)
 }
 
 type rssFeedRequest struct {
-	URL             string            `yaml:"url"`
-	Title           string            `yaml:"title"`
-	HideCategories  bool              `yaml:"hide-categories"`
-	HideDescription bool              `yaml:"hide-description"`
-	Limit           int               `yaml:"limit"`
-	ItemLinkPrefix  string            `yaml:"item-link-prefix"`
-	Headers         map[string]string `yaml:"headers"`
-	IsDetailed      bool              `yaml:"-"`
+	Source           string            `yaml:"source"`
+	URL              string            `yaml:"url"`
+	Title            string            `yaml:"title"`
+	HideCategories   bool              `yaml:"hide-categories"`
+	HideDescription  bool              `yaml:"hide-description"`
+	Limit            int               `yaml:"limit"`
+	ItemLinkPrefix   string            `yaml:"item-link-prefix"`
+	Headers          map[string]string `yaml:"headers"`
+	FetchFullContent bool              `yaml:"fetch-full-content"`
+	Tags             []string          `yaml:"tags"`
+	SortBy           string            `yaml:"sort-by"`
+	IsDetailed       bool              `yaml:"-"`
 }
 
 type rssFeedItemList []rssFeedItem
@@ -169,12 +290,15 @@ func (widget *rssWidget) fetchItemsFromFeeds() (rssFeedItemList, error) {
 			continue
 		}
 
+		// the same article can be syndicated by multiple feeds, in which case
+		// the first one seen is kept along with its title
 		for _, item := range feeds[i] {
-			if _, exists := seen[item.Link]; exists {
+			key := normalizeFeedItemLink(item.Link)
+			if _, exists := seen[key]; exists {
 				continue
 			}
 			entries = append(entries, item)
-			seen[item.Link] = struct{}{}
+			seen[key] = struct{}{}
 		}
 	}
 
@@ -190,7 +314,19 @@ func (widget *rssWidget) fetchItemsFromFeeds() (rssFeedItemList, error) {
 }
 
 func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFeedItem, error) {
-	req, err := http.NewRequest("GET", request.URL, nil)
+	if request.Source == "lobsters" {
+		return widget.fetchItemsFromLobsters(request)
+	}
+
+	widget.cachedFeedsMutex.Lock()
+	feedURL, isDiscovered := widget.discoveredFeedURLs[request.URL]
+	widget.cachedFeedsMutex.Unlock()
+
+	if !isDiscovered {
+		feedURL = request.URL
+	}
+
+	req, err := http.NewRequest("GET", feedURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -213,18 +349,19 @@ func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFe
 		req.Header.Set(key, value)
 	}
 
-	resp, err := defaultHTTPClient.Do(req)
+	resp, err := widget.httpClient(false).Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotModified && isCached {
+		slog.Debug("RSS feed not modified, reusing cached items", "url", request.URL, "items", len(cache.items))
 		return cache.items, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, request.URL)
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, feedURL)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -232,18 +369,36 @@ func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFe
 		return nil, err
 	}
 
-	feed, err := feedParser.ParseString(string(body))
+	feed, err := parseFeed(resp.Header.Get("Content-Type"), body)
 	if err != nil {
-		return nil, err
-	}
+		// the URL may be that of a page rather than the feed itself, in which
+		// case the feed it links to gets used from then on
+		if isDiscovered || !isHTMLResponse(resp, body) {
+			return nil, err
+		}
+
+		discovered, discoverErr := discoverFeedsInHTML(body, resp.Request.URL)
+		if discoverErr != nil || len(discovered) == 0 {
+			return nil, fmt.Errorf("%s is a page rather than a feed and no feeds were found on it", request.URL)
+		}
 
-	if request.Limit > 0 && len(feed.Items) > request.Limit {
-		feed.Items = feed.Items[:request.Limit]
+		slog.Info("Using feed found on page", "page", request.URL, "feed", discovered[0].URL)
+
+		widget.cachedFeedsMutex.Lock()
+		widget.discoveredFeedURLs[request.URL] = discovered[0].URL
+		widget.cachedFeedsMutex.Unlock()
+
+		return widget.fetchItemsFromFeedTask(request)
 	}
 
-	items := make(rssFeedItemList, 0, len(feed.Items))
+	items := make(rssFeedItemList, 0, min(len(feed.Items), max(request.Limit, 0)))
 
 	for i := range feed.Items {
+		// muted items don't count towards the limit
+		if request.Limit > 0 && len(items) >= request.Limit {
+			break
+		}
+
 		item := feed.Items[i]
 
 		rssItem := rssFeedItem{
@@ -257,7 +412,7 @@ func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFe
 		} else {
 			parsedUrl, err := url.Parse(feed.Link)
 			if err != nil {
-				parsedUrl, err = url.Parse(request.URL)
+				parsedUrl, err = url.Parse(feedURL)
 			}
 
 			if err == nil {
@@ -279,27 +434,19 @@ func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFe
 			rssItem.Title = shortenFeedDescriptionLen(item.Description, 100)
 		}
 
+		if widget.isMuted(rssItem.Title) {
+			continue
+		}
+
 		if request.IsDetailed {
 			if !request.HideDescription && item.Description != "" && item.Title != "" {
 				rssItem.Description = shortenFeedDescriptionLen(item.Description, 200)
 			}
 
-			if !request.HideCategories {
-				var categories = make([]string, 0, 6)
-
-				for _, category := range item.Categories {
-					if len(categories) == 6 {
-						break
-					}
+			rssItem.fetchFullContent = request.FetchFullContent && !request.HideDescription
 
-					if len(category) == 0 || len(category) > 30 {
-						continue
-					}
-
-					categories = append(categories, category)
-				}
-
-				rssItem.Categories = categories
+			if !request.HideCategories {
+				rssItem.Categories = limitFeedItemCategories(item.Categories)
 			}
 		}
 
@@ -327,6 +474,8 @@ func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFe
 			rssItem.PublishedAt = time.Now()
 		}
 
+		rssItem.Audio = findAudioInFeedItem(item, feedURL)
+
 		items = append(items, rssItem)
 	}
 
@@ -343,6 +492,332 @@ func (widget *rssWidget) fetchItemsFromFeedTask(request rssFeedRequest) ([]rssFe
 	return items, nil
 }
 
+// Terms wrapped in slashes such as /v\d+\.\d+/ are used as regular expressions,
+// everything else is matched literally, both ignore the case
+func compileFeedTermsPattern(terms []string, wholeWords bool) (*regexp.Regexp, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	alternatives := make([]string, 0, len(terms))
+
+	for _, term := range terms {
+		if len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/") {
+			expression := term[1 : len(term)-1]
+			if _, err := regexp.Compile(expression); err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %v", term, err)
+			}
+
+			alternatives = append(alternatives, "(?:"+expression+")")
+			continue
+		}
+
+		if strings.TrimSpace(term) == "" {
+			return nil, errors.New("terms cannot be empty")
+		}
+
+		alternatives = append(alternatives, regexp.QuoteMeta(term))
+	}
+
+	pattern := strings.Join(alternatives, "|")
+	if wholeWords {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func (widget *rssWidget) isMuted(title string) bool {
+	return widget.mutePattern != nil && widget.mutePattern.MatchString(title)
+}
+
+// Done when rendering rather than when fetching so that the titles
+// stay as they are in the feed everywhere else
+func (widget *rssWidget) HighlightTitle(title string) template.HTML {
+	if widget.highlightPattern == nil {
+		return template.HTML(html.EscapeString(title))
+	}
+
+	var builder strings.Builder
+	var last int
+
+	for _, match := range widget.highlightPattern.FindAllStringIndex(title, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+
+		builder.WriteString(html.EscapeString(title[last:match[0]]))
+		builder.WriteString(`<mark class="rss-highlight">`)
+		builder.WriteString(html.EscapeString(title[match[0]:match[1]]))
+		builder.WriteString("</mark>")
+		last = match[1]
+	}
+
+	builder.WriteString(html.EscapeString(title[last:]))
+
+	return template.HTML(builder.String())
+}
+
+func limitFeedItemCategories(categories []string) []string {
+	limited := make([]string, 0, 6)
+
+	for _, category := range categories {
+		if len(limited) == 6 {
+			break
+		}
+
+		if len(category) == 0 || len(category) > 30 {
+			continue
+		}
+
+		limited = append(limited, category)
+	}
+
+	return limited
+}
+
+// The format is taken from the content type when the server sets a specific
+// one and is otherwise detected from the contents of the response
+func parseFeed(contentType string, body []byte) (*gofeed.Feed, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "application/feed+json", "application/json":
+		return parseJSONFeed(body)
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml", "application/xml", "text/xml":
+		return feedParser.ParseString(string(body))
+	}
+
+	if gofeed.DetectFeedType(bytes.NewReader(body)) == gofeed.FeedTypeJSON {
+		return parseJSONFeed(body)
+	}
+
+	return feedParser.ParseString(string(body))
+}
+
+// Parses JSON Feed 1.0 and 1.1 documents, filling in the fields which gofeed
+// leaves empty when a feed uses the alternatives that the spec allows for them
+func parseJSONFeed(body []byte) (*gofeed.Feed, error) {
+	parsed, err := (&jsonfeed.Parser{}).Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parsing JSON feed: %v", err)
+	}
+
+	if !strings.Contains(parsed.Version, "jsonfeed.org/version/") {
+		return nil, errors.New("response is JSON but not a JSON feed")
+	}
+
+	feed, err := (&gofeed.DefaultJSONTranslator{}).Translate(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range feed.Items {
+		if i >= len(parsed.Items) {
+			break
+		}
+		source := parsed.Items[i]
+
+		if item.Description == "" {
+			item.Description = ternary(source.ContentText != "", source.ContentText, source.ContentHTML)
+		}
+
+		if item.Link == "" {
+			if source.ExternalURL != "" {
+				item.Link = source.ExternalURL
+			} else if strings.HasPrefix(source.ID, "http://") || strings.HasPrefix(source.ID, "https://") {
+				item.Link = source.ID
+			}
+		}
+
+		if item.Image == nil && source.BannerImage != "" {
+			item.Image = &gofeed.Image{URL: source.BannerImage}
+		}
+
+		if item.PublishedParsed == nil {
+			item.PublishedParsed = item.UpdatedParsed
+		}
+
+		// the translator puts the duration of attachments in place of their size
+		if source.Attachments != nil {
+			for j, attachment := range *source.Attachments {
+				if j >= len(item.Enclosures) {
+					break
+				}
+
+				item.Enclosures[j].Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+
+				if attachment.DurationInSeconds > 0 && item.ITunesExt == nil {
+					item.ITunesExt = &gofeedext.ITunesItemExtension{Duration: strconv.FormatInt(attachment.DurationInSeconds, 10)}
+				}
+			}
+		}
+	}
+
+	return feed, nil
+}
+
+var feedAudioExtensions = []string{".mp3", ".m4a", ".aac", ".oga", ".ogg", ".opus", ".wav", ".flac"}
+
+// Podcast episodes link to their audio through an enclosure, with the duration
+// coming from the iTunes extension which nearly all podcast feeds include
+func findAudioInFeedItem(item *gofeed.Item, feedURL string) *rssFeedItemAudio {
+	for _, enclosure := range item.Enclosures {
+		if enclosure == nil || enclosure.URL == "" {
+			continue
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(enclosure.Type)
+		if !strings.HasPrefix(mediaType, "audio/") {
+			// some feeds don't set a type or use a generic one for everything
+			if mediaType != "" && mediaType != "application/octet-stream" {
+				continue
+			}
+
+			parsed, err := url.Parse(enclosure.URL)
+			if err != nil || !slices.Contains(feedAudioExtensions, strings.ToLower(path.Ext(parsed.Path))) {
+				continue
+			}
+		}
+
+		audio := &rssFeedItemAudio{
+			URL:  enclosure.URL,
+			Type: mediaType,
+		}
+
+		if base, err := url.Parse(feedURL); err == nil {
+			if resolved, err := base.Parse(enclosure.URL); err == nil {
+				audio.URL = resolved.String()
+			}
+		}
+
+		if length, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && length > 0 {
+			audio.Length = length
+		}
+
+		if item.ITunesExt != nil {
+			audio.Duration = parseITunesDuration(item.ITunesExt.Duration)
+		}
+
+		return audio
+	}
+
+	return nil
+}
+
+// Either a number of seconds or in the form of HH:MM:SS or MM:SS, zero
+// is returned for anything else since the duration is only informative
+func parseITunesDuration(value string) time.Duration {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return 0
+	}
+
+	var total float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil || number < 0 || (i > 0 && number >= 60) {
+			return 0
+		}
+
+		total = total*60 + number
+	}
+
+	return time.Duration(total * float64(time.Second))
+}
+
+func (widget *rssWidget) fetchItemsFromLobsters(request rssFeedRequest) ([]rssFeedItem, error) {
+	req, err := http.NewRequest("GET", lobstersFeedURL(request.URL, request.SortBy, request.Tags), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("User-Agent", glanceUserAgentString)
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	posts, err := decodeJsonFromRequest[lobstersFeedResponseJson](widget.httpClient(false), req)
+	if err != nil {
+		return nil, err
+	}
+
+	channelURL := ternary(request.URL != "", strings.TrimRight(request.URL, "/"), "https://lobste.rs")
+	channelName := ternary(request.Title != "", request.Title, "Lobsters")
+	items := make(rssFeedItemList, 0, len(posts))
+
+	for i := range posts {
+		if request.Limit > 0 && len(items) >= request.Limit {
+			break
+		}
+
+		post := &posts[i]
+		if widget.isMuted(html.UnescapeString(post.Title)) {
+			continue
+		}
+
+		item := rssFeedItem{
+			ChannelName: channelName,
+			ChannelURL:  channelURL,
+			Title:       html.UnescapeString(post.Title),
+			// text posts don't link anywhere other than their discussion
+			Link: ternary(post.URL != "", post.URL, post.CommentsURL),
+		}
+
+		if request.ItemLinkPrefix != "" {
+			item.Link = request.ItemLinkPrefix + item.Link
+		}
+
+		if request.IsDetailed {
+			if !request.HideDescription && post.Description != "" {
+				item.Description = shortenFeedDescriptionLen(post.Description, 200)
+			}
+
+			item.fetchFullContent = request.FetchFullContent && !request.HideDescription && post.URL != ""
+
+			if !request.HideCategories {
+				item.Categories = limitFeedItemCategories(post.Tags)
+			}
+		}
+
+		if createdAt, err := time.Parse(time.RFC3339, post.CreatedAt); err == nil {
+			item.PublishedAt = createdAt
+		} else {
+			item.PublishedAt = time.Now()
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+var feedItemTrackingParams = []string{"fbclid", "gclid", "mc_cid", "mc_eid"}
+
+// Used to detect duplicate articles, strips tracking query parameters and
+// other parts of the URL that don't change which article it points to
+func normalizeFeedItemLink(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+
+	query := parsed.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || slices.Contains(feedItemTrackingParams, strings.ToLower(key)) {
+			query.Del(key)
+		}
+	}
+
+	parsed.RawQuery = query.Encode()
+	parsed.Fragment = ""
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+
+	return parsed.String()
+}
+
 func findThumbnailInItemExtensions(item *gofeed.Item) string {
 	media, ok := item.Extensions["media"]
 
@@ -400,3 +875,181 @@ func shortenFeedDescriptionLen(description string, maxLen int) string {
 
 	return description
 }
+
+// How many articles at most get fetched during a single update, the rest
+// get fetched during the following updates
+const rssFullContentFetchesPerUpdate = 10
+
+// Articles which couldn't be extracted get retried after this long
+const rssFullContentRetryAfter = 6 * time.Hour
+
+type rssFullContent struct {
+	text      string
+	failedAt  time.Time
+	succeeded bool
+}
+
+// Replaces the descriptions of items with a longer preview of the article
+// they link to, keeping the description from the feed when that fails
+func (widget *rssWidget) populateFullContent(items rssFeedItemList) {
+	widget.fullContentMutex.Lock()
+	defer widget.fullContentMutex.Unlock()
+
+	toFetch := make([]string, 0, rssFullContentFetchesPerUpdate)
+	wanted := make(map[string]struct{})
+
+	for i := range items {
+		if !items[i].fetchFullContent || items[i].Link == "" {
+			continue
+		}
+
+		link := items[i].Link
+		wanted[link] = struct{}{}
+
+		cached, exists := widget.fullContent[link]
+		if exists && (cached.succeeded || time.Since(cached.failedAt) < rssFullContentRetryAfter) {
+			continue
+		}
+
+		if len(toFetch) < rssFullContentFetchesPerUpdate && !slices.Contains(toFetch, link) {
+			toFetch = append(toFetch, link)
+		}
+	}
+
+	// only the articles of the items which are still in the feeds are kept
+	for link := range widget.fullContent {
+		if _, exists := wanted[link]; !exists {
+			delete(widget.fullContent, link)
+		}
+	}
+
+	if len(toFetch) > 0 {
+		client := widget.httpClient(false)
+		job := newJob(func(link string) (string, error) {
+			return fetchArticleText(client, link, widget.FullContentLen)
+		}, toFetch).withWorkers(2)
+
+		texts, errs, err := workerPoolDo(job)
+		if err != nil {
+			slog.Error("Failed to fetch full content of RSS items", "error", err)
+			texts, errs = nil, nil
+		}
+
+		for i := range texts {
+			if errs[i] != nil {
+				slog.Warn("Could not extract content of RSS item, using its description instead", "url", toFetch[i], "error", errs[i])
+				widget.fullContent[toFetch[i]] = &rssFullContent{failedAt: time.Now()}
+				continue
+			}
+
+			widget.fullContent[toFetch[i]] = &rssFullContent{text: texts[i], succeeded: true}
+		}
+	}
+
+	for i := range items {
+		if !items[i].fetchFullContent {
+			continue
+		}
+
+		if cached, exists := widget.fullContent[items[i].Link]; exists && cached.succeeded {
+			items[i].Description = cached.text
+			items[i].IsFullContent = true
+		}
+	}
+}
+
+// Elements which are never part of the content of an article
+const articleNonContentSelector = "script, style, noscript, template, iframe, svg, form, nav, header, footer, aside, figure, [role=navigation], [role=banner], [role=contentinfo], [aria-hidden=true]"
+
+// Articles whose extracted text is shorter than this are assumed to have
+// been extracted incorrectly, such as pages which require JavaScript
+const articleMinTextLen = 150
+
+// A simplified take on readability, the container with the most text in its
+// paragraphs is assumed to be the article, preferring an <article> element
+func fetchArticleText(client requestDoer, link string, maxLen int) (string, error) {
+	request, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return "", err
+	}
+	setBrowserUserAgentHeader(request)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return "", fmt.Errorf("unexpected content type %s", contentType)
+	}
+
+	document, err := goquery.NewDocumentFromReader(io.LimitReader(response.Body, 2*1024*1024))
+	if err != nil {
+		return "", err
+	}
+
+	document.Find(articleNonContentSelector).Remove()
+
+	paragraphText := func(container *goquery.Selection) string {
+		var builder strings.Builder
+		container.Find("p").Each(func(_ int, p *goquery.Selection) {
+			text := strings.TrimSpace(sequentialWhitespacePattern.ReplaceAllString(p.Text(), " "))
+			if text == "" {
+				return
+			}
+			if builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(text)
+		})
+		return builder.String()
+	}
+
+	var text string
+
+	document.Find("article, main, [role=main], [itemprop=articleBody]").EachWithBreak(func(_ int, container *goquery.Selection) bool {
+		text = paragraphText(container)
+		return len(text) < articleMinTextLen
+	})
+
+	if len(text) < articleMinTextLen {
+		scores := make(map[*nethtml.Node]int)
+		var best *goquery.Selection
+		bestScore := 0
+
+		document.Find("p").Each(func(_ int, p *goquery.Selection) {
+			parent := p.Parent()
+			if parent.Length() == 0 {
+				return
+			}
+
+			node := parent.Get(0)
+			scores[node] += len(strings.TrimSpace(p.Text()))
+
+			if scores[node] > bestScore {
+				bestScore = scores[node]
+				best = parent
+			}
+		})
+
+		if best != nil {
+			text = paragraphText(best)
+		}
+	}
+
+	if len(text) < articleMinTextLen {
+		return "", errors.New("no article content found")
+	}
+
+	text, limited := limitStringLength(text, maxLen)
+	if limited {
+		text = strings.TrimRightFunc(text, unicode.IsSpace) + "…"
+	}
+
+	return text, nil
+}
@@ -1,9 +1,16 @@
 package glance
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"net/http"
+	"net/url"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 var searchWidgetTemplate = mustParseTemplate("search.html", "widget-base.html")
@@ -16,13 +23,50 @@ type SearchBang struct {
 
 type searchWidget struct {
 	widgetBase   `yaml:",inline"`
-	cachedHTML   template.HTML `yaml:"-"`
-	SearchEngine string        `yaml:"search-engine"`
-	Bangs        []SearchBang  `yaml:"bangs"`
-	NewTab       bool          `yaml:"new-tab"`
-	Target       string        `yaml:"target"`
-	Autofocus    bool          `yaml:"autofocus"`
-	Placeholder  string        `yaml:"placeholder"`
+	cachedHTML   template.HTML    `yaml:"-"`
+	SearchEngine string           `yaml:"search-engine"`
+	Bangs        searchBangsField `yaml:"bangs"`
+	NewTab       bool             `yaml:"new-tab"`
+	Target       string           `yaml:"target"`
+	Autofocus    bool             `yaml:"autofocus"`
+	Placeholder  string           `yaml:"placeholder"`
+
+	SuggestionsURL   string `yaml:"suggestions-url"`
+	ProxySuggestions bool   `yaml:"proxy-suggestions"`
+	suggestionsURL   string `yaml:"-"`
+}
+
+// Bangs can either be specified as a list of objects or as a map of the
+// shortcut to the URL, in which case the order they're defined in is kept
+// and the shortcut doubles as the title
+type searchBangsField []SearchBang
+
+func (f *searchBangsField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		var bangs []SearchBang
+		if err := node.Decode(&bangs); err != nil {
+			return err
+		}
+
+		*f = bangs
+		return nil
+	}
+
+	bangs := make([]SearchBang, 0, len(node.Content)/2)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		shortcut := node.Content[i].Value
+		bang := SearchBang{Shortcut: shortcut, Title: shortcut}
+
+		if err := node.Content[i+1].Decode(&bang.URL); err != nil {
+			return fmt.Errorf("search bang %q: url must be a string", bang.Shortcut)
+		}
+
+		bangs = append(bangs, bang)
+	}
+
+	*f = bangs
+	return nil
 }
 
 func convertSearchUrl(url string) string {
@@ -69,6 +113,22 @@ func (widget *searchWidget) initialize() error {
 		widget.Bangs[i].URL = convertSearchUrl(widget.Bangs[i].URL)
 	}
 
+	if widget.SuggestionsURL != "" {
+		if !strings.Contains(widget.SuggestionsURL, "{QUERY}") {
+			return errors.New("suggestions-url must contain {QUERY}")
+		}
+
+		widget.suggestionsURL = widget.SuggestionsURL
+
+		if widget.ProxySuggestions {
+			widget.SuggestionsURL = "/api/widgets/" + url.PathEscape(widget.stableID()) + "/suggestions?query={QUERY}"
+		}
+
+		widget.SuggestionsURL = convertSearchUrl(widget.SuggestionsURL)
+	} else if widget.ProxySuggestions {
+		return errors.New("proxy-suggestions requires suggestions-url to be set")
+	}
+
 	widget.cachedHTML = widget.renderTemplate(widget, searchWidgetTemplate)
 	return nil
 }
@@ -76,3 +136,98 @@ func (widget *searchWidget) initialize() error {
 func (widget *searchWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+const searchSuggestionsMaxResponseSize = 256 * 1024
+
+func (widget *searchWidget) proxiedHosts() []string {
+	if !widget.ProxySuggestions {
+		return nil
+	}
+
+	parsed, err := url.Parse(strings.ReplaceAll(widget.suggestionsURL, "{QUERY}", ""))
+	if err != nil {
+		return nil
+	}
+
+	return []string{parsed.Hostname()}
+}
+
+// Fetches suggestions on behalf of the browser for endpoints which don't allow
+// cross origin requests. Only the suggestions themselves are passed on, in the
+// same OpenSearch format that the endpoint is expected to respond with
+func (widget *searchWidget) handleRequest(w http.ResponseWriter, r *http.Request) {
+	if !widget.ProxySuggestions || r.PathValue("path") != "suggestions" || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	requestURL := strings.ReplaceAll(widget.suggestionsURL, "{QUERY}", url.QueryEscape(query))
+
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodGet, requestURL, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setBrowserUserAgentHeader(request)
+
+	// the endpoint itself is checked on startup, but it could
+	// redirect to anywhere
+	client := *widget.httpClient(false)
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		if widget.Providers != nil && !widget.Providers.proxyAllowedHosts.allows(request.URL.Hostname()) {
+			return fmt.Errorf("redirect to %s is not allowed", request.URL.Hostname())
+		}
+
+		return nil
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		http.Error(w, "fetching suggestions failed", http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		http.Error(w, fmt.Sprintf("suggestions endpoint responded with status %d", response.StatusCode), http.StatusBadGateway)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, searchSuggestionsMaxResponseSize))
+	if err != nil {
+		http.Error(w, "reading suggestions failed", http.StatusBadGateway)
+		return
+	}
+
+	suggestions, err := parseOpenSearchSuggestions(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	encoded, _ := json.Marshal([]any{query, suggestions})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	w.Write(encoded)
+}
+
+// The format is [query, [suggestion, ...], ...] where any
+// elements after the suggestions are optional and ignored
+func parseOpenSearchSuggestions(body []byte) ([]string, error) {
+	var decoded []json.RawMessage
+	if err := json.Unmarshal(body, &decoded); err != nil || len(decoded) < 2 {
+		return nil, errors.New("suggestions endpoint did not respond in the OpenSearch format")
+	}
+
+	var suggestions []string
+	if err := json.Unmarshal(decoded[1], &suggestions); err != nil {
+		return nil, errors.New("suggestions endpoint did not respond in the OpenSearch format")
+	}
+
+	return suggestions, nil
+}
@@ -2,9 +2,11 @@ package glance
 
 import (
 	"context"
+	"fmt"
 	"html/template"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +20,8 @@ var serverStatsWidgetTemplate = mustParseTemplate("server-stats.html", "widget-b
 type serverStatsWidget struct {
 	widgetBase `yaml:",inline"`
 	Servers    []serverStatsRequest `yaml:"servers"`
+	// Used for all remote servers which don't set their own
+	Token string `yaml:"token"`
 }
 
 func (widget *serverStatsWidget) initialize() error {
@@ -29,10 +33,30 @@ func (widget *serverStatsWidget) initialize() error {
 	}
 
 	for i := range widget.Servers {
-		widget.Servers[i].URL = strings.TrimRight(widget.Servers[i].URL, "/")
+		serv := &widget.Servers[i]
+
+		switch serv.Type {
+		case "local":
+		case "remote":
+			if serv.URL == "" {
+				return fmt.Errorf("server %d is missing a url", i+1)
+			}
+		default:
+			return fmt.Errorf("type of server %d must be either local or remote", i+1)
+		}
+
+		serv.URL = strings.TrimRight(serv.URL, "/")
 
-		if widget.Servers[i].Timeout == 0 {
-			widget.Servers[i].Timeout = durationField(3 * time.Second)
+		if serv.Token == "" {
+			serv.Token = widget.Token
+		}
+
+		if serv.Timeout == 0 {
+			serv.Timeout = durationField(3 * time.Second)
+		}
+
+		if req := serv.SystemInfoRequest; req != nil && req.Mountpoints.Listed != nil {
+			serv.SeparateMountpoints = true
 		}
 	}
 
@@ -65,9 +89,12 @@ func (widget *serverStatsWidget) update(context.Context) {
 				if err != nil {
 					slog.Warn("Getting remote system info: " + err.Error())
 					serv.IsReachable = false
-					serv.Info = &sysinfo.SystemInfo{
-						Hostname: "Unnamed server #" + strconv.Itoa(i+1),
+					hostname := "Unnamed server #" + strconv.Itoa(i+1)
+					if u, err := url.Parse(serv.URL); err == nil && u.Hostname() != "" {
+						hostname = u.Hostname()
 					}
+
+					serv.Info = &sysinfo.SystemInfo{Hostname: hostname}
 				} else {
 					serv.IsReachable = true
 					serv.Info = info
@@ -89,8 +116,11 @@ type serverStatsRequest struct {
 	Info                       *sysinfo.SystemInfo `yaml:"-"`
 	IsReachable                bool                `yaml:"-"`
 	StatusText                 string              `yaml:"-"`
+	SeparateMountpoints        bool                `yaml:"-"`
 	Name                       string              `yaml:"name"`
 	HideSwap                   bool                `yaml:"hide-swap"`
+	ShowPerCore                bool                `yaml:"show-per-core"`
+	ShowTemperature            bool                `yaml:"show-temperature"`
 	Type                       string              `yaml:"type"`
 	URL                        string              `yaml:"url"`
 	Token                      string              `yaml:"token"`
@@ -2,12 +2,18 @@ package glance
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"html/template"
 	"time"
 )
 
 var splitColumnWidgetTemplate = mustParseTemplate("split-column.html", "widget-base.html")
 
+// Every level splits the width that's available further, past this the
+// innermost widgets would end up too narrow to be readable
+const maxSplitColumnNesting = 2
+
 type splitColumnWidget struct {
 	widgetBase          `yaml:",inline"`
 	containerWidgetBase `yaml:",inline"`
@@ -25,15 +31,50 @@ func (widget *splitColumnWidget) initialize() error {
 		widget.MaxColumns = 2
 	}
 
+	for i := range widget.Widgets {
+		child := widget.Widgets[i]
+		span := child.ColumnSpan()
+
+		if span < 0 {
+			return formatWidgetInitError(errors.New("span must be a positive number"), child)
+		}
+
+		if span > widget.MaxColumns {
+			return formatWidgetInitError(fmt.Errorf("span can't be more than the max-columns of %d", widget.MaxColumns), child)
+		}
+	}
+
+	if splitColumnNesting(widget) > maxSplitColumnNesting {
+		return fmt.Errorf("split-column widgets can't be nested more than %d levels deep", maxSplitColumnNesting)
+	}
+
 	return nil
 }
 
+// Returns how many split-column widgets deep the most deeply nested
+// widget within w is, including w itself
+func splitColumnNesting(w widget) int {
+	var deepest int
+	if container, ok := w.(containerWidget); ok {
+		for _, child := range container.children() {
+			deepest = max(deepest, splitColumnNesting(child))
+		}
+	}
+
+	if _, ok := w.(*splitColumnWidget); ok {
+		return deepest + 1
+	}
+
+	return deepest
+}
+
 func (widget *splitColumnWidget) update(ctx context.Context) {
 	widget.containerWidgetBase._update(ctx)
 }
 
 func (widget *splitColumnWidget) setProviders(providers *widgetProviders) {
-	widget.containerWidgetBase._setProviders(providers)
+	widget.widgetBase.setProviders(providers)
+	widget.containerWidgetBase._setProviders(widget.Providers)
 }
 
 func (widget *splitColumnWidget) requiresUpdate(now *time.Time) bool {
@@ -0,0 +1,227 @@
+package glance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var statusSummaryWidgetTemplate = mustParseTemplate("status-summary.html", "widget-base.html")
+
+type statusSummaryWidget struct {
+	widgetBase           `yaml:",inline"`
+	URL                  string               `yaml:"url"`
+	Monitors             []string             `yaml:"monitors"`
+	Sites                []statusSummarySite  `yaml:"sites"`
+	DegradedResponseTime durationField        `yaml:"degraded-response-time"`
+	DegradedThreshold    statusThresholdField `yaml:"degraded-threshold"`
+	DownThreshold        statusThresholdField `yaml:"down-threshold"`
+	ShowAll              bool                 `yaml:"show-all"`
+
+	// One of operational, degraded or down
+	State            string `yaml:"-"`
+	Headline         string `yaml:"-"`
+	OperationalCount int    `yaml:"-"`
+	DegradedCount    int    `yaml:"-"`
+	DownCount        int    `yaml:"-"`
+}
+
+type statusSummarySite struct {
+	*SiteStatusRequest `yaml:",inline"`
+	Title              string      `yaml:"title"`
+	ErrorURL           string      `yaml:"error-url"`
+	SameTab            bool        `yaml:"same-tab"`
+	AltStatusCodes     []int       `yaml:"alt-status-codes"`
+	Status             *siteStatus `yaml:"-"`
+	URL                string      `yaml:"-"`
+	StatusText         string      `yaml:"-"`
+	// Same as the state of the widget, but for a single site
+	State string `yaml:"-"`
+}
+
+func (widget *statusSummaryWidget) initialize() error {
+	widget.withTitle("Status").withCacheDuration(5 * time.Minute)
+
+	if len(widget.Sites) == 0 && len(widget.Monitors) == 0 {
+		return errors.New("at least one site or monitor is required")
+	}
+
+	if widget.DegradedThreshold.isZero() {
+		widget.DegradedThreshold = statusThresholdField{count: 1}
+	}
+
+	if widget.DownThreshold.isZero() {
+		widget.DownThreshold = statusThresholdField{percent: 100}
+	}
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+
+		if site.SiteStatusRequest == nil || site.DefaultURL == "" {
+			return fmt.Errorf("site %q: url is required", site.Title)
+		}
+
+		if err := site.initialize(&widget.httpClientOptions, site.AltStatusCodes); err != nil {
+			return fmt.Errorf("site %q: %v", site.Title, err)
+		}
+	}
+
+	return nil
+}
+
+// The sites of referenced monitor widgets are checked by this widget on its
+// own rather than reusing the results of the monitor, since those only get
+// updated while the page they're on is being viewed
+func (widget *statusSummaryWidget) resolveReferences(lookup func(id string) (widget, bool)) error {
+	for _, id := range widget.Monitors {
+		referenced, exists := lookup(id)
+		if !exists {
+			return fmt.Errorf("monitor %q: no widget with this id exists", id)
+		}
+
+		monitor, ok := referenced.(*monitorWidget)
+		if !ok {
+			return fmt.Errorf("monitor %q: expected a monitor widget, got %s", id, referenced.GetType())
+		}
+
+		for i := range monitor.Sites {
+			site := &monitor.Sites[i]
+			widget.Sites = append(widget.Sites, statusSummarySite{
+				SiteStatusRequest: site.SiteStatusRequest,
+				Title:             site.Title,
+				ErrorURL:          site.ErrorURL,
+				SameTab:           site.SameTab,
+			})
+		}
+	}
+
+	if len(widget.Sites) == 0 {
+		return errors.New("none of the referenced monitors have any sites")
+	}
+
+	return nil
+}
+
+func (widget *statusSummaryWidget) update(ctx context.Context) {
+	requests := make([]*SiteStatusRequest, len(widget.Sites))
+
+	for i := range widget.Sites {
+		requests[i] = widget.Sites[i].SiteStatusRequest
+	}
+
+	statuses, err := fetchStatusForSites(requests)
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.OperationalCount, widget.DegradedCount, widget.DownCount = 0, 0, 0
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+		status := &statuses[i]
+		site.Status = status
+
+		if status.Error != nil && site.ErrorURL != "" {
+			site.URL = site.ErrorURL
+		} else {
+			site.URL = site.DefaultURL
+		}
+
+		switch {
+		case status.Error != nil || status.AssertionFailure != "":
+			site.State = "down"
+			site.StatusText = ternary(status.TimedOut, "Timed Out", site.statusText(status))
+			widget.DownCount++
+		case widget.DegradedResponseTime > 0 && status.ResponseTime > time.Duration(widget.DegradedResponseTime):
+			site.State = "degraded"
+			site.StatusText = "Slow"
+			widget.DegradedCount++
+		default:
+			site.State = "operational"
+			site.StatusText = "OK"
+			widget.OperationalCount++
+		}
+	}
+
+	total := len(widget.Sites)
+
+	switch {
+	case widget.DownThreshold.reached(widget.DownCount, total):
+		widget.State = "down"
+	case widget.DegradedThreshold.reached(widget.DownCount+widget.DegradedCount, total):
+		widget.State = "degraded"
+	default:
+		widget.State = "operational"
+	}
+
+	if widget.OperationalCount == total {
+		widget.Headline = "All systems operational"
+		return
+	}
+
+	counts := make([]string, 0, 2)
+	if widget.DownCount > 0 {
+		counts = append(counts, strconv.Itoa(widget.DownCount)+" down")
+	}
+	if widget.DegradedCount > 0 {
+		counts = append(counts, strconv.Itoa(widget.DegradedCount)+" degraded")
+	}
+
+	widget.Headline = strings.Join(counts, ", ")
+}
+
+func (widget *statusSummaryWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, statusSummaryWidgetTemplate)
+}
+
+// Either a number of sites, such as 2, or a percentage of all of them, such as 50%
+type statusThresholdField struct {
+	count   int
+	percent float64
+}
+
+func (f *statusThresholdField) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	value = strings.TrimSpace(value)
+
+	if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			return fmt.Errorf("line %d: invalid threshold %q, percentages must be greater than 0%% and at most 100%%", node.Line, value)
+		}
+
+		f.percent = parsed
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return fmt.Errorf("line %d: invalid threshold %q, must be a number of sites that's at least 1 or a percentage such as 50%%", node.Line, value)
+	}
+
+	f.count = parsed
+	return nil
+}
+
+func (f statusThresholdField) isZero() bool {
+	return f.count == 0 && f.percent == 0
+}
+
+func (f statusThresholdField) reached(count, total int) bool {
+	if f.percent > 0 {
+		return total > 0 && float64(count)*100 >= f.percent*float64(total)
+	}
+
+	return count >= f.count
+}
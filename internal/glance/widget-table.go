@@ -0,0 +1,370 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var tableWidgetTemplate = mustParseTemplate("table.html", "widget-base.html")
+
+type tableWidget struct {
+	widgetBase    `yaml:",inline"`
+	URL           string            `yaml:"url"`
+	Format        string            `yaml:"format"`
+	Root          string            `yaml:"root"`
+	Headers       map[string]string `yaml:"headers"`
+	AllowInsecure bool              `yaml:"allow-insecure"`
+	Columns       []tableColumn     `yaml:"columns"`
+	Limit         int               `yaml:"limit"`
+	Sortable      bool              `yaml:"sortable"`
+	Table         tableData         `yaml:"-"`
+}
+
+type tableColumn struct {
+	Field  string `yaml:"field"`
+	Title  string `yaml:"title"`
+	Format string `yaml:"format"`
+	// Only used with the link format, the field whose value is used as the
+	// text of the link instead of the URL
+	TextField string `yaml:"text-field"`
+}
+
+type tableData struct {
+	Columns []tableDataColumn
+	Rows    [][]tableCell
+}
+
+type tableDataColumn struct {
+	Title   string
+	Numeric bool
+}
+
+type tableCell struct {
+	Text string
+	URL  string
+	// Used for sorting, unset for cells which sort by their text
+	SortValue string
+	// Only set for the relative-time format, gets displayed as the time
+	// elapsed since it on the client
+	Time time.Time
+}
+
+var tableColumnFormats = []string{"text", "number", "date", "relative-time", "link"}
+
+func (widget *tableWidget) initialize() error {
+	widget.withTitle("Table").withCacheDuration(time.Hour)
+
+	if widget.URL == "" {
+		return errors.New("url is required")
+	}
+
+	if widget.Format == "" {
+		widget.Format = ternary(strings.EqualFold(path.Ext(widget.URL), ".csv"), "csv", "json")
+	} else if widget.Format != "csv" && widget.Format != "json" {
+		return errors.New("format must be either csv or json")
+	}
+
+	if widget.Format == "csv" && widget.Root != "" {
+		return errors.New("root can only be used with the json format")
+	}
+
+	if widget.Limit <= 0 {
+		widget.Limit = 50
+	}
+
+	for i := range widget.Columns {
+		column := &widget.Columns[i]
+
+		if column.Field == "" {
+			return fmt.Errorf("column #%d is missing a field", i+1)
+		}
+
+		if column.Title == "" {
+			column.Title = column.Field
+		}
+
+		if column.Format == "" {
+			column.Format = "text"
+		} else if !slices.Contains(tableColumnFormats, column.Format) {
+			return fmt.Errorf("column %s: format must be one of %s", column.Field, strings.Join(tableColumnFormats, ", "))
+		}
+
+		if column.TextField != "" && column.Format != "link" {
+			return fmt.Errorf("column %s: text-field can only be used with the link format", column.Field)
+		}
+	}
+
+	return nil
+}
+
+func (widget *tableWidget) update(ctx context.Context) {
+	table, err := fetchTableData(widget.httpClient(widget.AllowInsecure), widget)
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.Table = table
+}
+
+func (widget *tableWidget) dataModel() any {
+	return &widget.Table
+}
+
+func (widget *tableWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, tableWidgetTemplate)
+}
+
+// Rows are kept as a map of field to value regardless of the format, which
+// then get mapped to the configured columns
+type tableSourceRow map[string]string
+
+func fetchTableData(client requestDoer, widget *tableWidget) (tableData, error) {
+	request, err := http.NewRequest("GET", widget.URL, nil)
+	if err != nil {
+		return tableData{}, err
+	}
+
+	for key, value := range widget.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return tableData{}, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 5*1024*1024))
+	if err != nil {
+		return tableData{}, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		truncatedBody, _ := limitStringLength(string(body), 256)
+		return tableData{}, fmt.Errorf("unexpected status code %d from %s, response: %s", response.StatusCode, widget.URL, truncatedBody)
+	}
+
+	var fields []string
+	var rows []tableSourceRow
+
+	if widget.Format == "csv" {
+		fields, rows, err = parseTableCSV(body, widget.Limit)
+	} else {
+		fields, rows, err = parseTableJSON(body, widget.Root, widget.Limit)
+	}
+	if err != nil {
+		return tableData{}, err
+	}
+
+	columns := widget.Columns
+	if len(columns) == 0 {
+		columns = make([]tableColumn, 0, len(fields))
+		for _, field := range fields {
+			columns = append(columns, tableColumn{Field: field, Title: field, Format: "text"})
+		}
+	}
+
+	table := tableData{
+		Columns: make([]tableDataColumn, len(columns)),
+		Rows:    make([][]tableCell, 0, len(rows)),
+	}
+
+	for i := range columns {
+		table.Columns[i] = tableDataColumn{
+			Title:   columns[i].Title,
+			Numeric: columns[i].Format == "number",
+		}
+	}
+
+	for _, row := range rows {
+		cells := make([]tableCell, len(columns))
+		for i := range columns {
+			cells[i] = newTableCell(&columns[i], row, widget.formatter())
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+
+	return table, nil
+}
+
+func newTableCell(column *tableColumn, row tableSourceRow, formatter *localeFormatter) tableCell {
+	value := strings.TrimSpace(row[column.Field])
+	cell := tableCell{Text: value}
+
+	switch column.Format {
+	case "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			cell.SortValue = value
+			if number == float64(int64(number)) {
+				cell.Text = formatter.number(0, number)
+			} else {
+				cell.Text = formatter.number(2, number)
+			}
+		}
+	case "date", "relative-time":
+		if parsed, ok := parseTableTime(value); ok {
+			cell.SortValue = strconv.FormatInt(parsed.Unix(), 10)
+			if column.Format == "date" {
+				cell.Text = formatter.date(parsed)
+			} else {
+				cell.Time = parsed
+			}
+		}
+	case "link":
+		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			cell.URL = value
+			if column.TextField != "" && row[column.TextField] != "" {
+				cell.Text = row[column.TextField]
+			}
+		}
+	}
+
+	return cell
+}
+
+var tableTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Also accepts unix timestamps in seconds
+func parseTableTime(value string) (time.Time, bool) {
+	for _, layout := range tableTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, true
+		}
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds > 0 {
+		return time.Unix(seconds, 0), true
+	}
+
+	return time.Time{}, false
+}
+
+// The first record is used as the header
+func parseTableCSV(body []byte, limit int) ([]string, []tableSourceRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errNoContent
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("parsing CSV header: %v", err)
+	}
+
+	rows := make([]tableSourceRow, 0, min(limit, 64))
+
+	for len(rows) < limit {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("parsing CSV: %v", err)
+		}
+
+		row := make(tableSourceRow, len(header))
+		for i := range header {
+			if i < len(record) {
+				row[header[i]] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows, nil
+}
+
+// Expects an array of objects, either at the top level or at the root path,
+// values which aren't strings are displayed as their JSON representation
+func parseTableJSON(body []byte, root string, limit int) ([]string, []tableSourceRow, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, nil, errors.New("response is not valid JSON")
+	}
+
+	result := gjson.ParseBytes(body)
+	if root != "" {
+		result = result.Get(root)
+		if !result.Exists() {
+			return nil, nil, fmt.Errorf("root %s does not exist in the response", root)
+		}
+	}
+
+	if !result.IsArray() {
+		return nil, nil, errors.New("expected an array of objects")
+	}
+
+	var fields []string
+	seenFields := make(map[string]struct{})
+	rows := make([]tableSourceRow, 0, min(limit, 64))
+
+	for _, item := range result.Array() {
+		if len(rows) >= limit {
+			break
+		}
+
+		if !item.IsObject() {
+			return nil, nil, errors.New("expected an array of objects")
+		}
+
+		row := make(tableSourceRow)
+		item.ForEach(func(key, value gjson.Result) bool {
+			row[key.String()] = ternary(value.Type == gjson.String, value.String(), value.Raw)
+			if _, seen := seenFields[key.String()]; !seen {
+				seenFields[key.String()] = struct{}{}
+				fields = append(fields, key.String())
+			}
+			return true
+		})
+
+		// nested fields can be used as columns through their path
+		for _, field := range nestedTableFields(item) {
+			if _, exists := row[field]; !exists {
+				value := item.Get(field)
+				row[field] = ternary(value.Type == gjson.String, value.String(), value.Raw)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return fields, rows, nil
+}
+
+func nestedTableFields(item gjson.Result) []string {
+	var fields []string
+
+	item.ForEach(func(key, value gjson.Result) bool {
+		if !value.IsObject() {
+			return true
+		}
+
+		value.ForEach(func(nestedKey, _ gjson.Result) bool {
+			fields = append(fields, key.String()+"."+nestedKey.String())
+			return true
+		})
+
+		return true
+	})
+
+	return fields
+}
@@ -0,0 +1,311 @@
+package glance
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"reflect"
+	"text/template/parse"
+)
+
+// Replaces the template of the widget with the one in its template-file, if
+// set. Files are resolved relative to the config directory and aren't allowed
+// to be outside of it
+func loadWidgetTemplateFiles(ws widgets, configDir string) error {
+	for _, w := range ws {
+		if container, ok := w.(containerWidget); ok {
+			if err := loadWidgetTemplateFiles(container.children(), configDir); err != nil {
+				return err
+			}
+		}
+
+		path := w.templateFile()
+		if path == "" {
+			continue
+		}
+
+		t, err := parseWidgetTemplateFile(path, configDir, reflect.TypeOf(w))
+		if err != nil {
+			return formatWidgetInitError(fmt.Errorf("template-file: %v", err), w)
+		}
+
+		w.setCustomTemplate(t)
+	}
+
+	return nil
+}
+
+func parseWidgetTemplateFile(path, configDir string, dataType reflect.Type) (*template.Template, error) {
+	rootDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path of %s: %v", configDir, err)
+	}
+	rootDir = resolveSymlinksIfPossible(rootDir)
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(rootDir, path)
+	}
+
+	if !isPathWithinDir(resolveSymlinksIfPossible(path), rootDir) {
+		return nil, fmt.Errorf("%s is not allowed since it is outside of %s", path, rootDir)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := template.New("widget-base.html").Funcs(globalTemplateFunctions).ParseFS(templateFS, "widget-base.html")
+	if err != nil {
+		return nil, err
+	}
+	defaultContent := base.Lookup("widget-content").Tree
+
+	name := filepath.Base(path)
+	custom, err := base.New(name).Parse(string(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	// files copied from the built-in templates call widget-base.html and
+	// define widget-content themselves, anything else is used as the content
+	entry := base
+	if base.Lookup("widget-content").Tree != defaultContent {
+		entry = custom
+	} else if _, err := base.AddParseTree("widget-content", custom.Tree); err != nil {
+		return nil, err
+	}
+
+	checker := templateFieldChecker{templates: base, checked: make(map[string]struct{})}
+	if err := checker.checkTemplate(entry.Name(), dataType); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Templates only fail on fields which don't exist when they get executed, which
+// within a range over an empty list might not happen until much later. This
+// walks the parsed templates instead and follows the type of dot through them,
+// giving up on checking whenever the type can't be known ahead of time
+type templateFieldChecker struct {
+	templates *template.Template
+	checked   map[string]struct{}
+	root      reflect.Type
+}
+
+func (c *templateFieldChecker) checkTemplate(name string, dot reflect.Type) error {
+	if dot == nil {
+		return nil
+	}
+
+	key := name + " " + dot.String()
+	if _, checked := c.checked[key]; checked {
+		return nil
+	}
+	c.checked[key] = struct{}{}
+
+	t := c.templates.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return nil
+	}
+
+	// $ refers to the data that the template was called with
+	previousRoot := c.root
+	c.root = dot
+	defer func() { c.root = previousRoot }()
+
+	return c.checkNode(t.Tree, t.Tree.Root, dot)
+}
+
+func (c *templateFieldChecker) checkNode(tree *parse.Tree, node parse.Node, dot reflect.Type) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+
+		for _, n := range node.Nodes {
+			if err := c.checkNode(tree, n, dot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		_, err := c.pipeType(tree, node.Pipe, dot)
+		return err
+	case *parse.IfNode:
+		return c.checkBranch(tree, &node.BranchNode, dot, dot)
+	case *parse.WithNode:
+		typ, err := c.pipeType(tree, node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.checkBranch(tree, &node.BranchNode, typ, dot)
+	case *parse.RangeNode:
+		typ, err := c.pipeType(tree, node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.checkBranch(tree, &node.BranchNode, rangeElemType(typ), dot)
+	case *parse.TemplateNode:
+		typ, err := c.pipeType(tree, node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.checkTemplate(node.Name, typ)
+	}
+
+	return nil
+}
+
+func (c *templateFieldChecker) checkBranch(tree *parse.Tree, node *parse.BranchNode, listDot, elseDot reflect.Type) error {
+	if err := c.checkNode(tree, node.List, listDot); err != nil {
+		return err
+	}
+
+	return c.checkNode(tree, node.ElseList, elseDot)
+}
+
+// Returns the type the pipeline evaluates to, or nil if it's unknown
+func (c *templateFieldChecker) pipeType(tree *parse.Tree, pipe *parse.PipeNode, dot reflect.Type) (reflect.Type, error) {
+	if pipe == nil {
+		return dot, nil
+	}
+
+	var typ reflect.Type
+
+	for _, cmd := range pipe.Cmds {
+		var err error
+		for i, arg := range cmd.Args {
+			var argType reflect.Type
+			if argType, err = c.argType(tree, arg, dot); err != nil {
+				return nil, err
+			}
+
+			if i == 0 {
+				typ = argType
+			}
+		}
+
+		if len(cmd.Args) > 0 {
+			if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+				typ = templateFuncResultType(ident.Ident)
+			}
+		}
+	}
+
+	// variables aren't tracked, so the result of assigning one is unknown
+	if len(pipe.Decl) > 0 {
+		return nil, nil
+	}
+
+	return typ, nil
+}
+
+func (c *templateFieldChecker) argType(tree *parse.Tree, node parse.Node, dot reflect.Type) (reflect.Type, error) {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fieldChainType(tree, node, dot, node.Ident)
+	case *parse.VariableNode:
+		if node.Ident[0] != "$" {
+			return nil, nil
+		}
+		return c.fieldChainType(tree, node, c.root, node.Ident[1:])
+	case *parse.ChainNode:
+		typ, err := c.argType(tree, node.Node, dot)
+		if err != nil {
+			return nil, err
+		}
+		return c.fieldChainType(tree, node, typ, node.Field)
+	case *parse.PipeNode:
+		return c.pipeType(tree, node, dot)
+	}
+
+	return nil, nil
+}
+
+func (c *templateFieldChecker) fieldChainType(tree *parse.Tree, node parse.Node, typ reflect.Type, fields []string) (reflect.Type, error) {
+	for _, field := range fields {
+		if typ == nil {
+			return nil, nil
+		}
+
+		next, err := templateFieldType(typ, field)
+		if err != nil {
+			location, _ := tree.ErrorContext(node)
+			return nil, fmt.Errorf("%s: %v", location, err)
+		}
+
+		typ = next
+	}
+
+	return typ, nil
+}
+
+func templateFieldType(typ reflect.Type, name string) (reflect.Type, error) {
+	// methods with pointer receivers can be called since the
+	// widget and the fields within it are all addressable
+	method, ok := typ.MethodByName(name)
+	if !ok && typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		method, ok = reflect.PointerTo(typ).MethodByName(name)
+	}
+	if ok {
+		if method.Type.NumOut() == 0 {
+			return nil, nil
+		}
+		return method.Type.Out(0), nil
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		return nil, nil
+	case reflect.Map:
+		return typ.Elem(), nil
+	case reflect.Struct:
+		if field, ok := typ.FieldByName(name); ok && field.IsExported() {
+			return field.Type, nil
+		}
+	}
+
+	return nil, fmt.Errorf("field %s does not exist in %s", name, typ)
+}
+
+func rangeElemType(typ reflect.Type) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return typ.Elem()
+	case reflect.Int:
+		return typ
+	}
+
+	return nil
+}
+
+func templateFuncResultType(name string) reflect.Type {
+	fn, exists := globalTemplateFunctions[name]
+	if !exists {
+		return nil
+	}
+
+	typ := reflect.TypeOf(fn)
+	if typ.Kind() != reflect.Func || typ.NumOut() == 0 {
+		return nil
+	}
+
+	return typ.Out(0)
+}
@@ -1,20 +1,31 @@
 package glance
 
 import (
+	"encoding/json"
 	"html/template"
 )
 
 var todoWidgetTemplate = mustParseTemplate("todo.html", "widget-base.html")
 
 type todoWidget struct {
-	widgetBase `yaml:",inline"`
-	cachedHTML template.HTML `yaml:"-"`
-	TodoID     string        `yaml:"id"`
+	widgetBase      `yaml:",inline"`
+	Items           []string      `yaml:"items"`
+	ResetAtMidnight bool          `yaml:"reset-at-midnight"`
+	ItemsJSON       string        `yaml:"-"`
+	cachedHTML      template.HTML `yaml:"-"`
 }
 
 func (widget *todoWidget) initialize() error {
 	widget.withTitle("To-do").withError(nil)
 
+	if len(widget.Items) > 0 {
+		encoded, err := json.Marshal(widget.Items)
+		if err != nil {
+			return err
+		}
+		widget.ItemsJSON = string(encoded)
+	}
+
 	widget.cachedHTML = widget.renderTemplate(widget, todoWidgetTemplate)
 	return nil
 }
@@ -55,6 +55,10 @@ func (widget *twitchChannelsWidget) update(ctx context.Context) {
 	widget.Channels = channels
 }
 
+func (widget *twitchChannelsWidget) dataModel() any {
+	return &widget.Channels
+}
+
 func (widget *twitchChannelsWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, twitchChannelsWidgetTemplate)
 }
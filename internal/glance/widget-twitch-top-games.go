@@ -48,6 +48,10 @@ func (widget *twitchGamesWidget) update(ctx context.Context) {
 	widget.Categories = categories
 }
 
+func (widget *twitchGamesWidget) dataModel() any {
+	return &widget.Categories
+}
+
 func (widget *twitchGamesWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, twitchGamesWidgetTemplate)
 }
@@ -2,7 +2,6 @@ package glance
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -24,19 +23,13 @@ var (
 const defaultClientTimeout = 5 * time.Second
 
 var defaultHTTPClient = &http.Client{
-	Transport: &http.Transport{
-		MaxIdleConnsPerHost: 10,
-		Proxy:               http.ProxyFromEnvironment,
-	},
-	Timeout: defaultClientTimeout,
+	Transport: newLimitedTransport(defaultTransport),
+	Timeout:   defaultClientTimeout,
 }
 
 var defaultInsecureHTTPClient = &http.Client{
-	Timeout: defaultClientTimeout,
-	Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		Proxy:           http.ProxyFromEnvironment,
-	},
+	Timeout:   defaultClientTimeout,
+	Transport: newLimitedTransport(defaultInsecureTransport),
 }
 
 type requestDoer interface {
@@ -31,6 +31,10 @@ type videosWidget struct {
 	Playlists         []string  `yaml:"playlists"`
 	Limit             int       `yaml:"limit"`
 	IncludeShorts     bool      `yaml:"include-shorts"`
+
+	// the videos from the last successful fetch of each channel, which are
+	// used in place of the ones from channels whose feed couldn't be fetched
+	cachedChannels map[string]videoList `yaml:"-"`
 }
 
 func (widget *videosWidget) initialize() error {
@@ -60,11 +64,13 @@ func (widget *videosWidget) initialize() error {
 		}
 	}
 
+	widget.cachedChannels = make(map[string]videoList, len(widget.Channels))
+
 	return nil
 }
 
 func (widget *videosWidget) update(ctx context.Context) {
-	videos, err := fetchYoutubeChannelUploads(widget.Channels, widget.VideoUrlTemplate, widget.IncludeShorts)
+	videos, err := fetchYoutubeChannelUploads(widget.Channels, widget.VideoUrlTemplate, widget.IncludeShorts, widget.cachedChannels)
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
@@ -77,6 +83,10 @@ func (widget *videosWidget) update(ctx context.Context) {
 	widget.Videos = videos
 }
 
+func (widget *videosWidget) dataModel() any {
+	return &widget.Videos
+}
+
 func (widget *videosWidget) Render() template.HTML {
 	var template *template.Template
 
@@ -96,6 +106,7 @@ type youtubeFeedResponseXml struct {
 	Channel     string `xml:"author>name"`
 	ChannelLink string `xml:"author>uri"`
 	Videos      []struct {
+		VideoID   string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
 		Title     string `xml:"title"`
 		Published string `xml:"published"`
 		Link      struct {
@@ -138,7 +149,7 @@ func (v videoList) sortByNewest() videoList {
 	return v
 }
 
-func fetchYoutubeChannelUploads(channelOrPlaylistIDs []string, videoUrlTemplate string, includeShorts bool) (videoList, error) {
+func fetchYoutubeChannelUploads(channelOrPlaylistIDs []string, videoUrlTemplate string, includeShorts bool, cache map[string]videoList) (videoList, error) {
 	requests := make([]*http.Request, 0, len(channelOrPlaylistIDs))
 
 	for i := range channelOrPlaylistIDs {
@@ -170,28 +181,33 @@ func fetchYoutubeChannelUploads(channelOrPlaylistIDs []string, videoUrlTemplate
 		if errs[i] != nil {
 			failed++
 			slog.Error("Failed to fetch youtube feed", "channel", channelOrPlaylistIDs[i], "error", errs[i])
+			videos = append(videos, cache[channelOrPlaylistIDs[i]]...)
 			continue
 		}
 
 		response := responses[i]
+		channelVideos := make(videoList, 0, len(response.Videos))
 
 		for j := range response.Videos {
 			v := &response.Videos[j]
 			var videoUrl string
 
+			videoID := v.VideoID
+			if videoID == "" {
+				if parsedUrl, err := url.Parse(v.Link.Href); err == nil {
+					videoID = parsedUrl.Query().Get("v")
+				}
+			}
+
 			if videoUrlTemplate == "" {
 				videoUrl = v.Link.Href
+			} else if videoID != "" {
+				videoUrl = strings.ReplaceAll(videoUrlTemplate, "{VIDEO-ID}", videoID)
 			} else {
-				parsedUrl, err := url.Parse(v.Link.Href)
-
-				if err == nil {
-					videoUrl = strings.ReplaceAll(videoUrlTemplate, "{VIDEO-ID}", parsedUrl.Query().Get("v"))
-				} else {
-					videoUrl = "#"
-				}
+				videoUrl = "#"
 			}
 
-			videos = append(videos, video{
+			channelVideos = append(channelVideos, video{
 				ThumbnailUrl: v.Group.Thumbnail.Url,
 				Title:        v.Title,
 				Url:          videoUrl,
@@ -200,6 +216,9 @@ func fetchYoutubeChannelUploads(channelOrPlaylistIDs []string, videoUrlTemplate
 				TimePosted:   parseYoutubeFeedTime(v.Published),
 			})
 		}
+
+		cache[channelOrPlaylistIDs[i]] = channelVideos
+		videos = append(videos, channelVideos...)
 	}
 
 	if len(videos) == 0 {
@@ -209,7 +228,7 @@ func fetchYoutubeChannelUploads(channelOrPlaylistIDs []string, videoUrlTemplate
 	videos.sortByNewest()
 
 	if failed > 0 {
-		return videos, fmt.Errorf("%w: missing videos from %d channels", errPartialContent, failed)
+		return videos, fmt.Errorf("%w: could not update videos from %d channels", errPartialContent, failed)
 	}
 
 	return videos, nil
@@ -24,16 +24,27 @@ type weatherWidget struct {
 	HideLocation bool                        `yaml:"hide-location"`
 	HourFormat   string                      `yaml:"hour-format"`
 	Units        string                      `yaml:"units"`
+	ShowWind     bool                        `yaml:"show-wind"`
+	ForecastDays int                         `yaml:"forecast-days"`
 	Place        *openMeteoPlaceResponseJson `yaml:"-"`
 	Weather      *weather                    `yaml:"-"`
 	TimeLabels   [12]string                  `yaml:"-"`
 }
 
+// Any more than this don't fit in a single row within the small column
+const maxWeatherForecastDays = 7
+
 var timeLabels12h = [12]string{"2am", "4am", "6am", "8am", "10am", "12pm", "2pm", "4pm", "6pm", "8pm", "10pm", "12am"}
 var timeLabels24h = [12]string{"02:00", "04:00", "06:00", "08:00", "10:00", "12:00", "14:00", "16:00", "18:00", "20:00", "22:00", "00:00"}
 
 func (widget *weatherWidget) initialize() error {
-	widget.withTitle("Weather").withCacheOnTheHour()
+	widget.withTitle("Weather")
+
+	if custom := widget.customCacheDuration(); custom > 0 {
+		widget.withCacheDuration(custom)
+	} else {
+		widget.withCacheOnTheHour()
+	}
 
 	if widget.Location == "" {
 		return fmt.Errorf("location is required")
@@ -53,6 +64,10 @@ func (widget *weatherWidget) initialize() error {
 		return errors.New("units must be either metric or imperial")
 	}
 
+	if widget.ForecastDays < 0 || widget.ForecastDays > maxWeatherForecastDays {
+		return fmt.Errorf("forecast-days must be between 0 and %d", maxWeatherForecastDays)
+	}
+
 	return nil
 }
 
@@ -67,7 +82,18 @@ func (widget *weatherWidget) update(ctx context.Context) {
 		widget.Place = place
 	}
 
-	weather, err := fetchWeatherForOpenMeteoPlace(widget.Place, widget.Units)
+	// not kept when the place gets loaded from the persistent cache
+	if widget.Place.location == nil {
+		location, err := time.LoadLocation(widget.Place.Timezone)
+		if err != nil {
+			widget.withError(fmt.Errorf("loading location: %v", err)).scheduleEarlyUpdate()
+			return
+		}
+
+		widget.Place.location = location
+	}
+
+	weather, err := fetchWeatherForOpenMeteoPlace(widget.Place, widget.Units, widget.ForecastDays)
 
 	if !widget.canContinueUpdateAfterHandlingErr(err) {
 		return
@@ -76,6 +102,13 @@ func (widget *weatherWidget) update(ctx context.Context) {
 	widget.Weather = weather
 }
 
+func (widget *weatherWidget) dataModel() any {
+	return &struct {
+		Place   **openMeteoPlaceResponseJson
+		Weather **weather
+	}{&widget.Place, &widget.Weather}
+}
+
 func (widget *weatherWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, weatherWidgetTemplate)
 }
@@ -83,11 +116,13 @@ func (widget *weatherWidget) Render() template.HTML {
 type weather struct {
 	Temperature         int
 	ApparentTemperature int
+	WindSpeed           int
 	WeatherCode         int
 	CurrentColumn       int
 	SunriseColumn       int
 	SunsetColumn        int
 	Columns             []weatherColumn
+	Forecast            []weatherForecastDay
 }
 
 func (w *weather) WeatherCodeAsString() string {
@@ -98,6 +133,25 @@ func (w *weather) WeatherCodeAsString() string {
 	return ""
 }
 
+type weatherForecastDay struct {
+	Label       string
+	High        int
+	Low         int
+	WeatherCode int
+}
+
+func (d *weatherForecastDay) WeatherCodeAsString() string {
+	if weatherCode, ok := weatherCodeTable[d.WeatherCode]; ok {
+		return weatherCode
+	}
+
+	return ""
+}
+
+func (d *weatherForecastDay) Icon() string {
+	return weatherCodeIcon(d.WeatherCode)
+}
+
 type openMeteoPlacesResponseJson struct {
 	Results []openMeteoPlaceResponseJson
 }
@@ -114,8 +168,13 @@ type openMeteoPlaceResponseJson struct {
 
 type openMeteoWeatherResponseJson struct {
 	Daily struct {
+		Time    []int64 `json:"time"`
 		Sunrise []int64 `json:"sunrise"`
 		Sunset  []int64 `json:"sunset"`
+		// days without a forecast have their values set to null
+		TemperatureMax []*float64 `json:"temperature_2m_max"`
+		TemperatureMin []*float64 `json:"temperature_2m_min"`
+		WeatherCode    []*int     `json:"weather_code"`
 	} `json:"daily"`
 
 	Hourly struct {
@@ -126,6 +185,7 @@ type openMeteoWeatherResponseJson struct {
 	Current struct {
 		Temperature         float64 `json:"temperature_2m"`
 		ApparentTemperature float64 `json:"apparent_temperature"`
+		WindSpeed           float64 `json:"wind_speed_10m"`
 		WeatherCode         int     `json:"weather_code"`
 	} `json:"current"`
 }
@@ -210,25 +270,32 @@ func fetchOpenMeteoPlaceFromName(location string) (*openMeteoPlaceResponseJson,
 	return place, nil
 }
 
-func fetchWeatherForOpenMeteoPlace(place *openMeteoPlaceResponseJson, units string) (*weather, error) {
+func fetchWeatherForOpenMeteoPlace(place *openMeteoPlaceResponseJson, units string, forecastDays int) (*weather, error) {
 	query := url.Values{}
-	var temperatureUnit string
+	var temperatureUnit, windSpeedUnit string
 
 	if units == "imperial" {
 		temperatureUnit = "fahrenheit"
+		windSpeedUnit = "mph"
 	} else {
 		temperatureUnit = "celsius"
+		windSpeedUnit = "kmh"
 	}
 
 	query.Add("latitude", fmt.Sprintf("%f", place.Latitude))
 	query.Add("longitude", fmt.Sprintf("%f", place.Longitude))
 	query.Add("timeformat", "unixtime")
 	query.Add("timezone", place.Timezone)
-	query.Add("forecast_days", "1")
-	query.Add("current", "temperature_2m,apparent_temperature,weather_code")
+	query.Add("forecast_days", fmt.Sprintf("%d", max(1, forecastDays)))
+	query.Add("current", "temperature_2m,apparent_temperature,weather_code,wind_speed_10m")
 	query.Add("hourly", "temperature_2m,precipitation_probability")
-	query.Add("daily", "sunrise,sunset")
+	if forecastDays > 0 {
+		query.Add("daily", "sunrise,sunset,temperature_2m_max,temperature_2m_min,weather_code")
+	} else {
+		query.Add("daily", "sunrise,sunset")
+	}
 	query.Add("temperature_unit", temperatureUnit)
+	query.Add("wind_speed_unit", windSpeedUnit)
 
 	requestUrl := "https://api.open-meteo.com/v1/forecast?" + query.Encode()
 	request, _ := http.NewRequest("GET", requestUrl, nil)
@@ -247,7 +314,8 @@ func fetchWeatherForOpenMeteoPlace(place *openMeteoPlaceResponseJson, units stri
 		sunsetBar = 0
 	}
 
-	if len(responseJson.Hourly.Temperature) == 24 {
+	// the hourly values span all of the forecast days, only today's are shown
+	if len(responseJson.Hourly.Temperature) >= 24 && len(responseJson.Hourly.PrecipitationProbability) >= 24 {
 		temperatures := make([]int, 12)
 		precipitations := make([]bool, 12)
 
@@ -286,14 +354,63 @@ func fetchWeatherForOpenMeteoPlace(place *openMeteoPlaceResponseJson, units stri
 	return &weather{
 		Temperature:         int(responseJson.Current.Temperature),
 		ApparentTemperature: int(responseJson.Current.ApparentTemperature),
+		WindSpeed:           int(math.Round(responseJson.Current.WindSpeed)),
 		WeatherCode:         responseJson.Current.WeatherCode,
 		CurrentColumn:       currentBar,
 		SunriseColumn:       sunriseBar,
 		SunsetColumn:        sunsetBar,
 		Columns:             bars,
+		Forecast:            weatherForecastFromResponse(&responseJson, place.location, forecastDays),
 	}, nil
 }
 
+// Days which are missing any of their values get left out rather than failing
+// the whole widget, the forecast isn't shown at all if none of them are left
+func weatherForecastFromResponse(response *openMeteoWeatherResponseJson, location *time.Location, days int) []weatherForecastDay {
+	daily := &response.Daily
+	days = min(days, len(daily.Time), len(daily.TemperatureMax), len(daily.TemperatureMin), len(daily.WeatherCode))
+
+	forecast := make([]weatherForecastDay, 0, days)
+	for i := 0; i < days; i++ {
+		if daily.TemperatureMax[i] == nil || daily.TemperatureMin[i] == nil || daily.WeatherCode[i] == nil {
+			continue
+		}
+
+		forecast = append(forecast, weatherForecastDay{
+			Label:       ternary(i == 0, "Today", time.Unix(daily.Time[i], 0).In(location).Format("Mon")),
+			High:        int(math.Round(*daily.TemperatureMax[i])),
+			Low:         int(math.Round(*daily.TemperatureMin[i])),
+			WeatherCode: *daily.WeatherCode[i],
+		})
+	}
+
+	return forecast
+}
+
+// The name of the icon in weather.html for a weather code
+func weatherCodeIcon(code int) string {
+	switch {
+	case code <= 1:
+		return "clear"
+	case code == 2:
+		return "partly-cloudy"
+	case code == 3:
+		return "cloudy"
+	case code == 45 || code == 48:
+		return "fog"
+	case code >= 51 && code <= 57:
+		return "drizzle"
+	case code >= 61 && code <= 67, code >= 80 && code <= 82:
+		return "rain"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "snow"
+	case code >= 95:
+		return "thunderstorm"
+	}
+
+	return "cloudy"
+}
+
 var weatherCodeTable = map[int]string{
 	0:  "Clear Sky",
 	1:  "Mainly Clear",
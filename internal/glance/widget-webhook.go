@@ -0,0 +1,354 @@
+package glance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+var webhookWidgetTemplate = mustParseTemplate("webhook.html", "widget-base.html")
+
+const (
+	// Larger payloads than this get rejected
+	webhookPayloadSizeLimit = 64 * 1024
+	webhookMaxKeptPayloads  = 100
+	// Only the times of events are kept beyond the last payloads, the count
+	// stops going up once this many have been received within the window
+	webhookMaxTrackedEvents = 10_000
+	webhookValueMaxLength   = 300
+)
+
+var webhookHookPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+type webhookWidget struct {
+	widgetBase    `yaml:",inline"`
+	Hook          string        `yaml:"hook"`
+	Token         string        `yaml:"token"`
+	Window        durationField `yaml:"window"`
+	Keep          int           `yaml:"keep"`
+	JSONPath      string        `yaml:"json-path"`
+	CollapseAfter int           `yaml:"collapse-after"`
+
+	Events      []webhookEvent `yaml:"-"`
+	Count       int            `yaml:"-"`
+	CountCapped bool           `yaml:"-"`
+	WindowLabel string         `yaml:"-"`
+	seenVersion uint64
+}
+
+type webhookEvent struct {
+	ReceivedAt time.Time
+	Value      string
+	Error      string
+}
+
+func (widget *webhookWidget) initialize() error {
+	widget.withTitle("Webhook").withCacheDuration(time.Minute)
+
+	if widget.Hook == "" {
+		return errors.New("hook is required")
+	}
+
+	if !webhookHookPattern.MatchString(widget.Hook) {
+		return errors.New("hook can only contain letters, numbers, dashes and underscores")
+	}
+
+	if widget.Token == "" {
+		return errors.New("token is required so that only your systems can post events")
+	}
+
+	if widget.Keep == 0 {
+		widget.Keep = 10
+	} else if widget.Keep < 1 || widget.Keep > webhookMaxKeptPayloads {
+		return fmt.Errorf("keep must be between 1 and %d", webhookMaxKeptPayloads)
+	}
+
+	if widget.Window == 0 {
+		widget.Window = durationField(24 * time.Hour)
+	}
+
+	widget.WindowLabel = shortDurationLabel(time.Duration(widget.Window))
+
+	if widget.CollapseAfter == 0 || widget.CollapseAfter < -1 {
+		widget.CollapseAfter = 3
+	}
+
+	return nil
+}
+
+// Updates as soon as a new event comes in rather than waiting for the cache
+// to expire, the cache is what keeps the count of the window up to date
+func (widget *webhookWidget) requiresUpdate(now *time.Time) bool {
+	return widget.widgetBase.requiresUpdate(now) || webhookLogs.version(widget.Hook) != widget.seenVersion
+}
+
+func (widget *webhookWidget) update(_ context.Context) {
+	snapshot := webhookLogs.snapshot(widget.Hook, time.Now().Add(-time.Duration(widget.Window)))
+
+	events := make([]webhookEvent, 0, min(len(snapshot.payloads), widget.Keep))
+	for i := len(snapshot.payloads) - 1; i >= 0 && len(events) < widget.Keep; i-- {
+		events = append(events, widget.eventFromPayload(&snapshot.payloads[i]))
+	}
+
+	widget.Events = events
+	widget.Count = snapshot.count
+	widget.CountCapped = snapshot.capped
+	widget.seenVersion = snapshot.version
+
+	widget.canContinueUpdateAfterHandlingErr(nil)
+}
+
+func (widget *webhookWidget) eventFromPayload(payload *webhookPayload) webhookEvent {
+	event := webhookEvent{ReceivedAt: payload.receivedAt}
+
+	if widget.JSONPath != "" {
+		if !gjson.ValidBytes(payload.body) {
+			event.Error = "payload is not valid JSON"
+			return event
+		}
+
+		result := gjson.GetBytes(payload.body, widget.JSONPath)
+		if !result.Exists() {
+			event.Error = "json-path " + widget.JSONPath + " does not exist in the payload"
+			return event
+		}
+
+		event.Value = result.String()
+	} else if compacted := new(bytes.Buffer); json.Compact(compacted, payload.body) == nil {
+		event.Value = compacted.String()
+	} else {
+		event.Value = string(payload.body)
+	}
+
+	event.Value = strings.TrimSpace(event.Value)
+	if utf8.RuneCountInString(event.Value) > webhookValueMaxLength {
+		event.Value = string([]rune(event.Value)[:webhookValueMaxLength]) + "…"
+	}
+
+	return event
+}
+
+func (widget *webhookWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, webhookWidgetTemplate)
+}
+
+// Days are only used past a day since "24h" reads better than "1d"
+func shortDurationLabel(d time.Duration) string {
+	switch {
+	case d > 24*time.Hour && d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	case d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	case d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	default:
+		return strconv.Itoa(int(d/time.Second)) + "s"
+	}
+}
+
+type webhookPayload struct {
+	receivedAt time.Time
+	body       []byte
+}
+
+type webhookLog struct {
+	keep int
+	// Oldest first, at most keep of them
+	payloads []webhookPayload
+	// Oldest first, at most webhookMaxTrackedEvents of them
+	received []time.Time
+	// Goes up with every event so that widgets know when to update
+	version uint64
+}
+
+type webhookLogSnapshot struct {
+	payloads []webhookPayload
+	count    int
+	capped   bool
+	version  uint64
+}
+
+// Kept in memory only and shared by all instances of the application, so
+// that events aren't lost when the config gets reloaded but are when the
+// server restarts
+type webhookLogStore struct {
+	mu     sync.Mutex
+	byHook map[string]*webhookLog
+}
+
+var webhookLogs = &webhookLogStore{byHook: make(map[string]*webhookLog)}
+
+// Logs of hooks which are no longer used by any widget get removed, so
+// that the memory used is only ever bound by the current config
+func (s *webhookLogStore) configure(keepByHook map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hook := range s.byHook {
+		if _, exists := keepByHook[hook]; !exists {
+			delete(s.byHook, hook)
+		}
+	}
+
+	for hook, keep := range keepByHook {
+		log, exists := s.byHook[hook]
+		if !exists {
+			log = &webhookLog{}
+			s.byHook[hook] = log
+		}
+
+		log.keep = keep
+		if len(log.payloads) > keep {
+			log.payloads = append([]webhookPayload(nil), log.payloads[len(log.payloads)-keep:]...)
+		}
+	}
+}
+
+func (s *webhookLogStore) record(hook string, body []byte, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, exists := s.byHook[hook]
+	if !exists {
+		return false
+	}
+
+	if len(log.payloads) >= log.keep {
+		log.payloads = append(log.payloads[:0], log.payloads[len(log.payloads)-log.keep+1:]...)
+	}
+	log.payloads = append(log.payloads, webhookPayload{receivedAt: now, body: body})
+
+	if len(log.received) >= webhookMaxTrackedEvents {
+		log.received = append(log.received[:0], log.received[len(log.received)-webhookMaxTrackedEvents+1:]...)
+	}
+	log.received = append(log.received, now)
+
+	log.version++
+
+	return true
+}
+
+func (s *webhookLogStore) version(hook string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if log, exists := s.byHook[hook]; exists {
+		return log.version
+	}
+
+	return 0
+}
+
+func (s *webhookLogStore) snapshot(hook string, since time.Time) webhookLogSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, exists := s.byHook[hook]
+	if !exists {
+		return webhookLogSnapshot{}
+	}
+
+	snapshot := webhookLogSnapshot{
+		// the bodies never get modified, so they don't need to be copied
+		payloads: append([]webhookPayload(nil), log.payloads...),
+		version:  log.version,
+	}
+
+	for i := len(log.received) - 1; i >= 0 && log.received[i].After(since); i-- {
+		snapshot.count++
+	}
+
+	snapshot.capped = len(log.received) == webhookMaxTrackedEvents && snapshot.count == len(log.received)
+
+	return snapshot
+}
+
+type webhookHook struct {
+	tokenHash [32]byte
+}
+
+// Hooks aren't tied to a dashboard, so the widgets of all dashboards get
+// collected into the first one, which is the only one serving them
+func (a *application) initWebhooks() error {
+	a.webhooks = make(map[string]webhookHook)
+	keepByHook := make(map[string]int)
+	tokens := make(map[string]string)
+
+	apps := []*application{a}
+	for _, dashboardApp := range a.dashboardApps {
+		apps = append(apps, dashboardApp)
+	}
+
+	for _, app := range apps {
+		for _, entry := range app.widgetByStableID {
+			widget, ok := entry.widget.(*webhookWidget)
+			if !ok {
+				continue
+			}
+
+			if token, exists := tokens[widget.Hook]; exists && token != widget.Token {
+				return formatWidgetInitError(fmt.Errorf("hook %s is used by more than one widget with different tokens", widget.Hook), widget)
+			}
+
+			tokens[widget.Hook] = widget.Token
+			keepByHook[widget.Hook] = max(keepByHook[widget.Hook], widget.Keep)
+			a.webhooks[widget.Hook] = webhookHook{tokenHash: sha256.Sum256([]byte(widget.Token))}
+		}
+	}
+
+	webhookLogs.configure(keepByHook)
+
+	return nil
+}
+
+// The token can be given either as a bearer token or through the token query
+// parameter for services which only let you set the URL. Unknown hooks get the
+// same response as a wrong token so that which ones exist can't be guessed
+func (a *application) handleWebhookRequest(w http.ResponseWriter, r *http.Request) {
+	hook, exists := a.webhooks[r.PathValue("id")]
+
+	givenToken := r.URL.Query().Get("token")
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		givenToken = strings.TrimSpace(token)
+	}
+
+	givenTokenHash := sha256.Sum256([]byte(givenToken))
+	if !exists || givenToken == "" || subtle.ConstantTimeCompare(givenTokenHash[:], hook.tokenHash[:]) != 1 {
+		http.Error(w, "invalid hook or token", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookPayloadSizeLimit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "payload is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, "could not read payload", http.StatusBadRequest)
+		return
+	}
+
+	if !webhookLogs.record(r.PathValue("id"), body, time.Now()) {
+		http.Error(w, "invalid hook or token", http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,12 +3,15 @@ package glance
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"html/template"
 	"log/slog"
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +21,19 @@ import (
 var widgetIDCounter atomic.Uint64
 
 func newWidget(widgetType string) (widget, error) {
+	w, err := newWidgetOfType(widgetType)
+	if err != nil {
+		return nil, err
+	}
+
+	w.setID(widgetIDCounter.Add(1))
+
+	return w, nil
+}
+
+// Unlike newWidget, doesn't give the widget an ID, which lets the config be
+// checked against the properties of each widget type
+func newWidgetOfType(widgetType string) (widget, error) {
 	if widgetType == "" {
 		return nil, errors.New("widget 'type' property is empty or not specified")
 	}
@@ -39,13 +55,17 @@ func newWidget(widgetType string) (widget, error) {
 		w = &iframeWidget{}
 	case "html":
 		w = &htmlWidget{}
+	case "heading":
+		w = &headingWidget{}
 	case "hacker-news":
 		w = &hackerNewsWidget{}
 	case "releases":
 		w = &releasesWidget{}
+	case "issues":
+		w = &issuesWidget{}
 	case "videos":
 		w = &videosWidget{}
-	case "markets", "stocks":
+	case "markets":
 		w = &marketsWidget{}
 	case "reddit":
 		w = &redditWidget{}
@@ -53,6 +73,8 @@ func newWidget(widgetType string) (widget, error) {
 		w = &rssWidget{}
 	case "monitor":
 		w = &monitorWidget{}
+	case "status-summary":
+		w = &statusSummaryWidget{}
 	case "twitch-top-games":
 		w = &twitchGamesWidget{}
 	case "twitch-channels":
@@ -75,23 +97,40 @@ func newWidget(widgetType string) (widget, error) {
 		w = &splitColumnWidget{}
 	case "custom-api":
 		w = &customAPIWidget{}
+	case "graphql":
+		w = &graphqlWidget{}
 	case "docker-containers":
 		w = &dockerContainersWidget{}
+	case "ics":
+		w = &icsWidget{}
 	case "server-stats":
 		w = &serverStatsWidget{}
 	case "to-do":
 		w = &todoWidget{}
+	case "notes":
+		w = &notesWidget{}
+	case "prometheus":
+		w = &prometheusWidget{}
+	case "table":
+		w = &tableWidget{}
+	case "heatmap":
+		w = &heatmapWidget{}
+	case "mqtt":
+		w = &mqttWidget{}
+	case "webhook":
+		w = &webhookWidget{}
 	default:
 		return nil, fmt.Errorf("unknown widget type: %s", widgetType)
 	}
 
-	w.setID(widgetIDCounter.Add(1))
-
 	return w, nil
 }
 
 type widgets []widget
 
+// Errors are collected from all widgets rather than stopping at the first one
+// and returned as a yaml.TypeError, which the decoder accumulates instead of
+// aborting, so that every problem in the config can be reported at once
 func (w *widgets) UnmarshalYAML(node *yaml.Node) error {
 	var nodes []yaml.Node
 
@@ -99,27 +138,65 @@ func (w *widgets) UnmarshalYAML(node *yaml.Node) error {
 		return err
 	}
 
+	var errs []string
+	addErr := func(line int, err error) {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			errs = append(errs, typeErr.Errors...)
+		} else if strings.HasPrefix(err.Error(), "line ") {
+			errs = append(errs, err.Error())
+		} else {
+			errs = append(errs, fmt.Sprintf("line %d: %v", line, err))
+		}
+	}
+
 	for _, node := range nodes {
 		meta := struct {
 			Type string `yaml:"type"`
 		}{}
 
 		if err := node.Decode(&meta); err != nil {
-			return err
+			addErr(node.Line, err)
+			continue
 		}
 
 		widget, err := newWidget(meta.Type)
 		if err != nil {
-			return fmt.Errorf("line %d: %w", node.Line, err)
+			addErr(node.Line, err)
+			continue
 		}
 
 		if err = node.Decode(widget); err != nil {
-			return err
+			addErr(node.Line, err)
+			continue
+		}
+
+		if err = widget.initHTTPClient(); err != nil {
+			addErr(node.Line, err)
+			continue
 		}
 
+		if err = widget.initRetryOptions(); err != nil {
+			addErr(node.Line, err)
+			continue
+		}
+
+		if cached, ok := widget.(persistentlyCachedWidget); ok {
+			key, err := persistentCacheKeyFromNode(meta.Type, &node)
+			if err != nil {
+				addErr(node.Line, err)
+				continue
+			}
+			cached.setPersistentCacheKey(key)
+		}
+
+		widget.setLine(node.Line)
 		*w = append(*w, widget)
 	}
 
+	if len(errs) > 0 {
+		return &yaml.TypeError{Errors: errs}
+	}
+
 	return nil
 }
 
@@ -128,14 +205,44 @@ type widget interface {
 	Render() template.HTML
 	GetType() string
 	GetID() uint64
+	StaleSince() time.Time
+
+	stableID() string
+	getTitle() string
 
 	initialize() error
 	requiresUpdate(*time.Time) bool
 	setProviders(*widgetProviders)
 	update(context.Context)
+	updateError() error
+	isFirstUpdate() bool
+	initHTTPClient() error
+	initRetryOptions() error
+	startRetries() (attempts int, backoff time.Duration)
+	finishRetry(last bool) (retryAgain bool)
 	setID(uint64)
+	setLine(int)
+	getLine() int
 	handleRequest(w http.ResponseWriter, r *http.Request)
 	setHideHeader(bool)
+	hasVisibilityCondition() bool
+	browserCache() *browserCacheField
+	updateVisibility(now time.Time)
+	// Needs to be exported because it gets called in templates
+	IsHidden() bool
+	// Same as above, only has an effect on widgets within a split-column
+	ColumnSpan() int
+	templateFile() string
+	setCustomTemplate(*template.Template)
+	enforceMinimumRefresh(time.Duration) error
+}
+
+// Implemented by widgets whose data can be stored in the persistent
+// cache and exposed through the API
+type dataModelWidget interface {
+	widget
+	// Must return a pointer to the fields which hold the data of the widget
+	dataModel() any
 }
 
 type cacheType int
@@ -147,31 +254,57 @@ const (
 )
 
 type widgetBase struct {
-	ID                  uint64           `yaml:"-"`
-	Providers           *widgetProviders `yaml:"-"`
-	Type                string           `yaml:"type"`
-	Title               string           `yaml:"title"`
-	TitleURL            string           `yaml:"title-url"`
-	HideHeader          bool             `yaml:"hide-header"`
-	CSSClass            string           `yaml:"css-class"`
-	CustomCacheDuration durationField    `yaml:"cache"`
-	ContentAvailable    bool             `yaml:"-"`
-	WIP                 bool             `yaml:"-"`
-	Error               error            `yaml:"-"`
-	Notice              error            `yaml:"-"`
-	templateBuffer      bytes.Buffer     `yaml:"-"`
-	cacheDuration       time.Duration    `yaml:"-"`
-	cacheType           cacheType        `yaml:"-"`
-	nextUpdate          time.Time        `yaml:"-"`
-	updateRetriedTimes  int              `yaml:"-"`
+	ID                   uint64             `yaml:"-"`
+	CustomID             string             `yaml:"id"`
+	Providers            *widgetProviders   `yaml:"-"`
+	Type                 string             `yaml:"type"`
+	Title                string             `yaml:"title"`
+	TitleURL             string             `yaml:"title-url"`
+	HideHeader           bool               `yaml:"hide-header"`
+	CSSClass             string             `yaml:"css-class"`
+	CustomCacheDuration  durationField      `yaml:"cache"`
+	Refresh              durationField      `yaml:"refresh"`
+	StaleTimeout         durationField      `yaml:"stale-timeout"`
+	RetryAttempts        int                `yaml:"retry-attempts"`
+	RetryBackoff         durationField      `yaml:"retry-backoff"`
+	VisibleWhen          *visibleWhenField  `yaml:"visible-when"`
+	TemplateFile         string             `yaml:"template-file"`
+	Span                 int                `yaml:"span"`
+	BrowserCache         *browserCacheField `yaml:"browser-cache"`
+	Formatting           *formattingOptions `yaml:"formatting"`
+	httpClientOptions    `yaml:",inline"`
+	ContentAvailable     bool               `yaml:"-"`
+	WIP                  bool               `yaml:"-"`
+	Error                error              `yaml:"-"`
+	Notice               error              `yaml:"-"`
+	templateBuffer       bytes.Buffer       `yaml:"-"`
+	cacheDuration        time.Duration      `yaml:"-"`
+	cacheType            cacheType          `yaml:"-"`
+	nextUpdate           time.Time          `yaml:"-"`
+	updateRetriedTimes   int                `yaml:"-"`
+	retrying             bool               `yaml:"-"`
+	retriesExhausted     bool               `yaml:"-"`
+	line                 int                `yaml:"-"`
+	lastSuccessfulUpdate time.Time          `yaml:"-"`
+	updatedOnce          bool               `yaml:"-"`
+	persistentCacheKey   string             `yaml:"-"`
+	persistentCacheRead  bool               `yaml:"-"`
+	hidden               bool               `yaml:"-"`
+	customTemplate       *template.Template `yaml:"-"`
 }
 
 type widgetProviders struct {
-	assetResolver func(string) string
+	assetResolver     func(string) string
+	cache             widgetCacheStore
+	notes             *diskNoteStore
+	requiresAuth      bool
+	proxyAllowedHosts hostAllowlist
+	formatter         *localeFormatter
+	location          *time.Location
 }
 
 func (w *widgetBase) requiresUpdate(now *time.Time) bool {
-	if w.cacheType == cacheTypeInfinite {
+	if w.cacheType == cacheTypeInfinite || w.retrying {
 		return false
 	}
 
@@ -182,6 +315,10 @@ func (w *widgetBase) requiresUpdate(now *time.Time) bool {
 	return now.After(w.nextUpdate)
 }
 
+func (w *widgetBase) browserCache() *browserCacheField {
+	return w.BrowserCache
+}
+
 func (w *widgetBase) IsWIP() bool {
 	return w.WIP
 }
@@ -190,14 +327,236 @@ func (w *widgetBase) update(ctx context.Context) {
 
 }
 
+// Wraps the widget's update in order to record metrics and logs and
+// make use of the persistent cache when one is configured
+func updateWidget(ctx context.Context, widget widget) {
+	cached, isCached := widget.(persistentlyCachedWidget)
+	if isCached && cached.loadFromPersistentCache(cached.dataModel()) {
+		recordWidgetCacheHit(ctx, widget)
+		return
+	}
+
+	if _, isContainer := widget.(containerWidget); !isContainer && widget.isFirstUpdate() {
+		waitForInitialUpdateJitter()
+	}
+
+	start := time.Now()
+	widget.update(ctx)
+	recordWidgetUpdate(ctx, widget, time.Since(start))
+
+	if attempts, backoff := widget.startRetries(); attempts > 0 {
+		if p, ok := ctx.Value(widgetPageContextKey{}).(*page); ok {
+			go p.retryFailedWidgetUpdate(ctx, widget, attempts, backoff)
+		} else {
+			widget.finishRetry(true)
+		}
+	}
+
+	if isCached {
+		cached.saveToPersistentCache(cached.dataModel())
+	}
+}
+
+// Carries the page that the widgets being updated are on, so that the
+// retries of the ones that fail can lock it and publish their results
+type widgetPageContextKey struct{}
+
+// Each retry waits twice as long as the one before it. The page only gets
+// locked while the widget is updating, so requests for it don't wait for the
+// retries, and the ones that succeed are sent out as live updates right away
+func (p *page) retryFailedWidgetUpdate(ctx context.Context, widget widget, attempts int, backoff time.Duration) {
+	for attempt := 0; attempt < attempts; attempt++ {
+		timer := time.NewTimer(min(backoff<<attempt, maxWidgetRetryBackoff))
+
+		// the contexts of updates are derived from widgetUpdatesCtx,
+		// so this also stops the retries when shutting down
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			p.mu.Lock()
+			widget.finishRetry(true)
+			p.mu.Unlock()
+			return
+		case <-timer.C:
+		}
+
+		p.mu.Lock()
+
+		start := time.Now()
+		widget.update(ctx)
+		recordWidgetUpdate(ctx, widget, time.Since(start))
+
+		if widget.finishRetry(attempt == attempts-1) {
+			p.mu.Unlock()
+			continue
+		}
+
+		succeeded := widget.updateError() == nil
+		if cached, isCached := widget.(persistentlyCachedWidget); isCached && succeeded {
+			cached.saveToPersistentCache(cached.dataModel())
+		}
+
+		// either the data or the error that was held back is now shown
+		p.dataVersion++
+		p.dataUpdatedAt = time.Now()
+
+		var event liveUpdateEvent
+		publish := false
+		if succeeded && !widget.IsHidden() {
+			event, publish = p.renderLiveUpdate(widget)
+		}
+
+		if publish {
+			if p.live.sentHashes == nil {
+				p.live.sentHashes = make(map[uint64][sha256.Size]byte)
+			}
+			p.live.sentHashes[widget.GetID()] = sha256.Sum256(event.data)
+		}
+
+		p.mu.Unlock()
+
+		if publish {
+			p.live.broadcast(event)
+		}
+		return
+	}
+}
+
+const (
+	maxWidgetRetryAttempts = 5
+	maxWidgetRetryBackoff  = time.Minute
+)
+
+func (w *widgetBase) initHTTPClient() error {
+	return w.httpClientOptions.initClients(w.Type + " widget")
+}
+
+func (w *widgetBase) initRetryOptions() error {
+	if w.RetryAttempts < 0 || w.RetryAttempts > maxWidgetRetryAttempts {
+		return fmt.Errorf("retry-attempts must be between 0 and %d", maxWidgetRetryAttempts)
+	}
+
+	if w.RetryBackoff < 0 || time.Duration(w.RetryBackoff) > maxWidgetRetryBackoff {
+		return fmt.Errorf("retry-backoff must be at most %s", maxWidgetRetryBackoff)
+	}
+
+	if w.RetryBackoff == 0 {
+		w.RetryBackoff = durationField(time.Second)
+	}
+
+	return nil
+}
+
+// Returns the retries to make for the update that just finished, if it failed.
+// Its error is held back while retrying for as long as there's data from an
+// earlier update to show instead, and the usual updates are put on hold. Once
+// the retries run out, no more are made until an update succeeds again. Must
+// be called while holding the lock of the page, as must finishRetry
+func (w *widgetBase) startRetries() (int, time.Duration) {
+	if w.Error == nil {
+		w.retriesExhausted = false
+		return 0, 0
+	}
+
+	if w.RetryAttempts == 0 || w.retrying || w.retriesExhausted {
+		return 0, 0
+	}
+
+	w.retrying = true
+	w.holdBackError()
+
+	return w.RetryAttempts, time.Duration(w.RetryBackoff)
+}
+
+// Returns whether another retry should be made after the one that just
+// finished, which is the case only if it failed and it wasn't the last one
+func (w *widgetBase) finishRetry(last bool) bool {
+	if w.Error != nil && !last {
+		w.holdBackError()
+		return true
+	}
+
+	w.retrying = false
+	w.retriesExhausted = w.Error != nil
+
+	return false
+}
+
+func (w *widgetBase) holdBackError() {
+	if w.ContentAvailable {
+		w.Error = nil
+	}
+}
+
+func (w *widgetBase) templateFile() string {
+	return w.TemplateFile
+}
+
+func (w *widgetBase) setCustomTemplate(t *template.Template) {
+	w.customTemplate = t
+}
+
+func (w *widgetBase) ColumnSpan() int {
+	return w.Span
+}
+
+func (w *widgetBase) hasVisibilityCondition() bool {
+	return w.VisibleWhen != nil
+}
+
+// The given time must already be in the timezone of the dashboard
+func (w *widgetBase) updateVisibility(now time.Time) {
+	w.hidden = w.VisibleWhen != nil && !w.VisibleWhen.isVisibleAt(now)
+}
+
+// Hidden widgets don't get updated or rendered until they become visible
+func (w *widgetBase) IsHidden() bool {
+	return w.hidden
+}
+
+// Returns true only the first time it gets called
+func (w *widgetBase) isFirstUpdate() bool {
+	if w.updatedOnce {
+		return false
+	}
+
+	w.updatedOnce = true
+	return true
+}
+
+func (w *widgetBase) updateError() error {
+	return w.Error
+}
+
 func (w *widgetBase) GetID() uint64 {
 	return w.ID
 }
 
+// Unlike the ID, which depends on the order in which widgets were
+// created, this can be set through the config and stays the same
+// across restarts
+func (w *widgetBase) stableID() string {
+	if w.CustomID != "" {
+		return w.CustomID
+	}
+
+	return strconv.FormatUint(w.ID, 10)
+}
+
 func (w *widgetBase) setID(id uint64) {
 	w.ID = id
 }
 
+// The line the widget was defined on within the config, after includes have
+// been inlined, used when reporting errors
+func (w *widgetBase) setLine(line int) {
+	w.line = line
+}
+
+func (w *widgetBase) getLine() int {
+	return w.line
+}
+
 func (w *widgetBase) setHideHeader(value bool) {
 	w.HideHeader = value
 }
@@ -206,15 +565,55 @@ func (widget *widgetBase) handleRequest(w http.ResponseWriter, r *http.Request)
 	http.Error(w, "not implemented", http.StatusNotImplemented)
 }
 
+func (w *widgetBase) getTitle() string {
+	return w.Title
+}
+
 func (w *widgetBase) GetType() string {
 	return w.Type
 }
 
+// Widgets with their own formatting get a copy of the providers with it
+// merged in, which containers then pass on to their children
 func (w *widgetBase) setProviders(providers *widgetProviders) {
+	if w.Formatting != nil {
+		withFormatting := *providers
+		withFormatting.formatter = providers.localeFormatter().withOverride(w.Formatting)
+		providers = &withFormatting
+	}
+
 	w.Providers = providers
+	w.customTemplate = localizeCustomTemplate(w.customTemplate, w.formatter())
+}
+
+func (p *widgetProviders) localeFormatter() *localeFormatter {
+	if p == nil || p.formatter == nil {
+		return defaultLocaleFormatter
+	}
+
+	return p.formatter
+}
+
+func (w *widgetBase) formatter() *localeFormatter {
+	return w.Providers.localeFormatter()
+}
+
+// The timezone of the dashboard, or the local one before the providers are set
+func (w *widgetBase) dashboardLocation() *time.Location {
+	if w.Providers == nil || w.Providers.location == nil {
+		return time.Local
+	}
+
+	return w.Providers.location
 }
 
 func (w *widgetBase) renderTemplate(data any, t *template.Template) template.HTML {
+	if w.customTemplate != nil {
+		t = w.customTemplate
+	} else {
+		t = localizeTemplate(t, w.formatter())
+	}
+
 	w.templateBuffer.Reset()
 	err := t.Execute(&w.templateBuffer, data)
 	if err != nil {
@@ -259,15 +658,59 @@ func (w *widgetBase) withTitleURL(titleURL string) *widgetBase {
 func (w *widgetBase) withCacheDuration(duration time.Duration) *widgetBase {
 	w.cacheType = cacheTypeDuration
 
-	if duration == -1 || w.CustomCacheDuration == 0 {
+	if custom := w.customCacheDuration(); duration == -1 || custom == 0 {
 		w.cacheDuration = duration
 	} else {
-		w.cacheDuration = time.Duration(w.CustomCacheDuration)
+		w.cacheDuration = custom
 	}
 
 	return w
 }
 
+// refresh is an alias of cache
+func (w *widgetBase) customCacheDuration() time.Duration {
+	if w.Refresh != 0 {
+		return time.Duration(w.Refresh)
+	}
+
+	return time.Duration(w.CustomCacheDuration)
+}
+
+// Only applies to durations set through the config rather than the defaults
+// of the widgets, since those are already chosen with the upstreams in mind
+func (w *widgetBase) enforceMinimumRefresh(minimum time.Duration) error {
+	if w.Refresh != 0 && w.CustomCacheDuration != 0 {
+		return errors.New("cache and refresh are the same property, only one of them can be set")
+	}
+
+	custom := w.customCacheDuration()
+	if custom <= 0 || w.cacheType != cacheTypeDuration || w.cacheDuration >= minimum {
+		return nil
+	}
+
+	slog.Warn("Widget refresh interval is below the minimum, using the minimum instead",
+		"widget", w.Type, "line", w.line, "refresh", custom, "minimum", minimum)
+	w.cacheDuration = minimum
+
+	return nil
+}
+
+func enforceMinimumWidgetRefresh(ws widgets, minimum time.Duration) error {
+	for _, w := range ws {
+		if container, ok := w.(containerWidget); ok {
+			if err := enforceMinimumWidgetRefresh(container.children(), minimum); err != nil {
+				return err
+			}
+		}
+
+		if err := w.enforceMinimumRefresh(minimum); err != nil {
+			return formatWidgetInitError(err, w)
+		}
+	}
+
+	return nil
+}
+
 func (w *widgetBase) withCacheOnTheHour() *widgetBase {
 	w.cacheType = cacheTypeOnTheHour
 
@@ -310,20 +753,46 @@ func (w *widgetBase) canContinueUpdateAfterHandlingErr(err error) bool {
 		if !errors.Is(err, errPartialContent) {
 			w.withError(err)
 			w.withNotice(nil)
+			w.discardContentIfStaleForTooLong()
 			return false
 		}
 
 		w.withError(nil)
 		w.withNotice(err)
+		w.lastSuccessfulUpdate = time.Now()
 		return true
 	}
 
 	w.withNotice(nil)
 	w.withError(nil)
 	w.scheduleNextUpdate()
+	w.lastSuccessfulUpdate = time.Now()
 	return true
 }
 
+// The previously fetched content keeps getting shown when an update fails,
+// unless a stale-timeout is set and that much time has passed since the last
+// update that succeeded, in which case the error gets shown instead
+func (w *widgetBase) discardContentIfStaleForTooLong() {
+	if w.StaleTimeout == 0 || !w.ContentAvailable || w.lastSuccessfulUpdate.IsZero() {
+		return
+	}
+
+	if time.Since(w.lastSuccessfulUpdate) > time.Duration(w.StaleTimeout) {
+		w.ContentAvailable = false
+	}
+}
+
+// Returns the time of the last successful update if the content being
+// shown is from then because the updates after it failed
+func (w *widgetBase) StaleSince() time.Time {
+	if w.Error == nil || !w.ContentAvailable {
+		return time.Time{}
+	}
+
+	return w.lastSuccessfulUpdate
+}
+
 func (w *widgetBase) getNextUpdateTime() time.Time {
 	now := time.Now()
 
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -15,6 +16,7 @@ import (
 	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/sensors"
+	"gopkg.in/yaml.v3"
 )
 
 type timestampJSON struct {
@@ -46,6 +48,10 @@ type SystemInfo struct {
 		Load1Percent    uint8 `json:"load1_percent"`
 		Load15Percent   uint8 `json:"load15_percent"`
 
+		UsageIsAvailable  bool    `json:"usage_is_available"`
+		UsagePercent      uint8   `json:"usage_percent"`
+		CoresUsagePercent []uint8 `json:"cores_usage_percent"`
+
 		TemperatureIsAvailable bool  `json:"temperature_is_available"`
 		TemperatureC           uint8 `json:"temperature_c"`
 	} `json:"cpu"`
@@ -74,16 +80,61 @@ type MountpointInfo struct {
 }
 
 type SystemInfoRequest struct {
-	CPUTempSensor            string                       `yaml:"cpu-temp-sensor"`
-	HideMountpointsByDefault bool                         `yaml:"hide-mountpoints-by-default"`
-	Mountpoints              map[string]MointpointRequest `yaml:"mountpoints"`
+	CPUTempSensor            string             `yaml:"cpu-temp-sensor"`
+	HideMountpointsByDefault bool               `yaml:"hide-mountpoints-by-default"`
+	Mountpoints              MountpointRequests `yaml:"mountpoints"`
 }
 
 type MointpointRequest struct {
+	Path string `yaml:"path"`
 	Name string `yaml:"name"`
 	Hide *bool  `yaml:"hide"`
 }
 
+// Can be defined either as a map of paths to their properties or as a list
+// of paths/objects with a path. When defined as a list, only the listed
+// mountpoints are collected and they keep the order they were listed in.
+type MountpointRequests struct {
+	ByPath map[string]MointpointRequest
+	Listed []string
+}
+
+func (m *MountpointRequests) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		return node.Decode(&m.ByPath)
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("mountpoints must be either a map or a list")
+	}
+
+	m.ByPath = make(map[string]MointpointRequest, len(node.Content))
+	m.Listed = make([]string, 0, len(node.Content))
+
+	for _, item := range node.Content {
+		var mpReq MointpointRequest
+
+		if item.Kind == yaml.ScalarNode {
+			mpReq.Path = item.Value
+		} else if err := item.Decode(&mpReq); err != nil {
+			return err
+		}
+
+		if mpReq.Path == "" {
+			return fmt.Errorf("mountpoint on line %d is missing a path", item.Line)
+		}
+
+		if _, exists := m.ByPath[mpReq.Path]; exists {
+			continue
+		}
+
+		m.ByPath[mpReq.Path] = mpReq
+		m.Listed = append(m.Listed, mpReq.Path)
+	}
+
+	return nil
+}
+
 // Currently caches hostname indefinitely which isn't ideal
 // Potential issue with caching boot time as it may not initially get reported correctly:
 // https://github.com/shirou/gopsutil/issues/842#issuecomment-1908972344
@@ -178,6 +229,15 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 		addErr(fmt.Errorf("getting core count: %v", err))
 	}
 
+	usage, coresUsage, err := collectCPUUsage()
+	if err == nil {
+		info.CPU.UsageIsAvailable = true
+		info.CPU.UsagePercent = usage
+		info.CPU.CoresUsagePercent = coresUsage
+	} else {
+		addErr(fmt.Errorf("getting cpu usage: %v", err))
+	}
+
 	memory, err := mem.VirtualMemory()
 	if err == nil {
 		info.Memory.IsAvailable = true
@@ -234,7 +294,8 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 			return
 		}
 
-		isHidden := req.HideMountpointsByDefault
+		// listed mountpoints are always visible unless explicitly hidden
+		isHidden := req.HideMountpointsByDefault && req.Mountpoints.Listed == nil
 		if mpReq.Hide != nil {
 			isHidden = *mpReq.Hide
 		}
@@ -242,6 +303,11 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 			return
 		}
 
+		if _, err := os.Stat(requestedPath); err != nil {
+			addErr(fmt.Errorf("skipping mountpoint %s: %v", requestedPath, err))
+			return
+		}
+
 		usage, err := disk.Usage(requestedPath)
 		if err == nil {
 			mpInfo := MountpointInfo{
@@ -259,18 +325,26 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 		}
 	}
 
+	if req.Mountpoints.Listed != nil {
+		for _, mountpoint := range req.Mountpoints.Listed {
+			addMountpointInfo(mountpoint, req.Mountpoints.ByPath[mountpoint])
+		}
+
+		return info, errs
+	}
+
 	if !req.HideMountpointsByDefault {
 		filesystems, err := disk.Partitions(false)
 		if err == nil {
 			for _, fs := range filesystems {
-				addMountpointInfo(fs.Mountpoint, req.Mountpoints[fs.Mountpoint])
+				addMountpointInfo(fs.Mountpoint, req.Mountpoints.ByPath[fs.Mountpoint])
 			}
 		} else {
 			addErr(fmt.Errorf("getting filesystems: %v", err))
 		}
 	}
 
-	for mountpoint, mpReq := range req.Mountpoints {
+	for mountpoint, mpReq := range req.Mountpoints.ByPath {
 		addMountpointInfo(mountpoint, mpReq)
 	}
 
@@ -281,6 +355,101 @@ func Collect(req *SystemInfoRequest) (*SystemInfo, []error) {
 	return info, errs
 }
 
+type cpuTimesSample struct {
+	total cpu.TimesStat
+	cores []cpu.TimesStat
+}
+
+var (
+	lastCPUTimesSample      *cpuTimesSample
+	lastCPUTimesSampleMutex sync.Mutex
+)
+
+// Used when there is no previous sample to compare against
+const cpuUsageInitialSampleInterval = 500 * time.Millisecond
+
+func sampleCPUTimes() (*cpuTimesSample, error) {
+	total, err := cpu.Times(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(total) == 0 {
+		return nil, fmt.Errorf("no cpu times reported")
+	}
+
+	cores, err := cpu.Times(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cpuTimesSample{total: total[0], cores: cores}, nil
+}
+
+// Both the aggregate and the per-core usage are calculated from the same pair
+// of samples so that they always cover the same interval, that being the time
+// since the previous collection
+func collectCPUUsage() (uint8, []uint8, error) {
+	lastCPUTimesSampleMutex.Lock()
+	defer lastCPUTimesSampleMutex.Unlock()
+
+	previous := lastCPUTimesSample
+	if previous == nil {
+		sample, err := sampleCPUTimes()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		previous = sample
+		time.Sleep(cpuUsageInitialSampleInterval)
+	}
+
+	current, err := sampleCPUTimes()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	lastCPUTimesSample = current
+
+	cores := make([]uint8, 0, len(current.cores))
+	// the number of cores may change in between samples if one was
+	// taken offline, in which case the remaining ones are still valid
+	for i := range current.cores {
+		if i >= len(previous.cores) || previous.cores[i].CPU != current.cores[i].CPU {
+			break
+		}
+
+		cores = append(cores, cpuUsagePercentBetween(previous.cores[i], current.cores[i]))
+	}
+
+	return cpuUsagePercentBetween(previous.total, current.total), cores, nil
+}
+
+func cpuUsagePercentBetween(previous, current cpu.TimesStat) uint8 {
+	busyAndTotal := func(t cpu.TimesStat) (float64, float64) {
+		total := t.Total()
+		if runtime.GOOS == "linux" {
+			// already included in user and nice
+			total -= t.Guest + t.GuestNice
+		}
+
+		return total - t.Idle - t.Iowait, total
+	}
+
+	previousBusy, previousTotal := busyAndTotal(previous)
+	currentBusy, currentTotal := busyAndTotal(current)
+
+	if currentBusy <= previousBusy {
+		return 0
+	}
+
+	if currentTotal <= previousTotal {
+		return 100
+	}
+
+	return uint8(math.Min((currentBusy-previousBusy)/(currentTotal-previousTotal)*100, 100))
+}
+
 func inferCPUTempSensor(sensors []sensors.TemperatureStat) *sensors.TemperatureStat {
 	for i := range sensors {
 		switch sensors[i].SensorKey {
@@ -289,7 +458,8 @@ func inferCPUTempSensor(sensors []sensors.TemperatureStat) *sensors.TemperatureS
 			"coretemp",              // intel / linux
 			"k10temp",               // amd / linux
 			"zenpower",              // amd / linux
-			"cpu_thermal":           // raspberry pi / linux
+			"cpu_thermal",           // raspberry pi / linux
+			"x86_pkg_temp":          // intel / linux thermal zone
 			return &sensors[i]
 		}
 	}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The top level key of the documents under which the comments are kept when
+// converting with --comments, mapping the JSON pointer of each commented
+// value, such as /pages/0/name, to its comments. The root is keyed by ""
+const commentsKey = "$comments"
+
+// Comments are kept as written, including the #, so that converting them
+// back doesn't change them in any way
+type nodeComments struct {
+	Head string `json:"head,omitempty"`
+	Line string `json:"line,omitempty"`
+	Foot string `json:"foot,omitempty"`
+}
+
+func (c nodeComments) isEmpty() bool {
+	return c.Head == "" && c.Line == "" && c.Foot == ""
+}
+
+func joinComments(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+
+	return a + "\n" + b
+}
+
+func (c nodeComments) join(other nodeComments) nodeComments {
+	return nodeComments{
+		Head: joinComments(c.Head, other.Head),
+		Line: joinComments(c.Line, other.Line),
+		Foot: joinComments(c.Foot, other.Foot),
+	}
+}
+
+// The reverse of collectComments. Head and foot comments of map values go on
+// their key, while line comments go after the value when it fits on the same
+// line as the key, otherwise after the key
+func applyComments(document *yaml.Node, comments map[string]nodeComments) {
+	if root, exists := comments[""]; exists {
+		document.HeadComment = root.Head
+		document.FootComment = root.Foot
+		if len(document.Content) > 0 {
+			document.Content[0].LineComment = root.Line
+		}
+	}
+
+	var walk func(node *yaml.Node, pointer string)
+	walk = func(node *yaml.Node, pointer string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode, valueNode := node.Content[i], node.Content[i+1]
+				childPointer := pointer + "/" + escapePointerToken(keyNode.Value)
+
+				if c, exists := comments[childPointer]; exists {
+					keyNode.HeadComment, keyNode.FootComment = c.Head, c.Foot
+
+					if valueNode.Kind == yaml.ScalarNode || len(valueNode.Content) == 0 {
+						valueNode.LineComment = c.Line
+					} else {
+						keyNode.LineComment = c.Line
+					}
+				}
+
+				walk(valueNode, childPointer)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				childPointer := pointer + "/" + strconv.Itoa(i)
+
+				if c, exists := comments[childPointer]; exists {
+					item.HeadComment, item.LineComment, item.FootComment = c.Head, c.Line, c.Foot
+				}
+
+				walk(item, childPointer)
+			}
+		}
+	}
+
+	if len(document.Content) > 0 {
+		walk(document.Content[0], "")
+	}
+}
+
+// Removes the comments from the top level of a document converted from JSON,
+// returning them keyed by their pointer
+func takeComments(document orderedMap) (orderedMap, map[string]nodeComments, error) {
+	index := -1
+	for i, entry := range document.Entries {
+		if entry.Key == commentsKey {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		return document, nil, nil
+	}
+
+	entries, ok := document.Entries[index].Value.(orderedMap)
+	if !ok {
+		return document, nil, fmt.Errorf("%s must be an object", commentsKey)
+	}
+
+	comments := make(map[string]nodeComments, len(entries.Entries))
+	for _, entry := range entries.Entries {
+		fields, ok := entry.Value.(orderedMap)
+		if !ok {
+			return document, nil, fmt.Errorf("%s of %s must be an object", commentsKey, pointerOrRoot(entry.Key))
+		}
+
+		var c nodeComments
+		for _, field := range fields.Entries {
+			text, ok := field.Value.(string)
+			if !ok {
+				return document, nil, fmt.Errorf("%s of %s: %s must be a string", commentsKey, pointerOrRoot(entry.Key), field.Key)
+			}
+
+			switch field.Key {
+			case "head":
+				c.Head = text
+			case "line":
+				c.Line = text
+			case "foot":
+				c.Foot = text
+			default:
+				return document, nil, fmt.Errorf("%s of %s: unknown property %s, must be one of head, line or foot", commentsKey, pointerOrRoot(entry.Key), field.Key)
+			}
+		}
+
+		comments[entry.Key] = c
+	}
+
+	remaining := make([]mapEntry, 0, len(document.Entries)-1)
+	remaining = append(remaining, document.Entries[:index]...)
+	remaining = append(remaining, document.Entries[index+1:]...)
+
+	return orderedMap{Entries: remaining}, comments, nil
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func pointerOrRoot(pointer string) string {
+	return ternary(pointer == "", "#", "#"+pointer)
+}
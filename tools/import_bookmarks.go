@@ -0,0 +1,221 @@
+//go:build import_bookmarks
+
+// Converts a bookmarks export in the Netscape bookmark file format, which is
+// what Chrome, Firefox, Safari and most other browsers produce when exporting
+// bookmarks to HTML, into a bookmarks widget:
+//
+//	go run -tags import_bookmarks ./tools bookmarks.html > bookmarks.yml
+//
+// Folders become groups, with nested folders being flattened into groups
+// whose title contains the names of all of their parents.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "import_bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type bookmarkLink struct {
+	Title       string `yaml:"title"`
+	URL         string `yaml:"url"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type bookmarkGroup struct {
+	Title string         `yaml:"title,omitempty"`
+	Links []bookmarkLink `yaml:"links"`
+}
+
+type bookmarksWidget struct {
+	Type   string          `yaml:"type"`
+	Title  string          `yaml:"title,omitempty"`
+	Groups []bookmarkGroup `yaml:"groups"`
+}
+
+func run() error {
+	separator := flag.String("separator", " / ", "separator between the names of nested folders in group titles")
+	title := flag.String("title", "", "title of the widget")
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		return errors.New("expected at most one file")
+	}
+
+	var input io.Reader = os.Stdin
+	if flag.NArg() == 1 && flag.Arg(0) != "-" {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	groups, err := parseNetscapeBookmarks(input, *separator)
+	if err != nil {
+		return fmt.Errorf("parse bookmarks: %w", err)
+	}
+
+	if len(groups) == 0 {
+		return errors.New("no bookmarks found")
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+
+	widgets := []bookmarksWidget{{Type: "bookmarks", Title: *title, Groups: groups}}
+	if err := encoder.Encode(widgets); err != nil {
+		return fmt.Errorf("write yaml: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// The format isn't valid HTML, most tags are never closed, so rather than
+// building a tree the tokens are walked while keeping track of which folder
+// each <DL> list belongs to:
+//
+//	<DT><H3>Folder</H3>
+//	<DL><p>
+//	    <DT><A HREF="https://example.com">Example</A>
+//	    <DD>Optional description
+//	</DL><p>
+func parseNetscapeBookmarks(r io.Reader, separator string) ([]bookmarkGroup, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	groups := make([]bookmarkGroup, 0, 8)
+	groupIndexes := make(map[string]int)
+	folders := make([]string, 0, 4)
+	pendingFolder := ""
+
+	var text strings.Builder
+	capturing := ""
+	href := ""
+	var lastLink *bookmarkLink
+
+	addLink := func(link bookmarkLink) *bookmarkLink {
+		path := make([]string, 0, len(folders))
+		for _, folder := range folders {
+			if folder != "" {
+				path = append(path, folder)
+			}
+		}
+
+		groupTitle := strings.Join(path, separator)
+		index, exists := groupIndexes[groupTitle]
+		if !exists {
+			index = len(groups)
+			groupIndexes[groupTitle] = index
+			groups = append(groups, bookmarkGroup{Title: groupTitle})
+		}
+
+		groups[index].Links = append(groups[index].Links, link)
+		return &groups[index].Links[len(groups[index].Links)-1]
+	}
+
+	finishDescription := func() {
+		if capturing == "dd" && lastLink != nil {
+			lastLink.Description = strings.TrimSpace(text.String())
+		}
+
+		if capturing == "dd" {
+			capturing = ""
+		}
+	}
+
+	for {
+		tokenType := tokenizer.Next()
+
+		switch tokenType {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				finishDescription()
+				return groups, nil
+			}
+
+			return nil, tokenizer.Err()
+		case html.TextToken:
+			if capturing != "" {
+				text.Write(tokenizer.Text())
+			}
+		case html.StartTagToken, html.EndTagToken:
+			name, hasAttributes := tokenizer.TagName()
+			tag := string(name)
+
+			if tokenType == html.EndTagToken {
+				switch tag {
+				case "h3":
+					if capturing == "h3" {
+						pendingFolder = strings.TrimSpace(text.String())
+						capturing = ""
+					}
+				case "a":
+					if capturing == "a" {
+						capturing = ""
+						lastLink = nil
+
+						// entries such as separators or firefox's smart
+						// bookmarks don't link anywhere and are skipped
+						if href != "" && !strings.HasPrefix(href, "place:") {
+							title := strings.TrimSpace(text.String())
+							lastLink = addLink(bookmarkLink{Title: ternary(title != "", title, href), URL: href})
+						}
+					}
+				case "dl":
+					finishDescription()
+					lastLink = nil
+					if len(folders) > 0 {
+						folders = folders[:len(folders)-1]
+					}
+				}
+
+				continue
+			}
+
+			switch tag {
+			case "dt", "dl":
+				finishDescription()
+			}
+
+			switch tag {
+			case "h3":
+				capturing = "h3"
+				text.Reset()
+			case "a":
+				capturing = "a"
+				text.Reset()
+				href = ""
+
+				for hasAttributes {
+					var key, value []byte
+					key, value, hasAttributes = tokenizer.TagAttr()
+					if string(key) == "href" {
+						href = strings.TrimSpace(string(value))
+					}
+				}
+			case "dd":
+				capturing = "dd"
+				text.Reset()
+			case "dl":
+				folders = append(folders, pendingFolder)
+				pendingFolder = ""
+				lastLink = nil
+			}
+		}
+	}
+}
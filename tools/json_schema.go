@@ -0,0 +1,790 @@
+//go:build !json_to_yaml && !import_bookmarks
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A subset of JSON Schema that covers what's needed for describing configs,
+// the keywords for the structure and values of documents from draft-07 and
+// 2020-12 are supported while annotations such as format are ignored, along
+// with any other unknown keywords. References can only point within the schema
+type jsonSchema struct {
+	// set for the boolean schemas true and false
+	always *bool
+
+	types                []string
+	properties           map[string]*jsonSchema
+	patternProperties    []patternSchema
+	additionalProperties *jsonSchema
+	required             []string
+	minProperties        *int
+	maxProperties        *int
+
+	prefixItems []*jsonSchema
+	items       *jsonSchema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	enum       []interface{}
+	hasConst   bool
+	constValue interface{}
+
+	minimum          *big.Rat
+	maximum          *big.Rat
+	exclusiveMinimum *big.Rat
+	exclusiveMaximum *big.Rat
+	multipleOf       *big.Rat
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	ref   *jsonSchema
+	allOf []*jsonSchema
+	anyOf []*jsonSchema
+	oneOf []*jsonSchema
+	not   *jsonSchema
+
+	ifSchema   *jsonSchema
+	thenSchema *jsonSchema
+	elseSchema *jsonSchema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *jsonSchema
+}
+
+type schemaViolation struct {
+	// a JSON pointer to the offending value, such as /pages/0/name
+	path    string
+	message string
+}
+
+func (v schemaViolation) String() string {
+	return ternary(v.path == "", "/", v.path) + ": " + v.message
+}
+
+func loadJSONSchema(path string) (*jsonSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("parse schema %s: %w", path, err)
+	}
+
+	compiler := &schemaCompiler{root: root, refs: make(map[string]*jsonSchema)}
+	schema, err := compiler.compile(root, "")
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+
+	return schema, nil
+}
+
+type schemaCompiler struct {
+	root interface{}
+	// keyed by the JSON pointer of the referenced schema
+	refs map[string]*jsonSchema
+}
+
+func (c *schemaCompiler) compile(value interface{}, pointer string) (*jsonSchema, error) {
+	if always, ok := value.(bool); ok {
+		return &jsonSchema{always: &always}, nil
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", pointerOrRoot(pointer))
+	}
+
+	schema := &jsonSchema{}
+	var err error
+
+	keywordErr := func(keyword string, format string, args ...any) error {
+		return fmt.Errorf("%s: %s", pointerOrRoot(pointer+"/"+keyword), fmt.Sprintf(format, args...))
+	}
+
+	subschema := func(keyword string) (*jsonSchema, error) {
+		child, exists := object[keyword]
+		if !exists {
+			return nil, nil
+		}
+
+		return c.compile(child, pointer+"/"+escapePointerToken(keyword))
+	}
+
+	subschemas := func(keyword string) ([]*jsonSchema, error) {
+		child, exists := object[keyword]
+		if !exists {
+			return nil, nil
+		}
+
+		list, ok := child.([]interface{})
+		if !ok {
+			return nil, keywordErr(keyword, "must be an array of schemas")
+		}
+
+		compiled := make([]*jsonSchema, len(list))
+		for i := range list {
+			if compiled[i], err = c.compile(list[i], pointer+"/"+keyword+"/"+strconv.Itoa(i)); err != nil {
+				return nil, err
+			}
+		}
+
+		return compiled, nil
+	}
+
+	nonNegativeInt := func(keyword string) (*int, error) {
+		child, exists := object[keyword]
+		if !exists {
+			return nil, nil
+		}
+
+		number, ok := child.(json.Number)
+		if !ok {
+			return nil, keywordErr(keyword, "must be a non-negative integer")
+		}
+
+		parsed, err := strconv.Atoi(number.String())
+		if err != nil || parsed < 0 {
+			return nil, keywordErr(keyword, "must be a non-negative integer")
+		}
+
+		return &parsed, nil
+	}
+
+	number := func(keyword string) (*big.Rat, error) {
+		child, exists := object[keyword]
+		if !exists {
+			return nil, nil
+		}
+
+		rat, ok := jsonNumberAsRat(child)
+		if !ok {
+			return nil, keywordErr(keyword, "must be a number")
+		}
+
+		return rat, nil
+	}
+
+	if ref, exists := object["$ref"]; exists {
+		refString, ok := ref.(string)
+		if !ok {
+			return nil, keywordErr("$ref", "must be a string")
+		}
+
+		if schema.ref, err = c.resolve(refString); err != nil {
+			return nil, keywordErr("$ref", "%v", err)
+		}
+	}
+
+	if types, exists := object["type"]; exists {
+		switch t := types.(type) {
+		case string:
+			schema.types = []string{t}
+		case []interface{}:
+			for _, item := range t {
+				name, ok := item.(string)
+				if !ok {
+					return nil, keywordErr("type", "must be a string or an array of strings")
+				}
+				schema.types = append(schema.types, name)
+			}
+		default:
+			return nil, keywordErr("type", "must be a string or an array of strings")
+		}
+
+		for _, name := range schema.types {
+			if !slices.Contains(jsonSchemaTypes, name) {
+				return nil, keywordErr("type", "has unknown type %q", name)
+			}
+		}
+	}
+
+	if properties, exists := object["properties"]; exists {
+		propertiesObject, ok := properties.(map[string]interface{})
+		if !ok {
+			return nil, keywordErr("properties", "must be an object")
+		}
+
+		schema.properties = make(map[string]*jsonSchema, len(propertiesObject))
+		for name, property := range propertiesObject {
+			if schema.properties[name], err = c.compile(property, pointer+"/properties/"+escapePointerToken(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if patternProperties, exists := object["patternProperties"]; exists {
+		patternsObject, ok := patternProperties.(map[string]interface{})
+		if !ok {
+			return nil, keywordErr("patternProperties", "must be an object")
+		}
+
+		for pattern, property := range patternsObject {
+			compiledPattern, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, keywordErr("patternProperties", "has an invalid pattern %q: %v", pattern, err)
+			}
+
+			compiledProperty, err := c.compile(property, pointer+"/patternProperties/"+escapePointerToken(pattern))
+			if err != nil {
+				return nil, err
+			}
+
+			schema.patternProperties = append(schema.patternProperties, patternSchema{compiledPattern, compiledProperty})
+		}
+
+		// map iteration order is random, keep the violations stable
+		sort.Slice(schema.patternProperties, func(i, j int) bool {
+			return schema.patternProperties[i].pattern.String() < schema.patternProperties[j].pattern.String()
+		})
+	}
+
+	if schema.additionalProperties, err = subschema("additionalProperties"); err != nil {
+		return nil, err
+	}
+
+	if required, exists := object["required"]; exists {
+		list, ok := required.([]interface{})
+		if !ok {
+			return nil, keywordErr("required", "must be an array of strings")
+		}
+
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return nil, keywordErr("required", "must be an array of strings")
+			}
+			schema.required = append(schema.required, name)
+		}
+	}
+
+	if schema.prefixItems, err = subschemas("prefixItems"); err != nil {
+		return nil, err
+	}
+
+	// before 2020-12 tuples were defined through an array in items, with
+	// the schema for the rest of the items being in additionalItems
+	if list, ok := object["items"].([]interface{}); ok {
+		for i := range list {
+			item, err := c.compile(list[i], pointer+"/items/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			schema.prefixItems = append(schema.prefixItems, item)
+		}
+
+		if schema.items, err = subschema("additionalItems"); err != nil {
+			return nil, err
+		}
+	} else if schema.items, err = subschema("items"); err != nil {
+		return nil, err
+	}
+
+	if unique, exists := object["uniqueItems"]; exists {
+		if schema.uniqueItems, ok = unique.(bool); !ok {
+			return nil, keywordErr("uniqueItems", "must be a boolean")
+		}
+	}
+
+	if enum, exists := object["enum"]; exists {
+		if schema.enum, ok = enum.([]interface{}); !ok {
+			return nil, keywordErr("enum", "must be an array")
+		}
+	}
+
+	schema.constValue, schema.hasConst = object["const"]
+
+	if pattern, exists := object["pattern"]; exists {
+		patternString, ok := pattern.(string)
+		if !ok {
+			return nil, keywordErr("pattern", "must be a string")
+		}
+
+		if schema.pattern, err = regexp.Compile(patternString); err != nil {
+			return nil, keywordErr("pattern", "is invalid: %v", err)
+		}
+	}
+
+	for _, keyword := range []struct {
+		name  string
+		value **int
+	}{
+		{"minProperties", &schema.minProperties},
+		{"maxProperties", &schema.maxProperties},
+		{"minItems", &schema.minItems},
+		{"maxItems", &schema.maxItems},
+		{"minLength", &schema.minLength},
+		{"maxLength", &schema.maxLength},
+	} {
+		if *keyword.value, err = nonNegativeInt(keyword.name); err != nil {
+			return nil, err
+		}
+	}
+
+	if schema.minimum, err = number("minimum"); err != nil {
+		return nil, err
+	}
+	if schema.maximum, err = number("maximum"); err != nil {
+		return nil, err
+	}
+
+	// draft-04 used booleans to make minimum and maximum exclusive
+	if exclusive, isBool := object["exclusiveMinimum"].(bool); isBool {
+		if exclusive {
+			schema.exclusiveMinimum, schema.minimum = schema.minimum, nil
+		}
+	} else if schema.exclusiveMinimum, err = number("exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+
+	if exclusive, isBool := object["exclusiveMaximum"].(bool); isBool {
+		if exclusive {
+			schema.exclusiveMaximum, schema.maximum = schema.maximum, nil
+		}
+	} else if schema.exclusiveMaximum, err = number("exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+
+	if schema.multipleOf, err = number("multipleOf"); err != nil {
+		return nil, err
+	}
+	if schema.multipleOf != nil && schema.multipleOf.Sign() <= 0 {
+		return nil, keywordErr("multipleOf", "must be greater than 0")
+	}
+
+	if schema.allOf, err = subschemas("allOf"); err != nil {
+		return nil, err
+	}
+	if schema.anyOf, err = subschemas("anyOf"); err != nil {
+		return nil, err
+	}
+	if schema.oneOf, err = subschemas("oneOf"); err != nil {
+		return nil, err
+	}
+	if schema.not, err = subschema("not"); err != nil {
+		return nil, err
+	}
+	if schema.ifSchema, err = subschema("if"); err != nil {
+		return nil, err
+	}
+	if schema.thenSchema, err = subschema("then"); err != nil {
+		return nil, err
+	}
+	if schema.elseSchema, err = subschema("else"); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// Referenced schemas are compiled once and shared, the entry is added before
+// compiling so that recursive references point back to the same schema
+func (c *schemaCompiler) resolve(ref string) (*jsonSchema, error) {
+	fragment, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("%q is not supported, only references within the schema such as #/$defs/name are", ref)
+	}
+
+	pointer, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("%q is invalid: %v", ref, err)
+	}
+
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q is not supported, only JSON pointers such as #/$defs/name are", ref)
+	}
+
+	if schema, exists := c.refs[pointer]; exists {
+		return schema, nil
+	}
+
+	target := c.root
+	if pointer != "" {
+		for _, token := range strings.Split(pointer[1:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+			switch t := target.(type) {
+			case map[string]interface{}:
+				if target, ok = t[token]; !ok {
+					return nil, fmt.Errorf("%q points to a missing schema", ref)
+				}
+			case []interface{}:
+				index, err := strconv.Atoi(token)
+				if err != nil || index < 0 || index >= len(t) {
+					return nil, fmt.Errorf("%q points to a missing schema", ref)
+				}
+				target = t[index]
+			default:
+				return nil, fmt.Errorf("%q points to a missing schema", ref)
+			}
+		}
+	}
+
+	schema := &jsonSchema{}
+	c.refs[pointer] = schema
+
+	compiled, err := c.compile(target, pointer)
+	if err != nil {
+		delete(c.refs, pointer)
+		return nil, err
+	}
+
+	*schema = *compiled
+	return schema, nil
+}
+
+var jsonSchemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+func (s *jsonSchema) isValid(value interface{}) bool {
+	var violations []schemaViolation
+	s.validate(value, "", &violations)
+	return len(violations) == 0
+}
+
+func (s *jsonSchema) validate(value interface{}, path string, violations *[]schemaViolation) {
+	violation := func(format string, args ...any) {
+		*violations = append(*violations, schemaViolation{path: path, message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			violation("is not allowed")
+		}
+		return
+	}
+
+	if s.ref != nil {
+		s.ref.validate(value, path, violations)
+	}
+
+	kind := jsonKindOf(value)
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return jsonKindMatches(value, kind, t) }) {
+		violation("must be of type %s, got %s", strings.Join(s.types, " or "), kind)
+		// the rest of the checks would only repeat the same problem
+		return
+	}
+
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(option interface{}) bool { return jsonValuesEqual(value, option) }) {
+		options := make([]string, len(s.enum))
+		for i, option := range s.enum {
+			options[i] = formatJSONValue(option)
+		}
+		violation("must be one of %s", strings.Join(options, ", "))
+	}
+
+	if s.hasConst && !jsonValuesEqual(value, s.constValue) {
+		violation("must be %s", formatJSONValue(s.constValue))
+	}
+
+	switch kind {
+	case "object":
+		s.validateObject(value.(orderedMap), path, violations)
+	case "array":
+		s.validateArray(value.([]interface{}), path, violations)
+	case "string":
+		s.validateString(value.(string), violation)
+	case "number":
+		if rat, ok := jsonNumberAsRat(value); ok {
+			s.validateNumber(rat, violation)
+		}
+	}
+
+	for _, schema := range s.allOf {
+		schema.validate(value, path, violations)
+	}
+
+	if s.anyOf != nil && !slices.ContainsFunc(s.anyOf, func(schema *jsonSchema) bool { return schema.isValid(value) }) {
+		violation("must match at least one of the schemas in anyOf")
+	}
+
+	if s.oneOf != nil {
+		matching := 0
+		for _, schema := range s.oneOf {
+			if schema.isValid(value) {
+				matching++
+			}
+		}
+
+		if matching != 1 {
+			violation("must match exactly one of the schemas in oneOf, matched %d", matching)
+		}
+	}
+
+	if s.not != nil && s.not.isValid(value) {
+		violation("must not match the schema in not")
+	}
+
+	if s.ifSchema != nil {
+		if s.ifSchema.isValid(value) {
+			if s.thenSchema != nil {
+				s.thenSchema.validate(value, path, violations)
+			}
+		} else if s.elseSchema != nil {
+			s.elseSchema.validate(value, path, violations)
+		}
+	}
+}
+
+func (s *jsonSchema) validateObject(object orderedMap, path string, violations *[]schemaViolation) {
+	violation := func(format string, args ...any) {
+		*violations = append(*violations, schemaViolation{path: path, message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range s.required {
+		if !slices.ContainsFunc(object.Entries, func(entry mapEntry) bool { return entry.Key == name }) {
+			violation("missing required property %q", name)
+		}
+	}
+
+	if s.minProperties != nil && len(object.Entries) < *s.minProperties {
+		violation("must have at least %d properties, got %d", *s.minProperties, len(object.Entries))
+	}
+
+	if s.maxProperties != nil && len(object.Entries) > *s.maxProperties {
+		violation("must have at most %d properties, got %d", *s.maxProperties, len(object.Entries))
+	}
+
+	for _, entry := range object.Entries {
+		entryPath := path + "/" + escapePointerToken(entry.Key)
+		matched := false
+
+		if property, exists := s.properties[entry.Key]; exists {
+			matched = true
+			property.validate(entry.Value, entryPath, violations)
+		}
+
+		for _, patternProperty := range s.patternProperties {
+			if patternProperty.pattern.MatchString(entry.Key) {
+				matched = true
+				patternProperty.schema.validate(entry.Value, entryPath, violations)
+			}
+		}
+
+		if matched || s.additionalProperties == nil {
+			continue
+		}
+
+		if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+			*violations = append(*violations, schemaViolation{path: entryPath, message: "is not an allowed property"})
+			continue
+		}
+
+		s.additionalProperties.validate(entry.Value, entryPath, violations)
+	}
+}
+
+func (s *jsonSchema) validateArray(items []interface{}, path string, violations *[]schemaViolation) {
+	violation := func(format string, args ...any) {
+		*violations = append(*violations, schemaViolation{path: path, message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.minItems != nil && len(items) < *s.minItems {
+		violation("must have at least %d items, got %d", *s.minItems, len(items))
+	}
+
+	if s.maxItems != nil && len(items) > *s.maxItems {
+		violation("must have at most %d items, got %d", *s.maxItems, len(items))
+	}
+
+	if s.uniqueItems {
+	unique:
+		for i := range items {
+			for j := 0; j < i; j++ {
+				if jsonValuesEqual(items[i], items[j]) {
+					violation("must have unique items, items %d and %d are equal", j, i)
+					break unique
+				}
+			}
+		}
+	}
+
+	for i, item := range items {
+		itemPath := path + "/" + strconv.Itoa(i)
+
+		if i < len(s.prefixItems) {
+			s.prefixItems[i].validate(item, itemPath, violations)
+		} else if s.items != nil {
+			s.items.validate(item, itemPath, violations)
+		}
+	}
+}
+
+func (s *jsonSchema) validateString(value string, violation func(string, ...any)) {
+	length := utf8.RuneCountInString(value)
+
+	if s.minLength != nil && length < *s.minLength {
+		violation("must be at least %d characters long, got %d", *s.minLength, length)
+	}
+
+	if s.maxLength != nil && length > *s.maxLength {
+		violation("must be at most %d characters long, got %d", *s.maxLength, length)
+	}
+
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		violation("must match the pattern %q", s.pattern.String())
+	}
+}
+
+func (s *jsonSchema) validateNumber(value *big.Rat, violation func(string, ...any)) {
+	if s.minimum != nil && value.Cmp(s.minimum) < 0 {
+		violation("must be at least %s", s.minimum.RatString())
+	}
+
+	if s.maximum != nil && value.Cmp(s.maximum) > 0 {
+		violation("must be at most %s", s.maximum.RatString())
+	}
+
+	if s.exclusiveMinimum != nil && value.Cmp(s.exclusiveMinimum) <= 0 {
+		violation("must be greater than %s", s.exclusiveMinimum.RatString())
+	}
+
+	if s.exclusiveMaximum != nil && value.Cmp(s.exclusiveMaximum) >= 0 {
+		violation("must be less than %s", s.exclusiveMaximum.RatString())
+	}
+
+	if s.multipleOf != nil && !new(big.Rat).Quo(value, s.multipleOf).IsInt() {
+		violation("must be a multiple of %s", s.multipleOf.RatString())
+	}
+}
+
+// Returns the JSON type of either a converted document value or a value from
+// the schema, which differ in how objects are represented
+func jsonKindOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case orderedMap, map[string]interface{}:
+		return "object"
+	}
+
+	// anything else, such as the time.Time values of --raw-timestamps, is
+	// whatever it gets encoded as in the output
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "unknown"
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return "unknown"
+	}
+
+	return jsonKindOf(decoded)
+}
+
+func jsonKindMatches(value interface{}, kind string, schemaType string) bool {
+	if schemaType == "integer" {
+		rat, ok := jsonNumberAsRat(value)
+		return kind == "number" && ok && rat.IsInt()
+	}
+
+	return kind == schemaType
+}
+
+func jsonNumberAsRat(value interface{}) (*big.Rat, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(v.String())
+	case float64:
+		rat := new(big.Rat)
+		// infinity and NaN can't be represented, nor can they be encoded
+		if rat.SetFloat64(v) == nil {
+			return nil, false
+		}
+		return rat, true
+	}
+
+	return nil, false
+}
+
+func jsonValuesEqual(a, b interface{}) bool {
+	kindA, kindB := jsonKindOf(a), jsonKindOf(b)
+	if kindA != kindB {
+		return false
+	}
+
+	switch kindA {
+	case "number":
+		ratA, okA := jsonNumberAsRat(a)
+		ratB, okB := jsonNumberAsRat(b)
+		return okA && okB && ratA.Cmp(ratB) == 0
+	case "array":
+		itemsA, itemsB := a.([]interface{}), b.([]interface{})
+		return slices.EqualFunc(itemsA, itemsB, jsonValuesEqual)
+	case "object":
+		entriesA, entriesB := jsonObjectEntries(a), jsonObjectEntries(b)
+		if len(entriesA) != len(entriesB) {
+			return false
+		}
+
+		for key, valueA := range entriesA {
+			valueB, exists := entriesB[key]
+			if !exists || !jsonValuesEqual(valueA, valueB) {
+				return false
+			}
+		}
+
+		return true
+	case "null":
+		return true
+	case "string", "boolean":
+		return a == b
+	}
+
+	return false
+}
+
+func jsonObjectEntries(value interface{}) map[string]interface{} {
+	if object, ok := value.(map[string]interface{}); ok {
+		return object
+	}
+
+	object := value.(orderedMap)
+	entries := make(map[string]interface{}, len(object.Entries))
+	for _, entry := range object.Entries {
+		entries[entry.Key] = entry.Value
+	}
+
+	return entries
+}
+
+func formatJSONValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,173 @@
+//go:build json_to_yaml
+
+// Converts JSON read from stdin into YAML, the reverse of yaml_to_json.
+// Since both tools live in the same package, this one is selected through
+// a build tag:
+//
+//	go run -tags json_to_yaml ./tools < config.json
+//
+// Comments kept by yaml_to_json --comments under the top level $comments
+// key are put back in place rather than being written as a regular key
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "json_to_yaml: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	decoder := json.NewDecoder(os.Stdin)
+	decoder.UseNumber()
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+
+	for index := 0; ; index++ {
+		value, err := decodeJSONValue(decoder)
+		if errors.Is(err, io.EOF) {
+			if index == 0 {
+				return errors.New("empty document")
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("parse json document %d: %w", index, err)
+		}
+
+		var comments map[string]nodeComments
+		if object, ok := value.(orderedMap); ok {
+			if value, comments, err = takeComments(object); err != nil {
+				return fmt.Errorf("parse json document %d: %w", index, err)
+			}
+		}
+
+		document := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{valueToYAMLNode(value)}}
+		applyComments(document, comments)
+
+		if err := encoder.Encode(document); err != nil {
+			return fmt.Errorf("write yaml for document %d: %w", index, err)
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("write yaml: %w", err)
+	}
+
+	return nil
+}
+
+// Decodes the next JSON value from the token stream while keeping the
+// order of object keys, objects become an orderedMap, arrays a []interface{}
+// and numbers a json.Number so their exact representation is kept
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := orderedMap{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected object key, got %v", keyToken)
+			}
+
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+
+			object.Entries = append(object.Entries, mapEntry{Key: key, Value: value})
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		return object, nil
+	case '[':
+		items := make([]interface{}, 0)
+		for decoder.More() {
+			item, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+
+			items = append(items, item)
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected delimiter %v", delim)
+	}
+}
+
+// An EOF in the middle of a value means the input was truncated
+// and shouldn't be mistaken for the end of the stream
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+func valueToYAMLNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case orderedMap:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, entry := range v.Entries {
+			node.Content = append(
+				node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: entry.Key},
+				valueToYAMLNode(entry.Value),
+			)
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, valueToYAMLNode(item))
+		}
+		return node
+	case json.Number:
+		tag := ternary(strings.ContainsAny(v.String(), ".eE"), "!!float", "!!int")
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprintf("%t", v)}
+	case string:
+		// the encoder quotes strings that would otherwise resolve to a different type
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+func ternary[T any](condition bool, a, b T) T {
+	if condition {
+		return a
+	}
+
+	return b
+}
@@ -1,12 +1,21 @@
+//go:build !json_to_yaml && !import_bookmarks
+
 package main
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -19,86 +28,654 @@ func main() {
 }
 
 func run() error {
-	data, err := io.ReadAll(os.Stdin)
-	if err != nil {
-		return fmt.Errorf("read stdin: %w", err)
+	ndjson := flag.Bool("ndjson", false, "Emit one JSON document per line instead of a JSON array")
+	indent := flag.Int("indent", 0, "Pretty-print the output using the given number of spaces per level")
+	allowDuplicates := flag.Bool("allow-duplicates", false, "Allow duplicate map keys, the last definition wins")
+	rawTimestamps := flag.Bool("raw-timestamps", false, "Emit timestamps as parsed by the yaml package instead of their original text")
+	wrapBinary := flag.Bool("wrap-binary", false, "Emit !!binary values as a {\"$binary\": \"...\"} object instead of a plain string")
+	sortKeys := flag.Bool("sort-keys", false, "Sort map keys to produce canonical output for hashing and diffing, the original key order is lost")
+	schemaPath := flag.String("schema", "", "Validate the converted documents against the JSON Schema at the given path, nothing is written unless all of them are valid")
+	comments := flag.Bool("comments", false, "Keep the comments under a top level \"$comments\" key, keyed by the JSON pointer of the value they belong to, which json_to_yaml puts back")
+	flag.Parse()
+
+	if *indent < 0 {
+		return errors.New("indent cannot be negative")
 	}
-	var node yaml.Node
-	if err := yaml.Unmarshal(data, &node); err != nil {
-		return fmt.Errorf("parse yaml: %w", err)
+
+	if *ndjson && *indent > 0 {
+		return errors.New("ndjson and indent cannot be used together")
 	}
-	if len(node.Content) == 0 {
-		return errors.New("empty document")
+
+	options := conversionOptions{
+		allowDuplicates: *allowDuplicates,
+		rawTimestamps:   *rawTimestamps,
+		wrapBinary:      *wrapBinary,
+		comments:        *comments,
 	}
-	normalized := convertNode(node.Content[0])
+
+	var schema *jsonSchema
+	if *schemaPath != "" {
+		var err error
+		if schema, err = loadJSONSchema(*schemaPath); err != nil {
+			return err
+		}
+	}
+
+	// with no arguments the input is read from stdin, which
+	// can also be explicitly requested through -
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	files := make([][]interface{}, len(paths))
+	var violations []string
+	for i, path := range paths {
+		documents, err := decodeFile(path, options)
+		if err != nil {
+			return err
+		}
+
+		// validated before sorting so that the violations are listed in
+		// the same order as the values they refer to are in the source
+		if schema != nil {
+			violations = append(violations, validateDocuments(schema, path, ternary(*comments, withoutComments(documents), documents))...)
+		}
+
+		if *sortKeys {
+			for j := range documents {
+				documents[j] = sortMapKeys(documents[j])
+			}
+		}
+
+		files[i] = documents
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf(
+			"%d schema %s:\n  %s",
+			len(violations), ternary(len(violations) == 1, "violation", "violations"),
+			strings.Join(violations, "\n  "),
+		)
+	}
+
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetEscapeHTML(false)
-	if err := encoder.Encode(normalized); err != nil {
+	if *indent > 0 {
+		// the encoder re-indents the complete output, including the bytes
+		// produced by orderedMap.MarshalJSON, so nested maps indent correctly
+		encoder.SetIndent("", strings.Repeat(" ", *indent))
+	}
+
+	if *ndjson {
+		for i, documents := range files {
+			for j, document := range documents {
+				if err := encoder.Encode(document); err != nil {
+					return fmt.Errorf("%s: write json for document %d: %w", paths[i], j, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	var output interface{}
+	if len(files) == 1 {
+		output = documentsOutput(files[0])
+	} else {
+		outputs := make([]interface{}, len(files))
+		for i := range files {
+			outputs[i] = documentsOutput(files[i])
+		}
+		output = outputs
+	}
+
+	if err := encoder.Encode(output); err != nil {
 		return fmt.Errorf("write json: %w", err)
 	}
 	return nil
 }
 
-type mapEntry struct {
-	Key   string
-	Value interface{}
+// A single document is emitted as is so that the output for regular,
+// single document files doesn't change, multiple ones become an array
+func documentsOutput(documents []interface{}) interface{} {
+	if len(documents) == 1 {
+		return documents[0]
+	}
+
+	return documents
+}
+
+// Returns the violations of all documents in a file as a list of lines, each
+// prefixed with the name of the file and the index of the document when the
+// file contains more than one
+func validateDocuments(schema *jsonSchema, path string, documents []interface{}) []string {
+	name := ternary(path == "-", "stdin", path)
+
+	var lines []string
+	for i, document := range documents {
+		var violations []schemaViolation
+		schema.validate(document, "", &violations)
+
+		prefix := name
+		if len(documents) > 1 {
+			prefix += " document " + strconv.Itoa(i)
+		}
+
+		for _, violation := range violations {
+			lines = append(lines, prefix+": "+violation.String())
+		}
+	}
+
+	return lines
+}
+
+func decodeFile(path string, options conversionOptions) ([]interface{}, error) {
+	name := path
+	var data []byte
+	var err error
+
+	if path == "-" {
+		name = "stdin"
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+
+	documents, err := decodeDocuments(data, options)
+	if err != nil {
+		var sourceErr *sourceError
+		if errors.As(err, &sourceErr) {
+			return nil, fmt.Errorf("%s: %w\n%s", name, err, sourceSnippet(data, sourceErr.line, sourceErr.column))
+		}
+
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return documents, nil
 }
 
-type orderedMap struct {
-	Entries []mapEntry
+// Recursively sorts the entries of all maps by their key. Meant for producing
+// stable output for hashing and diffing, since the original order is lost the
+// result can't be converted back into the same YAML
+func sortMapKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case orderedMap:
+		entries := make([]mapEntry, len(v.Entries))
+		for i, entry := range v.Entries {
+			entries[i] = mapEntry{Key: entry.Key, Value: sortMapKeys(entry.Value)}
+		}
+
+		slices.SortStableFunc(entries, func(a, b mapEntry) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+
+		return orderedMap{Entries: entries}
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i := range v {
+			items[i] = sortMapKeys(v[i])
+		}
+
+		return items
+	default:
+		return value
+	}
 }
 
-func (o orderedMap) MarshalJSON() ([]byte, error) {
-	var buf bytes.Buffer
-	buf.WriteByte('{')
-	for i, entry := range o.Entries {
-		if i > 0 {
-			buf.WriteByte(',')
+func decodeDocuments(data []byte, options conversionOptions) ([]interface{}, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	documents := make([]interface{}, 0, 1)
+
+	for index := 0; ; index++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
 		}
-		keyBytes, err := json.Marshal(entry.Key)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("parse yaml document %d: %w", index, parseErrorWithLocation(err))
+		}
+
+		// skip empty documents, such as the ones produced by a trailing ---
+		if isEmptyDocument(&node) {
+			continue
 		}
-		buf.Write(keyBytes)
-		buf.WriteByte(':')
-		valBytes, err := json.Marshal(entry.Value)
+
+		document, err := newConverter(options).convertNode(node.Content[0])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("convert yaml document %d: %w", index, err)
 		}
-		buf.Write(valBytes)
+
+		if options.comments {
+			if document, err = withComments(document, &node); err != nil {
+				return nil, fmt.Errorf("convert yaml document %d: %w", index, err)
+			}
+		}
+
+		documents = append(documents, document)
+	}
+
+	if len(documents) == 0 {
+		return nil, errors.New("empty document")
+	}
+
+	return documents, nil
+}
+
+func commentsOfNode(node *yaml.Node) nodeComments {
+	return nodeComments{Head: node.HeadComment, Line: node.LineComment, Foot: node.FootComment}
+}
+
+// Adds the comments of the document as the last entry of its top level map,
+// documents without any comments are left as they are
+func withComments(document interface{}, node *yaml.Node) (interface{}, error) {
+	comments := collectComments(node)
+	if len(comments.Entries) == 0 {
+		return document, nil
+	}
+
+	root, ok := document.(orderedMap)
+	if !ok {
+		return nil, errors.New("comments can only be kept for documents whose top level is a map")
+	}
+
+	for _, entry := range root.Entries {
+		if entry.Key == commentsKey {
+			return nil, fmt.Errorf("cannot keep the comments since the document already has a %s key", commentsKey)
+		}
+	}
+
+	entries := append(slices.Clip(root.Entries), mapEntry{Key: commentsKey, Value: comments})
+	return orderedMap{Entries: entries}, nil
+}
+
+// The comments don't get validated since they aren't part of the config
+func withoutComments(documents []interface{}) []interface{} {
+	stripped := make([]interface{}, len(documents))
+
+	for i, document := range documents {
+		stripped[i] = document
+		if root, ok := document.(orderedMap); ok && len(root.Entries) > 0 && root.Entries[len(root.Entries)-1].Key == commentsKey {
+			stripped[i] = orderedMap{Entries: root.Entries[:len(root.Entries)-1]}
+		}
+	}
+
+	return stripped
+}
+
+// Returns the comments of a document in the order in which they appear. The
+// comments of map keys and their values are combined since JSON has no way
+// of telling them apart, while aliases and merge keys are kept as pointers
+// to the values they reference, whose comments are kept where they're defined
+func collectComments(document *yaml.Node) orderedMap {
+	collected := orderedMap{}
+	indexes := make(map[string]int)
+
+	add := func(pointer string, comments nodeComments) {
+		if comments.isEmpty() {
+			return
+		}
+
+		// duplicate keys, when they're allowed, end up with the same pointer
+		if index, exists := indexes[pointer]; exists {
+			collected.Entries[index].Value = collected.Entries[index].Value.(nodeComments).join(comments)
+			return
+		}
+
+		indexes[pointer] = len(collected.Entries)
+		collected.Entries = append(collected.Entries, mapEntry{Key: pointer, Value: comments})
+	}
+
+	var walk func(node *yaml.Node, pointer string)
+	walk = func(node *yaml.Node, pointer string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode, valueNode := node.Content[i], node.Content[i+1]
+				if isMergeKey(keyNode) {
+					continue
+				}
+
+				childPointer := pointer + "/" + escapePointerToken(keyNode.Value)
+				add(childPointer, commentsOfNode(keyNode).join(commentsOfNode(valueNode)))
+				walk(valueNode, childPointer)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				childPointer := pointer + "/" + strconv.Itoa(i)
+				add(childPointer, commentsOfNode(item))
+				walk(item, childPointer)
+			}
+		}
+	}
+
+	add("", commentsOfNode(document))
+	if len(document.Content) > 0 {
+		add("", commentsOfNode(document.Content[0]))
+		walk(document.Content[0], "")
+	}
+
+	return collected
+}
+
+func isEmptyDocument(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return true
+	}
+
+	content := node.Content[0]
+	return content.Kind == yaml.ScalarNode && content.Tag == "!!null" && content.Value == ""
+}
+
+type conversionOptions struct {
+	allowDuplicates bool
+	rawTimestamps   bool
+	wrapBinary      bool
+	comments        bool
+}
+
+type converter struct {
+	conversionOptions
+
+	// alias targets that are currently being expanded, used to detect cycles
+	expanding map[*yaml.Node]struct{}
+}
+
+func newConverter(options conversionOptions) *converter {
+	return &converter{
+		conversionOptions: options,
+		expanding:         make(map[*yaml.Node]struct{}),
 	}
-	buf.WriteByte('}')
-	return buf.Bytes(), nil
 }
 
-func convertNode(node *yaml.Node) interface{} {
+func (c *converter) convertNode(node *yaml.Node) (interface{}, error) {
 	switch node.Kind {
 	case yaml.MappingNode:
-		entries := make([]mapEntry, 0, len(node.Content)/2)
-		for i := 0; i < len(node.Content); i += 2 {
-			keyNode := node.Content[i]
-			valueNode := node.Content[i+1]
-			entries = append(entries, mapEntry{Key: keyNode.Value, Value: convertNode(valueNode)})
-		}
-		return orderedMap{Entries: entries}
+		return c.convertMapping(node)
 	case yaml.SequenceNode:
 		items := make([]interface{}, len(node.Content))
 		for i, child := range node.Content {
-			items[i] = convertNode(child)
+			item, err := c.convertNode(child)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
 		}
-		return items
+		return items, nil
 	case yaml.ScalarNode:
-		var out interface{}
-		if err := node.Decode(&out); err == nil {
-			return out
-		}
-		return node.Value
+		return c.convertScalar(node)
+	case yaml.AliasNode:
+		return c.convertAlias(node)
 	case yaml.DocumentNode:
 		if len(node.Content) > 0 {
-			return convertNode(node.Content[0])
+			return c.convertNode(node.Content[0])
 		}
-		return nil
+		return nil, nil
 	default:
-		return nil
+		return nil, nil
+	}
+}
+
+func (c *converter) convertScalar(node *yaml.Node) (interface{}, error) {
+	if number, ok := integerScalarAsNumber(node); ok {
+		return number, nil
+	}
+
+	// timestamps get decoded into a time.Time which is then encoded in a
+	// format that can differ from the source, so they're kept as written
+	if node.ShortTag() == "!!timestamp" && !c.rawTimestamps {
+		return node.Value, nil
+	}
+
+	if node.ShortTag() == "!!binary" {
+		return c.convertBinary(node)
+	}
+
+	var out interface{}
+	if err := node.Decode(&out); err != nil {
+		return nil, &sourceError{line: node.Line, column: node.Column, err: unwrapYAMLError(err)}
+	}
+	return out, nil
+}
+
+// Binary values are validated and emitted as canonical base64 without the
+// line breaks that are usually present in the source
+func (c *converter) convertBinary(node *yaml.Node) (interface{}, error) {
+	payload := strings.Join(strings.Fields(node.Value), "")
+
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, nodeErrorf(node, "invalid base64 in !!binary value: %v", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(decoded)
+	if c.wrapBinary {
+		return orderedMap{Entries: []mapEntry{{Key: "$binary", Value: encoded}}}, nil
+	}
+
+	return encoded, nil
+}
+
+var plainIntegerPattern = regexp.MustCompile(`^[-+]?[0-9][0-9_]*$`)
+
+// Integers get emitted verbatim since decoding them can land them in a float64,
+// either directly when they don't fit in 64 bits or later on when read by
+// something that treats all JSON numbers as floats, which loses precision
+func integerScalarAsNumber(node *yaml.Node) (json.Number, bool) {
+	switch node.ShortTag() {
+	case "!!int":
+	case "!!float":
+		// integers which don't fit in 64 bits get resolved as floats by the
+		// yaml package, unless they were explicitly tagged as such
+		if node.Style&yaml.TaggedStyle != 0 || !plainIntegerPattern.MatchString(node.Value) {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	value, ok := new(big.Int).SetString(strings.ReplaceAll(node.Value, "_", ""), 0)
+	if !ok {
+		return "", false
+	}
+
+	return json.Number(value.String()), true
+}
+
+func (c *converter) convertMapping(node *yaml.Node) (interface{}, error) {
+	// explicit keys always take precedence over merged ones, regardless of
+	// whether they're defined before or after the merge key
+	explicitKeys := make(map[string]*yaml.Node, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if isMergeKey(keyNode) {
+			continue
+		}
+
+		if first, ok := explicitKeys[keyNode.Value]; ok && !c.allowDuplicates {
+			return nil, nodeErrorf(
+				keyNode, "duplicate key \"%s\", first defined on line %d",
+				keyNode.Value, first.Line,
+			)
+		}
+
+		explicitKeys[keyNode.Value] = keyNode
+	}
+
+	entries := make([]mapEntry, 0, len(node.Content)/2)
+	merged := make(map[string]struct{})
+	explicitIndexes := make(map[string]int, len(explicitKeys))
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		if isMergeKey(keyNode) {
+			mergedMaps, err := c.convertMergeValue(valueNode)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, mergedMap := range mergedMaps {
+				for _, entry := range mergedMap.Entries {
+					if _, ok := explicitKeys[entry.Key]; ok {
+						continue
+					}
+					if _, ok := merged[entry.Key]; ok {
+						continue
+					}
+					merged[entry.Key] = struct{}{}
+					entries = append(entries, entry)
+				}
+			}
+			continue
+		}
+
+		value, err := c.convertNode(valueNode)
+		if err != nil {
+			return nil, err
+		}
+
+		// only reachable for duplicates when they're allowed, the last one wins
+		// but keeps the position of the first one
+		if index, ok := explicitIndexes[keyNode.Value]; ok {
+			entries[index].Value = value
+			continue
+		}
+
+		explicitIndexes[keyNode.Value] = len(entries)
+		entries = append(entries, mapEntry{Key: keyNode.Value, Value: value})
 	}
+
+	return orderedMap{Entries: entries}, nil
+}
+
+func isMergeKey(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!merge"
+}
+
+// Returns the maps referenced by the value of a merge key in order of
+// precedence, the value can either be a single map or a sequence of maps
+func (c *converter) convertMergeValue(node *yaml.Node) ([]orderedMap, error) {
+	sources := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	}
+
+	maps := make([]orderedMap, 0, len(sources))
+	for _, source := range sources {
+		value, err := c.convertNode(source)
+		if err != nil {
+			return nil, err
+		}
+
+		mergedMap, ok := value.(orderedMap)
+		if !ok {
+			return nil, nodeErrorf(source, "merge key value must be a map or a sequence of maps")
+		}
+
+		maps = append(maps, mergedMap)
+	}
+
+	return maps, nil
+}
+
+func (c *converter) convertAlias(node *yaml.Node) (interface{}, error) {
+	target := node.Alias
+	if target == nil {
+		return nil, nodeErrorf(node, "alias *%s has no target", node.Value)
+	}
+
+	if _, ok := c.expanding[target]; ok {
+		return nil, nodeErrorf(node, "alias *%s references itself", node.Value)
+	}
+
+	c.expanding[target] = struct{}{}
+	defer delete(c.expanding, target)
+
+	return c.convertNode(target)
+}
+
+// An error that points at a location in the source document
+type sourceError struct {
+	line   int
+	column int
+	err    error
+}
+
+func (e *sourceError) Error() string {
+	if e.column > 0 {
+		return fmt.Sprintf("line %d, column %d: %v", e.line, e.column, e.err)
+	}
+
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func (e *sourceError) Unwrap() error {
+	return e.err
+}
+
+func nodeErrorf(node *yaml.Node, format string, args ...any) error {
+	return &sourceError{
+		line:   node.Line,
+		column: node.Column,
+		err:    fmt.Errorf(format, args...),
+	}
+}
+
+var yamlErrorLinePattern = regexp.MustCompile(`^yaml: line (\d+): (.+)$`)
+
+// The yaml package only includes the line of parse errors within the message
+func parseErrorWithLocation(err error) error {
+	matches := yamlErrorLinePattern.FindStringSubmatch(err.Error())
+	if len(matches) != 3 {
+		return err
+	}
+
+	line, convErr := strconv.Atoi(matches[1])
+	if convErr != nil {
+		return err
+	}
+
+	return &sourceError{line: line, err: errors.New(matches[2])}
+}
+
+// Strips the generic prefix from the errors returned when decoding a node
+// since the location gets added separately
+func unwrapYAMLError(err error) error {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		return errors.New(strings.Join(typeErr.Errors, "; "))
+	}
+
+	return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+const sourceSnippetContextLines = 2
+
+func sourceSnippet(data []byte, line int, column int) string {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	first := max(1, line-sourceSnippetContextLines)
+	last := min(len(lines), line+sourceSnippetContextLines)
+	numberWidth := len(strconv.Itoa(last))
+
+	var snippet strings.Builder
+	for i := first; i <= last; i++ {
+		marker := ternary(i == line, ">", " ")
+		fmt.Fprintf(&snippet, "%s %*d | %s\n", marker, numberWidth, i, lines[i-1])
+
+		if i == line && column > 0 {
+			fmt.Fprintf(&snippet, "  %s   %s^\n", strings.Repeat(" ", numberWidth), strings.Repeat(" ", column-1))
+		}
+	}
+
+	return strings.TrimRight(snippet.String(), "\n")
 }
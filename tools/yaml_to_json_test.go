@@ -0,0 +1,241 @@
+//go:build !json_to_yaml && !import_bookmarks
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func convertYAMLStringToJSON(t *testing.T, input string, options conversionOptions) string {
+	t.Helper()
+
+	documents, err := decodeDocuments([]byte(input), options)
+	if err != nil {
+		t.Fatalf("Failed to convert yaml: %v", err)
+	}
+
+	output, err := json.Marshal(documentsOutput(documents))
+	if err != nil {
+		t.Fatalf("Failed to marshal json: %v", err)
+	}
+
+	return string(output)
+}
+
+func TestLargeIntegersKeepTheirPrecision(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 2^53 + 1, the first integer a float64 can't represent
+		{"value: 9007199254740993", `{"value":9007199254740993}`},
+		{"value: -9007199254740993", `{"value":-9007199254740993}`},
+		{"value: 1234567890123456789", `{"value":1234567890123456789}`},
+		{"value: 18446744073709551615", `{"value":18446744073709551615}`},
+		// beyond 64 bits, which the yaml package resolves as a float
+		{"value: 123456789012345678901234567890", `{"value":123456789012345678901234567890}`},
+		{"value: 9_007_199_254_740_993", `{"value":9007199254740993}`},
+		{"value: 0x20000000000001", `{"value":9007199254740993}`},
+		{"value: 1.5", `{"value":1.5}`},
+		{"value: !!float 12", `{"value":12}`},
+		{"value: '9007199254740993'", `{"value":"9007199254740993"}`},
+	}
+
+	for _, test := range tests {
+		output := convertYAMLStringToJSON(t, test.input, conversionOptions{})
+		if output != test.expected {
+			t.Errorf("Converting %q: expected %s, got %s", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestTimestampsArePreservedVerbatim(t *testing.T) {
+	tests := []struct {
+		input    string
+		options  conversionOptions
+		expected string
+	}{
+		{"at: 2024-01-02T15:04:05Z", conversionOptions{}, `{"at":"2024-01-02T15:04:05Z"}`},
+		{"at: 2024-01-02T15:04:05+02:00", conversionOptions{}, `{"at":"2024-01-02T15:04:05+02:00"}`},
+		{"at: 2024-01-02", conversionOptions{}, `{"at":"2024-01-02"}`},
+		{"at: 2024-01-02", conversionOptions{rawTimestamps: true}, `{"at":"2024-01-02T00:00:00Z"}`},
+	}
+
+	for _, test := range tests {
+		output := convertYAMLStringToJSON(t, test.input, test.options)
+		if output != test.expected {
+			t.Errorf("Converting %q: expected %s, got %s", test.input, test.expected, output)
+		}
+	}
+}
+
+func TestSchemaViolationsAreListedWithTheirPaths(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(schemaPath, []byte(`{
+		"type": "object",
+		"required": ["pages"],
+		"additionalProperties": false,
+		"properties": {
+			"port": { "type": "integer", "maximum": 65535 },
+			"pages": { "type": "array", "items": { "$ref": "#/$defs/page" } }
+		},
+		"$defs": {
+			"page": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {
+					"name": { "type": "string", "minLength": 1 },
+					"width": { "enum": ["default", "slim", "wide"] },
+					"pages": { "type": "array", "items": { "$ref": "#/$defs/page" } }
+				}
+			}
+		}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := loadJSONSchema(schemaPath)
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+
+	valid, err := decodeDocuments([]byte("port: 8080\npages:\n  - name: Home\n    width: slim\n"), conversionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if violations := validateDocuments(schema, "glance.yml", valid); len(violations) > 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+
+	invalid, err := decodeDocuments([]byte(`
+port: 99999999999999999999
+theme: dark
+pages:
+  - name: ""
+    width: narrow
+    pages:
+      - width: wide
+`), conversionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"glance.yml: /port: must be at most 65535",
+		"glance.yml: /theme: is not an allowed property",
+		"glance.yml: /pages/0/name: must be at least 1 characters long, got 0",
+		`glance.yml: /pages/0/width: must be one of "default", "slim", "wide"`,
+		`glance.yml: /pages/0/pages/0: missing required property "name"`,
+	}
+
+	if violations := validateDocuments(schema, "glance.yml", invalid); !slices.Equal(violations, expected) {
+		t.Errorf("Expected violations:\n%v\ngot:\n%v", expected, violations)
+	}
+}
+
+func TestCommentsAreKeptByPointer(t *testing.T) {
+	input := `# servers
+server:
+  port: 8080 # the port
+pages:
+  # first page
+  - name: Home
+    a/b: true # escaped
+`
+
+	expected := `{"server":{"port":8080},"pages":[{"name":"Home","a/b":true}],"$comments":{` +
+		`"/server":{"head":"# servers"},` +
+		`"/server/port":{"line":"# the port"},` +
+		`"/pages/0":{"head":"# first page"},` +
+		`"/pages/0/a~1b":{"line":"# escaped"}}}`
+
+	if output := convertYAMLStringToJSON(t, input, conversionOptions{comments: true}); output != expected {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+
+	if output := convertYAMLStringToJSON(t, input, conversionOptions{}); strings.Contains(output, commentsKey) {
+		t.Errorf("Expected the comments to be dropped by default, got %s", output)
+	}
+
+	if _, err := decodeDocuments([]byte("# list\n- a\n"), conversionOptions{comments: true}); err == nil {
+		t.Errorf("Expected an error when keeping the comments of a document that isn't a map")
+	}
+}
+
+func TestCommentsArePutBackInPlace(t *testing.T) {
+	input := `# top
+
+server: # on the key
+  port: 8080 # on the value
+  # after port
+pages:
+  # first page
+  - name: Home # name
+    widgets: [] # none yet
+# bottom
+`
+
+	var original yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &original); err != nil {
+		t.Fatal(err)
+	}
+
+	comments := make(map[string]nodeComments)
+	for _, entry := range collectComments(&original).Entries {
+		comments[entry.Key] = entry.Value.(nodeComments)
+	}
+
+	var stripped yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &stripped); err != nil {
+		t.Fatal(err)
+	}
+
+	var clear func(node *yaml.Node)
+	clear = func(node *yaml.Node) {
+		node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+		for _, child := range node.Content {
+			clear(child)
+		}
+	}
+	clear(&stripped)
+	applyComments(&stripped, comments)
+
+	encode := func(node *yaml.Node) string {
+		var output strings.Builder
+		encoder := yaml.NewEncoder(&output)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(node); err != nil {
+			t.Fatal(err)
+		}
+		return output.String()
+	}
+
+	if expected, got := encode(&original), encode(&stripped); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	document := orderedMap{Entries: []mapEntry{
+		{Key: "port", Value: "8080"},
+		{Key: commentsKey, Value: orderedMap{Entries: []mapEntry{
+			{Key: "/port", Value: orderedMap{Entries: []mapEntry{{Key: "line", Value: "# the port"}}}},
+		}}},
+	}}
+
+	remaining, taken, err := takeComments(document)
+	if err != nil {
+		t.Fatalf("Taking the comments returned an error: %v", err)
+	}
+
+	if len(remaining.Entries) != 1 || taken["/port"].Line != "# the port" {
+		t.Errorf("Expected the comments to be taken out of the document, got %v and %v", remaining, taken)
+	}
+}